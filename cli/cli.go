@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/laertkokona/crud-test/database"
+	"github.com/laertkokona/crud-test/initializers"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/repositories"
+	"github.com/laertkokona/crud-test/routes"
+	"github.com/laertkokona/crud-test/services"
+	"github.com/laertkokona/crud-test/utils"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"time"
+)
+
+// command is a subcommand of the binary
+type command struct {
+	name  string
+	usage string
+	run   func(app *App, args []string) error
+}
+
+// commands are the subcommands of the binary, serve runs when none is given
+var commands = []command{
+	{name: "serve", usage: "start the api server", run: serve},
+	{name: "migrate", usage: "apply, roll back or list the database migrations: up | down [steps] | status", run: migrate},
+	{name: "seed", usage: "create the built-in roles and their permissions, and sample items and trucks", run: seed},
+	{name: "create-admin", usage: "create a SysAdmin user: -username name [-password password] [-first-name name] [-last-name name]", run: createAdmin},
+	{name: "reset-password", usage: "set a new password for a user and sign them out everywhere: -username name [-password password]", run: resetPassword},
+}
+
+// App has the environment variables and the input and output of the commands
+type App struct {
+	Vars *initializers.Vars
+	In   io.Reader
+	Out  io.Writer
+}
+
+// Run runs the subcommand named by the first argument
+func (a *App) Run(args []string) error {
+	if len(args) == 0 {
+		return serve(a, nil)
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(a, args[1:])
+		}
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		a.usage()
+		return nil
+	}
+	a.usage()
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// usage writes the subcommands and what they do
+func (a *App) usage() {
+	fmt.Fprintln(a.Out, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(a.Out, "  %-15s %s\n", c.name, c.usage)
+	}
+}
+
+// serve connects to the database and serves the api until the process is interrupted or terminated
+func serve(a *App, args []string) error {
+	connection := database.Connect(a.Vars)
+	return routes.Serve(routes.SetupRoutes(connection, a.Vars), connection, a.Vars)
+}
+
+// migrate runs a migrate command against the database without checking its schema first
+func migrate(a *App, args []string) error {
+	migrator, err := database.NewMigrator(database.Open(a.Vars), a.Vars.TablePrefix, a.Vars.DefaultCurrency)
+	if err != nil {
+		return err
+	}
+	return migrator.Run(args, a.Out)
+}
+
+// seed creates the built-in roles and, unless turned off, the sample items and trucks
+func seed(a *App, args []string) error {
+	flags := a.flags("seed")
+	samples := flags.Bool("samples", true, "also create the sample items and trucks")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	connection := database.Connect(a.Vars)
+	if err := database.SeedRoles(connection); err != nil {
+		return err
+	}
+	fmt.Fprintln(a.Out, "seeded the roles and their permissions")
+	if !*samples {
+		return nil
+	}
+	if err := database.SeedSamples(connection, a.Vars.DefaultCurrency); err != nil {
+		return err
+	}
+	fmt.Fprintln(a.Out, "seeded the sample items and trucks")
+	return nil
+}
+
+// createAdmin creates a SysAdmin user, so the first one does not have to be inserted by hand
+func createAdmin(a *App, args []string) error {
+	flags := a.flags("create-admin")
+	username := flags.String("username", "", "username of the new user")
+	password := flags.String("password", "", "password of the new user, read from the input when empty")
+	firstName := flags.String("first-name", "", "first name of the new user")
+	lastName := flags.String("last-name", "", "last name of the new user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+	if err := a.readPassword(password); err != nil {
+		return err
+	}
+
+	connection := database.Connect(a.Vars)
+	if _, err := repositories.NewRoleRepo(connection).FindByID(utils.SysAdmin); err != nil {
+		return fmt.Errorf("the SysAdmin role does not exist, run seed first: %w", err)
+	}
+	if _, err := repositories.NewUserRepo(connection).FindByUsername(*username); err == nil {
+		return fmt.Errorf("user %q already exists", *username)
+	}
+	user, err := newUserService(connection, a.Vars).CreateUser(models.User{
+		FirstName: *firstName,
+		LastName:  *lastName,
+		Username:  *username,
+		Password:  *password,
+		RoleID:    utils.SysAdmin,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "created SysAdmin %q with id %d\n", user.Username, user.ID)
+	return nil
+}
+
+// resetPassword sets a new password for a user and revokes the tokens issued with the old one
+func resetPassword(a *App, args []string) error {
+	flags := a.flags("reset-password")
+	username := flags.String("username", "", "username of the user")
+	password := flags.String("password", "", "new password, read from the input when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+	if err := a.readPassword(password); err != nil {
+		return err
+	}
+
+	user, err := newUserService(database.Connect(a.Vars), a.Vars).ResetPassword(*username, *password)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Out, "reset the password of %q and signed them out everywhere\n", user.Username)
+	return nil
+}
+
+// flags returns the flag set of a subcommand that reports its errors instead of exiting
+func (a *App) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(a.Out)
+	return flags
+}
+
+// readPassword reads the password from the first line of the input if it was not given as a flag,
+// so it does not end up in the shell history
+func (a *App) readPassword(password *string) error {
+	if *password != "" {
+		return nil
+	}
+	fmt.Fprint(a.Out, "password: ")
+	scanner := bufio.NewScanner(a.In)
+	if scanner.Scan() {
+		*password = strings.TrimSpace(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if *password == "" {
+		return errors.New("password cannot be empty")
+	}
+	return nil
+}
+
+// newUserService returns the user service over the connection, with the same token revocations as the api
+func newUserService(connection *gorm.DB, vars *initializers.Vars) services.UserService {
+	tokens := vars.TokenConfig()
+	revocations := services.NewRevocationService(repositories.NewRevocationRepo(connection), 30*time.Second, tokens.AccessTTL)
+	return services.NewUserService(repositories.NewUserRepo(connection), repositories.NewRoleRepo(connection), repositories.NewRefreshTokenRepo(connection), revocations, tokens)
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// TestRun_UnknownCommand tests that an unknown command lists the commands and returns an error
+func TestRun_UnknownCommand(t *testing.T) {
+	var out strings.Builder
+	app := &App{In: strings.NewReader(""), Out: &out}
+
+	err := app.Run([]string{"launch"})
+
+	assert.EqualError(t, err, `unknown command "launch"`)
+	assert.Contains(t, out.String(), "create-admin")
+}
+
+// TestRun_Help tests that help lists every command
+func TestRun_Help(t *testing.T) {
+	var out strings.Builder
+	app := &App{In: strings.NewReader(""), Out: &out}
+
+	err := app.Run([]string{"help"})
+
+	assert.NoError(t, err)
+	for _, c := range commands {
+		assert.Contains(t, out.String(), c.name)
+	}
+}
+
+// TestCreateAdmin_MissingUsername tests that create-admin needs a username before connecting to the database
+func TestCreateAdmin_MissingUsername(t *testing.T) {
+	app := &App{In: strings.NewReader(""), Out: &strings.Builder{}}
+
+	err := app.Run([]string{"create-admin", "-password", "Password123!"})
+
+	assert.EqualError(t, err, "-username is required")
+}
+
+// TestResetPassword_EmptyPassword tests that reset-password refuses an empty password read from the input
+func TestResetPassword_EmptyPassword(t *testing.T) {
+	app := &App{In: strings.NewReader("\n"), Out: &strings.Builder{}}
+
+	err := app.Run([]string{"reset-password", "-username", "johndoe"})
+
+	assert.EqualError(t, err, "password cannot be empty")
+}
+
+// TestReadPassword tests that the password is read from the first line of the input when no flag is given
+func TestReadPassword(t *testing.T) {
+	app := &App{In: strings.NewReader("Secret123!\nignored\n"), Out: &strings.Builder{}}
+	password := ""
+
+	err := app.readPassword(&password)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Secret123!", password)
+}
+
+// TestSeed_UnknownFlag tests that seed reports unknown flags instead of exiting
+func TestSeed_UnknownFlag(t *testing.T) {
+	app := &App{In: strings.NewReader(""), Out: &strings.Builder{}}
+
+	err := app.Run([]string{"seed", "-everything"})
+
+	assert.Error(t, err)
+}
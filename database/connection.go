@@ -4,54 +4,86 @@ import (
 	"fmt"
 	"github.com/laertkokona/crud-test/initializers"
 	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/utils"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
+	"log"
 )
 
-// Connect connects to the postgres database by using the environment variables and returns the connection
-func Connect(vars *initializers.Vars) *gorm.DB {
+// Open connects to the postgres database by using the environment variables and returns the connection
+func Open(vars *initializers.Vars) *gorm.DB {
 	// connect to the database using the environment variables
-	// Migrate the models to the database
 	connection, err := gorm.Open(postgres.Open(fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s", vars.PGHost, vars.PGUser, vars.PGPassword, vars.PGDatabase, vars.PGPort)), &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{
 			TablePrefix:   vars.TablePrefix, // schema name
 			SingularTable: false,
-		}})
+		},
+		Logger: logger.Default.LogMode(logLevel(vars.LogLevel)),
+	})
 
 	if err != nil {
 		panic("Could not connect to database")
 	}
 
-	Migrate(connection)
-	return connection
-
-}
-
-// Migrate migrates the models to the database
-func Migrate(connection *gorm.DB) {
-	err := connection.AutoMigrate(&models.Role{})
+	// size the connection pool
+	sqlDB, err := connection.DB()
 	if err != nil {
 		panic(err)
 	}
-	err = connection.AutoMigrate(&models.User{})
-	if err != nil {
-		panic(err)
+	sqlDB.SetMaxOpenConns(vars.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(vars.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(vars.DBConnMaxLifetime)
+	return connection
+}
+
+// logLevel returns the gorm log level for LOG_LEVEL, every query is only logged at debug
+func logLevel(level string) logger.LogLevel {
+	switch level {
+	case "debug":
+		return logger.Info
+	case "error":
+		return logger.Error
+	default:
+		return logger.Warn
 	}
-	err = connection.AutoMigrate(&models.Item{})
+}
+
+// Connect opens the connection and checks the schema against the migrations of the binary.
+// It refuses to start on a schema newer than the binary or with pending migrations, unless ALLOW_PENDING_MIGRATIONS
+// is set, and only seeds the permissions once every migration is applied
+func Connect(vars *initializers.Vars) *gorm.DB {
+	connection := Open(vars)
+
+	migrator, err := NewMigrator(connection, vars.TablePrefix, vars.DefaultCurrency)
 	if err != nil {
 		panic(err)
 	}
-	err = connection.AutoMigrate(&models.Truck{})
+	pending, err := migrator.Pending()
 	if err != nil {
 		panic(err)
 	}
-	err = connection.AutoMigrate(&models.OrderItem{})
-	if err != nil {
-		panic(err)
+	if len(pending) > 0 {
+		if !vars.AllowPendingMigrations {
+			panic(fmt.Sprintf("%d database migrations are pending, run \"migrate up\" to apply them or set ALLOW_PENDING_MIGRATIONS to start anyway", len(pending)))
+		}
+		log.Printf("%d database migrations are pending, run \"migrate up\" to apply them", len(pending))
+		return connection
 	}
-	err = connection.AutoMigrate(&models.Order{})
-	if err != nil {
-		panic(err)
+
+	SeedPermissions(connection)
+	return connection
+
+}
+
+// SeedPermissions creates the permissions the routes check that do not exist yet. The roles are only given their default
+// permissions when they are created, by SeedRoles or by the migration that creates the role permissions, so the
+// permissions taken away from a role are not given back at the next start
+func SeedPermissions(connection *gorm.DB) {
+	for _, name := range utils.Permissions {
+		if err := connection.FirstOrCreate(&models.Permission{}, models.Permission{Name: name}).Error; err != nil {
+			panic(err)
+		}
 	}
 }
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// Ping returns a check that the database answers
+func Ping(connection *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if connection == nil {
+			return errors.New("no database connection")
+		}
+		sqlDB, err := connection.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// MigrationsCurrent returns a check that every migration of the binary is applied and none newer than it. The migrations
+// are loaded once, the check only reads the version of the database, so it runs no DDL and works for a role that can
+// only read
+func MigrationsCurrent(connection *gorm.DB, tablePrefix string, defaultCurrency string) func(ctx context.Context) error {
+	migrator, loadErr := NewMigrator(connection, tablePrefix, defaultCurrency)
+	return func(ctx context.Context) error {
+		if connection == nil {
+			return errors.New("no database connection")
+		}
+		if loadErr != nil {
+			return loadErr
+		}
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if version > migrator.Latest() {
+			return fmt.Errorf("%w: database is at version %d, binary knows up to %d", ErrSchemaTooNew, version, migrator.Latest())
+		}
+		if version < migrator.Latest() {
+			return fmt.Errorf("%d migrations are pending", migrator.Latest()-version)
+		}
+		return nil
+	}
+}
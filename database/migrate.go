@@ -0,0 +1,360 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/laertkokona/crud-test/models"
+	"gorm.io/gorm"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// embeddedMigrations are the numbered up and down migrations shipped with the binary
+//
+//go:embed migrations/*.sql
+var embeddedMigrations embed.FS
+
+// ErrSchemaTooNew is returned when the database has migrations applied that this binary does not know about
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// migrationFile matches migration file names like 0001_initial_schema.up.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change with the SQL to apply and to roll it back
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a known or applied migration and when it was applied, if it was
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies and rolls back migrations and records them in the schema_migrations table
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+	prefix     string
+	currency   string
+}
+
+// NewMigrator returns a Migrator for the migrations embedded in the binary, the amounts they give a currency are in the
+// default currency
+func NewMigrator(db *gorm.DB, tablePrefix string, defaultCurrency string) (*Migrator, error) {
+	migrations, err := LoadMigrations(embeddedMigrations, "migrations", tablePrefix, defaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations, prefix: tablePrefix, currency: defaultCurrency}, nil
+}
+
+// LoadMigrations reads the migrations of a directory, renders their table names with the prefix and their currency with
+// the default currency, and sorts them by version.
+// Every version needs an up and a down file and versions have to count up from 1 without gaps
+func LoadMigrations(fsys fs.FS, dir string, tablePrefix string, defaultCurrency string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	files := map[int]int{}
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sql, err := renderMigration(entry.Name(), string(content), tablePrefix, defaultCurrency)
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+		files[version]++
+		if match[3] == "up" {
+			migration.Up = sql
+		} else {
+			migration.Down = sql
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if files[migration.Version] != 2 {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// renderMigration replaces the table and index names of a migration with the ones gorm uses for the prefix, and its
+// currency with the default one
+func renderMigration(name, content, tablePrefix, defaultCurrency string) (string, error) {
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		// table quotes a table name with the prefix, "go-warehouse." turns items into "go-warehouse"."items"
+		"table": func(table string) string {
+			return quoteName(tablePrefix + table)
+		},
+		// name returns the name gorm gives an index or constraint, like "idx_go-warehouse_items_code"
+		"name": func(kind, suffix string) string {
+			return quoteName(kind + "_" + strings.ReplaceAll(tablePrefix, ".", "_") + suffix)
+		},
+		// currency returns the default currency as a string literal, like 'EUR'
+		"currency": func() string {
+			return "'" + strings.ReplaceAll(defaultCurrency, "'", "''") + "'"
+		},
+		// schema returns the quoted schema of the prefix, if it has one
+		"schema": func() string {
+			if i := strings.LastIndex(tablePrefix, "."); i > 0 {
+				return quoteName(tablePrefix[:i])
+			}
+			return ""
+		},
+	}).Parse(content)
+	if err != nil {
+		return "", err
+	}
+	var sql strings.Builder
+	if err := tmpl.Execute(&sql, nil); err != nil {
+		return "", err
+	}
+	return sql.String(), nil
+}
+
+// quoteName quotes every dot separated part of a name
+func quoteName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// Latest returns the version of the newest migration the binary knows
+func (m *Migrator) Latest() int {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Version returns the newest applied migration, 0 when none is. It only reads the schema_migrations table, which has to
+// exist, migrations are applied in order so every older one is applied as well
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var version int
+	err := m.DB.WithContext(ctx).Raw("SELECT COALESCE(max(version), 0) FROM " + quoteName(m.prefix+"schema_migrations")).Scan(&version).Error
+	return version, err
+}
+
+// Up applies every pending migration in order, each in its own transaction, and returns the applied ones
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkNotNewer(applied); err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the given number of the newest applied migrations and returns the rolled back ones
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkNotNewer(applied); err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(m.Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.Migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("rollback of migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status returns every known migration and every applied one the binary does not know, ordered by version
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		if version > m.Latest() {
+			record := record
+			statuses = append(statuses, MigrationStatus{Version: version, Name: record.Name, AppliedAt: &record.AppliedAt})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending returns the migrations that are not applied yet, or ErrSchemaTooNew if the database is ahead of the binary
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkNotNewer(applied); err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// checkNotNewer returns ErrSchemaTooNew if a migration newer than the binary was applied
+func (m *Migrator) checkNotNewer(applied map[int]models.SchemaMigration) error {
+	for version := range applied {
+		if version > m.Latest() {
+			return fmt.Errorf("%w: database is at version %d, binary knows up to %d", ErrSchemaTooNew, version, m.Latest())
+		}
+	}
+	return nil
+}
+
+// applied creates the schema_migrations table if needed and returns the applied migrations by version
+func (m *Migrator) applied() (map[int]models.SchemaMigration, error) {
+	create, err := renderMigration("schema_migrations", `{{if schema}}CREATE SCHEMA IF NOT EXISTS {{schema}};{{end}}
+CREATE TABLE IF NOT EXISTS {{table "schema_migrations"}} (
+    "version" bigint PRIMARY KEY,
+    "name" text NOT NULL,
+    "applied_at" timestamptz NOT NULL
+);`, m.prefix, m.currency)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.DB.Exec(create).Error; err != nil {
+		return nil, err
+	}
+	var records []models.SchemaMigration
+	if err := m.DB.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]models.SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// Run runs a migrate command, "up", "down [steps]" or "status", and writes what it did to out
+func (m *Migrator) Run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [steps] | status")
+	}
+	switch args[0] {
+	case "up":
+		done, err := m.Up()
+		for _, migration := range done {
+			fmt.Fprintf(out, "applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		done, err := m.Down(steps)
+		for _, migration := range done {
+			fmt.Fprintf(out, "rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Version > m.Latest() {
+				applied += ", unknown to this binary"
+			}
+			fmt.Fprintf(out, "%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"fmt"
+	"github.com/laertkokona/crud-test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestLoadMigrations_Embedded tests that the migrations shipped with the binary load and render
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := LoadMigrations(embeddedMigrations, "migrations", "go-warehouse.", "EUR")
+
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "initial_schema", migrations[0].Name)
+	assert.Contains(t, migrations[0].Up, `CREATE TABLE IF NOT EXISTS "go-warehouse"."items"`)
+	assert.Contains(t, migrations[0].Up, `"idx_go-warehouse_items_code"`)
+	assert.Contains(t, migrations[0].Up, `"idx_go-warehouse_shipments_truck_departure"`)
+	assert.Contains(t, migrations[0].Up, `ALTER TABLE "go-warehouse"."orders" ADD COLUMN IF NOT EXISTS "status"`)
+	assert.Contains(t, migrations[0].Down, `DROP TABLE IF EXISTS "go-warehouse"."items"`)
+	assert.NotContains(t, migrations[0].Up, "{{")
+}
+
+// TestLoadMigrations_DefaultPermissions tests that the initial migration gives the built-in roles the default permissions
+// of utils, the same ones the seed command gives the roles it creates
+func TestLoadMigrations_DefaultPermissions(t *testing.T) {
+	migrations, err := LoadMigrations(embeddedMigrations, "migrations", "go-warehouse.", "EUR")
+
+	require.NoError(t, err)
+	for _, name := range utils.Permissions {
+		assert.Contains(t, migrations[0].Up, fmt.Sprintf("('%s')", name))
+	}
+	for _, role := range []int{utils.User, utils.Admin, utils.SysAdmin} {
+		names := utils.DefaultRolePermissions(role)
+		assert.Equal(t, len(names), strings.Count(migrations[0].Up, fmt.Sprintf("(%d, '", role)), "role %d", role)
+		for _, name := range names {
+			assert.Contains(t, migrations[0].Up, fmt.Sprintf("(%d, '%s')", role, name))
+		}
+	}
+}
+
+// TestLoadMigrations_Sorted tests that migrations are paired and sorted by version
+func TestLoadMigrations_Sorted(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_second.up.sql":   {Data: []byte(`ALTER TABLE {{table "items"}} ADD "x" text;`)},
+		"m/0002_second.down.sql": {Data: []byte(`ALTER TABLE {{table "items"}} DROP "x";`)},
+		"m/0001_first.up.sql":    {Data: []byte(`{{if schema}}CREATE SCHEMA {{schema}};{{end}}`)},
+		"m/0001_first.down.sql":  {Data: []byte(`SELECT 1;`)},
+		"m/README.md":            {Data: []byte(`not a migration`)},
+	}
+
+	migrations, err := LoadMigrations(fsys, "m", "", "EUR")
+
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, "first", migrations[0].Name)
+	assert.Equal(t, "", migrations[0].Up)
+	assert.Equal(t, `ALTER TABLE "items" ADD "x" text;`, migrations[1].Up)
+}
+
+// TestLoadMigrations_MissingDown tests that a migration without a down file is rejected
+func TestLoadMigrations_MissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0001_first.up.sql": {Data: []byte(`SELECT 1;`)},
+	}
+
+	_, err := LoadMigrations(fsys, "m", "", "EUR")
+
+	assert.EqualError(t, err, "migration 1_first needs both an up and a down file")
+}
+
+// TestLoadMigrations_Gap tests that a missing version is rejected
+func TestLoadMigrations_Gap(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0001_first.up.sql":   {Data: []byte(`SELECT 1;`)},
+		"m/0001_first.down.sql": {Data: []byte(`SELECT 1;`)},
+		"m/0003_third.up.sql":   {Data: []byte(`SELECT 1;`)},
+		"m/0003_third.down.sql": {Data: []byte(`SELECT 1;`)},
+	}
+
+	_, err := LoadMigrations(fsys, "m", "", "EUR")
+
+	assert.EqualError(t, err, "migration 2 is missing")
+}
+
+// TestRenderMigration tests the table, name, schema and currency template functions
+func TestRenderMigration(t *testing.T) {
+	sql, err := renderMigration("test", `{{schema}} {{table "orders"}} {{name "fk" "orders_user"}} {{currency}}`, "go-warehouse.", "USD")
+
+	require.NoError(t, err)
+	assert.Equal(t, `"go-warehouse" "go-warehouse"."orders" "fk_go-warehouse_orders_user" 'USD'`, sql)
+}
+
+// TestMigratorRun_Usage tests that the migrate command rejects unknown arguments before touching the database
+func TestMigratorRun_Usage(t *testing.T) {
+	migrator := &Migrator{}
+	var out strings.Builder
+
+	assert.Error(t, migrator.Run(nil, &out))
+	assert.EqualError(t, migrator.Run([]string{"sideways"}, &out), `unknown migrate command "sideways"`)
+	assert.EqualError(t, migrator.Run([]string{"down", "zero"}, &out), `invalid number of steps "zero"`)
+	assert.Empty(t, out.String())
+}
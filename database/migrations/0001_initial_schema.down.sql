@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS {{table "refresh_tokens"}};
+DROP TABLE IF EXISTS {{table "token_cutoffs"}};
+DROP TABLE IF EXISTS {{table "revoked_tokens"}};
+DROP TABLE IF EXISTS {{table "shipment_stops"}};
+DROP TABLE IF EXISTS {{table "shipments"}};
+DROP TABLE IF EXISTS {{table "stock_movements"}};
+DROP TABLE IF EXISTS {{table "order_status_changes"}};
+DROP TABLE IF EXISTS {{table "order_items"}};
+DROP TABLE IF EXISTS {{table "orders"}};
+DROP TABLE IF EXISTS {{table "trucks"}};
+DROP TABLE IF EXISTS {{table "items"}};
+DROP TABLE IF EXISTS "go-warehouse"."users";
+DROP TABLE IF EXISTS {{table "role_permissions"}};
+DROP TABLE IF EXISTS "go-warehouse"."roles";
+DROP TABLE IF EXISTS {{table "permissions"}};
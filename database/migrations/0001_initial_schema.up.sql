@@ -0,0 +1,275 @@
+-- Baseline of the schema that AutoMigrate used to create.
+-- Every statement is idempotent, so it can be applied to a database created by AutoMigrate: the tables that are there are
+-- kept, the columns added to them since are added and the tables added since are created.
+-- roles and users keep the fixed "go-warehouse" schema of their TableName, every other table uses TABLE_PREFIX.
+
+CREATE SCHEMA IF NOT EXISTS "go-warehouse";
+{{if schema}}CREATE SCHEMA IF NOT EXISTS {{schema}};{{end}}
+
+CREATE TABLE IF NOT EXISTS {{table "permissions"}} (
+    "id" bigserial,
+    "name" text NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx" "permissions_name"}} ON {{table "permissions"}} ("name");
+
+CREATE TABLE IF NOT EXISTS "go-warehouse"."roles" (
+    "id" bigserial,
+    "name" text,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS {{table "role_permissions"}} (
+    "role_id" bigint,
+    "permission_id" bigint,
+    PRIMARY KEY ("role_id","permission_id"),
+    CONSTRAINT {{name "fk" "role_permissions_role"}} FOREIGN KEY ("role_id") REFERENCES "go-warehouse"."roles"("id") ON DELETE CASCADE,
+    CONSTRAINT {{name "fk" "role_permissions_permission"}} FOREIGN KEY ("permission_id") REFERENCES {{table "permissions"}}("id") ON DELETE CASCADE
+);
+
+-- the built-in roles of a database AutoMigrate created are given their default permissions, the ones of
+-- utils.DefaultRolePermissions, as the seed command gives them to the roles it creates. Only while no role has any
+-- permission yet, so the permissions taken away from a role since are not given back.
+INSERT INTO {{table "permissions"}} ("name") VALUES
+    ('users:read'),
+    ('users:write'),
+    ('roles:read'),
+    ('roles:write'),
+    ('items:read'),
+    ('items:write'),
+    ('stock:write'),
+    ('trucks:read'),
+    ('trucks:write'),
+    ('planning:write'),
+    ('shipments:read'),
+    ('shipments:write'),
+    ('orders:read'),
+    ('orders:write'),
+    ('orders:all'),
+    ('orders:approve'),
+    ('orders:fulfil')
+ON CONFLICT ("name") DO NOTHING;
+
+INSERT INTO {{table "role_permissions"}} ("role_id", "permission_id")
+SELECT r."id", p."id"
+FROM (VALUES
+    (1, 'items:read'),
+    (1, 'orders:read'),
+    (1, 'orders:write'),
+    (2, 'items:read'),
+    (2, 'items:write'),
+    (2, 'stock:write'),
+    (2, 'trucks:read'),
+    (2, 'trucks:write'),
+    (2, 'planning:write'),
+    (2, 'shipments:read'),
+    (2, 'shipments:write'),
+    (2, 'orders:read'),
+    (2, 'orders:all'),
+    (2, 'orders:approve'),
+    (2, 'orders:fulfil'),
+    (3, 'users:read'),
+    (3, 'users:write'),
+    (3, 'roles:read'),
+    (3, 'roles:write'),
+    (3, 'items:read'),
+    (3, 'items:write'),
+    (3, 'stock:write'),
+    (3, 'trucks:read'),
+    (3, 'trucks:write'),
+    (3, 'planning:write'),
+    (3, 'shipments:read'),
+    (3, 'shipments:write'),
+    (3, 'orders:read'),
+    (3, 'orders:write'),
+    (3, 'orders:all'),
+    (3, 'orders:approve'),
+    (3, 'orders:fulfil')
+) AS d("role_id", "name")
+JOIN "go-warehouse"."roles" AS r ON r."id" = d."role_id"
+JOIN {{table "permissions"}} AS p ON p."name" = d."name"
+WHERE NOT EXISTS (SELECT 1 FROM {{table "role_permissions"}})
+ON CONFLICT ("role_id", "permission_id") DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS "go-warehouse"."users" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "first_name" text,
+    "last_name" text,
+    "username" text,
+    "password" text,
+    "role_id" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_go-warehouse_roles_users" FOREIGN KEY ("role_id") REFERENCES "go-warehouse"."roles"("id") ON DELETE SET NULL ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_go-warehouse_users_username" ON "go-warehouse"."users" ("username");
+CREATE INDEX IF NOT EXISTS "idx_go-warehouse_users_deleted_at" ON "go-warehouse"."users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS {{table "items"}} (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" text,
+    "description" text,
+    "code" text NOT NULL,
+    "total_quantity" bigint,
+    "available_quantity" bigint,
+    "price" decimal,
+    "category" text,
+    "unit_weight" decimal,
+    "unit_volume" decimal,
+    PRIMARY KEY ("id")
+);
+ALTER TABLE {{table "items"}} ADD COLUMN IF NOT EXISTS "unit_weight" decimal;
+ALTER TABLE {{table "items"}} ADD COLUMN IF NOT EXISTS "unit_volume" decimal;
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx" "items_code"}} ON {{table "items"}} ("code");
+CREATE INDEX IF NOT EXISTS {{name "idx" "items_deleted_at"}} ON {{table "items"}} ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS {{table "trucks"}} (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "chassis_number" text,
+    "license_plate" text,
+    "max_weight" decimal,
+    "max_volume" decimal,
+    "out_of_service" boolean,
+    PRIMARY KEY ("id")
+);
+ALTER TABLE {{table "trucks"}} ADD COLUMN IF NOT EXISTS "max_weight" decimal;
+ALTER TABLE {{table "trucks"}} ADD COLUMN IF NOT EXISTS "max_volume" decimal;
+ALTER TABLE {{table "trucks"}} ADD COLUMN IF NOT EXISTS "out_of_service" boolean;
+CREATE INDEX IF NOT EXISTS {{name "idx" "trucks_deleted_at"}} ON {{table "trucks"}} ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS {{table "order_items"}} (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "item_id" bigint,
+    "order_id" bigint,
+    "quantity" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS {{name "idx" "order_items_deleted_at"}} ON {{table "order_items"}} ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS {{table "orders"}} (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "code" text NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'draft',
+    "submitted_date" timestamptz,
+    "deadline_date" timestamptz,
+    "user_id" bigint,
+    PRIMARY KEY ("id")
+);
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "status" varchar(20) NOT NULL DEFAULT 'draft';
+CREATE INDEX IF NOT EXISTS {{name "idx" "orders_status"}} ON {{table "orders"}} ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx" "orders_code"}} ON {{table "orders"}} ("code");
+CREATE INDEX IF NOT EXISTS {{name "idx" "orders_deleted_at"}} ON {{table "orders"}} ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS {{table "order_status_changes"}} (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "order_id" bigint NOT NULL,
+    "from_status" varchar(20),
+    "to_status" varchar(20) NOT NULL,
+    "changed_by" bigint,
+    "changed_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT {{name "fk" "orders_status_history"}} FOREIGN KEY ("order_id") REFERENCES {{table "orders"}}("id")
+);
+CREATE INDEX IF NOT EXISTS {{name "idx" "order_status_changes_order_id"}} ON {{table "order_status_changes"}} ("order_id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "order_status_changes_deleted_at"}} ON {{table "order_status_changes"}} ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS {{table "stock_movements"}} (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "item_id" bigint NOT NULL,
+    "type" varchar(20) NOT NULL,
+    "quantity" bigint,
+    "reason_code" varchar(30),
+    "note" text,
+    "order_id" bigint,
+    "user_id" bigint,
+    "total_delta" bigint,
+    "available_delta" bigint,
+    "total_after" bigint,
+    "available_after" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS {{name "idx" "stock_movements_order_id"}} ON {{table "stock_movements"}} ("order_id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "stock_movements_item_id"}} ON {{table "stock_movements"}} ("item_id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "stock_movements_deleted_at"}} ON {{table "stock_movements"}} ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS {{table "shipments"}} (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "truck_id" bigint NOT NULL,
+    "departure_date" date NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx" "shipments_truck_departure"}} ON {{table "shipments"}} ("truck_id","departure_date") WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS {{name "idx" "shipments_deleted_at"}} ON {{table "shipments"}} ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS {{table "shipment_stops"}} (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "shipment_id" bigint NOT NULL,
+    "order_id" bigint NOT NULL,
+    "sequence" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT {{name "fk" "shipments_stops"}} FOREIGN KEY ("shipment_id") REFERENCES {{table "shipments"}}("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx" "shipment_stops_order_id"}} ON {{table "shipment_stops"}} ("order_id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "shipment_stops_shipment_id"}} ON {{table "shipment_stops"}} ("shipment_id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "shipment_stops_deleted_at"}} ON {{table "shipment_stops"}} ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS {{table "revoked_tokens"}} (
+    "id" bigserial,
+    "token_id" text NOT NULL,
+    "user_id" bigint,
+    "expires_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS {{name "idx" "revoked_tokens_expires_at"}} ON {{table "revoked_tokens"}} ("expires_at");
+CREATE INDEX IF NOT EXISTS {{name "idx" "revoked_tokens_user_id"}} ON {{table "revoked_tokens"}} ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx" "revoked_tokens_token_id"}} ON {{table "revoked_tokens"}} ("token_id");
+
+CREATE TABLE IF NOT EXISTS {{table "token_cutoffs"}} (
+    "user_id" bigint,
+    "revoked_before" timestamptz NOT NULL,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("user_id")
+);
+CREATE INDEX IF NOT EXISTS {{name "idx" "token_cutoffs_revoked_before"}} ON {{table "token_cutoffs"}} ("revoked_before");
+
+CREATE TABLE IF NOT EXISTS {{table "refresh_tokens"}} (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "family_id" text NOT NULL,
+    "token_hash" text NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx" "refresh_tokens_token_hash"}} ON {{table "refresh_tokens"}} ("token_hash");
+CREATE INDEX IF NOT EXISTS {{name "idx" "refresh_tokens_family_id"}} ON {{table "refresh_tokens"}} ("family_id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "refresh_tokens_user_id"}} ON {{table "refresh_tokens"}} ("user_id");
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "go-warehouse"."idx_go-warehouse_users_created_at_id";
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "stock_movements_item_id_created_at_id"}};
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "shipments_created_at_id"}};
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "orders_user_id_created_at_id"}};
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "orders_created_at_id"}};
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "trucks_created_at_id"}};
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "items_created_at_id"}};
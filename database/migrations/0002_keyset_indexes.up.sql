@@ -0,0 +1,9 @@
+-- Indexes on the (created_at, id) key the list endpoints are ordered and walked by with a cursor.
+
+CREATE INDEX IF NOT EXISTS {{name "idx" "items_created_at_id"}} ON {{table "items"}} ("created_at","id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "trucks_created_at_id"}} ON {{table "trucks"}} ("created_at","id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "orders_created_at_id"}} ON {{table "orders"}} ("created_at","id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "orders_user_id_created_at_id"}} ON {{table "orders"}} ("user_id","created_at","id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "shipments_created_at_id"}} ON {{table "shipments"}} ("created_at","id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "stock_movements_item_id_created_at_id"}} ON {{table "stock_movements"}} ("item_id","created_at","id");
+CREATE INDEX IF NOT EXISTS "idx_go-warehouse_users_created_at_id" ON "go-warehouse"."users" ("created_at","id");
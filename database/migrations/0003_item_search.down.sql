@@ -0,0 +1,6 @@
+-- pg_trgm is left installed, other database objects may use it.
+
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "items_code_trgm"}};
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "items_name_trgm"}};
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "items_search"}};
+ALTER TABLE {{table "items"}} DROP COLUMN IF EXISTS "search";
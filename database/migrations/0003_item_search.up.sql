@@ -0,0 +1,16 @@
+-- Full-text search over items. The search column is kept up to date by PostgreSQL from the name, code, category and
+-- description, weighted in that order. The simple configuration leaves words unstemmed, so a prefix of a name or code
+-- typed into a picker matches. pg_trgm adds the similarity of names and codes for the words that are misspelled.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE {{table "items"}} ADD COLUMN IF NOT EXISTS "search" tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce("name", '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce("code", '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce("category", '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce("description", '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS {{name "idx" "items_search"}} ON {{table "items"}} USING GIN ("search");
+CREATE INDEX IF NOT EXISTS {{name "idx" "items_name_trgm"}} ON {{table "items"}} USING GIN ("name" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS {{name "idx" "items_code_trgm"}} ON {{table "items"}} USING GIN ("code" gin_trgm_ops);
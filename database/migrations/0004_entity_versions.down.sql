@@ -0,0 +1,6 @@
+ALTER TABLE {{table "shipments"}} DROP COLUMN IF EXISTS "version";
+ALTER TABLE {{table "orders"}} DROP COLUMN IF EXISTS "version";
+ALTER TABLE {{table "trucks"}} DROP COLUMN IF EXISTS "version";
+ALTER TABLE {{table "items"}} DROP COLUMN IF EXISTS "version";
+ALTER TABLE "go-warehouse"."users" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "go-warehouse"."roles" DROP COLUMN IF EXISTS "version";
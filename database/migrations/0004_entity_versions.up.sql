@@ -0,0 +1,9 @@
+-- Versions of the entities that can be edited. Every change moves the row to the next version, an update or delete
+-- only goes through while the row is still at the version it was read at, and the version is the ETag of the entity.
+
+ALTER TABLE "go-warehouse"."roles" ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "go-warehouse"."users" ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE {{table "items"}} ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE {{table "trucks"}} ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE {{table "shipments"}} ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
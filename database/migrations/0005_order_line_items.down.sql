@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "order_items_order_id"}};
+DROP INDEX IF EXISTS {{if schema}}{{schema}}.{{end}}{{name "idx" "order_items_item_id"}};
+-- the constraint of the order lines to their order goes back to the one AutoMigrate gave them
+ALTER TABLE {{table "order_items"}} DROP CONSTRAINT IF EXISTS {{name "fk" "orders_order_items"}};
+ALTER TABLE {{table "order_items"}} ADD CONSTRAINT {{name "fk" "orders_order_items"}}
+    FOREIGN KEY ("order_id") REFERENCES {{table "orders"}}("id") NOT VALID;
+ALTER TABLE {{table "order_items"}} DROP CONSTRAINT IF EXISTS {{name "fk" "order_items_item"}};
+ALTER TABLE {{table "order_items"}} DROP COLUMN IF EXISTS "line_total";
+ALTER TABLE {{table "order_items"}} DROP COLUMN IF EXISTS "unit_price";
@@ -0,0 +1,23 @@
+-- Order lines reference the item and the order they belong to and keep the unit price of the item when it was ordered,
+-- so a later change of the price does not change the total of the orders already placed. The lines stored before are
+-- priced at the current price of their item. Lines of items or orders that were removed before the constraints existed
+-- are left as they are, the constraints are not validated for the rows already there, only for the ones written later.
+-- AutoMigrate already gave the order lines a constraint to their order under the same name, it is replaced.
+
+ALTER TABLE {{table "order_items"}} ADD COLUMN IF NOT EXISTS "unit_price" decimal NOT NULL DEFAULT 0;
+ALTER TABLE {{table "order_items"}} ADD COLUMN IF NOT EXISTS "line_total" decimal NOT NULL DEFAULT 0;
+
+UPDATE {{table "order_items"}} AS l
+SET "unit_price" = coalesce(i."price", 0), "line_total" = coalesce(i."price", 0) * coalesce(l."quantity", 0)
+FROM {{table "items"}} AS i
+WHERE i."id" = l."item_id";
+
+ALTER TABLE {{table "order_items"}} DROP CONSTRAINT IF EXISTS {{name "fk" "order_items_item"}};
+ALTER TABLE {{table "order_items"}} ADD CONSTRAINT {{name "fk" "order_items_item"}}
+    FOREIGN KEY ("item_id") REFERENCES {{table "items"}}("id") ON UPDATE CASCADE ON DELETE RESTRICT NOT VALID;
+ALTER TABLE {{table "order_items"}} DROP CONSTRAINT IF EXISTS {{name "fk" "orders_order_items"}};
+ALTER TABLE {{table "order_items"}} ADD CONSTRAINT {{name "fk" "orders_order_items"}}
+    FOREIGN KEY ("order_id") REFERENCES {{table "orders"}}("id") ON UPDATE CASCADE ON DELETE CASCADE NOT VALID;
+
+CREATE INDEX IF NOT EXISTS {{name "idx" "order_items_item_id"}} ON {{table "order_items"}} ("item_id");
+CREATE INDEX IF NOT EXISTS {{name "idx" "order_items_order_id"}} ON {{table "order_items"}} ("order_id");
@@ -0,0 +1,9 @@
+ALTER TABLE {{table "orders"}} DROP COLUMN IF EXISTS "grand_total";
+ALTER TABLE {{table "orders"}} DROP COLUMN IF EXISTS "tax_amount";
+ALTER TABLE {{table "orders"}} DROP COLUMN IF EXISTS "discount_amount";
+ALTER TABLE {{table "orders"}} DROP COLUMN IF EXISTS "discount_percent";
+ALTER TABLE {{table "orders"}} DROP COLUMN IF EXISTS "subtotal";
+ALTER TABLE {{table "order_items"}} DROP COLUMN IF EXISTS "tax_amount";
+ALTER TABLE {{table "order_items"}} DROP COLUMN IF EXISTS "tax_percent";
+ALTER TABLE {{table "order_items"}} DROP COLUMN IF EXISTS "discount_amount";
+ALTER TABLE {{table "order_items"}} DROP COLUMN IF EXISTS "discount_percent";
@@ -0,0 +1,24 @@
+-- Totals of the orders and the discounts and taxes of their lines. The lines keep the tax percent of the category of
+-- their item when it was ordered, like their unit price. Orders placed before had no discounts and were not taxed, so
+-- their subtotal and grand total are the sum of their lines.
+
+ALTER TABLE {{table "order_items"}} ADD COLUMN IF NOT EXISTS "discount_percent" decimal NOT NULL DEFAULT 0;
+ALTER TABLE {{table "order_items"}} ADD COLUMN IF NOT EXISTS "discount_amount" decimal NOT NULL DEFAULT 0;
+ALTER TABLE {{table "order_items"}} ADD COLUMN IF NOT EXISTS "tax_percent" decimal NOT NULL DEFAULT 0;
+ALTER TABLE {{table "order_items"}} ADD COLUMN IF NOT EXISTS "tax_amount" decimal NOT NULL DEFAULT 0;
+
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "subtotal" decimal NOT NULL DEFAULT 0;
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "discount_percent" decimal NOT NULL DEFAULT 0;
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "discount_amount" decimal NOT NULL DEFAULT 0;
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "tax_amount" decimal NOT NULL DEFAULT 0;
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "grand_total" decimal NOT NULL DEFAULT 0;
+
+UPDATE {{table "orders"}} AS o
+SET "subtotal" = l."total", "grand_total" = l."total"
+FROM (
+    SELECT "order_id", sum("line_total") AS "total"
+    FROM {{table "order_items"}}
+    WHERE "deleted_at" IS NULL
+    GROUP BY "order_id"
+) AS l
+WHERE l."order_id" = o."id";
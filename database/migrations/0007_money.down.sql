@@ -0,0 +1,28 @@
+ALTER TABLE {{table "orders"}} DROP COLUMN IF EXISTS "grand_total_currency";
+ALTER TABLE {{table "orders"}} ALTER COLUMN "grand_total_amount" TYPE decimal;
+ALTER TABLE {{table "orders"}} RENAME COLUMN "grand_total_amount" TO "grand_total";
+ALTER TABLE {{table "orders"}} DROP COLUMN IF EXISTS "tax_amount_currency";
+ALTER TABLE {{table "orders"}} ALTER COLUMN "tax_amount_amount" TYPE decimal;
+ALTER TABLE {{table "orders"}} RENAME COLUMN "tax_amount_amount" TO "tax_amount";
+ALTER TABLE {{table "orders"}} DROP COLUMN IF EXISTS "discount_amount_currency";
+ALTER TABLE {{table "orders"}} ALTER COLUMN "discount_amount_amount" TYPE decimal;
+ALTER TABLE {{table "orders"}} RENAME COLUMN "discount_amount_amount" TO "discount_amount";
+ALTER TABLE {{table "orders"}} DROP COLUMN IF EXISTS "subtotal_currency";
+ALTER TABLE {{table "orders"}} ALTER COLUMN "subtotal_amount" TYPE decimal;
+ALTER TABLE {{table "orders"}} RENAME COLUMN "subtotal_amount" TO "subtotal";
+ALTER TABLE {{table "order_items"}} DROP COLUMN IF EXISTS "tax_amount_currency";
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "tax_amount_amount" TYPE decimal;
+ALTER TABLE {{table "order_items"}} RENAME COLUMN "tax_amount_amount" TO "tax_amount";
+ALTER TABLE {{table "order_items"}} DROP COLUMN IF EXISTS "line_total_currency";
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "line_total_amount" TYPE decimal;
+ALTER TABLE {{table "order_items"}} RENAME COLUMN "line_total_amount" TO "line_total";
+ALTER TABLE {{table "order_items"}} DROP COLUMN IF EXISTS "discount_amount_currency";
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "discount_amount_amount" TYPE decimal;
+ALTER TABLE {{table "order_items"}} RENAME COLUMN "discount_amount_amount" TO "discount_amount";
+ALTER TABLE {{table "order_items"}} DROP COLUMN IF EXISTS "unit_price_currency";
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "unit_price_amount" TYPE decimal;
+ALTER TABLE {{table "order_items"}} RENAME COLUMN "unit_price_amount" TO "unit_price";
+ALTER TABLE {{table "items"}} DROP COLUMN IF EXISTS "price_currency";
+ALTER TABLE {{table "items"}} ALTER COLUMN "price_amount" TYPE decimal;
+ALTER TABLE {{table "items"}} ALTER COLUMN "price_amount" DROP NOT NULL;
+ALTER TABLE {{table "items"}} RENAME COLUMN "price_amount" TO "price";
@@ -0,0 +1,58 @@
+-- Money is an exact amount with the currency it is in. Every amount becomes a numeric with four decimal places, which
+-- keeps the fractions of a cent of unit prices, next to a column of its currency code. The amounts stored before had no
+-- currency and were all in the DEFAULT_CURRENCY, the one new items are priced in when their price has none. The zero
+-- amounts are left without one so they can be added to any currency.
+
+ALTER TABLE {{table "items"}} RENAME COLUMN "price" TO "price_amount";
+ALTER TABLE {{table "items"}} ALTER COLUMN "price_amount" TYPE numeric(19,4) USING coalesce("price_amount", 0);
+ALTER TABLE {{table "items"}} ALTER COLUMN "price_amount" SET NOT NULL;
+ALTER TABLE {{table "items"}} ADD COLUMN IF NOT EXISTS "price_currency" varchar(3) NOT NULL DEFAULT '';
+UPDATE {{table "items"}} SET "price_currency" = {{currency}} WHERE "price_amount" <> 0;
+
+ALTER TABLE {{table "order_items"}} RENAME COLUMN "unit_price" TO "unit_price_amount";
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "unit_price_amount" TYPE numeric(19,4) USING coalesce("unit_price_amount", 0);
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "unit_price_amount" SET NOT NULL;
+ALTER TABLE {{table "order_items"}} ADD COLUMN IF NOT EXISTS "unit_price_currency" varchar(3) NOT NULL DEFAULT '';
+UPDATE {{table "order_items"}} SET "unit_price_currency" = {{currency}} WHERE "unit_price_amount" <> 0;
+
+ALTER TABLE {{table "order_items"}} RENAME COLUMN "discount_amount" TO "discount_amount_amount";
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "discount_amount_amount" TYPE numeric(19,4) USING coalesce("discount_amount_amount", 0);
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "discount_amount_amount" SET NOT NULL;
+ALTER TABLE {{table "order_items"}} ADD COLUMN IF NOT EXISTS "discount_amount_currency" varchar(3) NOT NULL DEFAULT '';
+UPDATE {{table "order_items"}} SET "discount_amount_currency" = {{currency}} WHERE "discount_amount_amount" <> 0;
+
+ALTER TABLE {{table "order_items"}} RENAME COLUMN "line_total" TO "line_total_amount";
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "line_total_amount" TYPE numeric(19,4) USING coalesce("line_total_amount", 0);
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "line_total_amount" SET NOT NULL;
+ALTER TABLE {{table "order_items"}} ADD COLUMN IF NOT EXISTS "line_total_currency" varchar(3) NOT NULL DEFAULT '';
+UPDATE {{table "order_items"}} SET "line_total_currency" = {{currency}} WHERE "line_total_amount" <> 0;
+
+ALTER TABLE {{table "order_items"}} RENAME COLUMN "tax_amount" TO "tax_amount_amount";
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "tax_amount_amount" TYPE numeric(19,4) USING coalesce("tax_amount_amount", 0);
+ALTER TABLE {{table "order_items"}} ALTER COLUMN "tax_amount_amount" SET NOT NULL;
+ALTER TABLE {{table "order_items"}} ADD COLUMN IF NOT EXISTS "tax_amount_currency" varchar(3) NOT NULL DEFAULT '';
+UPDATE {{table "order_items"}} SET "tax_amount_currency" = {{currency}} WHERE "tax_amount_amount" <> 0;
+
+ALTER TABLE {{table "orders"}} RENAME COLUMN "subtotal" TO "subtotal_amount";
+ALTER TABLE {{table "orders"}} ALTER COLUMN "subtotal_amount" TYPE numeric(19,4) USING coalesce("subtotal_amount", 0);
+ALTER TABLE {{table "orders"}} ALTER COLUMN "subtotal_amount" SET NOT NULL;
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "subtotal_currency" varchar(3) NOT NULL DEFAULT '';
+UPDATE {{table "orders"}} SET "subtotal_currency" = {{currency}} WHERE "subtotal_amount" <> 0;
+
+ALTER TABLE {{table "orders"}} RENAME COLUMN "discount_amount" TO "discount_amount_amount";
+ALTER TABLE {{table "orders"}} ALTER COLUMN "discount_amount_amount" TYPE numeric(19,4) USING coalesce("discount_amount_amount", 0);
+ALTER TABLE {{table "orders"}} ALTER COLUMN "discount_amount_amount" SET NOT NULL;
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "discount_amount_currency" varchar(3) NOT NULL DEFAULT '';
+UPDATE {{table "orders"}} SET "discount_amount_currency" = {{currency}} WHERE "discount_amount_amount" <> 0;
+
+ALTER TABLE {{table "orders"}} RENAME COLUMN "tax_amount" TO "tax_amount_amount";
+ALTER TABLE {{table "orders"}} ALTER COLUMN "tax_amount_amount" TYPE numeric(19,4) USING coalesce("tax_amount_amount", 0);
+ALTER TABLE {{table "orders"}} ALTER COLUMN "tax_amount_amount" SET NOT NULL;
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "tax_amount_currency" varchar(3) NOT NULL DEFAULT '';
+UPDATE {{table "orders"}} SET "tax_amount_currency" = {{currency}} WHERE "tax_amount_amount" <> 0;
+
+ALTER TABLE {{table "orders"}} RENAME COLUMN "grand_total" TO "grand_total_amount";
+ALTER TABLE {{table "orders"}} ALTER COLUMN "grand_total_amount" TYPE numeric(19,4) USING coalesce("grand_total_amount", 0);
+ALTER TABLE {{table "orders"}} ALTER COLUMN "grand_total_amount" SET NOT NULL;
+ALTER TABLE {{table "orders"}} ADD COLUMN IF NOT EXISTS "grand_total_currency" varchar(3) NOT NULL DEFAULT '';
+UPDATE {{table "orders"}} SET "grand_total_currency" = {{currency}} WHERE "grand_total_amount" <> 0;
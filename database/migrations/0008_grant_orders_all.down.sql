@@ -0,0 +1,3 @@
+DELETE FROM {{table "role_permissions"}}
+WHERE "role_id" IN (2, 3)
+  AND "permission_id" IN (SELECT "id" FROM {{table "permissions"}} WHERE "name" = 'orders:all');
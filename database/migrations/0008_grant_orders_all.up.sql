@@ -0,0 +1,13 @@
+-- orders:all lifts the restriction of the orders to their owner. The built-in roles are only given their default
+-- permissions when they are created, so the Admin and SysAdmin roles created before it existed are given it here, or
+-- their users would only see their own orders. Roles without any permission were emptied on purpose and are left so.
+
+INSERT INTO {{table "permissions"}} ("name") VALUES ('orders:all') ON CONFLICT ("name") DO NOTHING;
+
+INSERT INTO {{table "role_permissions"}} ("role_id", "permission_id")
+SELECT r."id", p."id"
+FROM "go-warehouse"."roles" AS r, {{table "permissions"}} AS p
+WHERE r."id" IN (2, 3)
+  AND p."name" = 'orders:all'
+  AND EXISTS (SELECT 1 FROM {{table "role_permissions"}} AS rp WHERE rp."role_id" = r."id")
+ON CONFLICT ("role_id", "permission_id") DO NOTHING;
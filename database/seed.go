@@ -0,0 +1,86 @@
+package database
+
+import (
+	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/repositories"
+	"github.com/laertkokona/crud-test/utils"
+	"gorm.io/gorm"
+)
+
+// sampleItems are the items the seed command creates for trying out the api. Their prices have no currency, they are
+// given the default one when they are seeded
+var sampleItems = []models.Item{
+	{Name: "Pallet of bottled water", Description: "80 packs of 6 x 1.5 l", Code: "SAMPLE-WATER", TotalQuantity: 40, AvailableQuantity: 40, Price: samplePrice("120.00"), Category: "Beverages", UnitWeight: 760, UnitVolume: 1.3},
+	{Name: "Office chair", Description: "Ergonomic chair with armrests", Code: "SAMPLE-CHAIR", TotalQuantity: 25, AvailableQuantity: 25, Price: samplePrice("89.90"), Category: "Furniture", UnitWeight: 14, UnitVolume: 0.25},
+	{Name: "Copy paper", Description: "Box of 5 reams A4 80 g/m²", Code: "SAMPLE-PAPER", TotalQuantity: 200, AvailableQuantity: 200, Price: samplePrice("24.50"), Category: "Stationery", UnitWeight: 12.5, UnitVolume: 0.02},
+}
+
+// sampleTrucks are the trucks the seed command creates for trying out the api
+var sampleTrucks = []models.Truck{
+	{ChassisNumber: "WDB9634031L000001", LicensePlate: "AA 001 AA", MaxWeight: 24000, MaxVolume: 90},
+	{ChassisNumber: "WDB9634031L000002", LicensePlate: "AA 002 AA", MaxWeight: 7500, MaxVolume: 40},
+}
+
+// samplePrice returns a price of a sample item without a currency, it panics when the amount can not be read
+func samplePrice(amount string) models.Money {
+	parsed, err := models.ParseAmount(amount)
+	if err != nil {
+		panic(err)
+	}
+	return models.NewMoney(parsed, "")
+}
+
+// SeedRoles creates the built-in roles that do not exist yet with the ids of the utils role constants and gives the ones
+// it creates their default permissions. The roles that exist already keep the permissions they have
+func SeedRoles(connection *gorm.DB) error {
+	SeedPermissions(connection)
+	for _, id := range []int{utils.User, utils.Admin, utils.SysAdmin} {
+		role := models.Role{ID: uint(id), Name: utils.GetRoleName(id)}
+		result := connection.FirstOrCreate(&role, models.Role{ID: uint(id)})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		var permissions []models.Permission
+		if err := connection.Where("name IN ?", utils.DefaultRolePermissions(id)).Find(&permissions).Error; err != nil {
+			return err
+		}
+		if err := connection.Model(&role).Association("Permissions").Append(permissions); err != nil {
+			return err
+		}
+	}
+	// the roles were inserted with their ids, move the sequence past them for the roles created through the api
+	return connection.Exec(`SELECT setval(pg_get_serial_sequence('"go-warehouse"."roles"', 'id'), (SELECT MAX("id") FROM "go-warehouse"."roles"))`).Error
+}
+
+// SeedSamples creates the sample items and trucks that do not exist yet. The items are saved by the item repository, so
+// their quantity is recorded as the opening balance of their ledger, made by the first SysAdmin if there is one. Their
+// prices are in the default currency, so they can be ordered together with the items created through the api
+func SeedSamples(connection *gorm.DB, defaultCurrency string) error {
+	var admin models.User
+	if err := connection.Where("role_id = ?", utils.SysAdmin).Order("id").Limit(1).Find(&admin).Error; err != nil {
+		return err
+	}
+	itemRepo := repositories.NewItemRepo(connection)
+	for _, item := range sampleItems {
+		var count int64
+		if err := connection.Unscoped().Model(&models.Item{}).Where("code = ?", item.Code).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		item.Price.Currency = defaultCurrency
+		if _, err := itemRepo.Save(item, admin.ID); err != nil {
+			return err
+		}
+	}
+	for _, truck := range sampleTrucks {
+		if err := connection.FirstOrCreate(&truck, models.Truck{LicensePlate: truck.LicensePlate}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/repositories"
+	"github.com/laertkokona/crud-test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestSeedSamples_Ledger tests that the sample items are balanced against their ledger and are only created once
+func TestSeedSamples_Ledger(t *testing.T) {
+	connection := openUpgradeDatabase(t)
+	migrator, err := NewMigrator(connection, upgradePrefix, "EUR")
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	require.NoError(t, SeedRoles(connection))
+
+	require.NoError(t, SeedSamples(connection, "EUR"))
+	require.NoError(t, SeedSamples(connection, "EUR"))
+
+	var items []models.Item
+	require.NoError(t, connection.Find(&items).Error)
+	assert.Len(t, items, len(sampleItems))
+	movementRepo := repositories.NewStockMovementRepo(connection)
+	for _, item := range items {
+		total, available, err := movementRepo.Balance(int(item.ID))
+		require.NoError(t, err)
+		assert.Equal(t, item.TotalQuantity, total, item.Code)
+		assert.Equal(t, item.AvailableQuantity, available, item.Code)
+		assert.NotZero(t, total, item.Code)
+	}
+}
+
+// TestSeedSamples_DefaultCurrency tests that the sample items are priced in the default currency, not in a fixed one
+func TestSeedSamples_DefaultCurrency(t *testing.T) {
+	connection := openUpgradeDatabase(t)
+	migrator, err := NewMigrator(connection, upgradePrefix, "USD")
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	require.NoError(t, SeedRoles(connection))
+
+	require.NoError(t, SeedSamples(connection, "USD"))
+
+	var items []models.Item
+	require.NoError(t, connection.Find(&items).Error)
+	require.Len(t, items, len(sampleItems))
+	for _, item := range items {
+		assert.Equal(t, "USD", item.Price.Currency, item.Code)
+		assert.False(t, item.Price.IsZero(), item.Code)
+	}
+}
+
+// TestSeedRoles_KeepsPermissions tests that the built-in roles are only given their default permissions when they are
+// created, so a role emptied on purpose stays empty after seeding and starting again
+func TestSeedRoles_KeepsPermissions(t *testing.T) {
+	connection := openUpgradeDatabase(t)
+	migrator, err := NewMigrator(connection, upgradePrefix, "EUR")
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	require.NoError(t, SeedRoles(connection))
+
+	var admin models.Role
+	require.NoError(t, connection.Preload("Permissions").First(&admin, utils.Admin).Error)
+	assert.Len(t, admin.Permissions, len(utils.DefaultRolePermissions(utils.Admin)))
+	require.NoError(t, connection.Model(&admin).Association("Permissions").Clear())
+
+	require.NoError(t, SeedRoles(connection))
+	SeedPermissions(connection)
+
+	admin = models.Role{}
+	require.NoError(t, connection.Preload("Permissions").First(&admin, utils.Admin).Error)
+	assert.Empty(t, admin.Permissions)
+	var user models.Role
+	require.NoError(t, connection.Preload("Permissions").First(&user, utils.User).Error)
+	assert.Len(t, user.Permissions, len(utils.DefaultRolePermissions(utils.User)))
+}
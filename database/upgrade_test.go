@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"os"
+	"testing"
+	"time"
+)
+
+// upgradePrefix is the table prefix of the upgrade tests, the one of the deployments AutoMigrate created
+const upgradePrefix = "go-warehouse."
+
+// baselineRole is the role as the baseline binary migrated it
+type baselineRole struct {
+	ID    uint
+	Name  string
+	Users []baselineUser `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:RoleID"`
+}
+
+// TableName returns the table of the roles
+func (baselineRole) TableName() string {
+	return "go-warehouse.roles"
+}
+
+// baselineUser is the user as the baseline binary migrated it
+type baselineUser struct {
+	gorm.Model
+	FirstName string
+	LastName  string
+	Username  string `gorm:"uniqueIndex"`
+	Password  string
+	RoleID    int
+}
+
+// TableName returns the table of the users
+func (baselineUser) TableName() string {
+	return "go-warehouse.users"
+}
+
+// baselineItem is the item as the baseline binary migrated it
+type baselineItem struct {
+	gorm.Model
+	Name              string
+	Description       string
+	Code              string `gorm:"uniqueIndex;not null"`
+	TotalQuantity     int
+	AvailableQuantity int
+	Price             float64
+	Category          string
+}
+
+// TableName returns the table of the items
+func (baselineItem) TableName(namer schema.Namer) string {
+	return namer.TableName("Item")
+}
+
+// baselineTruck is the truck as the baseline binary migrated it
+type baselineTruck struct {
+	gorm.Model
+	ChassisNumber string
+	LicensePlate  string
+}
+
+// TableName returns the table of the trucks
+func (baselineTruck) TableName(namer schema.Namer) string {
+	return namer.TableName("Truck")
+}
+
+// baselineOrderItem is the order line as the baseline binary migrated it
+type baselineOrderItem struct {
+	gorm.Model
+	ItemId   int
+	OrderId  int
+	Quantity int
+}
+
+// TableName returns the table of the order lines
+func (baselineOrderItem) TableName(namer schema.Namer) string {
+	return namer.TableName("OrderItem")
+}
+
+// baselineOrder is the order as the baseline binary migrated it
+type baselineOrder struct {
+	gorm.Model
+	Code          string `gorm:"uniqueIndex;not null"`
+	SubmittedDate time.Time
+	DeadlineDate  time.Time
+	UserID        int
+	OrderItems    []baselineOrderItem `gorm:"foreignKey:OrderId"`
+}
+
+// TableName returns the table of the orders
+func (baselineOrder) TableName(namer schema.Namer) string {
+	return namer.TableName("Order")
+}
+
+// openUpgradeDatabase connects to the database of TEST_DATABASE_DSN and drops the schemas of the application in it,
+// so the test starts from an empty database. The test is skipped without one, the database must be a throwaway one
+func openUpgradeDatabase(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	connection, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{TablePrefix: upgradePrefix},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, connection.Exec(`DROP SCHEMA IF EXISTS "go-warehouse" CASCADE`).Error)
+	require.NoError(t, connection.Exec(`CREATE SCHEMA "go-warehouse"`).Error)
+	return connection
+}
+
+// TestMigrator_UpgradesAutoMigrateSchema tests that the migrations apply to a database the baseline AutoMigrate built,
+// with its rows, and that the application reads it afterwards
+func TestMigrator_UpgradesAutoMigrateSchema(t *testing.T) {
+	connection := openUpgradeDatabase(t)
+	for _, model := range []interface{}{&baselineRole{}, &baselineUser{}, &baselineItem{}, &baselineTruck{}, &baselineOrderItem{}, &baselineOrder{}} {
+		require.NoError(t, connection.AutoMigrate(model))
+	}
+	require.NoError(t, connection.Create(&baselineRole{ID: 1, Name: "User"}).Error)
+	require.NoError(t, connection.Create(&baselineItem{Code: "itm1", Price: 9.99, TotalQuantity: 5, AvailableQuantity: 5}).Error)
+	require.NoError(t, connection.Create(&baselineTruck{ChassisNumber: "CH1", LicensePlate: "AB123CD"}).Error)
+	require.NoError(t, connection.Create(&baselineOrder{Code: "ord1", OrderItems: []baselineOrderItem{{ItemId: 1, Quantity: 2}}}).Error)
+
+	migrator, err := NewMigrator(connection, upgradePrefix, "EUR")
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+
+	pending, err := migrator.Pending()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	var order models.Order
+	require.NoError(t, connection.Preload("OrderItems.Item").First(&order).Error)
+	assert.Equal(t, models.OrderStatusDraft, order.Status)
+	require.Len(t, order.OrderItems, 1)
+	assert.Equal(t, "itm1", order.OrderItems[0].Item.Code)
+	var truck models.Truck
+	require.NoError(t, connection.First(&truck).Error)
+	assert.False(t, truck.OutOfService)
+	var role models.Role
+	require.NoError(t, connection.Preload("Permissions").First(&role, 1).Error)
+	assert.ElementsMatch(t, utils.DefaultRolePermissions(utils.User), role.PermissionNames())
+}
+
+// TestMigrator_DownAndUp tests that every migration rolls back and applies again on a database the baseline AutoMigrate
+// built
+func TestMigrator_DownAndUp(t *testing.T) {
+	connection := openUpgradeDatabase(t)
+	for _, model := range []interface{}{&baselineRole{}, &baselineUser{}, &baselineItem{}, &baselineTruck{}, &baselineOrderItem{}, &baselineOrder{}} {
+		require.NoError(t, connection.AutoMigrate(model))
+	}
+
+	migrator, err := NewMigrator(connection, upgradePrefix, "EUR")
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	_, err = migrator.Down(migrator.Latest())
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+}
+
+// TestMigrationsCurrent tests that the readiness check reports pending migrations and passes once they are applied
+func TestMigrationsCurrent(t *testing.T) {
+	connection := openUpgradeDatabase(t)
+	migrator, err := NewMigrator(connection, upgradePrefix, "EUR")
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	check := MigrationsCurrent(connection, upgradePrefix, "EUR")
+	assert.NoError(t, check(context.Background()))
+
+	_, err = migrator.Down(1)
+	require.NoError(t, err)
+	assert.EqualError(t, check(context.Background()), "1 migrations are pending")
+}
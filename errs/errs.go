@@ -0,0 +1,147 @@
+// Package errs has the errors the services return. Each error is of a kind that says what went wrong in terms of the
+// domain, the handlers map the kind to a response, so the services do not depend on http
+package errs
+
+import "errors"
+
+// Kind is the kind of failure an error reports
+type Kind int
+
+// Kind constants, an error without a kind is internal
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindForbidden
+	KindUnauthorized
+	KindUnavailable
+	KindUnsupported
+	KindUnprocessable
+	KindStale
+)
+
+// String returns the name of the kind
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindValidation:
+		return "validation"
+	case KindForbidden:
+		return "forbidden"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindUnavailable:
+		return "unavailable"
+	case KindUnsupported:
+		return "unsupported"
+	case KindUnprocessable:
+		return "unprocessable"
+	case KindStale:
+		return "stale"
+	default:
+		return "internal"
+	}
+}
+
+// Error is an error of a kind, about a field of the request when Field is set
+type Error struct {
+	Kind  Kind
+	Field string
+	Err   error
+}
+
+// Error returns the message of the wrapped error
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFound returns err as an error of something that does not exist
+func NotFound(err error) error {
+	return wrap(KindNotFound, "", err)
+}
+
+// Conflict returns err as an error of a change that conflicts with the current state
+func Conflict(err error) error {
+	return wrap(KindConflict, "", err)
+}
+
+// ConflictOn returns err as a conflict with the value of a field, like a duplicate of a unique field
+func ConflictOn(field string, err error) error {
+	return wrap(KindConflict, field, err)
+}
+
+// Validation returns err as an error of a request that is not valid
+func Validation(err error) error {
+	return wrap(KindValidation, "", err)
+}
+
+// Forbidden returns err as an error of a request the user may not make
+func Forbidden(err error) error {
+	return wrap(KindForbidden, "", err)
+}
+
+// Unauthorized returns err as an error of credentials or tokens that are not valid
+func Unauthorized(err error) error {
+	return wrap(KindUnauthorized, "", err)
+}
+
+// Unavailable returns err as an error of a dependency that can not be reached
+func Unavailable(err error) error {
+	return wrap(KindUnavailable, "", err)
+}
+
+// Unsupported returns err as an error of a request in a format that is not supported
+func Unsupported(err error) error {
+	return wrap(KindUnsupported, "", err)
+}
+
+// Unprocessable returns err as an error of a change that is well formed but would leave the entity invalid
+func Unprocessable(err error) error {
+	return wrap(KindUnprocessable, "", err)
+}
+
+// UnprocessableOn returns err as an unprocessable change because of the value of a field, like a reference to something
+// that does not exist
+func UnprocessableOn(field string, err error) error {
+	return wrap(KindUnprocessable, field, err)
+}
+
+// Stale returns err as an error of a change made to a version of the entity that is no longer the current one
+func Stale(err error) error {
+	return wrap(KindStale, "", err)
+}
+
+// KindOf returns the kind of the outermost Error in the chain of err, or KindInternal when there is none
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// FieldOf returns the field of the outermost Error in the chain of err, if it has one
+func FieldOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Field
+	}
+	return ""
+}
+
+// wrap returns err as an Error of the kind, a nil error stays nil
+func wrap(kind Kind, field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Field: field, Err: err}
+}
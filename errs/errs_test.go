@@ -0,0 +1,45 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestKindOf tests that the kind of an error survives wrapping and that errors without one are internal
+func TestKindOf(t *testing.T) {
+	err := NotFound(errors.New("item not found"))
+	assert.Equal(t, KindNotFound, KindOf(err))
+	assert.Equal(t, KindNotFound, KindOf(fmt.Errorf("item 1: %w", err)))
+	assert.Equal(t, "item not found", err.Error())
+	assert.Equal(t, KindInternal, KindOf(errors.New("connection refused")))
+	assert.Equal(t, KindInternal, KindOf(nil))
+}
+
+// TestKindOf_Outermost tests that the kind of the outermost error wins
+func TestKindOf_Outermost(t *testing.T) {
+	err := Validation(Conflict(errors.New("code already exists")))
+	assert.Equal(t, KindValidation, KindOf(err))
+}
+
+// TestFieldOf tests that a conflict or unprocessable change on a field keeps the field
+func TestFieldOf(t *testing.T) {
+	cause := errors.New("code already exists")
+	err := ConflictOn("code", cause)
+	assert.Equal(t, KindConflict, KindOf(err))
+	assert.Equal(t, "code", FieldOf(err))
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "", FieldOf(Conflict(cause)))
+	assert.Equal(t, "", FieldOf(cause))
+	assert.Equal(t, "orderItems[0].item", FieldOf(UnprocessableOn("orderItems[0].item", cause)))
+}
+
+// TestNil tests that wrapping a nil error returns nil
+func TestNil(t *testing.T) {
+	assert.Nil(t, NotFound(nil))
+	assert.Nil(t, ConflictOn("code", nil))
+	assert.Nil(t, Unavailable(nil))
+	assert.Nil(t, Stale(nil))
+	assert.Nil(t, UnprocessableOn("item", nil))
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/services"
+	"net/http"
+)
+
+// HealthHandler interface
+type HealthHandler interface {
+	Healthz(ctx *gin.Context)
+	Readyz(ctx *gin.Context)
+	Version(ctx *gin.Context)
+}
+
+// healthHandler struct
+type healthHandler struct {
+	healthService services.HealthService
+}
+
+// NewHealthHandler returns a new instance of healthHandler
+func NewHealthHandler(healthService services.HealthService) HealthHandler {
+	return healthHandler{
+		healthService: healthService,
+	}
+}
+
+// Healthz method that reports the process is alive, without checking its dependencies
+//
+// Healthz godoc
+// @Summary Liveness probe
+// @Description answers as long as the process can serve requests
+// @Tags Health
+// @ID healthz
+// @Produce  json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (h healthHandler) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz method that reports whether the service can take traffic and the state of each dependency
+//
+// Readyz godoc
+// @Summary Readiness probe
+// @Description pings the database and checks that the migrations are current
+// @Tags Health
+// @ID readyz
+// @Produce  json
+// @Success 200 {object} models.Readiness
+// @Failure 503 {object} models.Readiness
+// @Router /readyz [get]
+func (h healthHandler) Readyz(ctx *gin.Context) {
+	readiness, err := h.healthService.Readiness(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(helpers.StatusOf(err), readiness)
+		return
+	}
+	ctx.JSON(http.StatusOK, readiness)
+}
+
+// Version method that returns the commit, build time and go version of the binary
+//
+// Version godoc
+// @Summary Build information
+// @Description commit, build time and go version of the binary
+// @Tags Health
+// @ID version
+// @Produce  json
+// @Success 200 {object} models.BuildInfo
+// @Router /version [get]
+func (h healthHandler) Version(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.healthService.BuildInfo())
+}
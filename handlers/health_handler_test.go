@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// mockHealthService is a mock implementation of the services.HealthService interface
+type mockHealthService struct {
+	readiness func(ctx context.Context) (models.Readiness, error)
+	buildInfo func() models.BuildInfo
+}
+
+// Readiness method that reports the state of each dependency
+func (m *mockHealthService) Readiness(ctx context.Context) (models.Readiness, error) {
+	return m.readiness(ctx)
+}
+
+// BuildInfo method that returns the build information
+func (m *mockHealthService) BuildInfo() models.BuildInfo {
+	return m.buildInfo()
+}
+
+// newMockHealthService returns a new instance of mockHealthService whose database is up or down
+func newMockHealthService(up bool) *mockHealthService {
+	return &mockHealthService{
+		readiness: func(ctx context.Context) (models.Readiness, error) {
+			if !up {
+				return models.Readiness{
+					Status:       models.DependencyDown,
+					Dependencies: []models.DependencyStatus{{Name: "database", Status: models.DependencyDown, Error: "connection refused"}},
+				}, errs.Unavailable(errors.New("database: connection refused"))
+			}
+			return models.Readiness{
+				Status:       models.DependencyUp,
+				Dependencies: []models.DependencyStatus{{Name: "database", Status: models.DependencyUp}},
+			}, nil
+		},
+		buildInfo: func() models.BuildInfo {
+			return models.BuildInfo{Commit: "abc1234", BuildTime: "2024-01-01T00:00:00Z", GoVersion: "go1.20"}
+		},
+	}
+}
+
+// TestHealthz tests the Healthz method
+func TestHealthz(t *testing.T) {
+	healthHandler := NewHealthHandler(newMockHealthService(false))
+
+	r := gin.Default()
+	r.GET("/healthz", healthHandler.Healthz)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200 even when a dependency is down")
+}
+
+// TestReadyz tests the Readyz method
+func TestReadyz(t *testing.T) {
+	healthHandler := NewHealthHandler(newMockHealthService(true))
+
+	r := gin.Default()
+	r.GET("/readyz", healthHandler.Readyz)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
+	var readiness models.Readiness
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &readiness))
+	assert.Equal(t, models.DependencyUp, readiness.Status)
+}
+
+// TestReadyz_DependencyDown tests the Readyz method when a dependency is down
+func TestReadyz_DependencyDown(t *testing.T) {
+	healthHandler := NewHealthHandler(newMockHealthService(false))
+
+	r := gin.Default()
+	r.GET("/readyz", healthHandler.Readyz)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "Status code should be 503")
+	var readiness models.Readiness
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &readiness))
+	assert.Equal(t, "connection refused", readiness.Dependencies[0].Error, "should report why the dependency is down")
+}
+
+// TestVersion tests the Version method
+func TestVersion(t *testing.T) {
+	healthHandler := NewHealthHandler(newMockHealthService(true))
+
+	r := gin.Default()
+	r.GET("/version", healthHandler.Version)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/version", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
+	assert.JSONEq(t, `{"commit":"abc1234","buildTime":"2024-01-01T00:00:00Z","goVersion":"go1.20"}`, w.Body.String())
+}
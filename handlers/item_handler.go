@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/middleware"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/laertkokona/crud-test/services"
 	"net/http"
@@ -13,8 +15,13 @@ type ItemHandler interface {
 	CreateItem(ctx *gin.Context)
 	GetItem(ctx *gin.Context)
 	GetAllItems(ctx *gin.Context)
+	SearchItems(ctx *gin.Context)
 	UpdateItem(ctx *gin.Context)
+	PatchItem(ctx *gin.Context)
 	DeleteItem(ctx *gin.Context)
+	RecordMovement(ctx *gin.Context)
+	GetItemMovements(ctx *gin.Context)
+	ReconcileItem(ctx *gin.Context)
 }
 
 // itemHandler struct
@@ -34,17 +41,17 @@ func (p itemHandler) CreateItem(ctx *gin.Context) {
 	// get the item object from the request body
 	// call the item service to save the item
 	// return the item object
-	var item models.Item
-	if err := ctx.ShouldBindJSON(&item); err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+	var request models.CreateItemRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
 		return
 	}
-	itemDTO, status, err := p.itemService.CreateItem(item)
+	itemDTO, err := p.itemService.CreateItem(request.Item(), ctx.GetUint(middleware.UserIDKey))
 	if err != nil {
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	ctx.JSON(status, itemDTO)
+	ctx.JSON(http.StatusOK, itemDTO)
 }
 
 // GetItem method that takes an item id and returns the item object
@@ -55,15 +62,18 @@ func (p itemHandler) GetItem(ctx *gin.Context) {
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	itemDTO, status, err := p.itemService.GetItem(intId)
+	itemDTO, err := p.itemService.GetItem(intId)
 	if err != nil {
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	ctx.JSON(status, itemDTO)
+	if helpers.NotModified(ctx, itemDTO.Version) {
+		return
+	}
+	ctx.JSON(http.StatusOK, itemDTO)
 }
 
 // GetAllItems method that returns all items
@@ -75,67 +85,225 @@ func (p itemHandler) GetAllItems(ctx *gin.Context) {
 	intPage, err := strconv.Atoi(page)
 	if err != nil {
 		intPage = 0
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		//return
 	}
 	intLimit, err := strconv.Atoi(limit)
 	if err != nil {
 		intLimit = 0
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		//return
 	}
 	pagination := models.Pagination{
 		Page:  intPage,
 		Limit: intLimit,
+		After: ctx.Query("after"),
+	}
+	query, err := models.ParseListQuery(ctx.Query("filter"), ctx.Query("sort"), models.ItemQueryFields)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
 	}
-	itemsDTO, status, err := p.itemService.GetAllItems(pagination)
+	itemsDTO, err := p.itemService.GetAllItems(pagination, query)
 	if err != nil {
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	ctx.JSON(status, itemsDTO)
+	ctx.JSON(http.StatusOK, itemsDTO.WithLinks(ctx.Request.URL))
+}
+
+// SearchItems method that takes a search text from the query and returns a page of the matching items
+func (p itemHandler) SearchItems(ctx *gin.Context) {
+	// get the search text and the page from the query
+	// call the item service to search the items
+	// return the matching items
+	intPage, err := strconv.Atoi(ctx.Query("page"))
+	if err != nil {
+		intPage = 0
+	}
+	intLimit, err := strconv.Atoi(ctx.Query("limit"))
+	if err != nil {
+		intLimit = 0
+	}
+	pagination := models.Pagination{
+		Page:  intPage,
+		Limit: intLimit,
+	}
+	results, err := p.itemService.SearchItems(ctx.Query("q"), pagination)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, results.WithLinks(ctx.Request.URL))
 }
 
 // UpdateItem method that takes an item id and updates the item object
 func (p itemHandler) UpdateItem(ctx *gin.Context) {
 	// get the item id from the request params
+	// get the version the request was made to from the If-Match header
 	// get the item object from the request body
 	// call the item service to update the item
 	// return the item object
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	var request models.UpdateItemRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	itemDTO, err := p.itemService.UpdateItem(intId, request.Item(), version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SetETag(ctx, itemDTO.Version)
+	ctx.JSON(http.StatusOK, itemDTO)
+}
+
+// PatchItem method that takes an item id and applies the merge patch or json patch of the request body to the item
+func (p itemHandler) PatchItem(ctx *gin.Context) {
+	// get the item id from the request params
+	// get the version the request was made to from the If-Match header
+	// get the stored item and apply the patch to it
+	// call the item service to replace the item with the patched one
+	// return the item object
+	id := ctx.Param("id")
+	intId, err := strconv.Atoi(id)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	var item models.Item
-	if err := ctx.ShouldBindJSON(&item); err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	itemDTO, status, err := p.itemService.UpdateItem(intId, item)
+	itemDTO, err := p.itemService.GetItem(intId)
 	if err != nil {
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	ctx.JSON(status, itemDTO)
+	// the patch is made to the version that was read, unless the request names another one
+	if version == services.AnyVersion {
+		version = itemDTO.Version
+	}
+	request, err := helpers.ShouldBindPatch[models.UpdateItemRequest](ctx, itemDTO)
+	if err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	itemDTO, err = p.itemService.UpdateItem(intId, request.Item(), version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SetETag(ctx, itemDTO.Version)
+	ctx.JSON(http.StatusOK, itemDTO)
 }
 
 // DeleteItem method that takes an item id and deletes the item object
 func (p itemHandler) DeleteItem(ctx *gin.Context) {
 	// get the item id from the request params
+	// get the version the request was made to from the If-Match header
 	// call the item service to delete the item
 	// return the item object
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	itemDTO, err := p.itemService.DeleteItem(intId, version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, itemDTO)
+}
+
+// RecordMovement method that takes an item id and a stock movement and records the movement in the ledger of the item
+func (p itemHandler) RecordMovement(ctx *gin.Context) {
+	// get the item id from the request params
+	// get the movement object from the request body
+	// call the item service to record the movement, made by the authenticated user
+	// return the movement object
+	id := ctx.Param("id")
+	intId, err := strconv.Atoi(id)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var request models.StockMovementRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	movement, err := p.itemService.RecordMovement(intId, request.StockMovement(), ctx.GetUint(middleware.UserIDKey))
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, movement)
+}
+
+// GetItemMovements method that takes an item id and returns the stock movements of the item
+func (p itemHandler) GetItemMovements(ctx *gin.Context) {
+	// get the item id from the request params
+	// call the item service to get the movements
+	// return the movements
+	id := ctx.Param("id")
+	intId, err := strconv.Atoi(id)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	intPage, err := strconv.Atoi(ctx.Query("page"))
+	if err != nil {
+		intPage = 0
+	}
+	intLimit, err := strconv.Atoi(ctx.Query("limit"))
+	if err != nil {
+		intLimit = 0
+	}
+	pagination := models.Pagination{
+		Page:  intPage,
+		Limit: intLimit,
+		After: ctx.Query("after"),
+	}
+	movements, err := p.itemService.GetItemMovements(intId, pagination)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, movements.WithLinks(ctx.Request.URL))
+}
+
+// ReconcileItem method that takes an item id and compares the stored quantities of the item with its ledger
+func (p itemHandler) ReconcileItem(ctx *gin.Context) {
+	id := ctx.Param("id")
+	intId, err := strconv.Atoi(id)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	itemDTO, status, err := p.itemService.DeleteItem(intId)
+	reconciliation, err := p.itemService.ReconcileItem(intId)
 	if err != nil {
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	ctx.JSON(status, itemDTO)
+	ctx.JSON(http.StatusOK, reconciliation)
 }
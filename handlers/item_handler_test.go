@@ -5,7 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/middleware"
 	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/services"
+	"github.com/laertkokona/crud-test/utils"
 	"github.com/peteprogrammer/go-automapper"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
@@ -40,7 +45,7 @@ var mockItems = []models.Item{
 		Code:              "itm1",
 		TotalQuantity:     100,
 		AvailableQuantity: 100,
-		Price:             9.99,
+		Price:             models.MustParseMoney("9.99 EUR"),
 		Category:          "Category Test",
 	},
 	{
@@ -50,7 +55,7 @@ var mockItems = []models.Item{
 		Code:              "itm2",
 		TotalQuantity:     200,
 		AvailableQuantity: 200,
-		Price:             19.99,
+		Price:             models.MustParseMoney("19.99 EUR"),
 		Category:          "Category Test",
 	},
 	{
@@ -60,7 +65,7 @@ var mockItems = []models.Item{
 		Code:              "itm3",
 		TotalQuantity:     300,
 		AvailableQuantity: 300,
-		Price:             29.99,
+		Price:             models.MustParseMoney("29.99 EUR"),
 		Category:          "Category Test",
 	},
 	{
@@ -70,7 +75,7 @@ var mockItems = []models.Item{
 		Code:              "itm4",
 		TotalQuantity:     400,
 		AvailableQuantity: 400,
-		Price:             39.99,
+		Price:             models.MustParseMoney("39.99 EUR"),
 		Category:          "Category Test",
 	},
 	{
@@ -80,73 +85,114 @@ var mockItems = []models.Item{
 		Code:              "itm5",
 		TotalQuantity:     500,
 		AvailableQuantity: 500,
-		Price:             49.99,
+		Price:             models.MustParseMoney("49.99 EUR"),
 		Category:          "Category Test",
 	},
 }
 
 // mockItemService struct that implements the ItemService interface
 type mockItemService struct {
-	createItem  func(item models.Item) (models.ItemDTO, int, error)
-	getItem     func(id int) (models.ItemDTO, int, error)
-	getAllItems func(pagination models.Pagination) ([]models.ItemDTO, int, error)
-	updateItem  func(id int, item models.Item) (models.ItemDTO, int, error)
-	deleteItem  func(id int) (models.ItemDTO, int, error)
+	createItem  func(item models.Item, userID uint) (models.ItemDTO, error)
+	getItem     func(id int) (models.ItemDTO, error)
+	getAllItems func(pagination models.Pagination, query models.ListQuery) (models.Page[models.ItemDTO], error)
+	searchItems func(text string, pagination models.Pagination) (models.Page[models.ItemSearchResult], error)
+	updateItem  func(id int, item models.Item, version uint) (models.ItemDTO, error)
+	deleteItem  func(id int, version uint) (models.ItemDTO, error)
+
+	recordMovement   func(id int, movement models.StockMovement, userID uint) (models.StockMovement, error)
+	getItemMovements func(id int, pagination models.Pagination) (models.Page[models.StockMovement], error)
+	reconcileItem    func(id int) (models.StockReconciliation, error)
 }
 
 // CreateItem mock function
-func (_m *mockItemService) CreateItem(item models.Item) (models.ItemDTO, int, error) {
-	return _m.createItem(item)
+func (_m *mockItemService) CreateItem(item models.Item, userID uint) (models.ItemDTO, error) {
+	return _m.createItem(item, userID)
 }
 
 // GetItem mock function
-func (_m *mockItemService) GetItem(id int) (models.ItemDTO, int, error) {
+func (_m *mockItemService) GetItem(id int) (models.ItemDTO, error) {
 	return _m.getItem(id)
 }
 
 // GetAllItems mock function
-func (_m *mockItemService) GetAllItems(pagination models.Pagination) ([]models.ItemDTO, int, error) {
-	return _m.getAllItems(pagination)
+func (_m *mockItemService) GetAllItems(pagination models.Pagination, query models.ListQuery) (models.Page[models.ItemDTO], error) {
+	return _m.getAllItems(pagination, query)
+}
+
+// SearchItems mock function
+func (_m *mockItemService) SearchItems(text string, pagination models.Pagination) (models.Page[models.ItemSearchResult], error) {
+	return _m.searchItems(text, pagination)
 }
 
 // UpdateItem mock function
-func (_m *mockItemService) UpdateItem(id int, item models.Item) (models.ItemDTO, int, error) {
-	return _m.updateItem(id, item)
+func (_m *mockItemService) UpdateItem(id int, item models.Item, version uint) (models.ItemDTO, error) {
+	return _m.updateItem(id, item, version)
 }
 
 // DeleteItem mock function
-func (_m *mockItemService) DeleteItem(id int) (models.ItemDTO, int, error) {
-	return _m.deleteItem(id)
+func (_m *mockItemService) DeleteItem(id int, version uint) (models.ItemDTO, error) {
+	return _m.deleteItem(id, version)
+}
+
+// RecordMovement mock function
+func (_m *mockItemService) RecordMovement(id int, movement models.StockMovement, userID uint) (models.StockMovement, error) {
+	return _m.recordMovement(id, movement, userID)
+}
+
+// GetItemMovements mock function
+func (_m *mockItemService) GetItemMovements(id int, pagination models.Pagination) (models.Page[models.StockMovement], error) {
+	return _m.getItemMovements(id, pagination)
+}
+
+// ReconcileItem mock function
+func (_m *mockItemService) ReconcileItem(id int) (models.StockReconciliation, error) {
+	return _m.reconcileItem(id)
 }
 
 // newMockItemService returns a new instance of mockItemService
 func newMockItemService() *mockItemService {
 	return &mockItemService{
-		createItem: func(item models.Item) (models.ItemDTO, int, error) {
+		createItem: func(item models.Item, userID uint) (models.ItemDTO, error) {
 			var itemDTO models.ItemDTO
 			automapper.Map(item, &itemDTO)
-			return itemDTO, http.StatusOK, nil
+			return itemDTO, nil
 		},
-		getItem: func(id int) (models.ItemDTO, int, error) {
+		getItem: func(id int) (models.ItemDTO, error) {
 			var itemDTO models.ItemDTO
 			automapper.Map(mockItems[id-1], &itemDTO)
-			return itemDTO, http.StatusOK, nil
+			return itemDTO, nil
 		},
-		getAllItems: func(pagination models.Pagination) ([]models.ItemDTO, int, error) {
+		getAllItems: func(pagination models.Pagination, query models.ListQuery) (models.Page[models.ItemDTO], error) {
 			var mockItemsDTO []models.ItemDTO
-			automapper.Map(mockItems, &mockItems)
-			return mockItemsDTO, http.StatusOK, nil
+			automapper.Map(mockItems, &mockItemsDTO)
+			return models.NewPage(mockItemsDTO, int64(len(mockItemsDTO)), pagination), nil
+		},
+		searchItems: func(text string, pagination models.Pagination) (models.Page[models.ItemSearchResult], error) {
+			results := []models.ItemSearchResult{{ItemDTO: models.ItemDTO{ID: 1, Name: "Item 1"}, Rank: 0.6, Snippet: "<mark>Item</mark> 1"}}
+			return models.NewPage(results, int64(len(results)), pagination), nil
 		},
-		updateItem: func(id int, item models.Item) (models.ItemDTO, int, error) {
+		updateItem: func(id int, item models.Item, version uint) (models.ItemDTO, error) {
 			var itemDTO models.ItemDTO
 			item.ID = uint(id)
 			automapper.Map(item, &itemDTO)
-			return itemDTO, http.StatusOK, nil
+			return itemDTO, nil
 		},
-		deleteItem: func(id int) (models.ItemDTO, int, error) {
+		deleteItem: func(id int, version uint) (models.ItemDTO, error) {
 			var itemDTO models.ItemDTO
 			automapper.Map(mockItems[id-1], &itemDTO)
-			return itemDTO, http.StatusOK, nil
+			return itemDTO, nil
+		},
+		recordMovement: func(id int, movement models.StockMovement, userID uint) (models.StockMovement, error) {
+			movement.ItemID = uint(id)
+			movement.UserID = userID
+			return movement, nil
+		},
+		getItemMovements: func(id int, pagination models.Pagination) (models.Page[models.StockMovement], error) {
+			movements := []models.StockMovement{{ItemID: uint(id), Type: models.StockMovementReceipt, Quantity: 100}}
+			return models.NewPage(movements, int64(len(movements)), pagination), nil
+		},
+		reconcileItem: func(id int) (models.StockReconciliation, error) {
+			return models.StockReconciliation{ItemID: uint(id), Balanced: true}, nil
 		},
 	}
 }
@@ -154,20 +200,32 @@ func newMockItemService() *mockItemService {
 // newMockItemErrorService returns a new instance of mockItemService with errors
 func newMockItemErrorService() *mockItemService {
 	return &mockItemService{
-		createItem: func(item models.Item) (models.ItemDTO, int, error) {
-			return models.ItemDTO{}, http.StatusInternalServerError, errors.New("error while creating item")
+		createItem: func(item models.Item, userID uint) (models.ItemDTO, error) {
+			return models.ItemDTO{}, errors.New("error while creating item")
+		},
+		getItem: func(id int) (models.ItemDTO, error) {
+			return models.ItemDTO{}, errors.New("error while getting item")
+		},
+		getAllItems: func(pagination models.Pagination, query models.ListQuery) (models.Page[models.ItemDTO], error) {
+			return models.Page[models.ItemDTO]{}, errors.New("error while getting all items")
+		},
+		searchItems: func(text string, pagination models.Pagination) (models.Page[models.ItemSearchResult], error) {
+			return models.Page[models.ItemSearchResult]{}, errs.Validation(errors.New("search text must not be empty"))
+		},
+		updateItem: func(id int, item models.Item, version uint) (models.ItemDTO, error) {
+			return models.ItemDTO{}, errors.New("error while updating item")
 		},
-		getItem: func(id int) (models.ItemDTO, int, error) {
-			return models.ItemDTO{}, http.StatusInternalServerError, errors.New("error while getting item")
+		deleteItem: func(id int, version uint) (models.ItemDTO, error) {
+			return models.ItemDTO{}, errors.New("error while deleting item")
 		},
-		getAllItems: func(pagination models.Pagination) ([]models.ItemDTO, int, error) {
-			return []models.ItemDTO{}, http.StatusInternalServerError, errors.New("error while getting all items")
+		recordMovement: func(id int, movement models.StockMovement, userID uint) (models.StockMovement, error) {
+			return models.StockMovement{}, errs.Conflict(errors.New("insufficient stock"))
 		},
-		updateItem: func(id int, item models.Item) (models.ItemDTO, int, error) {
-			return models.ItemDTO{}, http.StatusInternalServerError, errors.New("error while updating item")
+		getItemMovements: func(id int, pagination models.Pagination) (models.Page[models.StockMovement], error) {
+			return models.Page[models.StockMovement]{}, errs.NotFound(errors.New("item not found"))
 		},
-		deleteItem: func(id int) (models.ItemDTO, int, error) {
-			return models.ItemDTO{}, http.StatusInternalServerError, errors.New("error while deleting item")
+		reconcileItem: func(id int) (models.StockReconciliation, error) {
+			return models.StockReconciliation{}, errors.New("error while reconciling item")
 		},
 	}
 }
@@ -188,9 +246,12 @@ func TestCreateItem(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 
+	// the service only gets the fields of the request, not the id or the available quantity
+	var request models.CreateItemRequest
+	assert.NoError(t, json.Unmarshal(mockItemString, &request))
 	var item models.ItemDTO
 	var mockItemDTO models.ItemDTO
-	automapper.Map(mockItems[0], &mockItemDTO)
+	automapper.Map(request.Item(), &mockItemDTO)
 	err = json.Unmarshal(w.Body.Bytes(), &item)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
 	assert.Equal(t, mockItemDTO, item)
@@ -208,12 +269,89 @@ func TestCreateItem_BindError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
+}
+
+// TestCreateItem_ValidationError tests the CreateItem function with an invalid code and a negative price
+func TestCreateItem_ValidationError(t *testing.T) {
+	mockService := newMockItemService()
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.POST("/items", itemHandler.CreateItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(`{"name":"Item 1","code":"itm 1","price":"-1 EUR"}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, "the request has invalid fields", response.Detail)
+	assert.ElementsMatch(t, []helpers.FieldError{
+		{Field: "code", Message: "must be 2 to 32 letters, digits, dashes or underscores, starting with a letter or digit"},
+		{Field: "price", Message: "must be at least 0"},
+	}, response.Errors)
+}
+
+// TestCreateItem_TypeError tests the CreateItem function with a field of the wrong json type
+func TestCreateItem_TypeError(t *testing.T) {
+	mockService := newMockItemService()
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.POST("/items", itemHandler.CreateItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(`{"name":"Item 1","code":"itm1","price":"cheap"}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, "the request has a string cheap that must be an amount with a currency, like 9.99 EUR", response.Detail)
+}
+
+// TestCreateItem_PriceWithoutCurrency tests that a price given as only an amount reaches the service without a currency,
+// for it to price the item in the default currency
+func TestCreateItem_PriceWithoutCurrency(t *testing.T) {
+	var gotItem models.Item
+	mockService := newMockItemService()
+	mockService.createItem = func(item models.Item, userID uint) (models.ItemDTO, error) {
+		gotItem = item
+		return models.ItemDTO{}, nil
+	}
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.POST("/items", itemHandler.CreateItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(`{"name":"Item 1","code":"itm1","price":"9.99"}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "9.99", gotItem.Price.String())
+	assert.Empty(t, gotItem.Price.Currency)
+}
+
+// TestCreateItem_NumberPrice tests that a price given as a number is refused, so it is never read inexactly
+func TestCreateItem_NumberPrice(t *testing.T) {
+	r := gin.Default()
+	itemHandler := NewItemHandler(newMockItemService())
+	r.POST("/items", itemHandler.CreateItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(`{"name":"Item 1","code":"itm1","price":9.99}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "must be an amount with a currency")
 }
 
 // TestCreateItem_ServiceError tests the CreateItem function with a service error
@@ -230,12 +368,37 @@ func TestCreateItem_ServiceError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var response helpers.Problem
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.NotEmpty(t, response.Detail)
+}
+
+// TestCreateItem_DuplicateCode tests the CreateItem function when the code is taken
+func TestCreateItem_DuplicateCode(t *testing.T) {
+	mockService := newMockItemService()
+	mockService.createItem = func(item models.Item, userID uint) (models.ItemDTO, error) {
+		return models.ItemDTO{}, errs.ConflictOn("code", errors.New("code already exists"))
+	}
+
+	r := gin.Default()
+	mockItemString, err := json.Marshal(mockItems[0])
+	assert.NoError(t, err)
+	itemHandler := NewItemHandler(mockService)
+	r.POST("/items", itemHandler.CreateItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBuffer(mockItemString))
+	r.ServeHTTP(w, req)
 
-	var response map[string]string
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var response helpers.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.Equal(t, "code already exists", response.Detail)
+	assert.Equal(t, []helpers.FieldError{{Field: "code", Message: "code already exists"}}, response.Errors)
 }
 
 // TestGetItem tests the GetItem function
@@ -270,12 +433,12 @@ func TestGetItem_InvalidIDError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestGetItem_ServiceError tests the GetItem function with a service error
@@ -290,12 +453,70 @@ func TestGetItem_ServiceError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.NotEmpty(t, response.Detail)
+	assert.NotContains(t, response.Detail, "error while getting item", "should not show the internal error")
+}
+
+// TestGetItem_Problem tests that a failed GetItem is answered with the problem details of the request
+func TestGetItem_Problem(t *testing.T) {
+	mockService := newMockItemService()
+	mockService.getItem = func(id int) (models.ItemDTO, error) {
+		return models.ItemDTO{}, errs.NotFound(errors.New("item not found"))
+	}
+
+	r := gin.Default()
+	r.Use(middleware.RequestID())
+	itemHandler := NewItemHandler(mockService)
+	r.GET("/items/:id", itemHandler.GetItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/7", nil)
+	req.Header.Set(helpers.RequestIDHeader, "req-7")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "req-7", w.Header().Get(helpers.RequestIDHeader))
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, helpers.Problem{
+		Type:      "about:blank",
+		Title:     "Not Found",
+		Status:    http.StatusNotFound,
+		Detail:    "item not found",
+		Instance:  "/items/7",
+		RequestID: "req-7",
+	}, response)
+}
+
+// TestGetItem_JSONEnvelope tests that clients asking for plain json still get the JSONResult envelope on failure
+func TestGetItem_JSONEnvelope(t *testing.T) {
+	mockService := newMockItemService()
+	mockService.getItem = func(id int) (models.ItemDTO, error) {
+		return models.ItemDTO{}, errs.NotFound(errors.New("item not found"))
+	}
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.GET("/items/:id", itemHandler.GetItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/7", nil)
+	req.Header.Set("Accept", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.JSONResult
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.Equal(t, helpers.JSONResult{Code: http.StatusNotFound, Message: "item not found"}, response)
 }
 
 // TestGetAllItems tests the GetAllItems function
@@ -312,11 +533,137 @@ func TestGetAllItems(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 
-	var items []models.ItemDTO
+	var items models.Page[models.ItemDTO]
 	err := json.Unmarshal(w.Body.Bytes(), &items)
 	log.Println("Items: ", items)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.Equal(t, mockItems, items)
+	var mockItemsDTO []models.ItemDTO
+	automapper.Map(mockItems, &mockItemsDTO)
+	assert.Equal(t, mockItemsDTO, items.Data)
+	assert.Equal(t, int64(len(mockItems)), *items.Total)
+	assert.Equal(t, "/items?limit=20&page=1", items.Links.Self)
+	assert.Empty(t, items.Links.Next)
+	assert.Empty(t, items.Links.Prev)
+}
+
+// TestGetAllItems_PageLinks tests that the GetAllItems function links the pages around the requested one
+func TestGetAllItems_PageLinks(t *testing.T) {
+	mockService := newMockItemService()
+	mockService.getAllItems = func(pagination models.Pagination, query models.ListQuery) (models.Page[models.ItemDTO], error) {
+		return models.NewPage([]models.ItemDTO{{ID: 3}, {ID: 4}}, 5, pagination), nil
+	}
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.GET("/items", itemHandler.GetAllItems)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items?page=2&limit=2&category=tools", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var items models.Page[models.ItemDTO]
+	err := json.Unmarshal(w.Body.Bytes(), &items)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, int64(5), *items.Total)
+	assert.Equal(t, 3, *items.TotalPages)
+	assert.Equal(t, "/items?category=tools&limit=2&page=2", items.Links.Self)
+	assert.Equal(t, "/items?category=tools&limit=2&page=3", items.Links.Next)
+	assert.Equal(t, "/items?category=tools&limit=2&page=1", items.Links.Prev)
+}
+
+// TestGetAllItems_Query tests that the GetAllItems function parses the filter and sort parameters
+func TestGetAllItems_Query(t *testing.T) {
+	var gotQuery models.ListQuery
+	mockService := newMockItemService()
+	getAllItems := mockService.getAllItems
+	mockService.getAllItems = func(pagination models.Pagination, query models.ListQuery) (models.Page[models.ItemDTO], error) {
+		gotQuery = query
+		return getAllItems(pagination, query)
+	}
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.GET("/items", itemHandler.GetAllItems)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items?filter=category:eq:tools,price:lt:20,name:like:ham&sort=-price,name", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.ListQuery{
+		Filters: []models.Filter{
+			{Column: "category", Operator: models.FilterEq, Value: "tools"},
+			{Column: "price_amount", Operator: models.FilterLt, Value: float64(20)},
+			{Column: "name", Operator: models.FilterLike, Value: "ham"},
+		},
+		Sorts: []models.Sort{{Column: "price_amount", Desc: true}, {Column: "name"}},
+	}, gotQuery)
+}
+
+// TestGetAllItems_InvalidQuery tests that the GetAllItems function rejects fields, operators and values it does not allow
+func TestGetAllItems_InvalidQuery(t *testing.T) {
+	tests := []string{
+		"filter=description:eq:x",
+		"filter=price:like:2",
+		"filter=price:lt:cheap",
+		"filter=category",
+		"filter=category:regex:tools",
+		"sort=-description",
+	}
+	for _, test := range tests {
+		r := gin.Default()
+		itemHandler := NewItemHandler(newMockItemService())
+		r.GET("/items", itemHandler.GetAllItems)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/items?"+test, nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, test)
+		assert.Contains(t, w.Body.String(), models.ErrInvalidQuery.Error(), test)
+	}
+}
+
+// TestSearchItems tests the SearchItems function
+func TestSearchItems(t *testing.T) {
+	var gotText string
+	var gotPagination models.Pagination
+	mockService := newMockItemService()
+	searchItems := mockService.searchItems
+	mockService.searchItems = func(text string, pagination models.Pagination) (models.Page[models.ItemSearchResult], error) {
+		gotText = text
+		gotPagination = pagination
+		return searchItems(text, pagination)
+	}
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.GET("/items/search", itemHandler.SearchItems)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/search?q=ite&limit=5", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ite", gotText)
+	assert.Equal(t, models.Pagination{Limit: 5}, gotPagination)
+
+	var results models.Page[models.ItemSearchResult]
+	err := json.Unmarshal(w.Body.Bytes(), &results)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Len(t, results.Data, 1)
+	assert.Equal(t, "<mark>Item</mark> 1", results.Data[0].Snippet)
+	assert.Equal(t, "/items/search?limit=5&page=1&q=ite", results.Links.Self)
+}
+
+// TestSearchItems_ServiceError tests the SearchItems function with a service error
+func TestSearchItems_ServiceError(t *testing.T) {
+	r := gin.Default()
+	itemHandler := NewItemHandler(newMockItemErrorService())
+	r.GET("/items/search", itemHandler.SearchItems)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/search", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 // TestGetAllItems_ServiceError tests the GetAllItems function with a service error
@@ -331,12 +678,12 @@ func TestGetAllItems_ServiceError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestUpdateItem tests the UpdateItem function
@@ -355,10 +702,14 @@ func TestUpdateItem(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 
+	var request models.UpdateItemRequest
+	assert.NoError(t, json.Unmarshal(mockItemString, &request))
+	expected := request.Item()
+	expected.ID = 1
 	var item models.Item
 	err = json.Unmarshal(w.Body.Bytes(), &item)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.Equal(t, mockItems[0], item)
+	assert.Equal(t, expected, item)
 }
 
 // TestUpdateItem_InvalidIDError tests the UpdateItem function with an invalid id
@@ -375,12 +726,12 @@ func TestUpdateItem_InvalidIDError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestUpdateItem_InvalidBodyError tests the UpdateItem function with an invalid body
@@ -395,12 +746,12 @@ func TestUpdateItem_InvalidBodyError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestUpdateItem_ServiceError tests the UpdateItem function with a service error
@@ -417,12 +768,223 @@ func TestUpdateItem_ServiceError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
+}
+
+// patchItem sends a patch of the content type to the PatchItem function and returns the response
+func patchItem(mockService services.ItemService, contentType, patch string) *httptest.ResponseRecorder {
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.PATCH("/items/:id", itemHandler.PatchItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/items/1", bytes.NewBufferString(patch))
+	req.Header.Set("Content-Type", contentType)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestPatchItem_MergePatch tests that a merge patch can set a field to zero and remove another
+func TestPatchItem_MergePatch(t *testing.T) {
+	var updated models.Item
+	mockService := newMockItemService()
+	mockService.updateItem = func(id int, item models.Item, version uint) (models.ItemDTO, error) {
+		updated = item
+		return models.ItemDTO{ID: uint(id)}, nil
+	}
+
+	w := patchItem(mockService, utils.MergePatchContentType, `{"price":"0","description":null}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Item{Name: "Item 1", Code: "itm1", Category: "Category Test"}, updated)
+}
+
+// TestPatchItem_JSONPatch tests that a json patch is applied to the stored item
+func TestPatchItem_JSONPatch(t *testing.T) {
+	var updated models.Item
+	mockService := newMockItemService()
+	mockService.updateItem = func(id int, item models.Item, version uint) (models.ItemDTO, error) {
+		updated = item
+		return models.ItemDTO{ID: uint(id)}, nil
+	}
+
+	w := patchItem(mockService, utils.JSONPatchContentType, `[{"op":"test","path":"/code","value":"itm1"},{"op":"replace","path":"/name","value":"Hammer"}]`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Hammer", updated.Name)
+	assert.Equal(t, models.MustParseMoney("9.99 EUR"), updated.Price)
+}
+
+// TestPatchItem_UnsupportedMediaType tests that a patch of another content type is refused with the accepted ones
+func TestPatchItem_UnsupportedMediaType(t *testing.T) {
+	w := patchItem(newMockItemService(), "application/json", `{"price":0}`)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Equal(t, helpers.AcceptPatch, w.Header().Get("Accept-Patch"))
+}
+
+// TestPatchItem_TestFailed tests that a json patch whose test fails is a conflict
+func TestPatchItem_TestFailed(t *testing.T) {
+	w := patchItem(newMockItemService(), utils.JSONPatchContentType, `[{"op":"test","path":"/code","value":"itm2"}]`)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// TestPatchItem_Unprocessable tests that a patch that leaves the item invalid is unprocessable, with the fields it broke
+func TestPatchItem_Unprocessable(t *testing.T) {
+	w := patchItem(newMockItemService(), utils.MergePatchContentType, `{"code":null,"price":"-1 EUR"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.ElementsMatch(t, []helpers.FieldError{
+		{Field: "code", Message: "is required"},
+		{Field: "price", Message: "must be at least 0"},
+	}, response.Errors)
+}
+
+// newMockItemServiceAtVersion returns a mockItemService whose items are at the version
+func newMockItemServiceAtVersion(version uint) *mockItemService {
+	mockService := newMockItemService()
+	mockService.getItem = func(id int) (models.ItemDTO, error) {
+		var itemDTO models.ItemDTO
+		automapper.Map(mockItems[id-1], &itemDTO)
+		itemDTO.Version = version
+		return itemDTO, nil
+	}
+	return mockService
+}
+
+// TestGetItem_ETag tests that the item is returned with the tag of its version
+func TestGetItem_ETag(t *testing.T) {
+	r := gin.Default()
+	itemHandler := NewItemHandler(newMockItemServiceAtVersion(3))
+	r.GET("/items/:id", itemHandler.GetItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("If-None-Match", `"2"`)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+}
+
+// TestGetItem_NotModified tests that a client that has the current version gets no body
+func TestGetItem_NotModified(t *testing.T) {
+	for _, tags := range []string{`"3"`, `"2", W/"3"`, `*`} {
+		r := gin.Default()
+		itemHandler := NewItemHandler(newMockItemServiceAtVersion(3))
+		r.GET("/items/:id", itemHandler.GetItem)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/items/1", nil)
+		req.Header.Set("If-None-Match", tags)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotModified, w.Code, tags)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"), tags)
+		assert.Empty(t, w.Body.Bytes(), tags)
+	}
+}
+
+// TestUpdateItem_IfMatch tests that the version of If-Match is passed on and the tag of the new version is returned
+func TestUpdateItem_IfMatch(t *testing.T) {
+	var updatedVersion uint
+	mockService := newMockItemService()
+	mockService.updateItem = func(id int, item models.Item, version uint) (models.ItemDTO, error) {
+		updatedVersion = version
+		return models.ItemDTO{ID: uint(id), Version: version + 1}, nil
+	}
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.PUT("/items/:id", itemHandler.UpdateItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/items/1", bytes.NewBufferString(`{"name":"Hammer","code":"itm1"}`))
+	req.Header.Set("If-Match", `"3"`)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(3), updatedVersion)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+}
+
+// TestUpdateItem_Stale tests that a change made to an older version is a failed precondition
+func TestUpdateItem_Stale(t *testing.T) {
+	mockService := newMockItemService()
+	mockService.updateItem = func(id int, item models.Item, version uint) (models.ItemDTO, error) {
+		return models.ItemDTO{}, errs.Stale(errors.New("version 2 is not the current version 3"))
+	}
+
+	for _, tag := range []string{`"2"`, `W/"3"`} {
+		r := gin.Default()
+		itemHandler := NewItemHandler(mockService)
+		r.PUT("/items/:id", itemHandler.UpdateItem)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/items/1", bytes.NewBufferString(`{"name":"Hammer","code":"itm1"}`))
+		req.Header.Set("If-Match", tag)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code, tag)
+		assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), tag)
+	}
+}
+
+// TestUpdateItem_InvalidIfMatch tests that an If-Match that is not a single entity tag is a bad request
+func TestUpdateItem_InvalidIfMatch(t *testing.T) {
+	for _, tag := range []string{`3`, `"2", "3"`} {
+		r := gin.Default()
+		itemHandler := NewItemHandler(newMockItemService())
+		r.PUT("/items/:id", itemHandler.UpdateItem)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/items/1", bytes.NewBufferString(`{"name":"Hammer","code":"itm1"}`))
+		req.Header.Set("If-Match", tag)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, tag)
+	}
+}
+
+// TestPatchItem_Version tests that a patch without If-Match is made to the version it was applied to
+func TestPatchItem_Version(t *testing.T) {
+	var updatedVersion uint
+	mockService := newMockItemServiceAtVersion(3)
+	mockService.updateItem = func(id int, item models.Item, version uint) (models.ItemDTO, error) {
+		updatedVersion = version
+		return models.ItemDTO{ID: uint(id), Version: version + 1}, nil
+	}
+
+	w := patchItem(mockService, utils.MergePatchContentType, `{"price":"0"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(3), updatedVersion)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+}
+
+// TestDeleteItem_IfMatch tests that the version of If-Match is passed on to the delete
+func TestDeleteItem_IfMatch(t *testing.T) {
+	var deletedVersion uint
+	mockService := newMockItemService()
+	mockService.deleteItem = func(id int, version uint) (models.ItemDTO, error) {
+		deletedVersion = version
+		return models.ItemDTO{ID: uint(id)}, nil
+	}
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.DELETE("/items/:id", itemHandler.DeleteItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/items/1", nil)
+	req.Header.Set("If-Match", `"7"`)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(7), deletedVersion)
 }
 
 // TestDeleteItem tests the DeleteItem function
@@ -457,12 +1019,12 @@ func TestDeleteItem_InvalidIDError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestDeleteItem_ServiceError tests the DeleteItem function with a service error
@@ -477,10 +1039,125 @@ func TestDeleteItem_ServiceError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
+}
+
+// TestRecordMovement tests the RecordMovement function
+func TestRecordMovement(t *testing.T) {
+	mockService := newMockItemService()
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.POST("/items/:id/movements", func(ctx *gin.Context) {
+		ctx.Set(middleware.UserIDKey, uint(2))
+	}, itemHandler.RecordMovement)
+	w := httptest.NewRecorder()
+	body := `{"type":"receipt","quantity":10,"reasonCode":"purchase"}`
+	req, _ := http.NewRequest("POST", "/items/3/movements", bytes.NewBufferString(body))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var movement models.StockMovement
+	err := json.Unmarshal(w.Body.Bytes(), &movement)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, uint(3), movement.ItemID)
+	assert.Equal(t, uint(2), movement.UserID)
+	assert.Equal(t, models.StockMovementReceipt, movement.Type)
+	assert.Equal(t, 10, movement.Quantity)
+}
+
+// TestRecordMovement_BindError tests the RecordMovement function with a bind error
+func TestRecordMovement_BindError(t *testing.T) {
+	mockService := newMockItemService()
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.POST("/items/:id/movements", itemHandler.RecordMovement)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/items/3/movements", bytes.NewBufferString("{"))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestRecordMovement_ServiceError tests the RecordMovement function with a service error
+func TestRecordMovement_ServiceError(t *testing.T) {
+	mockService := newMockItemErrorService()
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.POST("/items/:id/movements", itemHandler.RecordMovement)
+	w := httptest.NewRecorder()
+	body := `{"type":"write_off","quantity":1000,"reasonCode":"lost"}`
+	req, _ := http.NewRequest("POST", "/items/3/movements", bytes.NewBufferString(body))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// TestGetItemMovements tests the GetItemMovements function
+func TestGetItemMovements(t *testing.T) {
+	var gotPagination models.Pagination
+	mockService := newMockItemService()
+	getItemMovements := mockService.getItemMovements
+	mockService.getItemMovements = func(id int, pagination models.Pagination) (models.Page[models.StockMovement], error) {
+		gotPagination = pagination
+		return getItemMovements(id, pagination)
+	}
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.GET("/items/:id/movements", itemHandler.GetItemMovements)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1/movements?page=2&limit=5", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Pagination{Page: 2, Limit: 5}, gotPagination)
+
+	var movements models.Page[models.StockMovement]
+	err := json.Unmarshal(w.Body.Bytes(), &movements)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Len(t, movements.Data, 1)
+	assert.Equal(t, "/items/1/movements?limit=5&page=2", movements.Links.Self)
+	assert.Equal(t, "/items/1/movements?limit=5&page=1", movements.Links.Prev)
+}
+
+// TestGetItemMovements_ServiceError tests the GetItemMovements function with a service error
+func TestGetItemMovements_ServiceError(t *testing.T) {
+	mockService := newMockItemErrorService()
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.GET("/items/:id/movements", itemHandler.GetItemMovements)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1/movements", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestReconcileItem tests the ReconcileItem function
+func TestReconcileItem(t *testing.T) {
+	mockService := newMockItemService()
+
+	r := gin.Default()
+	itemHandler := NewItemHandler(mockService)
+	r.GET("/items/:id/reconciliation", itemHandler.ReconcileItem)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1/reconciliation", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var reconciliation models.StockReconciliation
+	err := json.Unmarshal(w.Body.Bytes(), &reconciliation)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.True(t, reconciliation.Balanced)
 }
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/middleware"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/laertkokona/crud-test/services"
+	"github.com/laertkokona/crud-test/utils"
 	"net/http"
 	"strconv"
 )
@@ -14,7 +18,10 @@ type OrderHandler interface {
 	GetOrder(ctx *gin.Context)
 	GetAllOrders(ctx *gin.Context)
 	UpdateOrder(ctx *gin.Context)
+	PatchOrder(ctx *gin.Context)
 	DeleteOrder(ctx *gin.Context)
+	TransitionOrder(status models.OrderStatus) gin.HandlerFunc
+	GetOrderHistory(ctx *gin.Context)
 }
 
 // orderHandler is the handler for the order resource
@@ -34,17 +41,17 @@ func (p orderHandler) CreateOrder(ctx *gin.Context) {
 	// get the order object from the request body
 	// call the order service to save the order
 	// return the order object
-	var order models.Order
-	if err := ctx.ShouldBindJSON(&order); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request models.CreateOrderRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
 		return
 	}
-	order, status, err := p.orderService.CreateOrder(order)
+	order, err := p.orderService.CreateOrder(request.Order(), orderScope(ctx))
 	if err != nil {
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		orderErrorResponse(ctx, err)
 		return
 	}
-	ctx.JSON(status, order)
+	ctx.JSON(http.StatusOK, order)
 }
 
 // GetOrder method that takes an order id and returns the order object
@@ -55,15 +62,18 @@ func (p orderHandler) GetOrder(ctx *gin.Context) {
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	order, status, err := p.orderService.GetOrder(intId)
+	order, err := p.orderService.GetOrder(intId, orderScope(ctx))
 	if err != nil {
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	ctx.JSON(status, order)
+	if helpers.NotModified(ctx, order.Version) {
+		return
+	}
+	ctx.JSON(http.StatusOK, order)
 }
 
 // GetAllOrders method that returns all the orders
@@ -75,67 +85,201 @@ func (p orderHandler) GetAllOrders(ctx *gin.Context) {
 	intPage, err := strconv.Atoi(page)
 	if err != nil {
 		intPage = 0
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		//return
 	}
 	intLimit, err := strconv.Atoi(limit)
 	if err != nil {
 		intLimit = 0
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		//return
 	}
 	pagination := models.Pagination{
 		Page:  intPage,
 		Limit: intLimit,
+		After: ctx.Query("after"),
+	}
+	query, err := models.ParseListQuery(ctx.Query("filter"), ctx.Query("sort"), models.OrderQueryFields)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
 	}
-	orders, status, err := p.orderService.GetAllOrders(pagination)
+	orders, err := p.orderService.GetAllOrders(pagination, query, orderScope(ctx))
 	if err != nil {
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	ctx.JSON(status, orders)
+	ctx.JSON(http.StatusOK, orders.WithLinks(ctx.Request.URL))
 }
 
 // UpdateOrder method that takes an order id and a models.Order object and updates the order
 func (p orderHandler) UpdateOrder(ctx *gin.Context) {
 	// get the order id from the request params
+	// get the version the request was made to from the If-Match header
 	// get the order object from the request body
 	// call the order service to update the order
 	// return the order object
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	var request models.UpdateOrderRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	order, err := p.orderService.UpdateOrder(intId, request.Order(), version, orderScope(ctx))
+	if err != nil {
+		orderErrorResponse(ctx, err)
+		return
+	}
+	helpers.SetETag(ctx, order.Version)
+	ctx.JSON(http.StatusOK, order)
+}
+
+// PatchOrder method that takes an order id and applies the merge patch or json patch of the request body to the order
+func (p orderHandler) PatchOrder(ctx *gin.Context) {
+	// get the order id from the request params
+	// get the version the request was made to from the If-Match header
+	// get the stored order and apply the patch to it
+	// call the order service to replace the order with the patched one
+	// return the order object
+	id := ctx.Param("id")
+	intId, err := strconv.Atoi(id)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	order, err := p.orderService.GetOrder(intId, orderScope(ctx))
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	var order models.Order
-	if err := ctx.ShouldBindJSON(&order); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// the patch is made to the version that was read, unless the request names another one
+	if version == services.AnyVersion {
+		version = order.Version
+	}
+	request, err := helpers.ShouldBindPatch[models.UpdateOrderRequest](ctx, order)
+	if err != nil {
+		helpers.BindErrorResponse(ctx, err)
 		return
 	}
-	order, status, err := p.orderService.UpdateOrder(intId, order)
+	order, err = p.orderService.UpdateOrder(intId, request.Order(), version, orderScope(ctx))
 	if err != nil {
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		orderErrorResponse(ctx, err)
 		return
 	}
-	ctx.JSON(status, order)
+	helpers.SetETag(ctx, order.Version)
+	ctx.JSON(http.StatusOK, order)
 }
 
 // DeleteOrder method that takes an order id and deletes the order
 func (p orderHandler) DeleteOrder(ctx *gin.Context) {
 	// get the order id from the request params
+	// get the version the request was made to from the If-Match header
 	// call the order service to delete the order
 	// return the order object
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	order, status, err := p.orderService.DeleteOrder(intId)
+	version, err := helpers.IfMatch(ctx)
 	if err != nil {
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	ctx.JSON(status, order)
+	order, err := p.orderService.DeleteOrder(intId, version, orderScope(ctx))
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, order)
+}
+
+// TransitionOrder returns a handler that moves the order from the request params to the given status
+func (p orderHandler) TransitionOrder(target models.OrderStatus) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// get the order id from the request params
+		// call the order service to move the order to the status
+		// return the order object
+		id := ctx.Param("id")
+		intId, err := strconv.Atoi(id)
+		if err != nil {
+			helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		order, err := p.orderService.TransitionOrder(intId, target, orderScope(ctx))
+		if err != nil {
+			helpers.ErrorResponse(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, order)
+	}
+}
+
+// GetOrderHistory method that takes an order id and returns a page of its status history
+func (p orderHandler) GetOrderHistory(ctx *gin.Context) {
+	// get the order id from the request params
+	// call the order service to get the status history
+	// return the status changes
+	id := ctx.Param("id")
+	intId, err := strconv.Atoi(id)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	intPage, err := strconv.Atoi(ctx.Query("page"))
+	if err != nil {
+		intPage = 0
+	}
+	intLimit, err := strconv.Atoi(ctx.Query("limit"))
+	if err != nil {
+		intLimit = 0
+	}
+	pagination := models.Pagination{
+		Page:  intPage,
+		Limit: intLimit,
+		After: ctx.Query("after"),
+	}
+	history, err := p.orderService.GetOrderHistory(intId, pagination, orderScope(ctx))
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, history.WithLinks(ctx.Request.URL))
+}
+
+// orderScope returns the orders the authenticated user may touch, their own unless the token grants every order
+func orderScope(ctx *gin.Context) models.OrderScope {
+	scope := models.OrderScope{UserID: ctx.GetUint(middleware.UserIDKey)}
+	for _, p := range ctx.GetStringSlice(middleware.PermissionsKey) {
+		if p == utils.OrdersAll {
+			scope.All = true
+		}
+	}
+	return scope
+}
+
+// orderErrorResponse writes an error of the order service, listing every short line when the stock is insufficient
+func orderErrorResponse(ctx *gin.Context, err error) {
+	problem := helpers.ErrorProblem(ctx, err)
+	var shortage *models.InsufficientStockError
+	if errors.As(err, &shortage) {
+		problem = problem.With("shortages", shortage.Shortages)
+	}
+	helpers.ProblemResponse(ctx, problem)
 }
@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/middleware"
 	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/utils"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
 	"net/http"
@@ -70,55 +74,76 @@ var mockOrders = []models.Order{
 
 // mockOrderService is a mock implementation of the services.OrderService interface
 type mockOrderService struct {
-	createOrder  func(order models.Order) (models.Order, int, error)
-	getOrder     func(id int) (models.Order, int, error)
-	getAllOrders func(pagination models.Pagination) ([]models.Order, int, error)
-	updateOrder  func(id int, order models.Order) (models.Order, int, error)
-	deleteOrder  func(id int) (models.Order, int, error)
+	createOrder  func(order models.Order, scope models.OrderScope) (models.Order, error)
+	getOrder     func(id int, scope models.OrderScope) (models.Order, error)
+	getAllOrders func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) (models.Page[models.Order], error)
+	updateOrder  func(id int, order models.Order, version uint, scope models.OrderScope) (models.Order, error)
+	deleteOrder  func(id int, version uint, scope models.OrderScope) (models.Order, error)
+
+	transitionOrder func(id int, status models.OrderStatus, scope models.OrderScope) (models.Order, error)
+	getOrderHistory func(id int, pagination models.Pagination, scope models.OrderScope) (models.Page[models.OrderStatusChange], error)
 }
 
 // CreateOrder is a mock implementation of the services.OrderService.CreateOrder method
-func (m *mockOrderService) CreateOrder(order models.Order) (models.Order, int, error) {
-	return m.createOrder(order)
+func (m *mockOrderService) CreateOrder(order models.Order, scope models.OrderScope) (models.Order, error) {
+	return m.createOrder(order, scope)
 }
 
 // GetOrder is a mock implementation of the services.OrderService.GetOrder method
-func (m *mockOrderService) GetOrder(id int) (models.Order, int, error) {
-	return m.getOrder(id)
+func (m *mockOrderService) GetOrder(id int, scope models.OrderScope) (models.Order, error) {
+	return m.getOrder(id, scope)
 }
 
 // GetAllOrders is a mock implementation of the services.OrderService.GetAllOrders method
-func (m *mockOrderService) GetAllOrders(pagination models.Pagination) ([]models.Order, int, error) {
-	return m.getAllOrders(pagination)
+func (m *mockOrderService) GetAllOrders(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) (models.Page[models.Order], error) {
+	return m.getAllOrders(pagination, query, scope)
 }
 
 // UpdateOrder is a mock implementation of the services.OrderService.UpdateOrder method
-func (m *mockOrderService) UpdateOrder(id int, order models.Order) (models.Order, int, error) {
-	return m.updateOrder(id, order)
+func (m *mockOrderService) UpdateOrder(id int, order models.Order, version uint, scope models.OrderScope) (models.Order, error) {
+	return m.updateOrder(id, order, version, scope)
 }
 
 // DeleteOrder is a mock implementation of the services.OrderService.DeleteOrder method
-func (m *mockOrderService) DeleteOrder(id int) (models.Order, int, error) {
-	return m.deleteOrder(id)
+func (m *mockOrderService) DeleteOrder(id int, version uint, scope models.OrderScope) (models.Order, error) {
+	return m.deleteOrder(id, version, scope)
+}
+
+// TransitionOrder is a mock implementation of the services.OrderService.TransitionOrder method
+func (m *mockOrderService) TransitionOrder(id int, status models.OrderStatus, scope models.OrderScope) (models.Order, error) {
+	return m.transitionOrder(id, status, scope)
+}
+
+// GetOrderHistory is a mock implementation of the services.OrderService.GetOrderHistory method
+func (m *mockOrderService) GetOrderHistory(id int, pagination models.Pagination, scope models.OrderScope) (models.Page[models.OrderStatusChange], error) {
+	return m.getOrderHistory(id, pagination, scope)
 }
 
 // newMockOrderService returns a new instance of mockOrderService
 func newMockOrderService() *mockOrderService {
 	return &mockOrderService{
-		createOrder: func(order models.Order) (models.Order, int, error) {
-			return order, http.StatusOK, nil
+		createOrder: func(order models.Order, scope models.OrderScope) (models.Order, error) {
+			return order, nil
+		},
+		getOrder: func(id int, scope models.OrderScope) (models.Order, error) {
+			return mockOrders[id-1], nil
+		},
+		getAllOrders: func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) (models.Page[models.Order], error) {
+			return models.NewPage(mockOrders, int64(len(mockOrders)), pagination), nil
 		},
-		getOrder: func(id int) (models.Order, int, error) {
-			return mockOrders[id-1], http.StatusOK, nil
+		updateOrder: func(id int, order models.Order, version uint, scope models.OrderScope) (models.Order, error) {
+			return order, nil
 		},
-		getAllOrders: func(pagination models.Pagination) ([]models.Order, int, error) {
-			return mockOrders, http.StatusOK, nil
+		deleteOrder: func(id int, version uint, scope models.OrderScope) (models.Order, error) {
+			return mockOrders[id-1], nil
 		},
-		updateOrder: func(id int, order models.Order) (models.Order, int, error) {
-			return order, http.StatusOK, nil
+		transitionOrder: func(id int, status models.OrderStatus, scope models.OrderScope) (models.Order, error) {
+			order := mockOrders[id-1]
+			order.Status = status
+			return order, nil
 		},
-		deleteOrder: func(id int) (models.Order, int, error) {
-			return mockOrders[id-1], http.StatusOK, nil
+		getOrderHistory: func(id int, pagination models.Pagination, scope models.OrderScope) (models.Page[models.OrderStatusChange], error) {
+			return models.NewPage([]models.OrderStatusChange{{OrderID: uint(id), ToStatus: models.OrderStatusDraft}}, 1, pagination), nil
 		},
 	}
 }
@@ -126,20 +151,26 @@ func newMockOrderService() *mockOrderService {
 // NewMockOrderErrorService returns a new instance of mockOrderService with errors
 func NewMockOrderErrorService() *mockOrderService {
 	return &mockOrderService{
-		createOrder: func(order models.Order) (models.Order, int, error) {
-			return order, http.StatusInternalServerError, errors.New("error creating order")
+		createOrder: func(order models.Order, scope models.OrderScope) (models.Order, error) {
+			return order, errors.New("error creating order")
 		},
-		getOrder: func(id int) (models.Order, int, error) {
-			return models.Order{}, http.StatusInternalServerError, errors.New("error getting order")
+		getOrder: func(id int, scope models.OrderScope) (models.Order, error) {
+			return models.Order{}, errors.New("error getting order")
 		},
-		getAllOrders: func(pagination models.Pagination) ([]models.Order, int, error) {
-			return []models.Order{}, http.StatusInternalServerError, errors.New("error getting all orders")
+		getAllOrders: func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) (models.Page[models.Order], error) {
+			return models.Page[models.Order]{}, errors.New("error getting all orders")
 		},
-		updateOrder: func(id int, order models.Order) (models.Order, int, error) {
-			return models.Order{}, http.StatusInternalServerError, errors.New("error updating order")
+		updateOrder: func(id int, order models.Order, version uint, scope models.OrderScope) (models.Order, error) {
+			return models.Order{}, errors.New("error updating order")
 		},
-		deleteOrder: func(id int) (models.Order, int, error) {
-			return models.Order{}, http.StatusInternalServerError, errors.New("error deleting order")
+		deleteOrder: func(id int, version uint, scope models.OrderScope) (models.Order, error) {
+			return models.Order{}, errors.New("error deleting order")
+		},
+		transitionOrder: func(id int, status models.OrderStatus, scope models.OrderScope) (models.Order, error) {
+			return models.Order{}, errs.Conflict(errors.New("cannot move order"))
+		},
+		getOrderHistory: func(id int, pagination models.Pagination, scope models.OrderScope) (models.Page[models.OrderStatusChange], error) {
+			return models.Page[models.OrderStatusChange]{}, errors.New("error getting order history")
 		},
 	}
 }
@@ -160,10 +191,12 @@ func TestCreateOrder(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 
+	var request models.CreateOrderRequest
+	assert.NoError(t, json.Unmarshal(mockOrderString, &request))
 	var order models.Order
 	err = json.Unmarshal(w.Body.Bytes(), &order)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.Equal(t, mockOrders[0], order)
+	assert.Equal(t, request.Order(), order)
 }
 
 // TestCreateOrder_BindError tests the CreateOrder method with a bind error
@@ -178,12 +211,37 @@ func TestCreateOrder_BindError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.NotEmpty(t, response.Detail)
+}
+
+// TestCreateOrder_ValidationError tests that every field of the order that breaks a rule is reported at once
+func TestCreateOrder_ValidationError(t *testing.T) {
+	mockOrderService := newMockOrderService()
+
+	r := gin.Default()
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.POST("/orders", orderHandler.CreateOrder)
+	w := httptest.NewRecorder()
+	body := `{"code":"ord1","submittedDate":"2024-02-01T00:00:00Z","deadlineDate":"2024-01-01T00:00:00Z",` +
+		`"orderItems":[{"item":1,"quantity":-5}]}`
+	req, _ := http.NewRequest("POST", "/orders", bytes.NewBufferString(body))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.ElementsMatch(t, []helpers.FieldError{
+		{Field: "orderItems[0].quantity", Message: "must be greater than 0"},
+		{Field: "deadlineDate", Message: "must not be before the submitted date"},
+	}, response.Errors)
 }
 
 // TestCreateOrder_ServiceError tests the CreateOrder method with a service error
@@ -200,12 +258,43 @@ func TestCreateOrder_ServiceError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var response helpers.Problem
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.NotEmpty(t, response.Detail)
+}
+
+// TestCreateOrder_InsufficientStock tests that the short lines of an order are a member of the problem
+func TestCreateOrder_InsufficientStock(t *testing.T) {
+	mockOrderService := newMockOrderService()
+	mockOrderService.createOrder = func(order models.Order, scope models.OrderScope) (models.Order, error) {
+		return models.Order{}, errs.Conflict(&models.InsufficientStockError{
+			Shortages: []models.StockShortage{{ItemID: 1, Requested: 5, Available: 2}},
+		})
+	}
+
+	r := gin.Default()
+	mockOrderString, err := json.Marshal(mockOrders[0])
+	assert.NoError(t, err)
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.POST("/orders", orderHandler.CreateOrder)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/orders", bytes.NewBuffer(mockOrderString))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response struct {
+		helpers.Problem
+		Shortages []models.StockShortage `json:"shortages"`
+	}
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.Equal(t, http.StatusConflict, response.Status)
+	assert.Equal(t, []models.StockShortage{{ItemID: 1, Requested: 5, Available: 2}}, response.Shortages)
 }
 
 // TestGetOrder tests the GetOrder method
@@ -240,12 +329,34 @@ func TestGetOrder_ServiceError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
+}
+
+// TestGetOrder_NotFound tests the GetOrder method when the order does not exist
+func TestGetOrder_NotFound(t *testing.T) {
+	mockOrderService := newMockOrderService()
+	mockOrderService.getOrder = func(id int, scope models.OrderScope) (models.Order, error) {
+		return models.Order{}, errs.NotFound(gorm.ErrRecordNotFound)
+	}
+
+	r := gin.Default()
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.GET("/orders/:id", orderHandler.GetOrder)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/orders/9", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestGetOrder_InvalidIDError tests the GetOrder method with an invalid id
@@ -260,12 +371,12 @@ func TestGetOrder_InvalidIDError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestGetAllOrders tests the GetAllOrders method
@@ -282,10 +393,47 @@ func TestGetAllOrders(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 
-	var orders []models.Order
+	var orders models.Page[models.Order]
 	err := json.Unmarshal(w.Body.Bytes(), &orders)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.Equal(t, mockOrders, orders)
+	assert.Equal(t, mockOrders, orders.Data)
+	assert.Equal(t, int64(len(mockOrders)), *orders.Total)
+}
+
+// TestGetAllOrders_CursorLinks tests that the GetAllOrders method passes the cursor and links to the page after the next cursor
+func TestGetAllOrders_CursorLinks(t *testing.T) {
+	var gotPagination models.Pagination
+	mockOrderService := newMockOrderService()
+	mockOrderService.getAllOrders = func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) (models.Page[models.Order], error) {
+		gotPagination = pagination
+		// the repository returns the order after the page as well when there is one
+		orders := append(append([]models.Order{}, mockOrders...), models.Order{Code: "ord3"})
+		page := models.NewPage(orders, 0, pagination)
+		return page.WithNextCursor(mockOrders[len(mockOrders)-1].Model), nil
+	}
+	orderHandler := NewOrderHandler(mockOrderService)
+
+	after := models.Cursor{ID: 7}.Encode()
+	r := gin.Default()
+	r.GET("/orders", orderHandler.GetAllOrders)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/orders?after="+after+"&limit=2", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Pagination{Limit: 2, After: after}, gotPagination)
+
+	var orders models.Page[models.Order]
+	err := json.Unmarshal(w.Body.Bytes(), &orders)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, mockOrders, orders.Data)
+	next := models.CursorOf(mockOrders[len(mockOrders)-1].Model).Encode()
+	assert.Equal(t, next, orders.NextCursor)
+	assert.Equal(t, "/orders?after="+after+"&limit=2", orders.Links.Self)
+	assert.Equal(t, "/orders?after="+next+"&limit=2", orders.Links.Next)
+	assert.Empty(t, orders.Links.Prev)
+	assert.NotContains(t, w.Body.String(), `"total"`)
+	assert.NotContains(t, w.Body.String(), `"totalPages"`)
 }
 
 // TestGetAllOrders_ServiceError tests the GetAllOrders method with a service error
@@ -300,12 +448,12 @@ func TestGetAllOrders_ServiceError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestUpdateOrder tests the UpdateOrder method
@@ -324,10 +472,12 @@ func TestUpdateOrder(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
 
+	var request models.UpdateOrderRequest
+	assert.NoError(t, json.Unmarshal(mockOrderString, &request))
 	var order models.Order
 	err = json.Unmarshal(w.Body.Bytes(), &order)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.Equal(t, mockOrders[0], order)
+	assert.Equal(t, request.Order(), order)
 }
 
 // TestUpdateOrder_InvalidIDError tests the UpdateOrder method with an invalid id
@@ -344,12 +494,12 @@ func TestUpdateOrder_InvalidIDError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestUpdateOrder_InvalidJSONError tests the UpdateOrder method with an invalid json
@@ -364,12 +514,12 @@ func TestUpdateOrder_InvalidJSONError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestUpdateOrder_ServiceError tests the UpdateOrder method with a service error
@@ -386,12 +536,77 @@ func TestUpdateOrder_ServiceError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
+}
+
+// TestPatchOrder_EmptyLines tests that a merge patch can empty the lines of an order and keeps its other fields
+func TestPatchOrder_EmptyLines(t *testing.T) {
+	mockOrderService := newMockOrderService()
+
+	r := gin.Default()
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.PATCH("/orders/:id", orderHandler.PatchOrder)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/orders/1", bytes.NewBufferString(`{"orderItems":[]}`))
+	req.Header.Set("Content-Type", utils.MergePatchContentType)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var order models.Order
+	err := json.Unmarshal(w.Body.Bytes(), &order)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, "ord1", order.Code)
+	assert.Empty(t, order.OrderItems)
+}
+
+// TestPatchOrder_Discount tests that a merge patch can change the discounts of an order and keeps its lines
+func TestPatchOrder_Discount(t *testing.T) {
+	mockOrderService := newMockOrderService()
+
+	r := gin.Default()
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.PATCH("/orders/:id", orderHandler.PatchOrder)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/orders/1", bytes.NewBufferString(`{"discountPercent":15}`))
+	req.Header.Set("Content-Type", utils.MergePatchContentType)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var order models.Order
+	err := json.Unmarshal(w.Body.Bytes(), &order)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, 15.0, order.DiscountPercent)
+	assert.Len(t, order.OrderItems, len(mockOrders[0].OrderItems))
+}
+
+// TestCreateOrder_InvalidDiscount tests that discounts over 100 percent are reported on their fields
+func TestCreateOrder_InvalidDiscount(t *testing.T) {
+	mockOrderService := newMockOrderService()
+
+	r := gin.Default()
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.POST("/orders", orderHandler.CreateOrder)
+	w := httptest.NewRecorder()
+	body := `{"code":"ord1","discountPercent":120,"orderItems":[{"item":1,"quantity":5,"discountPercent":-1}]}`
+	req, _ := http.NewRequest("POST", "/orders", bytes.NewBufferString(body))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.ElementsMatch(t, []helpers.FieldError{
+		{Field: "discountPercent", Message: "must be at most 100"},
+		{Field: "orderItems[0].discountPercent", Message: "must be at least 0"},
+	}, response.Errors)
 }
 
 // TestDeleteOrder tests the DeleteOrder method
@@ -426,12 +641,12 @@ func TestDeleteOrder_InvalidIDError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
 }
 
 // TestDeleteOrder_ServiceError tests the DeleteOrder method with a service error
@@ -446,10 +661,138 @@ func TestDeleteOrder_ServiceError(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"))
 
-	var response map[string]string
+	var response helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
-	assert.NotNil(t, response["error"])
+	assert.NotEmpty(t, response.Detail)
+}
+
+// TestTransitionOrder tests the TransitionOrder method
+func TestTransitionOrder(t *testing.T) {
+	var gotScope models.OrderScope
+	mockOrderService := newMockOrderService()
+	transition := mockOrderService.transitionOrder
+	mockOrderService.transitionOrder = func(id int, status models.OrderStatus, scope models.OrderScope) (models.Order, error) {
+		gotScope = scope
+		return transition(id, status, scope)
+	}
+
+	r := gin.Default()
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.POST("/orders/:id/submit", func(ctx *gin.Context) {
+		ctx.Set(middleware.UserIDKey, uint(5))
+	}, orderHandler.TransitionOrder(models.OrderStatusSubmitted))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/orders/1/submit", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var order models.Order
+	err := json.Unmarshal(w.Body.Bytes(), &order)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, models.OrderStatusSubmitted, order.Status)
+	assert.Equal(t, models.OrderScope{UserID: 5}, gotScope)
+}
+
+// TestTransitionOrder_InvalidIDError tests the TransitionOrder method with an invalid id
+func TestTransitionOrder_InvalidIDError(t *testing.T) {
+	mockOrderService := newMockOrderService()
+
+	r := gin.Default()
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.POST("/orders/:id/submit", orderHandler.TransitionOrder(models.OrderStatusSubmitted))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/orders/invalid/submit", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestTransitionOrder_ServiceError tests the TransitionOrder method with an illegal transition
+func TestTransitionOrder_ServiceError(t *testing.T) {
+	mockOrderService := NewMockOrderErrorService()
+
+	r := gin.Default()
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.POST("/orders/:id/ship", orderHandler.TransitionOrder(models.OrderStatusShipped))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/orders/1/ship", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.NotEmpty(t, response.Detail)
+}
+
+// TestGetOrderHistory tests the GetOrderHistory method
+func TestGetOrderHistory(t *testing.T) {
+	mockOrderService := newMockOrderService()
+
+	r := gin.Default()
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.GET("/orders/:id/history", orderHandler.GetOrderHistory)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/orders/1/history", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var history models.Page[models.OrderStatusChange]
+	err := json.Unmarshal(w.Body.Bytes(), &history)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Len(t, history.Data, 1)
+	assert.Equal(t, int64(1), *history.Total)
+	assert.Equal(t, "/orders/1/history?limit=20&page=1", history.Links.Self)
+}
+
+// TestGetOrderHistory_ServiceError tests the GetOrderHistory method with a service error
+func TestGetOrderHistory_ServiceError(t *testing.T) {
+	mockOrderService := NewMockOrderErrorService()
+
+	r := gin.Default()
+	orderHandler := NewOrderHandler(mockOrderService)
+	r.GET("/orders/:id/history", orderHandler.GetOrderHistory)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/orders/1/history", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+// TestGetAllOrders_Scope tests that the GetAllOrders method scopes the orders to the authenticated user unless the token grants every order
+func TestGetAllOrders_Scope(t *testing.T) {
+	var gotScope models.OrderScope
+	mockOrderService := newMockOrderService()
+	mockOrderService.getAllOrders = func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) (models.Page[models.Order], error) {
+		gotScope = scope
+		return models.NewPage(mockOrders, int64(len(mockOrders)), pagination), nil
+	}
+	orderHandler := NewOrderHandler(mockOrderService)
+
+	tests := []struct {
+		permissions []string
+		expected    models.OrderScope
+	}{
+		{permissions: []string{utils.OrdersRead}, expected: models.OrderScope{UserID: 5}},
+		{permissions: []string{utils.OrdersRead, utils.OrdersAll}, expected: models.OrderScope{UserID: 5, All: true}},
+	}
+	for _, test := range tests {
+		r := gin.Default()
+		r.GET("/orders", func(ctx *gin.Context) {
+			ctx.Set(middleware.UserIDKey, uint(5))
+			ctx.Set(middleware.PermissionsKey, test.permissions)
+		}, orderHandler.GetAllOrders)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/orders", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, test.expected, gotScope)
+	}
 }
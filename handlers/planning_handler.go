@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/services"
+)
+
+// PlanningHandler interface
+type PlanningHandler interface {
+	PlanLoads(ctx *gin.Context)
+}
+
+// planningHandler struct
+type planningHandler struct {
+	planningService services.PlanningService
+}
+
+// NewPlanningHandler returns a new instance of planningHandler
+func NewPlanningHandler(planningService services.PlanningService) PlanningHandler {
+	return planningHandler{
+		planningService: planningService,
+	}
+}
+
+// PlanLoads method that takes a models.LoadPlanRequest object and returns the orders assigned to trucks
+//
+// PlanLoads godoc
+// @Summary Plan truck loads
+// @Description assign orders to trucks without exceeding their weight or volume capacity
+// @Tags planning
+// @Accept  json
+// @Produce  json
+// @Param request body models.LoadPlanRequest true "Orders and trucks to plan"
+// @Success 200 {object} models.LoadPlan
+func (p planningHandler) PlanLoads(ctx *gin.Context) {
+	var request models.LoadPlanRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	plan, err := p.planningService.PlanLoads(request)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SuccessResponse(ctx, plan)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var mockLoadPlan = models.LoadPlan{
+	Loads: []models.TruckLoad{
+		{
+			Truck:             models.TruckDTO{ID: 1, LicensePlate: "AA111AA", MaxWeight: 1000, MaxVolume: 10},
+			OrderIDs:          []uint{1, 2},
+			Weight:            500,
+			Volume:            5,
+			WeightUtilization: 0.5,
+			VolumeUtilization: 0.5,
+		},
+	},
+	Unplanned: []models.UnplannedOrder{
+		{OrderID: 3, Weight: 2000, Volume: 1, Reason: "larger than any available truck"},
+	},
+}
+
+// mockPlanningService is a mock implementation of the services.PlanningService interface
+type mockPlanningService struct {
+	planLoads func(request models.LoadPlanRequest) (models.LoadPlan, error)
+}
+
+// PlanLoads is a mock function with given fields: request
+func (m *mockPlanningService) PlanLoads(request models.LoadPlanRequest) (models.LoadPlan, error) {
+	return m.planLoads(request)
+}
+
+// newMockPlanningService returns a new instance of mockPlanningService
+func newMockPlanningService() *mockPlanningService {
+	return &mockPlanningService{
+		planLoads: func(request models.LoadPlanRequest) (models.LoadPlan, error) {
+			return mockLoadPlan, nil
+		},
+	}
+}
+
+// newMockPlanningErrorService returns a new instance of mockPlanningService with error
+func newMockPlanningErrorService() *mockPlanningService {
+	return &mockPlanningService{
+		planLoads: func(request models.LoadPlanRequest) (models.LoadPlan, error) {
+			return models.LoadPlan{}, errs.NotFound(errors.New("error"))
+		},
+	}
+}
+
+// TestPlanLoads tests handlers.PlanLoads using mockPlanningService and gin
+func TestPlanLoads(t *testing.T) {
+	planningHandler := NewPlanningHandler(newMockPlanningService())
+
+	r := gin.Default()
+	r.POST("/planning/loads", planningHandler.PlanLoads)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/planning/loads", bytes.NewBufferString(`{"orders":[1,2,3]}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
+
+	var response struct {
+		Data models.LoadPlan `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, mockLoadPlan, response.Data)
+}
+func TestPlanLoads_BindError(t *testing.T) {
+	planningHandler := NewPlanningHandler(newMockPlanningService())
+
+	r := gin.Default()
+	r.POST("/planning/loads", planningHandler.PlanLoads)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/planning/loads", bytes.NewBufferString("{"))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
+}
+func TestPlanLoads_ServiceError(t *testing.T) {
+	planningHandler := NewPlanningHandler(newMockPlanningErrorService())
+
+	r := gin.Default()
+	r.POST("/planning/loads", planningHandler.PlanLoads)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/planning/loads", bytes.NewBufferString(`{"orders":[1]}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code, "Status code should be 404")
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/services"
+	"net/http"
+	"strconv"
+)
+
+// ShipmentHandler is the handler for the shipment resource
+type ShipmentHandler interface {
+	CreateShipment(ctx *gin.Context)
+	GetShipment(ctx *gin.Context)
+	GetAllShipments(ctx *gin.Context)
+	UpdateShipment(ctx *gin.Context)
+	PatchShipment(ctx *gin.Context)
+	DeleteShipment(ctx *gin.Context)
+}
+
+// shipmentHandler is the handler for the shipment resource
+type shipmentHandler struct {
+	shipmentService services.ShipmentService
+}
+
+// NewShipmentHandler returns a new instance of shipmentHandler
+func NewShipmentHandler(shipmentService services.ShipmentService) ShipmentHandler {
+	return shipmentHandler{
+		shipmentService: shipmentService,
+	}
+}
+
+// CreateShipment method that takes a models.Shipment object and saves it to the database
+func (p shipmentHandler) CreateShipment(ctx *gin.Context) {
+	// get the shipment object from the request body
+	// call the shipment service to save the shipment
+	// return the shipment object
+	var request models.CreateShipmentRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	shipment, err := p.shipmentService.CreateShipment(request.Shipment())
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, shipment)
+}
+
+// GetShipment method that takes a shipment id and returns the shipment object
+func (p shipmentHandler) GetShipment(ctx *gin.Context) {
+	// get the shipment id from the request params
+	// call the shipment service to get the shipment
+	// return the shipment object
+	intId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	shipment, err := p.shipmentService.GetShipment(intId)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	if helpers.NotModified(ctx, shipment.Version) {
+		return
+	}
+	ctx.JSON(http.StatusOK, shipment)
+}
+
+// GetAllShipments method that returns all the shipments
+func (p shipmentHandler) GetAllShipments(ctx *gin.Context) {
+	// get the pagination from the query
+	// call the shipment service to get all the shipments
+	// return the shipments
+	var pagination models.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	shipments, err := p.shipmentService.GetAllShipments(pagination)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, shipments.WithLinks(ctx.Request.URL))
+}
+
+// UpdateShipment method that takes a shipment id and a models.Shipment object and updates the shipment
+func (p shipmentHandler) UpdateShipment(ctx *gin.Context) {
+	// get the shipment id from the request params
+	// get the version the request was made to from the If-Match header
+	// get the shipment object from the request body
+	// call the shipment service to update the shipment
+	// return the shipment object
+	intId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	var request models.UpdateShipmentRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	shipment, err := p.shipmentService.UpdateShipment(intId, request.Shipment(), version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SetETag(ctx, shipment.Version)
+	ctx.JSON(http.StatusOK, shipment)
+}
+
+// PatchShipment method that takes a shipment id and applies the merge patch or json patch of the request body to the shipment
+func (p shipmentHandler) PatchShipment(ctx *gin.Context) {
+	// get the shipment id from the request params
+	// get the version the request was made to from the If-Match header
+	// get the stored shipment and apply the patch to it
+	// call the shipment service to replace the shipment with the patched one
+	// return the shipment object
+	intId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	shipment, err := p.shipmentService.GetShipment(intId)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	// the patch is made to the version that was read, unless the request names another one
+	if version == services.AnyVersion {
+		version = shipment.Version
+	}
+	request, err := helpers.ShouldBindPatch[models.UpdateShipmentRequest](ctx, shipment)
+	if err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	shipment, err = p.shipmentService.UpdateShipment(intId, request.Shipment(), version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SetETag(ctx, shipment.Version)
+	ctx.JSON(http.StatusOK, shipment)
+}
+
+// DeleteShipment method that takes a shipment id and deletes the shipment
+func (p shipmentHandler) DeleteShipment(ctx *gin.Context) {
+	// get the shipment id from the request params
+	// get the version the request was made to from the If-Match header
+	// call the shipment service to delete the shipment
+	// return the deleted shipment object
+	intId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	shipment, err := p.shipmentService.DeleteShipment(intId, version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, shipment)
+}
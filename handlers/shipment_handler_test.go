@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var mockShipment = models.Shipment{
+	Model:         gorm.Model{ID: 1},
+	TruckID:       1,
+	DepartureDate: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+	Stops: []models.ShipmentStop{
+		{OrderID: 1, Sequence: 1},
+		{OrderID: 2, Sequence: 2},
+	},
+}
+
+// mockShipmentService is a mock implementation of the services.ShipmentService interface
+type mockShipmentService struct {
+	createShipment  func(shipment models.Shipment) (models.Shipment, error)
+	getShipment     func(id int) (models.Shipment, error)
+	getAllShipments func(pagination models.Pagination) (models.Page[models.Shipment], error)
+	updateShipment  func(id int, shipment models.Shipment, version uint) (models.Shipment, error)
+	deleteShipment  func(id int, version uint) (models.Shipment, error)
+}
+
+// CreateShipment is a mock function with given fields: shipment
+func (m *mockShipmentService) CreateShipment(shipment models.Shipment) (models.Shipment, error) {
+	return m.createShipment(shipment)
+}
+
+// GetShipment is a mock function with given fields: id
+func (m *mockShipmentService) GetShipment(id int) (models.Shipment, error) {
+	return m.getShipment(id)
+}
+
+// GetAllShipments is a mock function with given fields: pagination
+func (m *mockShipmentService) GetAllShipments(pagination models.Pagination) (models.Page[models.Shipment], error) {
+	return m.getAllShipments(pagination)
+}
+
+// UpdateShipment is a mock function with given fields: id, shipment
+func (m *mockShipmentService) UpdateShipment(id int, shipment models.Shipment, version uint) (models.Shipment, error) {
+	return m.updateShipment(id, shipment, version)
+}
+
+// DeleteShipment is a mock function with given fields: id
+func (m *mockShipmentService) DeleteShipment(id int, version uint) (models.Shipment, error) {
+	return m.deleteShipment(id, version)
+}
+
+// newMockShipmentService returns a new instance of mockShipmentService
+func newMockShipmentService() *mockShipmentService {
+	return &mockShipmentService{
+		createShipment: func(shipment models.Shipment) (models.Shipment, error) {
+			return mockShipment, nil
+		},
+		getShipment: func(id int) (models.Shipment, error) {
+			return mockShipment, nil
+		},
+		getAllShipments: func(pagination models.Pagination) (models.Page[models.Shipment], error) {
+			return models.NewPage([]models.Shipment{mockShipment}, 1, pagination), nil
+		},
+		updateShipment: func(id int, shipment models.Shipment, version uint) (models.Shipment, error) {
+			return mockShipment, nil
+		},
+		deleteShipment: func(id int, version uint) (models.Shipment, error) {
+			return mockShipment, nil
+		},
+	}
+}
+
+// newMockShipmentConflictService returns a new instance of mockShipmentService that rejects every change as a conflict
+func newMockShipmentConflictService() *mockShipmentService {
+	conflict := errors.New("truck 1 is already booked on 2024-03-10 by shipment 2")
+	return &mockShipmentService{
+		createShipment: func(shipment models.Shipment) (models.Shipment, error) {
+			return models.Shipment{}, errs.Conflict(conflict)
+		},
+		getShipment: func(id int) (models.Shipment, error) {
+			return models.Shipment{}, errs.NotFound(errors.New("error"))
+		},
+		getAllShipments: func(pagination models.Pagination) (models.Page[models.Shipment], error) {
+			return models.Page[models.Shipment]{}, errors.New("error")
+		},
+		updateShipment: func(id int, shipment models.Shipment, version uint) (models.Shipment, error) {
+			return models.Shipment{}, errs.Conflict(conflict)
+		},
+		deleteShipment: func(id int, version uint) (models.Shipment, error) {
+			return models.Shipment{}, errs.NotFound(errors.New("error"))
+		},
+	}
+}
+
+// TestCreateShipment tests handlers.CreateShipment using mockShipmentService and gin
+func TestCreateShipment(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentService())
+
+	r := gin.Default()
+	r.POST("/shipments", shipmentHandler.CreateShipment)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/shipments", bytes.NewBufferString(`{"truck":1,"departureDate":"2024-03-10T00:00:00Z","stops":[{"order":1},{"order":2}]}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var shipment models.Shipment
+	err := json.Unmarshal(w.Body.Bytes(), &shipment)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, mockShipment.Stops, shipment.Stops)
+}
+func TestCreateShipment_BindError(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentService())
+
+	r := gin.Default()
+	r.POST("/shipments", shipmentHandler.CreateShipment)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/shipments", bytes.NewBufferString("{"))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+func TestCreateShipment_Conflict(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentConflictService())
+
+	r := gin.Default()
+	r.POST("/shipments", shipmentHandler.CreateShipment)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/shipments", bytes.NewBufferString(`{"truck":1,"departureDate":"2024-01-01T08:00:00Z","stops":[{"order":1}]}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Contains(t, response.Detail, "already booked")
+}
+
+// TestGetShipment tests handlers.GetShipment using mockShipmentService and gin
+func TestGetShipment(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentService())
+
+	r := gin.Default()
+	r.GET("/shipments/:id", shipmentHandler.GetShipment)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/shipments/1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+func TestGetShipment_InvalidID(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentService())
+
+	r := gin.Default()
+	r.GET("/shipments/:id", shipmentHandler.GetShipment)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/shipments/abc", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+func TestGetShipment_NotFound(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentConflictService())
+
+	r := gin.Default()
+	r.GET("/shipments/:id", shipmentHandler.GetShipment)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/shipments/1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestGetAllShipments tests handlers.GetAllShipments using mockShipmentService and gin
+func TestGetAllShipments(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentService())
+
+	r := gin.Default()
+	r.GET("/shipments", shipmentHandler.GetAllShipments)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/shipments?page=1&limit=10", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var shipments models.Page[models.Shipment]
+	err := json.Unmarshal(w.Body.Bytes(), &shipments)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Len(t, shipments.Data, 1)
+	assert.Equal(t, 1, *shipments.TotalPages)
+}
+
+// TestUpdateShipment tests handlers.UpdateShipment using mockShipmentService and gin
+func TestUpdateShipment(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentService())
+
+	r := gin.Default()
+	r.PUT("/shipments/:id", shipmentHandler.UpdateShipment)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/shipments/1", bytes.NewBufferString(`{"truck":1,"departureDate":"2024-01-01T08:00:00Z","stops":[{"order":2},{"order":1}]}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+func TestUpdateShipment_Conflict(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentConflictService())
+
+	r := gin.Default()
+	r.PUT("/shipments/:id", shipmentHandler.UpdateShipment)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/shipments/1", bytes.NewBufferString(`{"truck":1,"departureDate":"2024-01-01T08:00:00Z","stops":[{"order":1}]}`))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// TestDeleteShipment tests handlers.DeleteShipment using mockShipmentService and gin
+func TestDeleteShipment(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentService())
+
+	r := gin.Default()
+	r.DELETE("/shipments/:id", shipmentHandler.DeleteShipment)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/shipments/1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+func TestDeleteShipment_NotFound(t *testing.T) {
+	shipmentHandler := NewShipmentHandler(newMockShipmentConflictService())
+
+	r := gin.Default()
+	r.DELETE("/shipments/:id", shipmentHandler.DeleteShipment)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/shipments/1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
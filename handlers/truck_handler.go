@@ -15,6 +15,7 @@ type TruckHandler interface {
 	GetTruck(ctx *gin.Context)
 	GetAllTrucks(ctx *gin.Context)
 	UpdateTruck(ctx *gin.Context)
+	PatchTruck(ctx *gin.Context)
 	DeleteTruck(ctx *gin.Context)
 }
 
@@ -38,17 +39,17 @@ func NewTruckHandler(truckService services.TruckService) TruckHandler {
 // @Tags trucks
 // @Accept  json
 // @Produce  json
-// @Param truck body models.Truck true "Truck object"
+// @Param truck body models.CreateTruckRequest true "Truck object"
 // @Success 200 {object} models.Truck
 func (p truckHandler) CreateTruck(ctx *gin.Context) {
-	var truck models.Truck
-	if err := ctx.ShouldBindJSON(&truck); err != nil {
-		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+	var request models.CreateTruckRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
 		return
 	}
-	truckDTO, status, err := p.truckService.CreateTruck(truck)
+	truckDTO, err := p.truckService.CreateTruck(request.Truck())
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
 	helpers.SuccessResponse(ctx, truckDTO)
@@ -62,9 +63,12 @@ func (p truckHandler) GetTruck(ctx *gin.Context) {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	truckDTO, status, err := p.truckService.GetTruck(intId)
+	truckDTO, err := p.truckService.GetTruck(intId)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	if helpers.NotModified(ctx, truckDTO.Version) {
 		return
 	}
 	helpers.SuccessResponse(ctx, truckDTO)
@@ -77,12 +81,17 @@ func (p truckHandler) GetAllTrucks(ctx *gin.Context) {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	trucksDTO, status, err := p.truckService.GetAllTrucks(pagination)
+	query, err := models.ParseListQuery(ctx.Query("filter"), ctx.Query("sort"), models.TruckQueryFields)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	helpers.SuccessResponse(ctx, trucksDTO)
+	trucksDTO, err := p.truckService.GetAllTrucks(pagination, query)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SuccessResponse(ctx, trucksDTO.WithLinks(ctx.Request.URL))
 }
 
 // UpdateTruck method that takes a truck id and updates the truck in the database
@@ -93,16 +102,58 @@ func (p truckHandler) UpdateTruck(ctx *gin.Context) {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	var truck models.Truck
-	if err := ctx.ShouldBindJSON(&truck); err != nil {
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	var request models.UpdateTruckRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	truckDTO, err := p.truckService.UpdateTruck(intId, request.Truck(), version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SetETag(ctx, truckDTO.Version)
+	helpers.SuccessResponse(ctx, truckDTO)
+}
+
+// PatchTruck method that takes a truck id and applies the merge patch or json patch of the request body to the truck
+func (p truckHandler) PatchTruck(ctx *gin.Context) {
+	id := ctx.Param("id")
+	intId, err := strconv.Atoi(id)
+	if err != nil {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	truckDTO, status, err := p.truckService.UpdateTruck(intId, truck)
+	version, err := helpers.IfMatch(ctx)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
+	truckDTO, err := p.truckService.GetTruck(intId)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	// the patch is made to the version that was read, unless the request names another one
+	if version == services.AnyVersion {
+		version = truckDTO.Version
+	}
+	request, err := helpers.ShouldBindPatch[models.UpdateTruckRequest](ctx, truckDTO)
+	if err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	truckDTO, err = p.truckService.UpdateTruck(intId, request.Truck(), version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SetETag(ctx, truckDTO.Version)
 	helpers.SuccessResponse(ctx, truckDTO)
 }
 
@@ -114,9 +165,14 @@ func (p truckHandler) DeleteTruck(ctx *gin.Context) {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	truckDTO, status, err := p.truckService.DeleteTruck(intId)
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	truckDTO, err := p.truckService.DeleteTruck(intId, version)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
 	helpers.SuccessResponse(ctx, truckDTO)
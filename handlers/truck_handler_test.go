@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/helpers"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/peteprogrammer/go-automapper"
 	"github.com/stretchr/testify/assert"
@@ -50,65 +51,65 @@ var mockTrucks = []models.Truck{
 
 // mockTruckService is a mock implementation of the TruckService interface
 type mockTruckService struct {
-	createTruck  func(truck models.Truck) (models.TruckDTO, int, error)
-	getTruck     func(id int) (models.TruckDTO, int, error)
-	getAllTrucks func(pagination models.Pagination) ([]models.TruckDTO, int, error)
-	updateTruck  func(id int, truck models.Truck) (models.TruckDTO, int, error)
-	deleteTruck  func(id int) (models.TruckDTO, int, error)
+	createTruck  func(truck models.Truck) (models.TruckDTO, error)
+	getTruck     func(id int) (models.TruckDTO, error)
+	getAllTrucks func(pagination models.Pagination, query models.ListQuery) (models.Page[models.TruckDTO], error)
+	updateTruck  func(id int, truck models.Truck, version uint) (models.TruckDTO, error)
+	deleteTruck  func(id int, version uint) (models.TruckDTO, error)
 }
 
 // CreateTruck is a mock implementation of the CreateTruck method
-func (m mockTruckService) CreateTruck(truck models.Truck) (models.TruckDTO, int, error) {
+func (m mockTruckService) CreateTruck(truck models.Truck) (models.TruckDTO, error) {
 	return m.createTruck(truck)
 }
 
 // GetTruck is a mock implementation of the GetTruck method
-func (m mockTruckService) GetTruck(id int) (models.TruckDTO, int, error) {
+func (m mockTruckService) GetTruck(id int) (models.TruckDTO, error) {
 	return m.getTruck(id)
 }
 
 // GetAllTrucks is a mock implementation of the GetAllTrucks method
-func (m mockTruckService) GetAllTrucks(pagination models.Pagination) ([]models.TruckDTO, int, error) {
-	return m.getAllTrucks(pagination)
+func (m mockTruckService) GetAllTrucks(pagination models.Pagination, query models.ListQuery) (models.Page[models.TruckDTO], error) {
+	return m.getAllTrucks(pagination, query)
 }
 
 // UpdateTruck is a mock implementation of the UpdateTruck method
-func (m mockTruckService) UpdateTruck(id int, truck models.Truck) (models.TruckDTO, int, error) {
-	return m.updateTruck(id, truck)
+func (m mockTruckService) UpdateTruck(id int, truck models.Truck, version uint) (models.TruckDTO, error) {
+	return m.updateTruck(id, truck, version)
 }
 
 // DeleteTruck is a mock implementation of the DeleteTruck method
-func (m mockTruckService) DeleteTruck(id int) (models.TruckDTO, int, error) {
-	return m.deleteTruck(id)
+func (m mockTruckService) DeleteTruck(id int, version uint) (models.TruckDTO, error) {
+	return m.deleteTruck(id, version)
 }
 
 // newMockTruckService returns a new instance of mockTruckService
 func newMockTruckService() *mockTruckService {
 	return &mockTruckService{
-		createTruck: func(truck models.Truck) (models.TruckDTO, int, error) {
+		createTruck: func(truck models.Truck) (models.TruckDTO, error) {
 			var truckDTO models.TruckDTO
 			automapper.Map(truck, &truckDTO)
-			return truckDTO, http.StatusOK, nil
+			return truckDTO, nil
 		},
-		getTruck: func(id int) (models.TruckDTO, int, error) {
+		getTruck: func(id int) (models.TruckDTO, error) {
 			var truckDTO models.TruckDTO
 			automapper.Map(mockTrucks[id-1], &truckDTO)
-			return truckDTO, http.StatusOK, nil
+			return truckDTO, nil
 		},
-		getAllTrucks: func(pagination models.Pagination) ([]models.TruckDTO, int, error) {
+		getAllTrucks: func(pagination models.Pagination, query models.ListQuery) (models.Page[models.TruckDTO], error) {
 			var trucksDTO []models.TruckDTO
 			automapper.Map(mockTrucks, &trucksDTO)
-			return trucksDTO, http.StatusOK, nil
+			return models.NewPage(trucksDTO, int64(len(trucksDTO)), pagination), nil
 		},
-		updateTruck: func(id int, truck models.Truck) (models.TruckDTO, int, error) {
+		updateTruck: func(id int, truck models.Truck, version uint) (models.TruckDTO, error) {
 			var truckDTO models.TruckDTO
 			automapper.Map(truck, &truckDTO)
-			return truckDTO, http.StatusOK, nil
+			return truckDTO, nil
 		},
-		deleteTruck: func(id int) (models.TruckDTO, int, error) {
+		deleteTruck: func(id int, version uint) (models.TruckDTO, error) {
 			var truckDTO models.TruckDTO
 			automapper.Map(mockTrucks[id-1], &truckDTO)
-			return truckDTO, http.StatusOK, nil
+			return truckDTO, nil
 		},
 	}
 }
@@ -116,20 +117,20 @@ func newMockTruckService() *mockTruckService {
 // newMockTruckErrorService returns a new instance of mockTruckService with errors
 func newMockTruckErrorService() *mockTruckService {
 	return &mockTruckService{
-		createTruck: func(truck models.Truck) (models.TruckDTO, int, error) {
-			return models.TruckDTO{}, http.StatusInternalServerError, nil
+		createTruck: func(truck models.Truck) (models.TruckDTO, error) {
+			return models.TruckDTO{}, nil
 		},
-		getTruck: func(id int) (models.TruckDTO, int, error) {
-			return models.TruckDTO{}, http.StatusInternalServerError, nil
+		getTruck: func(id int) (models.TruckDTO, error) {
+			return models.TruckDTO{}, nil
 		},
-		getAllTrucks: func(pagination models.Pagination) ([]models.TruckDTO, int, error) {
-			return []models.TruckDTO{}, http.StatusInternalServerError, nil
+		getAllTrucks: func(pagination models.Pagination, query models.ListQuery) (models.Page[models.TruckDTO], error) {
+			return models.Page[models.TruckDTO]{}, nil
 		},
-		updateTruck: func(id int, truck models.Truck) (models.TruckDTO, int, error) {
-			return models.TruckDTO{}, http.StatusInternalServerError, nil
+		updateTruck: func(id int, truck models.Truck, version uint) (models.TruckDTO, error) {
+			return models.TruckDTO{}, nil
 		},
-		deleteTruck: func(id int) (models.TruckDTO, int, error) {
-			return models.TruckDTO{}, http.StatusInternalServerError, nil
+		deleteTruck: func(id int, version uint) (models.TruckDTO, error) {
+			return models.TruckDTO{}, nil
 		},
 	}
 }
@@ -151,3 +152,23 @@ func TestCreateTruck(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
 
 }
+
+// TestCreateTruck_ValidationError tests the CreateTruck method with an invalid license plate
+func TestCreateTruck_ValidationError(t *testing.T) {
+	mockTruckService := newMockTruckService()
+	truckHandler := NewTruckHandler(mockTruckService)
+
+	r := gin.Default()
+	r.POST("/trucks", truckHandler.CreateTruck)
+	w := httptest.NewRecorder()
+	body := `{"chassisNumber":"1HGBH41JXMN109186","licensePlate":"NO PLATE!","maxWeight":1000,"maxVolume":10}`
+	req, _ := http.NewRequest(http.MethodPost, "/trucks", bytes.NewBufferString(body))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
+
+	var response helpers.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err, "Error while unmarshalling response: %v", err)
+	assert.Equal(t, []helpers.FieldError{{Field: "licensePlate", Message: "must be a license plate like AB123CD or AB-123-CD"}}, response.Errors)
+}
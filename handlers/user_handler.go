@@ -3,9 +3,9 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/middleware"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/laertkokona/crud-test/services"
-	"github.com/peteprogrammer/go-automapper"
 	"net/http"
 	"strconv"
 )
@@ -16,14 +16,20 @@ type UserHandler interface {
 	GetUser(ctx *gin.Context)
 	GetAllUsers(ctx *gin.Context)
 	SignInUser(ctx *gin.Context)
+	RefreshToken(ctx *gin.Context)
 	SignOutUser(ctx *gin.Context)
+	SignOutAllUser(ctx *gin.Context)
 	UpdateUser(ctx *gin.Context)
+	PatchUser(ctx *gin.Context)
 	DeleteUser(ctx *gin.Context)
 	CreateRole(ctx *gin.Context)
 	GetRole(ctx *gin.Context)
 	GetAllRoles(ctx *gin.Context)
 	UpdateRole(ctx *gin.Context)
+	PatchRole(ctx *gin.Context)
 	DeleteRole(ctx *gin.Context)
+	GetAllPermissions(ctx *gin.Context)
+	SetRolePermissions(ctx *gin.Context)
 }
 
 // userHandler struct that implements UserHandler interface and has a userService field and a roleService field
@@ -42,22 +48,23 @@ type userHandler struct {
 // @ID create-user
 // @Accept  json
 // @Produce  json
-// @Param user body models.User true "User object"
+// @Param user body models.CreateUserRequest true "User object"
 // @Success 200 {object} helpers.JSONSuccessResult{data=models.UserDTO}
-// @Failure 400 {object} helpers.JSONBadRequestResult
-// @Failure 401 {object} helpers.JSONUnauthorizedResult
-// @Failure 500 {object} helpers.JSONInternalServerErrorResult
+// @Failure 400 {object} helpers.Problem
+// @Failure 401 {object} helpers.Problem
+// @Failure 409 {object} helpers.Problem
+// @Failure 500 {object} helpers.Problem
 // @Router /users [post]
 func (u userHandler) CreateUser(ctx *gin.Context) {
-	var user models.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+	var request models.CreateUserRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
 		return
 	}
-	userDTO, status, err := u.userService.CreateUser(user)
+	userDTO, err := u.userService.CreateUser(request.User())
 	if err != nil {
 		//ctx.JSON(status, gin.H{"error": err.Error()})
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
 	//ctx.JSON(status, user)
@@ -75,26 +82,31 @@ func (u userHandler) CreateUser(ctx *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id path int true "User ID"
+// @Param        If-None-Match header string false "ETag of the version the client has"
 // @Success      200 {object} helpers.JSONSuccessResult{data=models.UserDTO}
-// @Failure      400 {object} helpers.JSONBadRequestResult
-// @Failure      401 {object} helpers.JSONUnauthorizedResult
-// @Failure      404 {object} helpers.JSONNotFoundResult
-// @Failure      500 {object} helpers.JSONInternalServerErrorResult
+// @Success      304 "Not Modified"
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      404 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /users/{id} [get]
 func (u userHandler) GetUser(ctx *gin.Context) {
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	user, status, err := u.userService.GetUser(intId)
+	user, err := u.userService.GetUser(intId)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		//ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
+	if helpers.NotModified(ctx, user.Version) {
+		return
+	}
 	helpers.SuccessResponse(ctx, user)
 	//ctx.JSON(status, user)
 }
@@ -110,11 +122,14 @@ func (u userHandler) GetUser(ctx *gin.Context) {
 // @Tags User
 // @Param 	  	 page query string false "Page number"
 // @Param 	  	 limit query string false "Limit number"
+// @Param 	  	 after query string false "Cursor of the last user of the previous page, used instead of the page"
+// @Param 	  	 filter query string false "Filters as field:operator:value separated by commas, like role:eq:1,username:like:doe"
+// @Param 	  	 sort query string false "Fields to sort by separated by commas, descending when they start with -, like -lastName,firstName"
 // Param		 Bearer header string true "Bearer token"
-// @Success      200 {array} helpers.JSONSuccessResult{data=[]models.UserDTO}
-// @Failure      400 {object} helpers.JSONBadRequestResult
-// @Failure      401 {object} helpers.JSONUnauthorizedResult
-// @Failure      500 {object} helpers.JSONInternalServerErrorResult
+// @Success      200 {object} helpers.JSONSuccessResult{data=models.Page[models.UserDTO]}
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /users [get]
 func (u userHandler) GetAllUsers(ctx *gin.Context) {
 	page := ctx.Query("page")
@@ -122,32 +137,36 @@ func (u userHandler) GetAllUsers(ctx *gin.Context) {
 	intPage, err := strconv.Atoi(page)
 	if err != nil {
 		intPage = 0
-		//ctx.JSON(400, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		//return
 	}
 	intLimit, err := strconv.Atoi(limit)
 	if err != nil {
 		intLimit = 0
-		//ctx.JSON(400, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		//return
 	}
 	pagination := models.Pagination{
 		Page:  intPage,
 		Limit: intLimit,
+		After: ctx.Query("after"),
+	}
+	query, err := models.ParseListQuery(ctx.Query("filter"), ctx.Query("sort"), models.UserQueryFields)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
 	}
-	users, status, err := u.userService.GetAllUsers(pagination)
-	var usersDTO []models.UserDTO
+	users, err := u.userService.GetAllUsers(pagination, query)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		//ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
-	automapper.Map(users, &usersDTO)
 	//ctx.JSON(status, usersDTO)
-	helpers.SuccessResponse(ctx, usersDTO)
+	helpers.SuccessResponse(ctx, users.WithLinks(ctx.Request.URL))
 }
 
-// SignInUser gets a user object from the request body and returns a token
+// SignInUser gets a user object from the request body and returns an access token and a refresh token
 //
 // SignInUser godoc
 // @Summary      Sign in user
@@ -156,44 +175,112 @@ func (u userHandler) GetAllUsers(ctx *gin.Context) {
 // @Produce      json
 // @Tags Auth
 // @Param        user body models.Login true "User object"
-// @Success      200 {object} helpers.JSONSuccessResultNoData
-// @Failure      400 {object} helpers.JSONBadRequestResult
-// @Failure      401 {object} helpers.JSONUnauthorizedResult
-// @Failure      500 {object} helpers.JSONInternalServerErrorResult
+// @Success      200 {object} models.TokenPair
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /signIn [post]
 func (u userHandler) SignInUser(ctx *gin.Context) {
 	var loginUser models.Login
 	if err := ctx.ShouldBindJSON(&loginUser); err != nil {
-		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		helpers.BindErrorResponse(ctx, err)
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	tokens, err := u.userService.SignInUser(loginUser)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.Header("Authorization", tokens.AccessToken)
+	helpers.SuccessResponse(ctx, tokens)
+}
+
+// RefreshToken gets a refresh token from the request body and returns a new access token and refresh token
+//
+// RefreshToken godoc
+// @Summary      Refresh tokens
+// @Description  Exchange a refresh token for a new access token and refresh token, each refresh token can only be used once
+// @Accept       json
+// @Produce      json
+// @Tags Auth
+// @Param        request body models.RefreshRequest true "Refresh token"
+// @Success      200 {object} models.TokenPair
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
+// @Router       /token/refresh [post]
+func (u userHandler) RefreshToken(ctx *gin.Context) {
+	var request models.RefreshRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
 		return
 	}
-	token, status, err := u.userService.SignInUser(loginUser)
+	tokens, err := u.userService.RefreshToken(request.RefreshToken)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	ctx.Header("Authorization", token)
-	helpers.SuccessResponseNoData(ctx)
+	ctx.Header("Authorization", tokens.AccessToken)
+	helpers.SuccessResponse(ctx, tokens)
 }
 
-// SignOutUser gets a user object from the request body and returns a token
+// SignOutUser revokes the token of the request and the refresh token in the request body, if any, and returns an expired token
 //
 // SignOutUser godoc
 // @Summary      Sign out user
-// @Description  Sign out user
+// @Description  Sign out user by revoking the presented token and the session of the given refresh token
+// @Security 	 ApiKeyAuth
 // @Accept       json
 // @Produce      json
 // @Tags Auth
+// @Param        request body models.SignOutRequest false "Refresh token of the session"
 // @Success      200 {object} helpers.JSONSuccessResultNoData
+// @Failure      401 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /signOut [post]
 func (u userHandler) SignOutUser(ctx *gin.Context) {
-	expToken := u.userService.SignOutUser()
+	// the body is optional, without it only the access token is revoked
+	var request models.SignOutRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			helpers.BindErrorResponse(ctx, err)
+			return
+		}
+	}
+	expToken, err := u.userService.SignOutUser(ctx.GetString(middleware.TokenIDKey), ctx.GetUint(middleware.UserIDKey), ctx.GetTime(middleware.TokenExpiresAtKey), request.RefreshToken)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
 	ctx.Header("Authorization", expToken)
 	helpers.SuccessResponse(ctx, gin.H{"message": "User logged out successfully"})
 	//ctx.JSON(http.StatusOK, gin.H{"message": "User logged out successfully"})
 }
 
+// SignOutAllUser revokes every token of the user of the request and returns an expired token
+//
+// SignOutAllUser godoc
+// @Summary      Sign out user everywhere
+// @Description  Sign out user from every device by revoking all the tokens issued to them
+// @Security 	 ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Tags Auth
+// @Success      200 {object} helpers.JSONSuccessResultNoData
+// @Failure      401 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
+// @Router       /signOutAll [post]
+func (u userHandler) SignOutAllUser(ctx *gin.Context) {
+	expToken, err := u.userService.SignOutAllUser(ctx.GetUint(middleware.UserIDKey))
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	ctx.Header("Authorization", expToken)
+	helpers.SuccessResponse(ctx, gin.H{"message": "User logged out everywhere successfully"})
+}
+
 // UpdateUser gets a user object from the request body and returns the updated user object
 //
 // UpdateUser godoc
@@ -204,36 +291,102 @@ func (u userHandler) SignOutUser(ctx *gin.Context) {
 // @Security 	 ApiKeyAuth
 // @Tags User
 // @Param        id path string true "User ID"
-// @Param        user body models.User true "User object"
+// @Param        If-Match header string false "ETag of the version the change is made to"
+// @Param        user body models.UpdateUserRequest true "User object"
 // @Success      200 {object} helpers.JSONSuccessResult{data=models.UserDTO}
-// @Failure      400 {object} helpers.JSONBadRequestResult
-// @Failure      401 {object} helpers.JSONUnauthorizedResult
-// @Failure      500 {object} helpers.JSONInternalServerErrorResult
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      409 {object} helpers.Problem
+// @Failure      412 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /users/{id} [put]
 func (u userHandler) UpdateUser(ctx *gin.Context) {
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	var user models.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	var request models.UpdateUserRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	userDTO, status, err := u.userService.UpdateUser(intId, user)
+	userDTO, err := u.userService.UpdateUser(intId, request.User(), version)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		//ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
+	helpers.SetETag(ctx, userDTO.Version)
 	helpers.SuccessResponse(ctx, userDTO)
 	//ctx.JSON(status, user)
 }
 
+// PatchUser applies the merge patch or json patch of the request body to a user and returns the updated user object
+//
+// PatchUser godoc
+// @Summary      Patch user
+// @Description  Patch user with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Accept       application/merge-patch+json,application/json-patch+json
+// @Produce      json
+// @Security 	 ApiKeyAuth
+// @Tags User
+// @Param        id path string true "User ID"
+// @Param        If-Match header string false "ETag of the version the change is made to"
+// @Param        patch body object true "Merge patch or json patch"
+// @Success      200 {object} helpers.JSONSuccessResult{data=models.UserDTO}
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      404 {object} helpers.Problem
+// @Failure      409 {object} helpers.Problem
+// @Failure      412 {object} helpers.Problem
+// @Failure      415 {object} helpers.Problem
+// @Failure      422 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
+// @Router       /users/{id} [patch]
+func (u userHandler) PatchUser(ctx *gin.Context) {
+	id := ctx.Param("id")
+	intId, err := strconv.Atoi(id)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	userDTO, err := u.userService.GetUser(intId)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	// the patch is made to the version that was read, unless the request names another one
+	if version == services.AnyVersion {
+		version = userDTO.Version
+	}
+	request, err := helpers.ShouldBindPatch[models.UpdateUserRequest](ctx, userDTO)
+	if err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	userDTO, err = u.userService.UpdateUser(intId, request.User(), version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SetETag(ctx, userDTO.Version)
+	helpers.SuccessResponse(ctx, userDTO)
+}
+
 // DeleteUser gets a user object from the request body and returns the updated user object
 //
 // DeleteUser godoc
@@ -244,10 +397,12 @@ func (u userHandler) UpdateUser(ctx *gin.Context) {
 // @Security 	 ApiKeyAuth
 // @Tags User
 // @Param        id path string true "User ID"
+// @Param        If-Match header string false "ETag of the version the change is made to"
 // @Success      200 {object} helpers.JSONSuccessResult{data=models.UserDTO}
-// @Failure      400 {object} helpers.JSONBadRequestResult
-// @Failure      401 {object} helpers.JSONUnauthorizedResult
-// @Failure      500 {object} helpers.JSONInternalServerErrorResult
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      412 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /users/{id} [delete]
 func (u userHandler) DeleteUser(ctx *gin.Context) {
 	// get id from url
@@ -258,12 +413,17 @@ func (u userHandler) DeleteUser(ctx *gin.Context) {
 	intId, err := strconv.Atoi(id)
 	if err != nil {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	user, status, err := u.userService.DeleteUser(intId)
+	version, err := helpers.IfMatch(ctx)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	user, err := u.userService.DeleteUser(intId, version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
 		//ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
@@ -280,22 +440,22 @@ func (u userHandler) DeleteUser(ctx *gin.Context) {
 // @Produce      json
 // @Security 	 ApiKeyAuth
 // @Tags Role
-// @Param        role body models.Role true "Role object"
+// @Param        role body models.RoleRequest true "Role object"
 // @Success      200 {object} helpers.JSONSuccessResult{data=models.RoleDTO}
-// @Failure      400 {object} helpers.JSONBadRequestResult
-// @Failure      401 {object} helpers.JSONUnauthorizedResult
-// @Failure      500 {object} helpers.JSONInternalServerErrorResult
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /roles [post]
 func (u userHandler) CreateRole(ctx *gin.Context) {
-	var role models.Role
-	if err := ctx.ShouldBindJSON(&role); err != nil {
-		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request models.RoleRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	userDTO, status, err := u.roleService.CreateRole(role)
+	userDTO, err := u.roleService.CreateRole(request.Role())
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		//ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
@@ -313,51 +473,63 @@ func (u userHandler) CreateRole(ctx *gin.Context) {
 // @Security 	 ApiKeyAuth
 // @Tags Role
 // @Param        id path string true "Role ID"
+// @Param        If-None-Match header string false "ETag of the version the client has"
 // @Success      200 {object} helpers.JSONSuccessResult{data=models.RoleDTO}
-// @Failure      400 {object} helpers.JSONBadRequestResult
-// @Failure      401 {object} helpers.JSONUnauthorizedResult
-// @Failure      500 {object} helpers.JSONInternalServerErrorResult
+// @Success      304 "Not Modified"
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /roles/{id} [get]
 func (u userHandler) GetRole(ctx *gin.Context) {
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	role, status, err := u.roleService.GetRole(intId)
+	role, err := u.roleService.GetRole(intId)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		//ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
+	if helpers.NotModified(ctx, role.Version) {
+		return
+	}
 	helpers.SuccessResponse(ctx, role)
 	//ctx.JSON(status, role)
 }
 
-// GetAllRoles gets a role object from the request body and returns the created role object
+// GetAllRoles returns a page of the roles, the roles are only paged by number
 //
 // GetAllRoles godoc
 // @Summary      Get all roles
-// @Description  Get all roles
+// @Description  Get a page of the roles
 // @Accept       json
 // @Produce      json
+// @Param 	  	 page query string false "Page number"
+// @Param 	  	 limit query string false "Limit number"
 // @Security 	 ApiKeyAuth
 // @Tags Role
-// @Success      200 {object} helpers.JSONSuccessResult{data=[]models.RoleDTO}
-// @Failure      400 {object} helpers.JSONBadRequestResult
-// @Failure      401 {object} helpers.JSONUnauthorizedResult
-// @Failure      500 {object} helpers.JSONInternalServerErrorResult
+// @Success      200 {object} helpers.JSONSuccessResult{data=models.Page[models.RoleDTO]}
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /roles [get]
 func (u userHandler) GetAllRoles(ctx *gin.Context) {
-	roles, status, err := u.roleService.GetAllRoles()
+	var pagination models.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	roles, err := u.roleService.GetAllRoles(pagination)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		//ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
-	helpers.SuccessResponse(ctx, roles)
+	helpers.SuccessResponse(ctx, roles.WithLinks(ctx.Request.URL))
 	//ctx.JSON(status, roles)
 }
 
@@ -371,36 +543,101 @@ func (u userHandler) GetAllRoles(ctx *gin.Context) {
 // @Security 	 ApiKeyAuth
 // @Tags Role
 // @Param        id path string true "Role ID"
-// @Param        role body models.Role true "Role object"
+// @Param        If-Match header string false "ETag of the version the change is made to"
+// @Param        role body models.RoleRequest true "Role object"
 // @Success      200 {object} helpers.JSONSuccessResult{data=models.RoleDTO}
-// @Failure      400 {object} helpers.JSONBadRequestResult
-// @Failure      401 {object} helpers.JSONUnauthorizedResult
-// @Failure      500 {object} helpers.JSONInternalServerErrorResult
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      412 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /roles/{id} [put]
 func (u userHandler) UpdateRole(ctx *gin.Context) {
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	var role models.Role
-	if err := ctx.ShouldBindJSON(&role); err != nil {
-		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
 		return
 	}
-	roleDTO, status, err := u.roleService.UpdateRole(intId, role)
+	var request models.RoleRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	roleDTO, err := u.roleService.UpdateRole(intId, request.Role(), version)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
 		//ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
+	helpers.SetETag(ctx, roleDTO.Version)
 	helpers.SuccessResponse(ctx, roleDTO)
 	//ctx.JSON(status, role)
 }
 
+// PatchRole applies the merge patch or json patch of the request body to a role and returns the updated role object
+//
+// PatchRole godoc
+// @Summary      Patch role
+// @Description  Patch role with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Accept       application/merge-patch+json,application/json-patch+json
+// @Produce      json
+// @Security 	 ApiKeyAuth
+// @Tags Role
+// @Param        id path string true "Role ID"
+// @Param        If-Match header string false "ETag of the version the change is made to"
+// @Param        patch body object true "Merge patch or json patch"
+// @Success      200 {object} helpers.JSONSuccessResult{data=models.RoleDTO}
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      404 {object} helpers.Problem
+// @Failure      409 {object} helpers.Problem
+// @Failure      412 {object} helpers.Problem
+// @Failure      415 {object} helpers.Problem
+// @Failure      422 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
+// @Router       /roles/{id} [patch]
+func (u userHandler) PatchRole(ctx *gin.Context) {
+	id := ctx.Param("id")
+	intId, err := strconv.Atoi(id)
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	version, err := helpers.IfMatch(ctx)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	roleDTO, err := u.roleService.GetRole(intId)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	// the patch is made to the version that was read, unless the request names another one
+	if version == services.AnyVersion {
+		version = roleDTO.Version
+	}
+	request, err := helpers.ShouldBindPatch[models.RoleRequest](ctx, roleDTO)
+	if err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	roleDTO, err = u.roleService.UpdateRole(intId, request.Role(), version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SetETag(ctx, roleDTO.Version)
+	helpers.SuccessResponse(ctx, roleDTO)
+}
+
 // DeleteRole gets a role object from the request body and returns the created role object
 //
 // DeleteRole godoc
@@ -411,22 +648,29 @@ func (u userHandler) UpdateRole(ctx *gin.Context) {
 // @Security 	 ApiKeyAuth
 // @Tags Role
 // @Param        id path string true "Role ID"
+// @Param        If-Match header string false "ETag of the version the change is made to"
 // @Success      200 {object} helpers.JSONSuccessResult{data=models.RoleDTO}
-// @Failure      400 {object} helpers.JSONBadRequestResult
-// @Failure      401 {object} helpers.JSONUnauthorizedResult
-// @Failure      500 {object} helpers.JSONInternalServerErrorResult
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      412 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
 // @Router       /roles/{id} [delete]
 func (u userHandler) DeleteRole(ctx *gin.Context) {
 	id := ctx.Param("id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
 		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
-		//ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		//helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
-	role, status, err := u.roleService.DeleteRole(intId)
+	version, err := helpers.IfMatch(ctx)
 	if err != nil {
-		helpers.FailedResponse(ctx, status, err.Error(), nil)
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	role, err := u.roleService.DeleteRole(intId, version)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
 		//ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
@@ -434,6 +678,73 @@ func (u userHandler) DeleteRole(ctx *gin.Context) {
 	//ctx.JSON(status, role)
 }
 
+// GetAllPermissions returns a page of the permissions a role can be given, they are only paged by number
+//
+// GetAllPermissions godoc
+// @Summary      Get all permissions
+// @Description  Get a page of the permissions
+// @Accept       json
+// @Produce      json
+// @Param 	  	 page query string false "Page number"
+// @Param 	  	 limit query string false "Limit number"
+// @Security 	 ApiKeyAuth
+// @Tags Role
+// @Success      200 {object} helpers.JSONSuccessResult{data=models.Page[models.Permission]}
+// @Failure      401 {object} helpers.Problem
+// @Failure      403 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
+// @Router       /permissions [get]
+func (u userHandler) GetAllPermissions(ctx *gin.Context) {
+	var pagination models.Pagination
+	if err := ctx.ShouldBindQuery(&pagination); err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	permissions, err := u.roleService.GetAllPermissions(pagination)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SuccessResponse(ctx, permissions.WithLinks(ctx.Request.URL))
+}
+
+// SetRolePermissions gets permission names from the request body and replaces the permissions of the role
+//
+// SetRolePermissions godoc
+// @Summary      Set role permissions
+// @Description  Replace the permissions of a role, users of the role get them with their next token
+// @Accept       json
+// @Produce      json
+// @Security 	 ApiKeyAuth
+// @Tags Role
+// @Param        id path string true "Role ID"
+// @Param        permissions body models.RolePermissions true "Permission names"
+// @Success      200 {object} helpers.JSONSuccessResult{data=models.RoleDTO}
+// @Failure      400 {object} helpers.Problem
+// @Failure      401 {object} helpers.Problem
+// @Failure      403 {object} helpers.Problem
+// @Failure      404 {object} helpers.Problem
+// @Failure      500 {object} helpers.Problem
+// @Router       /roles/{id}/permissions [put]
+func (u userHandler) SetRolePermissions(ctx *gin.Context) {
+	intId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		helpers.FailedResponse(ctx, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	var request models.RolePermissions
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		helpers.BindErrorResponse(ctx, err)
+		return
+	}
+	role, err := u.roleService.SetRolePermissions(intId, request.Permissions)
+	if err != nil {
+		helpers.ErrorResponse(ctx, err)
+		return
+	}
+	helpers.SuccessResponse(ctx, role)
+}
+
 func NewUserHandler(userService services.UserService, roleService services.RoleService) UserHandler {
 	return userHandler{
 		userService: userService,
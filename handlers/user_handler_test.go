@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/middleware"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/laertkokona/crud-test/utils"
 	"github.com/peteprogrammer/go-automapper"
@@ -15,6 +17,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 var mockModel = []gorm.Model{
@@ -64,123 +67,174 @@ var mockRoles = []models.Role{
 	},
 }
 
+// mockTokens is the token configuration the mock services sign tokens with
+var mockTokens = utils.TokenConfig{Secret: "test-secret", AccessTTL: 15 * time.Minute, RefreshTTL: 30 * 24 * time.Hour}
+
 // mockUserService is a mock implementation of the services.UserService interface
 type mockUserService struct {
-	createUser  func(user models.User) (models.UserDTO, int, error)
-	getUser     func(id int) (models.UserDTO, int, error)
-	getAllUsers func(pagination models.Pagination) ([]models.UserDTO, int, error)
-	signInUser  func(loginUser models.Login) (string, int, error)
-	signOutUser func() string
-	updateUser  func(id int, user models.User) (models.UserDTO, int, error)
-	deleteUser  func(id int) (models.UserDTO, int, error)
+	createUser     func(user models.User) (models.UserDTO, error)
+	getUser        func(id int) (models.UserDTO, error)
+	getAllUsers    func(pagination models.Pagination, query models.ListQuery) (models.Page[models.UserDTO], error)
+	signInUser     func(loginUser models.Login) (models.TokenPair, error)
+	refreshToken   func(refreshToken string) (models.TokenPair, error)
+	signOutUser    func(tokenID string, userID uint, expiresAt time.Time, refreshToken string) (string, error)
+	signOutAllUser func(userID uint) (string, error)
+	resetPassword  func(username string, password string) (models.UserDTO, error)
+	updateUser     func(id int, user models.User, version uint) (models.UserDTO, error)
+	deleteUser     func(id int, version uint) (models.UserDTO, error)
 }
 
 // mockRoleService is a mock implementation of the services.RoleService interface
 type mockRoleService struct {
-	createRole  func(role models.Role) (models.RoleDTO, int, error)
-	getRole     func(id int) (models.RoleDTO, int, error)
-	getAllRoles func() ([]models.RoleDTO, int, error)
-	updateRole  func(id int, role models.Role) (models.RoleDTO, int, error)
-	deleteRole  func(id int) (models.RoleDTO, int, error)
+	createRole  func(role models.Role) (models.RoleDTO, error)
+	getRole     func(id int) (models.RoleDTO, error)
+	getAllRoles func(pagination models.Pagination) (models.Page[models.RoleDTO], error)
+	updateRole  func(id int, role models.Role, version uint) (models.RoleDTO, error)
+	deleteRole  func(id int, version uint) (models.RoleDTO, error)
+	// permissions
+	getAllPermissions  func(pagination models.Pagination) (models.Page[models.Permission], error)
+	setRolePermissions func(id int, names []string) (models.RoleDTO, error)
 }
 
 // CreateUser method that takes a models.User object and saves it to the database
-func (m *mockUserService) CreateUser(user models.User) (models.UserDTO, int, error) {
+func (m *mockUserService) CreateUser(user models.User) (models.UserDTO, error) {
 	return m.createUser(user)
 }
 
 // GetUser method that takes a user id and returns the user object
-func (m *mockUserService) GetUser(id int) (models.UserDTO, int, error) {
+func (m *mockUserService) GetUser(id int) (models.UserDTO, error) {
 	return m.getUser(id)
 }
 
 // GetAllUsers method that takes a models.Pagination object and returns a slice of user objects
-func (m *mockUserService) GetAllUsers(pagination models.Pagination) ([]models.UserDTO, int, error) {
-	return m.getAllUsers(pagination)
+func (m *mockUserService) GetAllUsers(pagination models.Pagination, query models.ListQuery) (models.Page[models.UserDTO], error) {
+	return m.getAllUsers(pagination, query)
 }
 
-// SignInUser method that takes a models.User object and returns a token
-func (m *mockUserService) SignInUser(loginUser models.Login) (string, int, error) {
+// SignInUser method that takes a models.User object and returns a token pair
+func (m *mockUserService) SignInUser(loginUser models.Login) (models.TokenPair, error) {
 	return m.signInUser(loginUser)
 }
 
-// SignOutUser method that takes a token and returns a message
-func (m *mockUserService) SignOutUser() string {
-	return m.signOutUser()
+// RefreshToken method that takes a refresh token and returns a new token pair
+func (m *mockUserService) RefreshToken(refreshToken string) (models.TokenPair, error) {
+	return m.refreshToken(refreshToken)
+}
+
+// SignOutUser method that takes the token of the request and returns an expired token
+func (m *mockUserService) SignOutUser(tokenID string, userID uint, expiresAt time.Time, refreshToken string) (string, error) {
+	return m.signOutUser(tokenID, userID, expiresAt, refreshToken)
+}
+
+// SignOutAllUser method that takes a user id and returns an expired token
+func (m *mockUserService) SignOutAllUser(userID uint) (string, error) {
+	return m.signOutAllUser(userID)
+}
+
+// ResetPassword method that takes a username and a new password and returns the user object
+func (m *mockUserService) ResetPassword(username string, password string) (models.UserDTO, error) {
+	return m.resetPassword(username, password)
 }
 
 // UpdateUser method that takes a user id and a user object and updates the user object in the database
-func (m *mockUserService) UpdateUser(id int, user models.User) (models.UserDTO, int, error) {
-	return m.updateUser(id, user)
+func (m *mockUserService) UpdateUser(id int, user models.User, version uint) (models.UserDTO, error) {
+	return m.updateUser(id, user, version)
 }
 
 // DeleteUser method that takes a user id and deletes the user object from the database
-func (m *mockUserService) DeleteUser(id int) (models.UserDTO, int, error) {
-	return m.deleteUser(id)
+func (m *mockUserService) DeleteUser(id int, version uint) (models.UserDTO, error) {
+	return m.deleteUser(id, version)
 }
 
 // CreateRole method that takes a models.Role object and saves it to the database
-func (m *mockRoleService) CreateRole(role models.Role) (models.RoleDTO, int, error) {
+func (m *mockRoleService) CreateRole(role models.Role) (models.RoleDTO, error) {
 	return m.createRole(role)
 }
 
 // GetRole method that takes a role id and returns the role object
-func (m *mockRoleService) GetRole(id int) (models.RoleDTO, int, error) {
+func (m *mockRoleService) GetRole(id int) (models.RoleDTO, error) {
 	return m.getRole(id)
 }
 
-// GetAllRoles method that takes a models.Pagination object and returns a slice of role objects
-func (m *mockRoleService) GetAllRoles() ([]models.RoleDTO, int, error) {
-	return m.getAllRoles()
+// GetAllRoles method that takes a models.Pagination object and returns a page of role objects
+func (m *mockRoleService) GetAllRoles(pagination models.Pagination) (models.Page[models.RoleDTO], error) {
+	return m.getAllRoles(pagination)
 }
 
 // UpdateRole method that takes a role id and a role object and updates the role object in the database
-func (m *mockRoleService) UpdateRole(id int, role models.Role) (models.RoleDTO, int, error) {
-	return m.updateRole(id, role)
+func (m *mockRoleService) UpdateRole(id int, role models.Role, version uint) (models.RoleDTO, error) {
+	return m.updateRole(id, role, version)
 }
 
 // DeleteRole method that takes a role id and deletes the role object from the database
-func (m *mockRoleService) DeleteRole(id int) (models.RoleDTO, int, error) {
-	return m.deleteRole(id)
+func (m *mockRoleService) DeleteRole(id int, version uint) (models.RoleDTO, error) {
+	return m.deleteRole(id, version)
+}
+
+// GetAllPermissions method that takes a models.Pagination object and returns a page of the permissions
+func (m *mockRoleService) GetAllPermissions(pagination models.Pagination) (models.Page[models.Permission], error) {
+	return m.getAllPermissions(pagination)
+}
+
+// SetRolePermissions method that takes a role id and permission names and replaces the permissions of the role
+func (m *mockRoleService) SetRolePermissions(id int, names []string) (models.RoleDTO, error) {
+	return m.setRolePermissions(id, names)
 }
 
 // newMockUserService returns a new mockUserService
 func newMockUserService() *mockUserService {
 	return &mockUserService{
-		createUser: func(user models.User) (models.UserDTO, int, error) {
+		createUser: func(user models.User) (models.UserDTO, error) {
 			var userDTO models.UserDTO
 			automapper.Map(user, &userDTO)
-			return userDTO, http.StatusOK, nil
+			return userDTO, nil
 		},
-		getUser: func(id int) (models.UserDTO, int, error) {
+		getUser: func(id int) (models.UserDTO, error) {
 			var userDTO models.UserDTO
 			automapper.Map(mockUsers[id-1], &userDTO)
-			return userDTO, http.StatusOK, nil
+			return userDTO, nil
 		},
-		getAllUsers: func(pagination models.Pagination) ([]models.UserDTO, int, error) {
+		getAllUsers: func(pagination models.Pagination, query models.ListQuery) (models.Page[models.UserDTO], error) {
 			var mockUsersDTO []models.UserDTO
 			automapper.Map(mockUsers, &mockUsersDTO)
-			return mockUsersDTO, http.StatusOK, nil
+			return models.NewPage(mockUsersDTO, int64(len(mockUsersDTO)), pagination), nil
 		},
-		signInUser: func(loginUser models.Login) (string, int, error) {
+		signInUser: func(loginUser models.Login) (models.TokenPair, error) {
 			var user models.User
 			automapper.MapLoose(loginUser, &user)
-			return utils.GenerateToken(user, utils.GetRoleName(utils.User)), http.StatusOK, nil
+			return models.TokenPair{
+				AccessToken:  mockTokens.GenerateToken(user, models.Role{Name: utils.GetRoleName(utils.User)}),
+				RefreshToken: utils.NewRefreshToken(),
+			}, nil
+		},
+		refreshToken: func(refreshToken string) (models.TokenPair, error) {
+			return models.TokenPair{
+				AccessToken:  mockTokens.GenerateToken(mockUsers[0], models.Role{Name: utils.GetRoleName(utils.User)}),
+				RefreshToken: utils.NewRefreshToken(),
+			}, nil
 		},
-		signOutUser: func() string {
-			return utils.GenerateExpiredToken()
+		signOutUser: func(tokenID string, userID uint, expiresAt time.Time, refreshToken string) (string, error) {
+			return mockTokens.GenerateExpiredToken(), nil
+		},
+		signOutAllUser: func(userID uint) (string, error) {
+			return mockTokens.GenerateExpiredToken(), nil
+		},
+		resetPassword: func(username string, password string) (models.UserDTO, error) {
+			var userDTO models.UserDTO
+			automapper.Map(mockUsers[0], &userDTO)
+			return userDTO, nil
 		},
-		updateUser: func(id int, user models.User) (models.UserDTO, int, error) {
+		updateUser: func(id int, user models.User, version uint) (models.UserDTO, error) {
 			mockUser := mockUsers[id-1]
 			utils.CopyNonEmptyFields(&mockUser, &user)
 			var userDTO models.UserDTO
 			automapper.Map(mockUser, &userDTO)
-			return userDTO, http.StatusOK, nil
+			return userDTO, nil
 		},
-		deleteUser: func(id int) (models.UserDTO, int, error) {
+		deleteUser: func(id int, version uint) (models.UserDTO, error) {
 			var userDTO models.UserDTO
 			automapper.Map(mockUsers[id-1], &userDTO)
-			return userDTO, http.StatusOK, nil
+			return userDTO, nil
 		},
 	}
 }
@@ -188,28 +242,37 @@ func newMockUserService() *mockUserService {
 // newMockUserErrorService returns a new mockUserService with errors
 func newMockUserErrorService() *mockUserService {
 	return &mockUserService{
-		createUser: func(user models.User) (models.UserDTO, int, error) {
-			return models.UserDTO{}, http.StatusInternalServerError, errors.New("error creating user")
+		createUser: func(user models.User) (models.UserDTO, error) {
+			return models.UserDTO{}, errors.New("error creating user")
 		},
-		getUser: func(id int) (models.UserDTO, int, error) {
-			return models.UserDTO{}, http.StatusInternalServerError, errors.New("error getting user")
+		getUser: func(id int) (models.UserDTO, error) {
+			return models.UserDTO{}, errors.New("error getting user")
 		},
-		getAllUsers: func(pagination models.Pagination) ([]models.UserDTO, int, error) {
-			return []models.UserDTO{}, http.StatusInternalServerError, errors.New("error getting all users")
+		getAllUsers: func(pagination models.Pagination, query models.ListQuery) (models.Page[models.UserDTO], error) {
+			return models.Page[models.UserDTO]{}, errors.New("error getting all users")
 		},
-		signInUser: func(loginUser models.Login) (string, int, error) {
+		signInUser: func(loginUser models.Login) (models.TokenPair, error) {
 			var user models.User
 			automapper.MapLoose(loginUser, &user)
-			return "", http.StatusInternalServerError, errors.New("error signing in user")
+			return models.TokenPair{}, errors.New("error signing in user")
 		},
-		signOutUser: func() string {
-			return utils.GenerateExpiredToken()
+		refreshToken: func(refreshToken string) (models.TokenPair, error) {
+			return models.TokenPair{}, errs.Unauthorized(errors.New("invalid refresh token"))
 		},
-		updateUser: func(id int, user models.User) (models.UserDTO, int, error) {
-			return models.UserDTO{}, http.StatusInternalServerError, errors.New("error updating user")
+		signOutUser: func(tokenID string, userID uint, expiresAt time.Time, refreshToken string) (string, error) {
+			return "", errors.New("error signing out user")
 		},
-		deleteUser: func(id int) (models.UserDTO, int, error) {
-			return models.UserDTO{}, http.StatusInternalServerError, errors.New("error deleting user")
+		signOutAllUser: func(userID uint) (string, error) {
+			return "", errors.New("error signing out user")
+		},
+		resetPassword: func(username string, password string) (models.UserDTO, error) {
+			return models.UserDTO{}, errors.New("error resetting password")
+		},
+		updateUser: func(id int, user models.User, version uint) (models.UserDTO, error) {
+			return models.UserDTO{}, errors.New("error updating user")
+		},
+		deleteUser: func(id int, version uint) (models.UserDTO, error) {
+			return models.UserDTO{}, errors.New("error deleting user")
 		},
 	}
 }
@@ -217,32 +280,43 @@ func newMockUserErrorService() *mockUserService {
 // newMockRoleService returns a new mockRoleService
 func newMockRoleService() *mockRoleService {
 	return &mockRoleService{
-		createRole: func(role models.Role) (models.RoleDTO, int, error) {
+		createRole: func(role models.Role) (models.RoleDTO, error) {
 			var roleDTO models.RoleDTO
 			automapper.Map(role, &roleDTO)
-			return roleDTO, http.StatusOK, nil
+			return roleDTO, nil
 		},
-		getRole: func(id int) (models.RoleDTO, int, error) {
+		getRole: func(id int) (models.RoleDTO, error) {
 			var roleDTO models.RoleDTO
 			automapper.Map(mockRoles[id-1], &roleDTO)
-			return roleDTO, http.StatusOK, nil
+			return roleDTO, nil
 		},
-		getAllRoles: func() ([]models.RoleDTO, int, error) {
+		getAllRoles: func(pagination models.Pagination) (models.Page[models.RoleDTO], error) {
 			var mockRolesDTO []models.RoleDTO
 			automapper.Map(mockRoles, &mockRolesDTO)
-			return mockRolesDTO, http.StatusOK, nil
+			return models.NewPage(mockRolesDTO, int64(len(mockRolesDTO)), pagination), nil
 		},
-		updateRole: func(id int, role models.Role) (models.RoleDTO, int, error) {
+		updateRole: func(id int, role models.Role, version uint) (models.RoleDTO, error) {
 			mockRole := mockRoles[id-1]
 			utils.CopyNonEmptyFields(&mockRole, &role)
 			var roleDTO models.RoleDTO
 			automapper.Map(mockRole, &roleDTO)
-			return roleDTO, http.StatusOK, nil
+			return roleDTO, nil
+		},
+		deleteRole: func(id int, version uint) (models.RoleDTO, error) {
+			var roleDTO models.RoleDTO
+			automapper.Map(mockRoles[id-1], &roleDTO)
+			return roleDTO, nil
+		},
+		getAllPermissions: func(pagination models.Pagination) (models.Page[models.Permission], error) {
+			return models.NewPage([]models.Permission{{ID: 1, Name: utils.ItemsRead}, {ID: 2, Name: utils.ItemsWrite}}, 2, pagination), nil
 		},
-		deleteRole: func(id int) (models.RoleDTO, int, error) {
+		setRolePermissions: func(id int, names []string) (models.RoleDTO, error) {
 			var roleDTO models.RoleDTO
 			automapper.Map(mockRoles[id-1], &roleDTO)
-			return roleDTO, http.StatusOK, nil
+			for i, name := range names {
+				roleDTO.Permissions = append(roleDTO.Permissions, models.Permission{ID: uint(i + 1), Name: name})
+			}
+			return roleDTO, nil
 		},
 	}
 }
@@ -250,20 +324,26 @@ func newMockRoleService() *mockRoleService {
 // newMockRoleErrorService returns a new mockRoleService with errors
 func newMockRoleErrorService() *mockRoleService {
 	return &mockRoleService{
-		createRole: func(role models.Role) (models.RoleDTO, int, error) {
-			return models.RoleDTO{}, http.StatusInternalServerError, errors.New("error creating role")
+		createRole: func(role models.Role) (models.RoleDTO, error) {
+			return models.RoleDTO{}, errors.New("error creating role")
 		},
-		getRole: func(id int) (models.RoleDTO, int, error) {
-			return models.RoleDTO{}, http.StatusInternalServerError, errors.New("error getting role")
+		getRole: func(id int) (models.RoleDTO, error) {
+			return models.RoleDTO{}, errors.New("error getting role")
 		},
-		getAllRoles: func() ([]models.RoleDTO, int, error) {
-			return []models.RoleDTO{}, http.StatusInternalServerError, errors.New("error getting all roles")
+		getAllRoles: func(pagination models.Pagination) (models.Page[models.RoleDTO], error) {
+			return models.Page[models.RoleDTO]{}, errors.New("error getting all roles")
 		},
-		updateRole: func(id int, role models.Role) (models.RoleDTO, int, error) {
-			return models.RoleDTO{}, http.StatusInternalServerError, errors.New("error updating role")
+		updateRole: func(id int, role models.Role, version uint) (models.RoleDTO, error) {
+			return models.RoleDTO{}, errors.New("error updating role")
 		},
-		deleteRole: func(id int) (models.RoleDTO, int, error) {
-			return models.RoleDTO{}, http.StatusInternalServerError, errors.New("error deleting role")
+		deleteRole: func(id int, version uint) (models.RoleDTO, error) {
+			return models.RoleDTO{}, errors.New("error deleting role")
+		},
+		getAllPermissions: func(pagination models.Pagination) (models.Page[models.Permission], error) {
+			return models.Page[models.Permission]{}, errors.New("error getting all permissions")
+		},
+		setRolePermissions: func(id int, names []string) (models.RoleDTO, error) {
+			return models.RoleDTO{}, errs.Validation(errors.New("unknown permission"))
 		},
 	}
 }
@@ -314,14 +394,14 @@ func TestCreateUser_InvalidJSONError(t *testing.T) {
 	req, _ := http.NewRequest("POST", "/users", bytes.NewBuffer([]byte("{{")))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestCreateUser_ServiceError tests the CreateUser method when the service returns an error
@@ -339,14 +419,40 @@ func TestCreateUser_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("POST", "/users", bytes.NewBuffer(mockUserString))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
+}
+
+// TestCreateUser_DuplicateUsername tests the CreateUser method when the username is taken
+func TestCreateUser_DuplicateUsername(t *testing.T) {
+	mockUserService := newMockUserService()
+	mockUserService.createUser = func(user models.User) (models.UserDTO, error) {
+		return models.UserDTO{}, errs.ConflictOn("username", errors.New("username already exists"))
+	}
+	mockRoleService := newMockRoleService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	mockUserString, err := json.Marshal(mockUsers[0])
+	assert.NoError(t, err, "Error marshalling mock user")
+
+	r := gin.Default()
+	r.POST("/users", userHandler.CreateUser)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users", bytes.NewBuffer(mockUserString))
+	r.ServeHTTP(w, req)
+
+	var result helpers.Problem
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, http.StatusConflict, w.Code, "Status code should be 409")
+	assert.NoError(t, err, "Error unmarshalling response")
+	assert.Equal(t, "username already exists", result.Detail)
+	assert.Equal(t, []helpers.FieldError{{Field: "username", Message: "username already exists"}}, result.Errors, "Errors should name the field")
 }
 
 // TestGetUser tests the GetUser method
@@ -389,14 +495,14 @@ func TestGetUser_InvalidIDError(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/users/abc", nil)
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestGetUser_ServiceError tests the GetUser method when the service returns an error
@@ -411,14 +517,14 @@ func TestGetUser_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/users/1", nil)
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestGetAllUsers tests the GetUsers method
@@ -439,7 +545,7 @@ func TestGetAllUsers(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.NoError(t, err, "Error unmarshalling user")
 	usersJSON, _ := json.Marshal(result.Data)
-	var users []models.UserDTO
+	var users models.Page[models.UserDTO]
 	err = json.Unmarshal(usersJSON, &users)
 	assert.NoError(t, err, "Error unmarshalling user")
 
@@ -453,7 +559,37 @@ func TestGetAllUsers(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
 	assert.NoError(t, err, "Error unmarshalling users")
-	assert.Equal(t, mockUsersDTO, users, "Users should be the same")
+	assert.Equal(t, mockUsersDTO, users.Data, "Users should be the same")
+	assert.Equal(t, int64(len(mockUsers)), *users.Total, "Total should be the number of users")
+}
+
+// TestGetAllUsers_Query tests the GetAllUsers method with a filter on a list of roles and rejects a filter on the password
+func TestGetAllUsers_Query(t *testing.T) {
+	var gotQuery models.ListQuery
+	mockUserService := newMockUserService()
+	mockUserService.getAllUsers = func(pagination models.Pagination, query models.ListQuery) (models.Page[models.UserDTO], error) {
+		gotQuery = query
+		return models.NewPage([]models.UserDTO{}, 0, pagination), nil
+	}
+	userHandler := NewUserHandler(mockUserService, newMockRoleService())
+
+	r := gin.Default()
+	r.GET("/users", userHandler.GetAllUsers)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users?filter=role:in:1|2&sort=lastName", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.ListQuery{
+		Filters: []models.Filter{{Column: "role_id", Operator: models.FilterIn, Value: []interface{}{float64(1), float64(2)}}},
+		Sorts:   []models.Sort{{Column: "last_name"}},
+	}, gotQuery)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users?filter=password:eq:secret", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 // TestGetAllUsers_ServiceError tests the GetUsers method when the service returns an error
@@ -468,14 +604,14 @@ func TestGetAllUsers_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/users", nil)
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestSignInUser tests the SignInUser method
@@ -521,14 +657,14 @@ func TestSignInUser_InvalidUserError(t *testing.T) {
 	req, _ := http.NewRequest("POST", "/users/signIn", bytes.NewBuffer([]byte(mockLogInUser)))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestSignInUser_ServiceError tests the SignInUser method when the service returns an error
@@ -549,14 +685,79 @@ func TestSignInUser_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("POST", "/users/signIn", bytes.NewBuffer(mockUserString))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
+}
+
+// TestRefreshToken tests the RefreshToken method
+func TestRefreshToken(t *testing.T) {
+	mockUserService := newMockUserService()
+	var presented string
+	refresh := mockUserService.refreshToken
+	mockUserService.refreshToken = func(refreshToken string) (models.TokenPair, error) {
+		presented = refreshToken
+		return refresh(refreshToken)
+	}
+	mockRoleService := newMockRoleService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	body, _ := json.Marshal(models.RefreshRequest{RefreshToken: "refresh-token"})
+
+	r := gin.Default()
+	r.POST("/token/refresh", userHandler.RefreshToken)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/token/refresh", bytes.NewBuffer(body))
+	r.ServeHTTP(w, req)
+
+	var result struct {
+		Data models.TokenPair `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
+	assert.NoError(t, err, "Error unmarshalling response")
+	assert.Equal(t, "refresh-token", presented, "Refresh token should be passed to the service")
+	assert.NotEmpty(t, result.Data.AccessToken, "Access token should not be empty")
+	assert.NotEmpty(t, result.Data.RefreshToken, "Refresh token should not be empty")
+	assert.Equal(t, result.Data.AccessToken, w.Header().Get("Authorization"), "Authorization header should be the access token")
+}
+
+// TestRefreshToken_MissingToken tests the RefreshToken method when the body has no refresh token
+func TestRefreshToken_MissingToken(t *testing.T) {
+	mockUserService := newMockUserService()
+	mockRoleService := newMockRoleService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	r := gin.Default()
+	r.POST("/token/refresh", userHandler.RefreshToken)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/token/refresh", bytes.NewBuffer([]byte("{}")))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
+}
+
+// TestRefreshToken_ServiceError tests the RefreshToken method when the service rejects the token
+func TestRefreshToken_ServiceError(t *testing.T) {
+	mockUserService := newMockUserErrorService()
+	mockRoleService := newMockRoleService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	body, _ := json.Marshal(models.RefreshRequest{RefreshToken: "refresh-token"})
+
+	r := gin.Default()
+	r.POST("/token/refresh", userHandler.RefreshToken)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/token/refresh", bytes.NewBuffer(body))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "Status code should be 401")
+	assert.Empty(t, w.Header().Get("Authorization"), "Should not return a token")
 }
 
 // TestSignOutUser tests the SignOutUser method
@@ -578,6 +779,44 @@ func TestSignOutUser(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
 	assert.NoError(t, err, "Error unmarshalling user")
 }
+func TestSignOutUser_ServiceError(t *testing.T) {
+	mockUserService := newMockUserErrorService()
+	mockRoleService := newMockRoleService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	r := gin.Default()
+	r.POST("/users/signOut", userHandler.SignOutUser)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users/signOut", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 500")
+	assert.Empty(t, w.Header().Get("Authorization"), "Should not return a token")
+}
+
+// TestSignOutAllUser tests the SignOutAllUser method
+func TestSignOutAllUser(t *testing.T) {
+	mockUserService := newMockUserService()
+	var signedOut uint
+	mockUserService.signOutAllUser = func(userID uint) (string, error) {
+		signedOut = userID
+		return mockTokens.GenerateExpiredToken(), nil
+	}
+	mockRoleService := newMockRoleService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	r := gin.Default()
+	r.POST("/signOutAll", func(ctx *gin.Context) {
+		ctx.Set(middleware.UserIDKey, uint(2))
+	}, userHandler.SignOutAllUser)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/signOutAll", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
+	assert.Equal(t, uint(2), signedOut, "Should sign out the user of the token")
+	assert.NotEmpty(t, w.Header().Get("Authorization"), "Should return an expired token")
+}
 
 // TestUpdateUser tests the UpdateUser method
 func TestUpdateUser(t *testing.T) {
@@ -588,6 +827,8 @@ func TestUpdateUser(t *testing.T) {
 	mockUser := models.User{
 		FirstName: "UserTest",
 		LastName:  "UserTest",
+		Username:  "UserTest",
+		RoleID:    1,
 	}
 
 	mockUserString, _ := json.Marshal(mockUser)
@@ -626,6 +867,8 @@ func TestUpdateUser_InvalidIDError(t *testing.T) {
 	mockUser := models.User{
 		FirstName: "UserTest",
 		LastName:  "UserTest",
+		Username:  "UserTest",
+		RoleID:    1,
 	}
 
 	mockUserString, _ := json.Marshal(mockUser)
@@ -636,14 +879,14 @@ func TestUpdateUser_InvalidIDError(t *testing.T) {
 	req, _ := http.NewRequest("PUT", "/users/invalidID", bytes.NewBuffer(mockUserString))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestUpdateUser_BindingError tests the UpdateUser method when the binding fails
@@ -658,14 +901,14 @@ func TestUpdateUser_BindingError(t *testing.T) {
 	req, _ := http.NewRequest("PUT", "/users/1", bytes.NewBuffer([]byte("")))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestUpdateUser_ServiceError tests the UpdateUser method when the service fails
@@ -677,6 +920,8 @@ func TestUpdateUser_ServiceError(t *testing.T) {
 	mockUser := models.User{
 		FirstName: "UserTest",
 		LastName:  "UserTest",
+		Username:  "UserTest",
+		RoleID:    1,
 	}
 
 	mockUserString, _ := json.Marshal(mockUser)
@@ -687,14 +932,14 @@ func TestUpdateUser_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("PUT", "/users/2", bytes.NewBuffer(mockUserString))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 500")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestDeleteUser tests the DeleteUser method
@@ -738,14 +983,14 @@ func TestDeleteUser_InvalidIDError(t *testing.T) {
 	req, _ := http.NewRequest("DELETE", "/users/invalidID", nil)
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestDeleteUser_ServiceError tests the DeleteUser method when the service fails
@@ -760,14 +1005,14 @@ func TestDeleteUser_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("DELETE", "/users/2", nil)
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 500")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestCreateRole tests the CreateRole method
@@ -821,14 +1066,14 @@ func TestCreateRole_InvalidIDError(t *testing.T) {
 	req, _ := http.NewRequest("POST", "/roles", bytes.NewBuffer([]byte("invalidRoleString")))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestCreateRole_ServiceError tests the CreateRole method when the service fails
@@ -849,14 +1094,14 @@ func TestCreateRole_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("POST", "/roles", bytes.NewBuffer(mockRoleString))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 500")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestGetRole tests the GetRole method
@@ -898,14 +1143,14 @@ func TestGetRole_InvalidIDError(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/roles/invalidID", nil)
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestGetRole_ServiceError tests the GetRole method when the service fails
@@ -920,14 +1165,14 @@ func TestGetRole_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/roles/1", nil)
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 500")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestGetAllRoles tests the GetAllRoles method
@@ -947,14 +1192,16 @@ func TestGetAllRoles(t *testing.T) {
 	var result helpers.JSONResult
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.NoError(t, err, "Error unmarshalling response")
-	var roles []models.Role
+	var roles models.Page[models.Role]
 	rolesJSON, _ := json.Marshal(result.Data)
 	err = json.Unmarshal(rolesJSON, &roles)
 	assert.NoError(t, err, "Error unmarshalling response")
 	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
 	assert.NoError(t, err, "Error unmarshalling response")
 	assert.Equal(t, result.Message, "Success")
-	assert.Equal(t, mockRoles, roles, "Data should be nil")
+	assert.Equal(t, mockRoles, roles.Data, "Data should be the roles")
+	assert.Equal(t, int64(len(mockRoles)), *roles.Total, "Total should be the number of roles")
+	assert.Equal(t, "/roles?limit=20&page=1", roles.Links.Self)
 }
 
 // TestGetAllRoles_ServiceError tests the GetAllRoles method when the service fails
@@ -969,14 +1216,14 @@ func TestGetAllRoles_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/roles", nil)
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 500")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestUpdateRole tests the UpdateRole method
@@ -1028,14 +1275,14 @@ func TestUpdateRole_InvalidIDError(t *testing.T) {
 	req, _ := http.NewRequest("PUT", "/roles/invalidID", strings.NewReader(`{"name": "newRole"}`))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestUpdateRole_InvalidJSONError tests the UpdateRole method when the json is invalid
@@ -1050,14 +1297,14 @@ func TestUpdateRole_InvalidJSONError(t *testing.T) {
 	req, _ := http.NewRequest("PUT", "/roles/1", strings.NewReader(`{"name": "newRole"`))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestUpdateRole_ServiceError tests the UpdateRole method when the service fails
@@ -1072,14 +1319,14 @@ func TestUpdateRole_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("PUT", "/roles/1", strings.NewReader(`{"name": "newRole"}`))
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 500")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestDeleteRole tests the DeleteRole method
@@ -1121,14 +1368,14 @@ func TestDeleteRole_InvalidIDError(t *testing.T) {
 	req, _ := http.NewRequest("DELETE", "/roles/invalidID", nil)
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
 }
 
 // TestDeleteRole_ServiceError tests the DeleteRole method when the service fails
@@ -1143,12 +1390,105 @@ func TestDeleteRole_ServiceError(t *testing.T) {
 	req, _ := http.NewRequest("DELETE", "/roles/1", nil)
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"), "Content-Type should be application/json")
+	assert.Equal(t, helpers.ProblemContentType, w.Header().Get("Content-Type"), "Content-Type should be application/json")
 
-	var result helpers.JSONResult
+	var result helpers.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 500")
 	assert.NoError(t, err, "Error unmarshalling response")
-	assert.NotNil(t, result.Message, "Error should not be nil")
-	assert.Nil(t, result.Data, "Data should be nil")
+	assert.NotEmpty(t, result.Detail, "Error should not be nil")
+	assert.Nil(t, result.Errors, "Errors should be nil")
+}
+
+// TestGetAllPermissions tests the GetAllPermissions method
+func TestGetAllPermissions(t *testing.T) {
+	mockUserService := newMockUserService()
+	mockRoleService := newMockRoleService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	r := gin.Default()
+	r.GET("/permissions", userHandler.GetAllPermissions)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/permissions", nil)
+	r.ServeHTTP(w, req)
+
+	var result struct {
+		Data models.Page[models.Permission] `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
+	assert.NoError(t, err, "Error unmarshalling response")
+	assert.Len(t, result.Data.Data, 2, "Should return every permission")
+	assert.Equal(t, int64(2), *result.Data.Total, "Total should be the number of permissions")
+}
+
+// TestGetAllPermissions_ServiceError tests the GetAllPermissions method when the service fails
+func TestGetAllPermissions_ServiceError(t *testing.T) {
+	mockUserService := newMockUserService()
+	mockRoleService := newMockRoleErrorService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	r := gin.Default()
+	r.GET("/permissions", userHandler.GetAllPermissions)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/permissions", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "Status code should be 500")
+}
+
+// TestSetRolePermissions tests the SetRolePermissions method
+func TestSetRolePermissions(t *testing.T) {
+	mockUserService := newMockUserService()
+	mockRoleService := newMockRoleService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	body, _ := json.Marshal(models.RolePermissions{Permissions: []string{utils.ItemsRead, utils.OrdersApprove}})
+
+	r := gin.Default()
+	r.PUT("/roles/:id/permissions", userHandler.SetRolePermissions)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/roles/2/permissions", bytes.NewBuffer(body))
+	r.ServeHTTP(w, req)
+
+	var result struct {
+		Data models.RoleDTO `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, http.StatusOK, w.Code, "Status code should be 200")
+	assert.NoError(t, err, "Error unmarshalling response")
+	assert.Equal(t, "Admin", result.Data.Name)
+	assert.Len(t, result.Data.Permissions, 2, "Role should have the given permissions")
+}
+
+// TestSetRolePermissions_InvalidJSONError tests the SetRolePermissions method when the body has no permissions
+func TestSetRolePermissions_InvalidJSONError(t *testing.T) {
+	mockUserService := newMockUserService()
+	mockRoleService := newMockRoleService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	r := gin.Default()
+	r.PUT("/roles/:id/permissions", userHandler.SetRolePermissions)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/roles/2/permissions", bytes.NewBuffer([]byte("{}")))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
+}
+
+// TestSetRolePermissions_ServiceError tests the SetRolePermissions method when the service rejects the permissions
+func TestSetRolePermissions_ServiceError(t *testing.T) {
+	mockUserService := newMockUserService()
+	mockRoleService := newMockRoleErrorService()
+	userHandler := NewUserHandler(mockUserService, mockRoleService)
+
+	body, _ := json.Marshal(models.RolePermissions{Permissions: []string{"items:steal"}})
+
+	r := gin.Default()
+	r.PUT("/roles/:id/permissions", userHandler.SetRolePermissions)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/roles/2/permissions", bytes.NewBuffer(body))
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Status code should be 400")
 }
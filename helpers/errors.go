@@ -0,0 +1,63 @@
+package helpers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/errs"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+)
+
+// StatusOf returns the http status of an error returned by a service.
+// A missing row the service did not wrap is not found as well, any other error is internal
+func StatusOf(err error) int {
+	switch errs.KindOf(err) {
+	case errs.KindNotFound:
+		return http.StatusNotFound
+	case errs.KindConflict:
+		return http.StatusConflict
+	case errs.KindValidation:
+		return http.StatusBadRequest
+	case errs.KindForbidden:
+		return http.StatusForbidden
+	case errs.KindUnauthorized:
+		return http.StatusUnauthorized
+	case errs.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case errs.KindUnsupported:
+		return http.StatusUnsupportedMediaType
+	case errs.KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case errs.KindStale:
+		return http.StatusPreconditionFailed
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// internalDetail is the detail of a problem the server failed on, the error itself is only logged
+const internalDetail = "the request failed on the server, quote the request id when reporting it"
+
+// ErrorProblem returns the problem of an error returned by a service, a conflict on a field is an error of that field.
+// The error of a 5xx status, like a failed query, is logged with the request id and not shown to the client
+func ErrorProblem(ctx *gin.Context, err error) Problem {
+	status := StatusOf(err)
+	if status >= http.StatusInternalServerError {
+		problem := NewProblem(ctx, status, internalDetail)
+		log.Printf("request %s: %s %s failed: %v", problem.RequestID, ctx.Request.Method, problem.Instance, err)
+		return problem
+	}
+	problem := NewProblem(ctx, status, err.Error())
+	if field := errs.FieldOf(err); field != "" {
+		problem.Errors = []FieldError{{Field: field, Message: err.Error()}}
+	}
+	return problem
+}
+
+// ErrorResponse writes the problem of an error returned by a service
+func ErrorResponse(ctx *gin.Context, err error) {
+	ProblemResponse(ctx, ErrorProblem(ctx, err))
+}
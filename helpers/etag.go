@@ -0,0 +1,67 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/errs"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag returns the entity tag of a version of an entity, a strong tag like "3"
+func ETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// SetETag sets the ETag header to the tag of the version of the entity the response has
+func SetETag(ctx *gin.Context, version uint) {
+	ctx.Header("ETag", ETag(version))
+}
+
+// NotModified sets the ETag header of the version of the entity a GET returns and answers 304 Not Modified when the
+// If-None-Match header of the request has that tag or *, the handler then writes nothing else.
+// Tags are compared weakly, so W/"3" matches version 3 as well
+func NotModified(ctx *gin.Context, version uint) bool {
+	SetETag(ctx, version)
+	header := ctx.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	etag := ETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			ctx.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// IfMatch returns the version of the entity in the If-Match header of a request that changes it, the change only goes
+// through while the entity is still at that version. A request without the header or with * returns 0, which changes
+// any version. Weak tags never match a change, so they are stale, and a header with more than one tag or a tag that is
+// not a version is not valid
+func IfMatch(ctx *gin.Context) (uint, error) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, errs.Validation(errors.New("If-Match must have a single entity tag"))
+	}
+	if strings.HasPrefix(header, "W/") {
+		return 0, errs.Stale(fmt.Errorf("weak entity tag %s never matches a change", header))
+	}
+	tag, err := strconv.Unquote(header)
+	if err != nil || !strings.HasPrefix(header, `"`) {
+		return 0, errs.Validation(fmt.Errorf("If-Match %s is not a quoted entity tag", header))
+	}
+	version, err := strconv.ParseUint(tag, 10, 32)
+	if err != nil || version == 0 {
+		return 0, errs.Stale(fmt.Errorf("entity tag %s is not a version of the entity", header))
+	}
+	return uint(version), nil
+}
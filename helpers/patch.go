@@ -0,0 +1,49 @@
+package helpers
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/utils"
+)
+
+// AcceptPatch is the Accept-Patch header of the resources that can be patched, sent back with a patch of another type
+const AcceptPatch = utils.MergePatchContentType + ", " + utils.JSONPatchContentType
+
+// ShouldBindPatch applies the patch in the body of the request to the current entity and returns the result as the
+// body of a PUT, validated the same way. The patch only sees the fields of the PUT body, so the fields the server
+// manages can not be patched. A patched entity that breaks a rule is unprocessable
+func ShouldBindPatch[T any](ctx *gin.Context, current interface{}) (T, error) {
+	var request T
+	patch, err := ctx.GetRawData()
+	if err != nil {
+		return request, err
+	}
+	document, err := json.Marshal(current)
+	if err != nil {
+		return request, err
+	}
+	if err := json.Unmarshal(document, &request); err != nil {
+		return request, err
+	}
+	if document, err = json.Marshal(request); err != nil {
+		return request, err
+	}
+	patched, err := utils.ApplyPatch(ctx.ContentType(), document, patch)
+	if errs.KindOf(err) == errs.KindUnsupported {
+		ctx.Header("Accept-Patch", AcceptPatch)
+	}
+	if err != nil {
+		return request, err
+	}
+	// the patched document replaces the request, so the members the patch removed are cleared
+	var result T
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return result, errs.Unprocessable(err)
+	}
+	if err := binding.Validator.ValidateStruct(result); err != nil {
+		return result, errs.Unprocessable(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package helpers
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ProblemContentType is the media type of the problem details of RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// RequestIDHeader is the header the request id is read from and echoed in
+const RequestIDHeader = "X-Request-ID"
+
+// Problem is the body of an error response as described by RFC 7807, with the id of the request and the errors of the
+// fields of the request body. Extensions are added as members next to the standard ones
+type Problem struct {
+	Type       string                 `json:"type" example:"about:blank"`
+	Title      string                 `json:"title" example:"Bad Request"`
+	Status     int                    `json:"status" example:"400"`
+	Detail     string                 `json:"detail,omitempty" example:"the request has invalid fields"`
+	Instance   string                 `json:"instance,omitempty" example:"/items/"`
+	RequestID  string                 `json:"requestId,omitempty" example:"5f0c6a3e-7b1d-4c2a-9f1e-2d3b4c5d6e7f"`
+	Errors     []FieldError           `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// FieldError is the error of one field of the request body, named by its path in the json
+type FieldError struct {
+	Field   string `json:"field" example:"orderItems[0].quantity"`
+	Message string `json:"message" example:"must be greater than 0"`
+}
+
+// problemMembers has the standard members of a problem, without its MarshalJSON
+type problemMembers Problem
+
+// MarshalJSON returns the json of the problem with its extensions as members of their own
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members, err := json.Marshal(problemMembers(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return members, err
+	}
+	extensions, err := json.Marshal(p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(members[:len(members)-1], ','), extensions[1:]...), nil
+}
+
+// NewProblem returns the problem of a request that failed with the status, about the path it was made to
+func NewProblem(ctx *gin.Context, status int, detail string) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  ctx.Request.URL.Path,
+		RequestID: ctx.Writer.Header().Get(RequestIDHeader),
+	}
+}
+
+// With returns the problem with an extension member
+func (p Problem) With(name string, value interface{}) Problem {
+	extensions := make(map[string]interface{}, len(p.Extensions)+1)
+	for k, v := range p.Extensions {
+		extensions[k] = v
+	}
+	extensions[name] = value
+	p.Extensions = extensions
+	return p
+}
+
+// ProblemResponse writes the problem as application/problem+json. Clients that ask for application/json get the
+// JSONResult envelope instead, with the field errors or the extensions as its data
+func ProblemResponse(ctx *gin.Context, problem Problem) {
+	if ctx.NegotiateFormat(ProblemContentType, gin.MIMEJSON) == gin.MIMEJSON {
+		var data interface{}
+		if len(problem.Errors) > 0 {
+			data = problem.Errors
+		} else if len(problem.Extensions) > 0 {
+			data = problem.Extensions
+		}
+		ctx.JSON(problem.Status, JSONResult{
+			Code:    problem.Status,
+			Message: problem.Detail,
+			Data:    data,
+		})
+		return
+	}
+	ctx.Header("Content-Type", ProblemContentType)
+	ctx.JSON(problem.Status, problem)
+}
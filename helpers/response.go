@@ -22,36 +22,6 @@ type JSONSuccessResultNoData struct {
 	Message string `json:"message" example:"Success"`
 }
 
-type JSONBadRequestResult struct {
-	Code    int         `json:"code" example:"400"`
-	Message string      `json:"message" example:"Wrong request"`
-	Data    interface{} `json:"data,omitempty"`
-}
-
-type JSONNotFoundResult struct {
-	Code    int         `json:"code" example:"404"`
-	Message string      `json:"message" example:"Not found"`
-	Data    interface{} `json:"data,omitempty"`
-}
-
-type JSONUnauthorizedResult struct {
-	Code    int         `json:"code" example:"401"`
-	Message string      `json:"message" example:"Unauthorized"`
-	Data    interface{} `json:"data,omitempty"`
-}
-
-type JSONForbiddenResult struct {
-	Code    int         `json:"code" example:"403"`
-	Message string      `json:"message" example:"Forbidden"`
-	Data    interface{} `json:"data,omitempty"`
-}
-
-type JSONInternalServerErrorResult struct {
-	Code    int         `json:"code" example:"500"`
-	Message string      `json:"message" example:"Internal server error"`
-	Data    interface{} `json:"data,omitempty"`
-}
-
 func SuccessResponse(ctx *gin.Context, data interface{}) {
 	if data == "" {
 		data = "Success"
@@ -70,42 +40,11 @@ func SuccessResponseNoData(ctx *gin.Context) {
 	})
 }
 
+// FailedResponse writes the problem of a request that failed with the status and message, the data is added as an extension
 func FailedResponse(ctx *gin.Context, respCode int, message string, data interface{}) {
-	switch respCode {
-	case http.StatusBadRequest:
-		ctx.JSON(respCode, JSONBadRequestResult{
-			Code:    http.StatusBadRequest,
-			Message: message,
-			Data:    data,
-		})
-		return
-	case http.StatusNotFound:
-		ctx.JSON(respCode, JSONNotFoundResult{
-			Code:    http.StatusNotFound,
-			Message: message,
-			Data:    data,
-		})
-		return
-	case http.StatusUnauthorized:
-		ctx.JSON(respCode, JSONUnauthorizedResult{
-			Code:    http.StatusUnauthorized,
-			Message: message,
-			Data:    data,
-		})
-		return
-	case http.StatusForbidden:
-		ctx.JSON(respCode, JSONForbiddenResult{
-			Code:    http.StatusForbidden,
-			Message: message,
-			Data:    data,
-		})
-		return
-	case http.StatusInternalServerError:
-		ctx.JSON(respCode, JSONInternalServerErrorResult{
-			Code:    http.StatusInternalServerError,
-			Message: message,
-			Data:    data,
-		})
-		return
+	problem := NewProblem(ctx, respCode, message)
+	if data != nil {
+		problem = problem.With("data", data)
 	}
+	ProblemResponse(ctx, problem)
 }
@@ -0,0 +1,162 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/models"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// itemCode matches the codes of items, like ITM-16 or itm_16
+var itemCode = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{1,31}$`)
+
+// licensePlate matches license plates, like AB123CD or AB-123-CD, with at least one digit
+var licensePlate = regexp.MustCompile(`(?i)^[A-Z0-9]{1,4}([ -]?[A-Z0-9]{1,4}){0,3}$`)
+
+// validationMessages are the messages of the field errors, by the tag of the rule the field broke.
+// The messages of the rules with a parameter are formats of it
+var validationMessages = map[string]string{
+	"required":     "is required",
+	"gt":           "must be greater than %s",
+	"gte":          "must be at least %s",
+	"lt":           "must be less than %s",
+	"lte":          "must be at most %s",
+	"ne":           "must not be %s",
+	"oneof":        "must be one of %s",
+	"alphanum":     "must only have letters and digits",
+	"itemcode":     "must be 2 to 32 letters, digits, dashes or underscores, starting with a letter or digit",
+	"licenseplate": "must be a license plate like AB123CD or AB-123-CD",
+	"orderdates":   "must not be before the submitted date",
+}
+
+// datedOrder is a request body of an order with a submitted and a deadline date
+type datedOrder interface {
+	Dates() (submitted, deadline time.Time)
+}
+
+// init registers the custom rules on the validator gin binds request bodies with
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		RegisterValidations(v)
+	}
+}
+
+// RegisterValidations registers the custom rules of the request bodies on a validator and names the fields of its
+// errors by their json names
+func RegisterValidations(v *validator.Validate) {
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	})
+	_ = v.RegisterValidation("itemcode", func(fl validator.FieldLevel) bool {
+		return itemCode.MatchString(fl.Field().String())
+	})
+	_ = v.RegisterValidation("licenseplate", func(fl validator.FieldLevel) bool {
+		plate := fl.Field().String()
+		return licensePlate.MatchString(plate) && strings.ContainsAny(plate, "0123456789")
+	})
+	// money is checked by its amount, so the rules of numbers like gte=0 apply to it
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(models.Money).Amount.Float64()
+	}, models.Money{})
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		submitted, deadline := sl.Current().Interface().(datedOrder).Dates()
+		if !submitted.IsZero() && !deadline.IsZero() && deadline.Before(submitted) {
+			sl.ReportError(deadline, "deadlineDate", "DeadlineDate", "orderdates", "")
+		}
+	}, models.CreateOrderRequest{}, models.UpdateOrderRequest{})
+}
+
+// BindErrorResponse writes the problem of a request body that could not be bound, with every field that broke a rule.
+// The body is a bad request unless the error has a kind of its own
+func BindErrorResponse(ctx *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errs.KindOf(err) != errs.KindInternal {
+		status = StatusOf(err)
+	}
+	problem := NewProblem(ctx, status, err.Error())
+	var invalid validator.ValidationErrors
+	var mistyped *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &invalid):
+		problem.Detail = "the request has invalid fields"
+		for _, fe := range invalid {
+			problem.Errors = append(problem.Errors, FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)})
+		}
+	case errors.As(err, &mistyped) && mistyped.Field == "":
+		// the errors of the values that read themselves, like money, do not know the field they were given for
+		problem.Detail = fmt.Sprintf("the request has a %s that must be %s", mistyped.Value, jsonKind(mistyped.Type))
+	case errors.As(err, &mistyped):
+		problem.Detail = "the request has invalid fields"
+		problem.Errors = []FieldError{{Field: mistyped.Field, Message: "must be " + jsonKind(mistyped.Type)}}
+	}
+	ProblemResponse(ctx, problem)
+}
+
+// fieldPath returns the path of the field of an error in the json of the request, like orderItems[0].quantity
+func fieldPath(fe validator.FieldError) string {
+	path := fe.Namespace()
+	if i := strings.Index(path, "."); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// fieldMessage returns the message of the rule a field broke
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "min", "max":
+		return lengthMessage(fe)
+	}
+	message, ok := validationMessages[fe.Tag()]
+	if !ok {
+		return "is not valid"
+	}
+	if strings.Contains(message, "%s") {
+		return fmt.Sprintf(message, fe.Param())
+	}
+	return message
+}
+
+// lengthMessage returns the message of a min or max rule, which limit the length of strings and lists and the value of numbers
+func lengthMessage(fe validator.FieldError) string {
+	bound := "at least"
+	if fe.Tag() == "max" {
+		bound = "at most"
+	}
+	switch fe.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("must have %s %s characters", bound, fe.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("must have %s %s entries", bound, fe.Param())
+	default:
+		return fmt.Sprintf("must be %s %s", bound, fe.Param())
+	}
+}
+
+// jsonKind returns the kind of json value a go type is decoded from
+func jsonKind(t reflect.Type) string {
+	if t == reflect.TypeOf(models.Money{}) {
+		return "an amount with a currency, like " + models.MoneyExample
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	default:
+		return "a number"
+	}
+}
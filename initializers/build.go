@@ -0,0 +1,23 @@
+package initializers
+
+import (
+	"github.com/laertkokona/crud-test/models"
+	"runtime"
+)
+
+// Build information, injected at build time with
+//
+//	go build -ldflags "-X github.com/laertkokona/crud-test/initializers.Commit=$(git rev-parse --short HEAD) -X github.com/laertkokona/crud-test/initializers.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Build returns the build information of the binary
+func Build() models.BuildInfo {
+	return models.BuildInfo{
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
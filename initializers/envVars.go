@@ -1,11 +1,20 @@
 package initializers
 
 import (
+	"errors"
+	"fmt"
 	"github.com/caarlos0/env/v6"
 	"github.com/joho/godotenv"
+	"github.com/laertkokona/crud-test/utils"
 	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Vars is the configuration of the application, read from the environment
 type Vars struct {
 	PGHost     string `env:"POSTGRES_HOST,required"`
 	PGUser     string `env:"POSTGRES_USER,required"`
@@ -16,12 +25,35 @@ type Vars struct {
 	SecretKey   string `env:"SECRET_KEY,required"`
 	Port        string `env:"PORT,required"`
 	TablePrefix string `env:"TABLE_PREFIX,required"`
+
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	CORSOrigins     []string      `env:"CORS_ORIGINS" envSeparator:","`
+	LogLevel        string        `env:"LOG_LEVEL" envDefault:"info"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"15s"`
+
+	DBMaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" envDefault:"25"`
+	DBMaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" envDefault:"5"`
+	DBConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"30m"`
+
+	AllowPendingMigrations bool `env:"ALLOW_PENDING_MIGRATIONS" envDefault:"false"`
+
+	DefaultCurrency    string   `env:"DEFAULT_CURRENCY" envDefault:"EUR"`
+	DefaultTaxPercent  float64  `env:"DEFAULT_TAX_PERCENT" envDefault:"0"`
+	CategoryTaxPercent []string `env:"CATEGORY_TAX_PERCENT" envSeparator:","`
 }
 
+// LogLevels are the values LOG_LEVEL can have, from the most to the least verbose
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// currencyCode matches the ISO 4217 code of a currency, like EUR
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
 // LoadEnvVariables loads the environment variables
 func LoadEnvVariables(envFilePath string) *Vars {
 	// load the environment variables from the .env file
-	// parse the environment variables and return them
+	// parse the environment variables
+	// validate them and return them
 	err := godotenv.Load(envFilePath)
 	if err != nil {
 		log.Fatalf("Error loading %s file", envFilePath)
@@ -36,5 +68,105 @@ func LoadEnvVariables(envFilePath string) *Vars {
 		panic(err)
 	}
 
+	err = vars.Validate()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+		panic(err)
+	}
+
 	return &vars
 }
+
+// Validate checks that the settings are usable and returns every problem it finds
+func (v *Vars) Validate() error {
+	var errs []error
+	if port, err := strconv.Atoi(v.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be a port number, got %q", v.Port))
+	}
+	if v.SecretKey == "" {
+		errs = append(errs, errors.New("SECRET_KEY cannot be empty"))
+	}
+	if v.AccessTokenTTL <= 0 {
+		errs = append(errs, errors.New("ACCESS_TOKEN_TTL must be positive"))
+	}
+	if v.RefreshTokenTTL < v.AccessTokenTTL {
+		errs = append(errs, errors.New("REFRESH_TOKEN_TTL cannot be shorter than ACCESS_TOKEN_TTL"))
+	}
+	for _, origin := range v.CORSOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			errs = append(errs, fmt.Errorf("CORS_ORIGINS must be origins like https://example.com or *, got %q", origin))
+		}
+	}
+	if !containsString(LogLevels, v.LogLevel) {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be one of %v, got %q", LogLevels, v.LogLevel))
+	}
+	if v.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if v.DBMaxOpenConns < 0 || v.DBMaxIdleConns < 0 || v.DBConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and DB_CONN_MAX_LIFETIME cannot be negative"))
+	}
+	if v.DBMaxOpenConns > 0 && v.DBMaxIdleConns > v.DBMaxOpenConns {
+		errs = append(errs, errors.New("DB_MAX_IDLE_CONNS cannot be more than DB_MAX_OPEN_CONNS"))
+	}
+	if !currencyCode.MatchString(v.DefaultCurrency) {
+		errs = append(errs, fmt.Errorf("DEFAULT_CURRENCY must be a currency code like EUR, got %q", v.DefaultCurrency))
+	}
+	if v.DefaultTaxPercent < 0 || v.DefaultTaxPercent > 100 {
+		errs = append(errs, fmt.Errorf("DEFAULT_TAX_PERCENT must be from 0 to 100, got %v", v.DefaultTaxPercent))
+	}
+	for _, entry := range v.CategoryTaxPercent {
+		if category, percent, err := categoryTaxPercent(entry); err != nil || percent < 0 || percent > 100 {
+			errs = append(errs, fmt.Errorf("CATEGORY_TAX_PERCENT must be categories with a percent from 0 to 100 like food:5, got %q", entry))
+		} else if category == "" {
+			errs = append(errs, fmt.Errorf("CATEGORY_TAX_PERCENT must name the category of %q", entry))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TokenConfig returns the settings the tokens are signed and validated with
+func (v *Vars) TokenConfig() utils.TokenConfig {
+	return utils.TokenConfig{
+		Secret:     v.SecretKey,
+		AccessTTL:  v.AccessTokenTTL,
+		RefreshTTL: v.RefreshTokenTTL,
+	}
+}
+
+// TaxRates returns the tax percents the order lines are taxed at, by the category of their item
+func (v *Vars) TaxRates() utils.TaxRates {
+	categories := make(map[string]float64, len(v.CategoryTaxPercent))
+	for _, entry := range v.CategoryTaxPercent {
+		if category, percent, err := categoryTaxPercent(entry); err == nil {
+			categories[category] = percent
+		}
+	}
+	return utils.TaxRates{
+		Default:    v.DefaultTaxPercent,
+		Categories: categories,
+	}
+}
+
+// categoryTaxPercent returns the category and tax percent of an entry of CATEGORY_TAX_PERCENT, like food:5
+func categoryTaxPercent(entry string) (string, float64, error) {
+	i := strings.LastIndex(entry, ":")
+	if i < 0 {
+		return "", 0, fmt.Errorf("%q has no tax percent", entry)
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSpace(entry[i+1:]), 64)
+	return strings.TrimSpace(entry[:i]), percent, err
+}
+
+// containsString checks if a string is in an array of strings
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"os"
 	"testing"
+	"time"
 )
 
 var vars *Vars
@@ -34,6 +35,8 @@ func TestLoadEnvVariables(t *testing.T) {
 		"SECRET_KEY":        expectedVars.SecretKey,
 		"PORT":              expectedVars.Port,
 		"TABLE_PREFIX":      expectedVars.TablePrefix,
+
+		"CATEGORY_TAX_PERCENT": "food:5,books:0",
 	}
 
 	// Path to the .env.test file
@@ -60,4 +63,80 @@ func TestLoadEnvVariables(t *testing.T) {
 	assert.Equal(t, expectedVars.PGPort, vars.PGPort)
 	assert.Equal(t, expectedVars.SecretKey, vars.SecretKey)
 	assert.Equal(t, expectedVars.Port, vars.Port)
+	assert.Equal(t, 15*time.Minute, vars.AccessTokenTTL, "should default the access token lifetime")
+	assert.Equal(t, "info", vars.LogLevel, "should default the log level")
+	assert.Equal(t, []string{"food:5", "books:0"}, vars.CategoryTaxPercent)
+	assert.Equal(t, 0.0, vars.DefaultTaxPercent, "should not tax other categories by default")
+	assert.Equal(t, "EUR", vars.DefaultCurrency, "should default the currency to euros")
+	assert.False(t, vars.AllowPendingMigrations, "should refuse to start with pending migrations by default")
+}
+
+// validVars returns settings that pass Validate
+func validVars() *Vars {
+	return &Vars{
+		SecretKey:         "secret",
+		Port:              "8001",
+		AccessTokenTTL:    15 * time.Minute,
+		RefreshTokenTTL:   720 * time.Hour,
+		CORSOrigins:       []string{"https://example.com", "*"},
+		LogLevel:          "info",
+		ShutdownTimeout:   15 * time.Second,
+		DBMaxOpenConns:    25,
+		DBMaxIdleConns:    5,
+		DBConnMaxLifetime: 30 * time.Minute,
+		DefaultCurrency:   "EUR",
+	}
+}
+
+// TestValidate tests that valid settings pass
+func TestValidate(t *testing.T) {
+	assert.NoError(t, validVars().Validate())
+}
+
+// TestValidate_Invalid tests that every invalid setting is reported
+func TestValidate_Invalid(t *testing.T) {
+	tests := map[string]func(v *Vars){
+		"PORT must be a port number":            func(v *Vars) { v.Port = "http" },
+		"SECRET_KEY cannot be empty":            func(v *Vars) { v.SecretKey = "" },
+		"ACCESS_TOKEN_TTL must be positive":     func(v *Vars) { v.AccessTokenTTL = 0 },
+		"REFRESH_TOKEN_TTL cannot be shorter":   func(v *Vars) { v.RefreshTokenTTL = time.Minute },
+		"CORS_ORIGINS must be origins":          func(v *Vars) { v.CORSOrigins = []string{"example.com/app"} },
+		"LOG_LEVEL must be one of":              func(v *Vars) { v.LogLevel = "verbose" },
+		"SHUTDOWN_TIMEOUT must be positive":     func(v *Vars) { v.ShutdownTimeout = 0 },
+		"cannot be negative":                    func(v *Vars) { v.DBMaxOpenConns = -1 },
+		"DB_MAX_IDLE_CONNS cannot be more than": func(v *Vars) { v.DBMaxIdleConns = 50 },
+		"DEFAULT_CURRENCY must be":              func(v *Vars) { v.DefaultCurrency = "eur" },
+		"DEFAULT_TAX_PERCENT must be from 0":    func(v *Vars) { v.DefaultTaxPercent = 120 },
+		"CATEGORY_TAX_PERCENT must be":          func(v *Vars) { v.CategoryTaxPercent = []string{"food:-5"} },
+		"CATEGORY_TAX_PERCENT must name":        func(v *Vars) { v.CategoryTaxPercent = []string{":5"} },
+	}
+	for message, invalidate := range tests {
+		vars := validVars()
+		invalidate(vars)
+		err := vars.Validate()
+		if assert.Error(t, err, message) {
+			assert.Contains(t, err.Error(), message)
+		}
+	}
+}
+
+// TestTokenConfig tests that the tokens are signed with the secret key and use the configured lifetimes
+func TestTokenConfig(t *testing.T) {
+	tokens := validVars().TokenConfig()
+
+	assert.Equal(t, "secret", tokens.Secret)
+	assert.Equal(t, 15*time.Minute, tokens.AccessTTL)
+	assert.Equal(t, 720*time.Hour, tokens.RefreshTTL)
+}
+
+// TestTaxRates tests that the order lines are taxed at the configured percents
+func TestTaxRates(t *testing.T) {
+	vars := validVars()
+	vars.DefaultTaxPercent = 20
+	vars.CategoryTaxPercent = []string{"food:5", "home and garden: 12.5"}
+	rates := vars.TaxRates()
+
+	assert.Equal(t, 5.0, rates.Percent("food"))
+	assert.Equal(t, 12.5, rates.Percent("home and garden"))
+	assert.Equal(t, 20.0, rates.Percent("tools"))
 }
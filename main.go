@@ -1,23 +1,19 @@
 package main
 
 import (
-	"github.com/laertkokona/crud-test/database"
+	"fmt"
+	"github.com/laertkokona/crud-test/cli"
 	"github.com/laertkokona/crud-test/initializers"
-	"github.com/laertkokona/crud-test/routes"
-	"gorm.io/gorm"
+	"os"
 	//"./docs"
 )
 
-var DB *gorm.DB
 var vars *initializers.Vars
 
 // init function
 func init() {
 	// Load environment variables
-	vars := initializers.LoadEnvVariables(".env")
-
-	// Connect to database
-	DB = database.Connect(vars)
+	vars = initializers.LoadEnvVariables(".env")
 }
 
 // main function
@@ -37,8 +33,12 @@ func init() {
 // @BasePath /
 func main() {
 
-	// Setup routes
-	routes.SetupRoutes(DB)
+	// Run the subcommand, serve when there is none
+	app := &cli.App{Vars: vars, In: os.Stdin, Out: os.Stdout}
+	if err := app.Run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	//docs.SwaggerInfo.Schemes = []string{"http", "https"}
 
@@ -7,10 +7,25 @@ import (
 	"github.com/laertkokona/crud-test/utils"
 	"net/http"
 	"strings"
+	"time"
 )
 
-// AuthMiddleware is a middleware that checks for a valid JWT token
-func AuthMiddleware(roles ...string) gin.HandlerFunc {
+// Gin context keys under which AuthMiddleware stores the authenticated user, their role and permissions and the token they presented
+const (
+	UserIDKey         = "userID"
+	RoleKey           = "role"
+	PermissionsKey    = "permissions"
+	TokenIDKey        = "tokenID"
+	TokenExpiresAtKey = "tokenExpiresAt"
+)
+
+// RevocationChecker reports whether a token was revoked before it expired
+type RevocationChecker interface {
+	IsRevoked(tokenID string, userID uint, issuedAt time.Time) bool
+}
+
+// AuthMiddleware is a middleware that checks for a valid JWT token that has not been revoked
+func AuthMiddleware(tokens utils.TokenConfig, revocations RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// get the authorization header from the request
 		// check if the authorization header is empty
@@ -18,8 +33,9 @@ func AuthMiddleware(roles ...string) gin.HandlerFunc {
 		// check if the array has a length of 2
 		// validate the token
 		// create a map of claims
-		// set the role name to the role claim in the token
-		// check if the role claim is empty or if the role claim is not equal to the role name in the token
+		// check if the role claim is empty
+		// check if the token was revoked
+		// store the user id from the sub claim, the role and permissions and the token id and expiry in the context
 		// next
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -33,7 +49,7 @@ func AuthMiddleware(roles ...string) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		token, err := utils.ValidateToken(bearerToken[1])
+		token, err := tokens.ValidateToken(bearerToken[1])
 		if err != nil {
 			helpers.FailedResponse(c, http.StatusUnauthorized, "invalid token", err.Error())
 			c.Abort()
@@ -51,21 +67,51 @@ func AuthMiddleware(roles ...string) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		if len(roles) > 0 && !contains(roles, role) {
-			helpers.FailedResponse(c, http.StatusForbidden, "forbidden", nil)
+		sub, _ := claims["sub"].(float64)
+		tokenID, _ := claims["jti"].(string)
+		exp, _ := claims["exp"].(float64)
+		if revocations != nil && revocations.IsRevoked(tokenID, uint(sub), utils.IssuedAt(claims)) {
+			helpers.FailedResponse(c, http.StatusUnauthorized, "token has been revoked", nil)
 			c.Abort()
 			return
 		}
+		var permissions []string
+		perms, _ := claims["perm"].([]interface{})
+		for _, p := range perms {
+			if name, ok := p.(string); ok {
+				permissions = append(permissions, name)
+			}
+		}
+		c.Set(UserIDKey, uint(sub))
+		c.Set(RoleKey, role)
+		c.Set(PermissionsKey, permissions)
+		c.Set(TokenIDKey, tokenID)
+		c.Set(TokenExpiresAtKey, time.Unix(int64(exp), 0))
+		c.Next()
+	}
+}
+
+// RequirePermissions is a middleware that must run after AuthMiddleware and checks that the token grants every given permission
+func RequirePermissions(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetStringSlice(PermissionsKey)
+		for _, p := range permissions {
+			if !contains(granted, p) {
+				helpers.FailedResponse(c, http.StatusForbidden, "missing permission "+p, nil)
+				c.Abort()
+				return
+			}
+		}
 		c.Next()
 	}
 }
 
 // contains checks if a string is in an array of strings
-func contains(roles []string, role string) bool {
+func contains(permissions []string, permission string) bool {
 	// range through the array of strings
-	// check if the string is equal to the role name
-	for _, r := range roles {
-		if r == role {
+	// check if the string is equal to the permission name
+	for _, p := range permissions {
+		if p == permission {
 			return true
 		}
 	}
@@ -1,6 +1,10 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/helpers"
+	"net/http"
+)
 
 // CSRFMiddleware is a middleware that checks if the request has a valid CSRF token
 func CSRFMiddleware() gin.HandlerFunc {
@@ -11,11 +15,13 @@ func CSRFMiddleware() gin.HandlerFunc {
 		// next
 		csrfToken := c.GetHeader("X-CSRF-Token")
 		if csrfToken == "" {
-			c.AbortWithStatusJSON(400, gin.H{"error": "missing csrf token"})
+			helpers.FailedResponse(c, http.StatusBadRequest, "missing csrf token", nil)
+			c.Abort()
 			return
 		}
 		if csrfToken != c.MustGet("csrf_token").(string) {
-			c.AbortWithStatusJSON(400, gin.H{"error": "invalid csrf token"})
+			helpers.FailedResponse(c, http.StatusBadRequest, "invalid csrf token", nil)
+			c.Abort()
 			return
 		}
 		c.Next()
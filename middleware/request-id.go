@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/helpers"
+	"regexp"
+)
+
+// RequestIDKey is the gin context key under which RequestID stores the id of the request
+const RequestIDKey = "requestID"
+
+// validRequestID matches the request ids taken over from the client, anything else is replaced by a new one
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID is a middleware that gives every request an id, the one the client sent in the X-Request-ID header or a
+// random one. The id is stored in the context and echoed in the response, so problems and logs can be matched up
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(helpers.RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(helpers.RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// newRequestID returns a random request id
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,27 @@
+package models
+
+// Dependency states reported by the readiness check
+const (
+	DependencyUp   = "up"
+	DependencyDown = "down"
+)
+
+// DependencyStatus model that has the name of a dependency the service needs, whether it is up and why not
+type DependencyStatus struct {
+	Name   string `json:"name" example:"database"`
+	Status string `json:"status" example:"up"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Readiness model that has whether the service can take traffic and the state of each dependency
+type Readiness struct {
+	Status       string             `json:"status" example:"up"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// BuildInfo model that has the commit and time the binary was built from and the go version it was built with
+type BuildInfo struct {
+	Commit    string `json:"commit" example:"52fb98a"`
+	BuildTime string `json:"buildTime" example:"2024-01-01T00:00:00Z"`
+	GoVersion string `json:"goVersion" example:"go1.20"`
+}
@@ -2,7 +2,7 @@ package models
 
 import "gorm.io/gorm"
 
-// Item model that has unique id as primary key, name, description, unique code, price and category
+// Item model that has unique id as primary key, name, description, unique code, quantities, price with its currency, category and the weight (kg) and volume (m³) of one unit, with the version of its last change
 type Item struct {
 	gorm.Model
 	Name              string  `json:"name,omitempty"`
@@ -10,15 +10,93 @@ type Item struct {
 	Code              string  `json:"code,omitempty" gorm:"uniqueIndex;not null"`
 	TotalQuantity     int     `json:"totalQuantity,omitempty"`
 	AvailableQuantity int     `json:"availableQuantity,omitempty"`
-	Price             float64 `json:"price,omitempty"`
+	Price             Money   `json:"price" gorm:"embedded;embeddedPrefix:price_"`
 	Category          string  `json:"category,omitempty"`
+	UnitWeight        float64 `json:"unitWeight,omitempty"`
+	UnitVolume        float64 `json:"unitVolume,omitempty"`
+	Version           uint    `json:"version" gorm:"not null;default:1"`
 }
 
 type ItemDTO struct {
-	ID          uint    `json:"id"`
-	Name        string  `json:"name,omitempty"`
-	Description string  `json:"description,omitempty"`
-	Code        string  `json:"code,omitempty"`
-	Price       float64 `json:"price,omitempty"`
-	Category    string  `json:"category,omitempty"`
+	ID                uint    `json:"id"`
+	Name              string  `json:"name,omitempty"`
+	Description       string  `json:"description,omitempty"`
+	Code              string  `json:"code,omitempty"`
+	TotalQuantity     int     `json:"totalQuantity,omitempty"`
+	AvailableQuantity int     `json:"availableQuantity,omitempty"`
+	Price             Money   `json:"price" gorm:"embedded;embeddedPrefix:price_" swaggertype:"string" example:"9.99 EUR"`
+	Category          string  `json:"category,omitempty"`
+	UnitWeight        float64 `json:"unitWeight,omitempty"`
+	UnitVolume        float64 `json:"unitVolume,omitempty"`
+	Version           uint    `json:"version" example:"1"`
+}
+
+// ItemSearchResult model that has an item matching a search, how well it matches and a snippet with the matching words highlighted.
+// The snippet is HTML, the text of the item in it is escaped so only the highlight marks are tags
+type ItemSearchResult struct {
+	ItemDTO
+	Rank    float64 `json:"rank" example:"0.6"`
+	Snippet string  `json:"snippet" example:"<mark>Hammer</mark> ITM-16 steel claw"`
+}
+
+// ItemQueryFields are the fields the list of items can be filtered and sorted by
+var ItemQueryFields = QueryFields{
+	"id":                {Column: "id", Type: NumberField},
+	"name":              {Column: "name", Type: StringField},
+	"code":              {Column: "code", Type: StringField},
+	"category":          {Column: "category", Type: StringField},
+	"price":             {Column: "price_amount", Type: NumberField},
+	"totalQuantity":     {Column: "total_quantity", Type: NumberField},
+	"availableQuantity": {Column: "available_quantity", Type: NumberField},
+}
+
+// CreateItemRequest model that has the fields of a new item, its total quantity is recorded as the opening balance
+type CreateItemRequest struct {
+	Name          string  `json:"name" binding:"required,max=100" example:"Hammer"`
+	Description   string  `json:"description" binding:"max=1000" example:"Steel claw hammer"`
+	Code          string  `json:"code" binding:"required,itemcode" example:"ITM-16"`
+	TotalQuantity int     `json:"totalQuantity" binding:"gte=0" example:"100"`
+	Price         Money   `json:"price" binding:"gte=0" swaggertype:"string" example:"9.99 EUR"`
+	Category      string  `json:"category" binding:"max=50" example:"tools"`
+	UnitWeight    float64 `json:"unitWeight" binding:"gte=0" example:"0.6"`
+	UnitVolume    float64 `json:"unitVolume" binding:"gte=0" example:"0.001"`
+}
+
+// Item returns the item of the request
+func (r CreateItemRequest) Item() Item {
+	return Item{
+		Name:          r.Name,
+		Description:   r.Description,
+		Code:          r.Code,
+		TotalQuantity: r.TotalQuantity,
+		Price:         r.Price,
+		Category:      r.Category,
+		UnitWeight:    r.UnitWeight,
+		UnitVolume:    r.UnitVolume,
+	}
+}
+
+// UpdateItemRequest model that has every field of an item a PUT replaces, the fields left out are cleared.
+// The quantities are left out since they only change through the ledger
+type UpdateItemRequest struct {
+	Name        string  `json:"name" binding:"required,max=100" example:"Hammer"`
+	Description string  `json:"description" binding:"max=1000" example:"Steel claw hammer"`
+	Code        string  `json:"code" binding:"required,itemcode" example:"ITM-16"`
+	Price       Money   `json:"price" binding:"gte=0" swaggertype:"string" example:"9.99 EUR"`
+	Category    string  `json:"category" binding:"max=50" example:"tools"`
+	UnitWeight  float64 `json:"unitWeight" binding:"gte=0" example:"0.6"`
+	UnitVolume  float64 `json:"unitVolume" binding:"gte=0" example:"0.001"`
+}
+
+// Item returns the item of the request
+func (r UpdateItemRequest) Item() Item {
+	return Item{
+		Name:        r.Name,
+		Description: r.Description,
+		Code:        r.Code,
+		Price:       r.Price,
+		Category:    r.Category,
+		UnitWeight:  r.UnitWeight,
+		UnitVolume:  r.UnitVolume,
+	}
 }
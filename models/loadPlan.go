@@ -0,0 +1,31 @@
+package models
+
+// LoadPlanRequest model that has the ids of the orders to plan and optionally the ids of the trucks to plan them on
+type LoadPlanRequest struct {
+	OrderIDs []int `json:"orders" binding:"required,min=1,dive,gt=0"`
+	TruckIDs []int `json:"trucks,omitempty" binding:"dive,gt=0"`
+}
+
+// TruckLoad model that has a truck, the orders assigned to it and how much of its capacity they use
+type TruckLoad struct {
+	Truck             TruckDTO `json:"truck"`
+	OrderIDs          []uint   `json:"orders"`
+	Weight            float64  `json:"weight"`
+	Volume            float64  `json:"volume"`
+	WeightUtilization float64  `json:"weightUtilization"`
+	VolumeUtilization float64  `json:"volumeUtilization"`
+}
+
+// UnplannedOrder model that has an order that could not be put on any truck and why
+type UnplannedOrder struct {
+	OrderID uint    `json:"order"`
+	Weight  float64 `json:"weight"`
+	Volume  float64 `json:"volume"`
+	Reason  string  `json:"reason"`
+}
+
+// LoadPlan model that has the loads of the trucks used and the orders that did not fit
+type LoadPlan struct {
+	Loads     []TruckLoad      `json:"loads"`
+	Unplanned []UnplannedOrder `json:"unplanned"`
+}
@@ -0,0 +1,243 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// amountScale is the number of decimal places of an amount, unit prices can have fractions of a cent
+const amountScale = 4
+
+// Amount scaling constants, in the units an amount counts
+const (
+	unitsPerCent  = 100
+	unitsPerWhole = 10000
+)
+
+// RoundingMode is how an amount is rounded to whole cents
+type RoundingMode int
+
+// Rounding modes of amounts. Taxes and the amounts of lines are rounded half up, away from zero like a cashier does.
+// Discounts are rounded down, towards zero, so a discount never takes off more than its percent
+const (
+	RoundHalfUp RoundingMode = iota
+	RoundDown
+)
+
+// Amount is an exact decimal amount of money, counted in ten-thousandths so unit prices can have fractions of a cent.
+// It is stored as numeric and written in json as a decimal string like "9.99"
+type Amount int64
+
+// amountPattern matches the decimal amounts that can be parsed, with at most four decimal places
+var amountPattern = regexp.MustCompile(`^-?\d+(\.\d{1,4})?$`)
+
+// ParseAmount parses a decimal amount like 9.99
+func ParseAmount(s string) (Amount, error) {
+	if !amountPattern.MatchString(s) {
+		return 0, fmt.Errorf("%q is not an amount with at most %d decimal places", s, amountScale)
+	}
+	negative := strings.HasPrefix(s, "-")
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	units, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", amountScale-len(fraction)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is too large an amount", s)
+	}
+	if negative {
+		units = -units
+	}
+	return Amount(units), nil
+}
+
+// String returns the amount as a decimal with at least two decimal places, like 9.99 or 0.3333
+func (a Amount) String() string {
+	units := int64(a)
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	fraction := strings.TrimRight(fmt.Sprintf("%04d", units%unitsPerWhole), "0")
+	if len(fraction) < 2 {
+		fraction += strings.Repeat("0", 2-len(fraction))
+	}
+	return fmt.Sprintf("%s%d.%s", sign, units/unitsPerWhole, fraction)
+}
+
+// Float64 returns the amount as a float, for the comparisons that do not need it to be exact
+func (a Amount) Float64() float64 {
+	return float64(a) / unitsPerWhole
+}
+
+// Value returns the amount as the text of a numeric
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan reads an amount from a numeric column
+func (a *Amount) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.Scan(string(v))
+	case string:
+		// numeric columns have trailing zeros up to their scale, which are dropped before parsing
+		if whole, fraction, ok := strings.Cut(v, "."); ok {
+			fraction = strings.TrimRight(fraction, "0")
+			v = whole
+			if fraction != "" {
+				v += "." + fraction
+			}
+		}
+		amount, err := ParseAmount(v)
+		if err != nil {
+			return err
+		}
+		*a = amount
+		return nil
+	case int64:
+		*a = Amount(v * unitsPerWhole)
+		return nil
+	case float64:
+		*a = Amount(math.Round(v * unitsPerWhole))
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into an amount", value)
+}
+
+// Money is an exact amount of money in a currency, named by its ISO 4217 code. It is written in json as a string of the
+// amount and the currency, like "9.99 EUR", and stored in an amount and a currency column. A zero amount may have no
+// currency, it can then be added to an amount of any currency
+type Money struct {
+	Amount   Amount `gorm:"column:amount;type:numeric(19,4);not null"`
+	Currency string `gorm:"column:currency;type:varchar(3);not null"`
+}
+
+// moneyPattern matches money written as an amount and a currency code, like 9.99 EUR
+var moneyPattern = regexp.MustCompile(`^(\S+) ([A-Z]{3})$`)
+
+// MoneyExample is how money is written, for the messages of the money that can not be read
+const MoneyExample = "9.99 EUR"
+
+// NewMoney returns an amount of money in a currency
+func NewMoney(amount Amount, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses money written as an amount and a currency code, like 9.99 EUR
+func ParseMoney(s string) (Money, error) {
+	match := moneyPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return Money{}, fmt.Errorf("%q is not an amount with a currency like %s", s, MoneyExample)
+	}
+	amount, err := ParseAmount(match[1])
+	if err != nil {
+		return Money{}, err
+	}
+	return NewMoney(amount, match[2]), nil
+}
+
+// MustParseMoney parses money like ParseMoney and panics when it can not be read, for the money written in code
+func MustParseMoney(s string) Money {
+	money, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return money
+}
+
+// String returns the amount and the currency, like 9.99 EUR, or only the amount when there is no currency
+func (m Money) String() string {
+	if m.Currency == "" {
+		return m.Amount.String()
+	}
+	return m.Amount.String() + " " + m.Currency
+}
+
+// MarshalJSON writes the money as a string, like "9.99 EUR"
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON reads money written as a string of an amount and a currency, like "9.99 EUR", or of only an amount,
+// which is left without a currency for the service taking it to give it its default one. Anything else is a type
+// error, so it is reported on the field it was given for
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return &json.UnmarshalTypeError{Value: string(data), Type: reflect.TypeOf(m).Elem()}
+	}
+	money, err := ParseMoney(s)
+	if err != nil {
+		amount, amountErr := ParseAmount(strings.TrimSpace(s))
+		if amountErr != nil {
+			return &json.UnmarshalTypeError{Value: "string " + s, Type: reflect.TypeOf(m).Elem()}
+		}
+		money = NewMoney(amount, "")
+	}
+	*m = money
+	return nil
+}
+
+// IsZero reports whether the amount is zero, whatever the currency
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// SameCurrency reports whether two amounts can be added, they are in the same currency or one of them has none
+func (m Money) SameCurrency(other Money) bool {
+	return m.Currency == "" || other.Currency == "" || m.Currency == other.Currency
+}
+
+// Add returns the sum of two amounts, which must be in the same currency
+func (m Money) Add(other Money) Money {
+	return NewMoney(m.Amount+other.Amount, m.currencyWith(other))
+}
+
+// Sub returns the amount less another one, which must be in the same currency
+func (m Money) Sub(other Money) Money {
+	return NewMoney(m.Amount-other.Amount, m.currencyWith(other))
+}
+
+// Times returns the amount multiplied by a quantity, exactly
+func (m Money) Times(quantity int) Money {
+	return NewMoney(m.Amount*Amount(quantity), m.Currency)
+}
+
+// Round returns the amount rounded to whole cents
+func (m Money) Round(mode RoundingMode) Money {
+	return m.scaled(big.NewInt(1), big.NewInt(1), mode)
+}
+
+// Percent returns the percent of the amount rounded to whole cents. The percent is taken with four decimal places
+func (m Money) Percent(percent float64, mode RoundingMode) Money {
+	return m.scaled(big.NewInt(int64(math.Round(percent*unitsPerWhole))), big.NewInt(100*unitsPerWhole), mode)
+}
+
+// scaled returns the amount multiplied by num/den and rounded to whole cents. The product is exact, so the only rounding
+// is the one to cents
+func (m Money) scaled(num, den *big.Int, mode RoundingMode) Money {
+	product := new(big.Int).Mul(big.NewInt(int64(m.Amount)), num)
+	den = new(big.Int).Mul(den, big.NewInt(unitsPerCent))
+	cents, remainder := new(big.Int).QuoRem(product, den, new(big.Int))
+	if mode == RoundHalfUp && new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(den) >= 0 {
+		cents.Add(cents, big.NewInt(int64(product.Sign())))
+	}
+	return NewMoney(Amount(cents.Int64()*unitsPerCent), m.Currency)
+}
+
+// currencyWith returns the currency of the result of adding two amounts, the one that has a currency
+func (m Money) currencyWith(other Money) string {
+	if m.Currency == "" {
+		return other.Currency
+	}
+	return m.Currency
+}
@@ -1,11 +1,40 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
 
-// OrderItem model that has unique id as primary key, item id, order id and quantity
+// OrderItem model that has unique id as primary key, the item and the order it belongs to, quantity, the unit price and
+// tax percent of the item when it was ordered, the discount of the line, the total of the line after its discount, the
+// tax of the line and a summary of the item
 type OrderItem struct {
 	gorm.Model
-	ItemId   int `json:"item" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignkey:itemId"`
-	OrderId  int `json:"order" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignkey:roleId"`
-	Quantity int `json:"quantity"`
+	ItemId          int          `json:"item" gorm:"index"`
+	OrderId         int          `json:"order" gorm:"index"`
+	Quantity        int          `json:"quantity"`
+	UnitPrice       Money        `json:"unitPrice" gorm:"embedded;embeddedPrefix:unit_price_" swaggertype:"string" example:"9.99 EUR"`
+	DiscountPercent float64      `json:"discountPercent" gorm:"not null;default:0"`
+	DiscountAmount  Money        `json:"discountAmount" gorm:"embedded;embeddedPrefix:discount_amount_" swaggertype:"string" example:"0.99 EUR"`
+	LineTotal       Money        `json:"lineTotal" gorm:"embedded;embeddedPrefix:line_total_" swaggertype:"string" example:"8.99 EUR"`
+	TaxPercent      float64      `json:"taxPercent" gorm:"not null;default:0"`
+	TaxAmount       Money        `json:"taxAmount" gorm:"embedded;embeddedPrefix:tax_amount_" swaggertype:"string" example:"1.80 EUR"`
+	Item            *ItemSummary `json:"itemSummary,omitempty" gorm:"foreignKey:ItemId"`
+}
+
+// ItemSummary model that has the id, code and name of the item of an order line
+type ItemSummary struct {
+	ID   uint   `json:"id" example:"1"`
+	Code string `json:"code" example:"ITM-16"`
+	Name string `json:"name" example:"Hammer"`
+}
+
+// TableName returns the table of the items, with the prefix the naming strategy gives the other tables
+func (ItemSummary) TableName(namer schema.Namer) string {
+	return namer.TableName("Item")
+}
+
+// Summary returns the summary of the item
+func (i Item) Summary() *ItemSummary {
+	return &ItemSummary{ID: i.ID, Code: i.Code, Name: i.Name}
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// OrderStatusChange model that records a single status transition of an order, who made it and when
+type OrderStatusChange struct {
+	gorm.Model
+	OrderID    uint        `json:"order" gorm:"index;not null"`
+	FromStatus OrderStatus `json:"fromStatus,omitempty" gorm:"type:varchar(20)"`
+	ToStatus   OrderStatus `json:"toStatus" gorm:"type:varchar(20);not null"`
+	ChangedBy  uint        `json:"changedBy"`
+	ChangedAt  time.Time   `json:"changedAt"`
+}
@@ -5,14 +5,75 @@ import (
 	"time"
 )
 
-// Order model that has unique id as primary key, unique code, submitted date, deadline date, user id and order items
+// OrderStatus is the lifecycle state of an order
+type OrderStatus string
+
+// Order status constants
+const (
+	OrderStatusDraft     OrderStatus = "draft"
+	OrderStatusSubmitted OrderStatus = "submitted"
+	OrderStatusApproved  OrderStatus = "approved"
+	OrderStatusPicking   OrderStatus = "picking"
+	OrderStatusPacked    OrderStatus = "packed"
+	OrderStatusShipped   OrderStatus = "shipped"
+	OrderStatusDelivered OrderStatus = "delivered"
+	OrderStatusCancelled OrderStatus = "cancelled"
+)
+
+// ReservesStock reports whether an order in this status holds a reservation on the stock of its items
+func (s OrderStatus) ReservesStock() bool {
+	switch s {
+	case OrderStatusDraft, OrderStatusSubmitted, OrderStatusApproved, OrderStatusPicking, OrderStatusPacked:
+		return true
+	default:
+		return false
+	}
+}
+
+// Plannable reports whether an order in this status can still be put on a truck
+func (s OrderStatus) Plannable() bool {
+	switch s {
+	case OrderStatusSubmitted, OrderStatusApproved, OrderStatusPicking, OrderStatusPacked:
+		return true
+	default:
+		return false
+	}
+}
+
+// Order model that has unique id as primary key, unique code, status, submitted date, deadline date, user id, order items,
+// the totals of the order with its discount, status history and version
 type Order struct {
 	gorm.Model
 	Code          string      `json:"code,omitempty" gorm:"uniqueIndex;not null"`
+	Status        OrderStatus `json:"status,omitempty" gorm:"type:varchar(20);not null;default:draft;index"`
 	SubmittedDate time.Time   `json:"submittedDate"`
 	DeadlineDate  time.Time   `json:"deadlineDate"`
 	UserID        int         `json:"user"`
 	OrderItems    []OrderItem `json:"orderItems,omitempty"`
+	OrderTotals
+	StatusHistory []OrderStatusChange `json:"statusHistory,omitempty"`
+	Version       uint                `json:"version" gorm:"not null;default:1"`
+}
+
+// OrderTotals model that has the sum of the line totals of an order, the discount taken off it, the tax on what is left
+// and the grand total the order is invoiced at
+type OrderTotals struct {
+	Subtotal        Money   `json:"subtotal" gorm:"embedded;embeddedPrefix:subtotal_" swaggertype:"string" example:"99.90 EUR"`
+	DiscountPercent float64 `json:"discountPercent" gorm:"not null;default:0"`
+	DiscountAmount  Money   `json:"discountAmount" gorm:"embedded;embeddedPrefix:discount_amount_" swaggertype:"string" example:"9.99 EUR"`
+	TaxAmount       Money   `json:"taxAmount" gorm:"embedded;embeddedPrefix:tax_amount_" swaggertype:"string" example:"17.98 EUR"`
+	GrandTotal      Money   `json:"grandTotal" gorm:"embedded;embeddedPrefix:grand_total_" swaggertype:"string" example:"107.89 EUR"`
+}
+
+// OrderQueryFields are the fields the list of orders can be filtered and sorted by
+var OrderQueryFields = QueryFields{
+	"id":            {Column: "id", Type: NumberField},
+	"code":          {Column: "code", Type: StringField},
+	"status":        {Column: "status", Type: StringField},
+	"submittedDate": {Column: "submitted_date", Type: TimeField},
+	"deadlineDate":  {Column: "deadline_date", Type: TimeField},
+	"user":          {Column: "user_id", Type: NumberField},
+	"grandTotal":    {Column: "grand_total_amount", Type: NumberField},
 }
 
 // ComparableOrder model that has unique id as primary key, unique code, submitted date, deadline date and user id
@@ -23,3 +84,85 @@ type ComparableOrder struct {
 	DeadlineDate  time.Time `json:"deadlineDate"`
 	UserID        int       `json:"user"`
 }
+
+// OrderScope limits the orders a request may see and change to the orders of UserID, unless All is set
+type OrderScope struct {
+	UserID uint
+	All    bool
+}
+
+// Includes reports whether an order is in the scope
+func (s OrderScope) Includes(order Order) bool {
+	return s.All || uint(order.UserID) == s.UserID
+}
+
+// OrderLineRequest model that has the item, quantity and discount percent of a line of an order
+type OrderLineRequest struct {
+	ItemID          int     `json:"item" binding:"required,gt=0" example:"1"`
+	Quantity        int     `json:"quantity" binding:"gt=0" example:"10"`
+	DiscountPercent float64 `json:"discountPercent" binding:"gte=0,lte=100" example:"5"`
+}
+
+// CreateOrderRequest model that has the fields of a new order, its deadline can not be before it is submitted and its
+// discount percent is taken off after the discounts of its lines
+type CreateOrderRequest struct {
+	Code            string             `json:"code" binding:"required,max=50" example:"ORD-2024-001"`
+	SubmittedDate   time.Time          `json:"submittedDate"`
+	DeadlineDate    time.Time          `json:"deadlineDate"`
+	OrderItems      []OrderLineRequest `json:"orderItems" binding:"dive"`
+	DiscountPercent float64            `json:"discountPercent" binding:"gte=0,lte=100" example:"10"`
+}
+
+// Dates returns the submitted and deadline date of the request
+func (r CreateOrderRequest) Dates() (submitted, deadline time.Time) {
+	return r.SubmittedDate, r.DeadlineDate
+}
+
+// Order returns the order of the request
+func (r CreateOrderRequest) Order() Order {
+	return Order{
+		Code:          r.Code,
+		SubmittedDate: r.SubmittedDate,
+		DeadlineDate:  r.DeadlineDate,
+		OrderItems:    orderLines(r.OrderItems),
+		OrderTotals:   OrderTotals{DiscountPercent: r.DiscountPercent},
+	}
+}
+
+// UpdateOrderRequest model that has every field of an order a PUT replaces, the fields left out are cleared and
+// an order without lines has its lines removed. The status only changes through the transitions
+type UpdateOrderRequest struct {
+	Code            string             `json:"code" binding:"required,max=50" example:"ORD-2024-001"`
+	SubmittedDate   time.Time          `json:"submittedDate"`
+	DeadlineDate    time.Time          `json:"deadlineDate"`
+	OrderItems      []OrderLineRequest `json:"orderItems" binding:"dive"`
+	DiscountPercent float64            `json:"discountPercent" binding:"gte=0,lte=100" example:"10"`
+}
+
+// Dates returns the submitted and deadline date of the request
+func (r UpdateOrderRequest) Dates() (submitted, deadline time.Time) {
+	return r.SubmittedDate, r.DeadlineDate
+}
+
+// Order returns the order of the request
+func (r UpdateOrderRequest) Order() Order {
+	return Order{
+		Code:          r.Code,
+		SubmittedDate: r.SubmittedDate,
+		DeadlineDate:  r.DeadlineDate,
+		OrderItems:    orderLines(r.OrderItems),
+		OrderTotals:   OrderTotals{DiscountPercent: r.DiscountPercent},
+	}
+}
+
+// orderLines returns the order items of the lines of a request
+func orderLines(lines []OrderLineRequest) []OrderItem {
+	if len(lines) == 0 {
+		return nil
+	}
+	items := make([]OrderItem, len(lines))
+	for i, line := range lines {
+		items[i] = OrderItem{ItemId: line.ItemID, Quantity: line.Quantity, DiscountPercent: line.DiscountPercent}
+	}
+	return items
+}
@@ -1,7 +1,196 @@
 package models
 
-// Pagination model that has page and limit
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Page sizes of the list endpoints, a missing limit gets the default and a larger one is capped at the max
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// ErrInvalidCursor is returned when the cursor of a pagination was not made by a previous page
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrNoCursor is returned when a cursor is given for a list that is only paged by number, like the one of the roles
+var ErrNoCursor = errors.New("this list is paged by page number, not by cursor")
+
+// Pagination model that has page and limit, or the cursor of the row to continue after instead of the page
 type Pagination struct {
-	Page  int `json:"page"`
-	Limit int `json:"limit"`
+	Page  int    `json:"page" form:"page"`
+	Limit int    `json:"limit" form:"limit"`
+	After string `json:"after,omitempty" form:"after"`
+}
+
+// Cursor model that is the (created_at, id) key of the last row of a page, the next page starts after it
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
+}
+
+// CursorOf returns the cursor of a row
+func CursorOf(row gorm.Model) Cursor {
+	return Cursor{CreatedAt: row.CreatedAt, ID: row.ID}
+}
+
+// Encode returns the cursor as an opaque string that can be put in a query
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the cursor of an encoded string or ErrInvalidCursor
+func DecodeCursor(encoded string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(data, &cursor) != nil || cursor.ID == 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// Keyset returns whether the pagination continues after a cursor instead of going to a page
+func (p Pagination) Keyset() bool {
+	return p.After != ""
+}
+
+// Validate returns ErrInvalidCursor when the pagination has a cursor that can not be decoded
+func (p Pagination) Validate() error {
+	if !p.Keyset() {
+		return nil
+	}
+	_, err := DecodeCursor(p.After)
+	return err
+}
+
+// Normalize returns the pagination starting at page 1 with the default limit when they are not set,
+// and the limit capped at MaxPageLimit. A pagination with a cursor has no page
+func (p Pagination) Normalize() Pagination {
+	if p.Keyset() {
+		p.Page = 0
+	} else if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.Limit < 1 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+	return p
+}
+
+// Offset returns how many rows come before the page
+func (p Pagination) Offset() int {
+	if p.Page < 1 {
+		return 0
+	}
+	return (p.Page - 1) * p.Limit
+}
+
+// Page model that is one page of a list with the total count and the links to the pages around it.
+// A page after a cursor has no page number and is not counted, so it has no total, it links only to the next page
+type Page[T any] struct {
+	Data       []T       `json:"data"`
+	Total      *int64    `json:"total,omitempty" example:"42"`
+	Page       int       `json:"page" example:"1"`
+	Limit      int       `json:"limit" example:"20"`
+	TotalPages *int      `json:"totalPages,omitempty" example:"3"`
+	NextCursor string    `json:"nextCursor,omitempty" example:"eyJ0IjoiMjAyNC0wMy0xMFQwODowMDowMFoiLCJpZCI6NDJ9"`
+	Links      PageLinks `json:"links"`
+	more       bool
+}
+
+// PageLinks model that has the links to the page itself and to the next and previous pages if there are any
+type PageLinks struct {
+	Self string `json:"self" example:"/items/?limit=20&page=1"`
+	Next string `json:"next,omitempty" example:"/items/?limit=20&page=2"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// NewPage returns the page of the pagination with its data and the total count of the list. A page after a cursor is
+// not counted and has no total, its data has one row more than the limit when another page follows it, which is left out
+func NewPage[T any](data []T, total int64, pagination Pagination) Page[T] {
+	pagination = pagination.Normalize()
+	if data == nil {
+		data = []T{}
+	}
+	page := Page[T]{
+		Data:  data,
+		Page:  pagination.Page,
+		Limit: pagination.Limit,
+	}
+	if pagination.Keyset() {
+		if len(data) > pagination.Limit {
+			page.Data = data[:pagination.Limit]
+			page.more = true
+		}
+		return page
+	}
+	totalPages := int((total + int64(pagination.Limit) - 1) / int64(pagination.Limit))
+	page.Total = &total
+	page.TotalPages = &totalPages
+	return page
+}
+
+// WithNextCursor returns the page with the cursor of its last row, when more rows come after it
+func (p Page[T]) WithNextCursor(last gorm.Model) Page[T] {
+	more := p.more
+	if p.Total != nil {
+		more = int64((p.Page-1)*p.Limit+len(p.Data)) < *p.Total
+	}
+	if len(p.Data) > 0 && more {
+		p.NextCursor = CursorOf(last).Encode()
+	}
+	return p
+}
+
+// WithLinks returns the page with its links built from the url of the request, keeping the other query parameters
+func (p Page[T]) WithLinks(u *url.URL) Page[T] {
+	if p.Page == 0 {
+		return p.withCursorLinks(u)
+	}
+	link := func(page int) string {
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(p.Limit))
+		return u.Path + "?" + query.Encode()
+	}
+	totalPages := 0
+	if p.TotalPages != nil {
+		totalPages = *p.TotalPages
+	}
+	p.Links = PageLinks{Self: link(p.Page)}
+	if p.Page < totalPages {
+		p.Links.Next = link(p.Page + 1)
+	}
+	if p.Page > 1 {
+		// past the end the previous page is the last one
+		prev := p.Page - 1
+		if prev > totalPages && totalPages > 0 {
+			prev = totalPages
+		}
+		p.Links.Prev = link(prev)
+	}
+	return p
+}
+
+// withCursorLinks returns the page after a cursor with the links to itself and to the page after its next cursor
+func (p Page[T]) withCursorLinks(u *url.URL) Page[T] {
+	query := u.Query()
+	query.Del("page")
+	query.Set("limit", strconv.Itoa(p.Limit))
+	p.Links = PageLinks{Self: u.Path + "?" + query.Encode()}
+	if p.NextCursor != "" {
+		query.Set("after", p.NextCursor)
+		p.Links.Next = u.Path + "?" + query.Encode()
+	}
+	return p
 }
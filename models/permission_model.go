@@ -0,0 +1,13 @@
+package models
+
+// Permission model that has unique id as primary key and a unique name like "items:write".
+// Permissions are attached to roles and checked by the routes instead of role names
+type Permission struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name" gorm:"uniqueIndex;not null" example:"items:write"`
+}
+
+// RolePermissions model that has the names of the permissions a role should have
+type RolePermissions struct {
+	Permissions []string `json:"permissions" binding:"required" example:"items:read,items:write"`
+}
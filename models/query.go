@@ -0,0 +1,178 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery is returned when the filter or sort of a list names a field or operator it does not allow
+var ErrInvalidQuery = errors.New("invalid query")
+
+// ErrSortWithCursor is returned when a list is sorted and continued after a cursor, the cursor only follows the default order
+var ErrSortWithCursor = errors.New("sort can not be combined with a cursor")
+
+// FieldType is the type of the values a field of a list is compared with
+type FieldType int
+
+// Field type constants
+const (
+	StringField FieldType = iota
+	NumberField
+	BoolField
+	TimeField
+)
+
+// QueryField is a field a list can be filtered and sorted by, with the column it is stored in and the type of its values
+type QueryField struct {
+	Column string
+	Type   FieldType
+}
+
+// QueryFields is the allow-list of the fields a list can be filtered and sorted by, by their name in the json
+type QueryFields map[string]QueryField
+
+// FilterOperator is how a filter compares a field with its value
+type FilterOperator string
+
+// Filter operator constants
+const (
+	FilterEq   FilterOperator = "eq"
+	FilterNe   FilterOperator = "ne"
+	FilterLt   FilterOperator = "lt"
+	FilterLte  FilterOperator = "lte"
+	FilterGt   FilterOperator = "gt"
+	FilterGte  FilterOperator = "gte"
+	FilterLike FilterOperator = "like"
+	FilterIn   FilterOperator = "in"
+)
+
+// Filter model that has the column a filter compares, its operator and the value parsed to the type of the field.
+// The value of the in operator is a slice of the values
+type Filter struct {
+	Column   string
+	Operator FilterOperator
+	Value    interface{}
+}
+
+// Sort model that has the column a list is sorted by and whether it is sorted descending
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// ListQuery model that has the filters and sorts of a list, checked against the allow-list of its fields
+type ListQuery struct {
+	Filters []Filter
+	Sorts   []Sort
+}
+
+// ParseListQuery parses the filter and sort parameters of a list, like filter=category:eq:tools,price:lt:20 and
+// sort=-price,name. The values of the in operator are separated by |, a field is sorted descending when it starts with -
+func ParseListQuery(filter, sort string, fields QueryFields) (ListQuery, error) {
+	var query ListQuery
+	for _, part := range splitParameter(filter) {
+		terms := strings.SplitN(part, ":", 3)
+		if len(terms) != 3 {
+			return ListQuery{}, fmt.Errorf("%w: filter %q is not field:operator:value", ErrInvalidQuery, part)
+		}
+		field, ok := fields[terms[0]]
+		if !ok {
+			return ListQuery{}, fmt.Errorf("%w: unknown filter field %q", ErrInvalidQuery, terms[0])
+		}
+		operator := FilterOperator(terms[1])
+		if !field.allows(operator) {
+			return ListQuery{}, fmt.Errorf("%w: operator %q is not allowed on field %q", ErrInvalidQuery, terms[1], terms[0])
+		}
+		value, err := field.parse(operator, terms[2])
+		if err != nil {
+			return ListQuery{}, fmt.Errorf("%w: value of field %q: %v", ErrInvalidQuery, terms[0], err)
+		}
+		query.Filters = append(query.Filters, Filter{Column: field.Column, Operator: operator, Value: value})
+	}
+	for _, part := range splitParameter(sort) {
+		name := strings.TrimPrefix(part, "-")
+		field, ok := fields[name]
+		if !ok {
+			return ListQuery{}, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, name)
+		}
+		query.Sorts = append(query.Sorts, Sort{Column: field.Column, Desc: strings.HasPrefix(part, "-")})
+	}
+	return query, nil
+}
+
+// Sorted returns whether the list is sorted by other fields than the default order
+func (q ListQuery) Sorted() bool {
+	return len(q.Sorts) > 0
+}
+
+// Validate returns the error of the pagination of the list, or ErrSortWithCursor when a sorted list has a cursor
+func (q ListQuery) Validate(pagination Pagination) error {
+	if err := pagination.Validate(); err != nil {
+		return err
+	}
+	if q.Sorted() && pagination.Keyset() {
+		return ErrSortWithCursor
+	}
+	return nil
+}
+
+// allows returns whether the operator can compare values of the type of the field
+func (f QueryField) allows(operator FilterOperator) bool {
+	switch operator {
+	case FilterEq, FilterNe, FilterIn:
+		return true
+	case FilterLt, FilterLte, FilterGt, FilterGte:
+		return f.Type != BoolField
+	case FilterLike:
+		return f.Type == StringField
+	default:
+		return false
+	}
+}
+
+// parse returns the value of a filter in the type of the field
+func (f QueryField) parse(operator FilterOperator, value string) (interface{}, error) {
+	if operator != FilterIn {
+		return f.parseValue(value)
+	}
+	var values []interface{}
+	for _, v := range strings.Split(value, "|") {
+		parsed, err := f.parseValue(v)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, parsed)
+	}
+	return values, nil
+}
+
+// parseValue returns one value in the type of the field, times are dates or RFC 3339 timestamps
+func (f QueryField) parseValue(value string) (interface{}, error) {
+	switch f.Type {
+	case NumberField:
+		return strconv.ParseFloat(value, 64)
+	case BoolField:
+		return strconv.ParseBool(value)
+	case TimeField:
+		if t, err := time.Parse("2006-01-02", value); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.RFC3339, value)
+	default:
+		return value, nil
+	}
+}
+
+// splitParameter returns the comma separated parts of a parameter, leaving out the empty ones
+func splitParameter(parameter string) []string {
+	var parts []string
+	for _, part := range strings.Split(parameter, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
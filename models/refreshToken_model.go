@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// RefreshToken model that has the hash of an opaque refresh token, the user it was issued to and the family it belongs to.
+// Every refresh replaces the token with a new one of the same family, so a family is one signed in session
+type RefreshToken struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"index;not null"`
+	FamilyID  string    `gorm:"index;not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// TokenPair model that has a short-lived access token, the refresh token to get the next one and how many seconds the access token is valid
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn" example:"900"`
+}
+
+// RefreshRequest model that has the refresh token to exchange for a new token pair
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// SignOutRequest model that optionally has the refresh token of the session to end together with the access token
+type SignOutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// RevokedToken model that has the id of a signed out token, the user it was issued to and when it would have expired.
+// Rows are only needed until the token expires on its own
+type RevokedToken struct {
+	ID        uint      `gorm:"primarykey"`
+	TokenID   string    `gorm:"uniqueIndex;not null"`
+	UserID    uint      `gorm:"index"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	CreatedAt time.Time
+}
+
+// TokenCutoff model that has a user who signed out everywhere and the time up to which every token issued to them is revoked
+type TokenCutoff struct {
+	UserID        uint      `gorm:"primaryKey;autoIncrement:false"`
+	RevokedBefore time.Time `gorm:"index;not null"`
+	UpdatedAt     time.Time
+}
@@ -1,19 +1,42 @@
 package models
 
-// Role model that has unique id as primary key, name and users
+// Role model that has unique id as primary key, name, users and the permissions it grants, with the version of its last change
 type Role struct {
-	ID    uint   `json:"id"`
-	Name  string `json:"name"`
-	Users []User `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignkey:role_id"`
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	Users       []User       `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignkey:role_id"`
+	Permissions []Permission `json:"-" gorm:"many2many:role_permissions;constraint:OnDelete:CASCADE"`
+	Version     uint         `json:"version" gorm:"not null;default:1"`
 }
 
-// RoleDTO model that has unique id as primary key, name and users
+// RoleDTO model that has unique id as primary key, name and permissions
 type RoleDTO struct {
-	ID   uint   `json:"id"`
-	Name string `json:"name"`
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	Permissions []Permission `json:"permissions"`
+	Version     uint         `json:"version" example:"1"`
 }
 
 // TableName returns the name of the table
 func (Role) TableName() string {
 	return "go-warehouse.roles"
 }
+
+// PermissionNames returns the names of the permissions of the role
+func (r Role) PermissionNames() []string {
+	names := make([]string, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// RoleRequest model that has the name of a role to create or rename
+type RoleRequest struct {
+	Name string `json:"name" binding:"required,max=50" example:"planner"`
+}
+
+// Role returns the role of the request
+func (r RoleRequest) Role() Role {
+	return Role{Name: r.Name}
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// SchemaMigration model that records a migration applied to the database
+type SchemaMigration struct {
+	Version   int       `gorm:"primarykey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
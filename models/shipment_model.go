@@ -0,0 +1,73 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Shipment model that has unique id as primary key, the truck that carries it, the planned departure date and the orders in delivery order, with the version of its last change.
+// A truck can only depart once per day, which is enforced by a unique index over the live shipments
+type Shipment struct {
+	gorm.Model
+	TruckID       uint           `json:"truck" gorm:"not null;uniqueIndex:idx_shipments_truck_departure,where:deleted_at IS NULL"`
+	DepartureDate time.Time      `json:"departureDate" gorm:"type:date;not null;uniqueIndex:idx_shipments_truck_departure,where:deleted_at IS NULL"`
+	Stops         []ShipmentStop `json:"stops,omitempty"`
+	Version       uint           `json:"version" gorm:"not null;default:1"`
+}
+
+// ShipmentStop model that has unique id as primary key, shipment id, order id and the position of the order in the delivery route
+type ShipmentStop struct {
+	gorm.Model
+	ShipmentID uint `json:"-" gorm:"not null;index"`
+	OrderID    uint `json:"order" gorm:"not null;uniqueIndex"`
+	Sequence   int  `json:"sequence"`
+}
+
+// ShipmentStopRequest model that has the order of a stop of a shipment
+type ShipmentStopRequest struct {
+	OrderID uint `json:"order" binding:"required" example:"1"`
+}
+
+// CreateShipmentRequest model that has the truck, departure date and the orders of a new shipment in delivery order
+type CreateShipmentRequest struct {
+	TruckID       uint                  `json:"truck" binding:"required" example:"1"`
+	DepartureDate time.Time             `json:"departureDate" binding:"required"`
+	Stops         []ShipmentStopRequest `json:"stops" binding:"required,min=1,dive"`
+}
+
+// Shipment returns the shipment of the request
+func (r CreateShipmentRequest) Shipment() Shipment {
+	return Shipment{
+		TruckID:       r.TruckID,
+		DepartureDate: r.DepartureDate,
+		Stops:         shipmentStops(r.Stops),
+	}
+}
+
+// UpdateShipmentRequest model that has the truck, departure date and stops a PUT replaces, the stops left out are dropped
+type UpdateShipmentRequest struct {
+	TruckID       uint                  `json:"truck" binding:"required" example:"1"`
+	DepartureDate time.Time             `json:"departureDate" binding:"required"`
+	Stops         []ShipmentStopRequest `json:"stops" binding:"required,min=1,dive"`
+}
+
+// Shipment returns the shipment of the request
+func (r UpdateShipmentRequest) Shipment() Shipment {
+	return Shipment{
+		TruckID:       r.TruckID,
+		DepartureDate: r.DepartureDate,
+		Stops:         shipmentStops(r.Stops),
+	}
+}
+
+// shipmentStops returns the shipment stops of the stops of a request
+func shipmentStops(stops []ShipmentStopRequest) []ShipmentStop {
+	if len(stops) == 0 {
+		return nil
+	}
+	shipmentStops := make([]ShipmentStop, len(stops))
+	for i, stop := range stops {
+		shipmentStops[i] = ShipmentStop{OrderID: stop.OrderID}
+	}
+	return shipmentStops
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StockShortage model that describes an order line that cannot be served from the available stock of an item
+type StockShortage struct {
+	ItemID    int `json:"item"`
+	Requested int `json:"requested"`
+	Available int `json:"available"`
+}
+
+// InsufficientStockError is returned when an order asks for more than the available stock of one or more items
+type InsufficientStockError struct {
+	Shortages []StockShortage
+}
+
+// Error returns a message listing every short item
+func (e *InsufficientStockError) Error() string {
+	lines := make([]string, 0, len(e.Shortages))
+	for _, s := range e.Shortages {
+		lines = append(lines, fmt.Sprintf("item %d: requested %d, available %d", s.ItemID, s.Requested, s.Available))
+	}
+	return "insufficient stock: " + strings.Join(lines, "; ")
+}
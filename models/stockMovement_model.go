@@ -0,0 +1,99 @@
+package models
+
+import "gorm.io/gorm"
+
+// StockMovementType is the kind of change a stock movement makes to the quantities of an item
+type StockMovementType string
+
+// Stock movement type constants
+const (
+	StockMovementReceipt     StockMovementType = "receipt"
+	StockMovementShipment    StockMovementType = "shipment"
+	StockMovementAdjustment  StockMovementType = "adjustment"
+	StockMovementReservation StockMovementType = "reservation"
+	StockMovementRelease     StockMovementType = "release"
+	StockMovementWriteOff    StockMovementType = "write_off"
+)
+
+// Stock movement reason code constants
+const (
+	ReasonOpeningBalance = "opening_balance"
+	ReasonPurchase       = "purchase"
+	ReasonCustomerReturn = "customer_return"
+	ReasonCycleCount     = "cycle_count"
+	ReasonCorrection     = "correction"
+	ReasonDamaged        = "damaged"
+	ReasonExpired        = "expired"
+	ReasonLost           = "lost"
+	ReasonOrder          = "order"
+)
+
+// StockReasonCodes lists the reason codes accepted for each movement type that can be recorded by hand
+var StockReasonCodes = map[StockMovementType][]string{
+	StockMovementReceipt:    {ReasonPurchase, ReasonCustomerReturn},
+	StockMovementAdjustment: {ReasonCycleCount, ReasonCorrection},
+	StockMovementWriteOff:   {ReasonDamaged, ReasonExpired, ReasonLost},
+}
+
+// Deltas returns how a movement of the given quantity changes the total and the available quantity of an item.
+// Reserved stock is the difference between the two, so a shipment only lowers the total: it ships stock that was already reserved.
+func (t StockMovementType) Deltas(quantity int) (total int, available int) {
+	switch t {
+	case StockMovementReceipt, StockMovementAdjustment:
+		return quantity, quantity
+	case StockMovementShipment:
+		return -quantity, 0
+	case StockMovementReservation:
+		return 0, -quantity
+	case StockMovementRelease:
+		return 0, quantity
+	case StockMovementWriteOff:
+		return -quantity, -quantity
+	default:
+		return 0, 0
+	}
+}
+
+// StockMovement model that is one entry of the inventory ledger: a change to the quantities of an item, why it happened and who made it
+type StockMovement struct {
+	gorm.Model
+	ItemID         uint              `json:"item" gorm:"index;not null"`
+	Type           StockMovementType `json:"type" gorm:"type:varchar(20);not null"`
+	Quantity       int               `json:"quantity"`
+	ReasonCode     string            `json:"reasonCode" gorm:"type:varchar(30)"`
+	Note           string            `json:"note,omitempty"`
+	OrderID        *uint             `json:"order,omitempty" gorm:"index"`
+	UserID         uint              `json:"user"`
+	TotalDelta     int               `json:"totalDelta"`
+	AvailableDelta int               `json:"availableDelta"`
+	TotalAfter     int               `json:"totalAfter"`
+	AvailableAfter int               `json:"availableAfter"`
+}
+
+// StockReconciliation model that compares the quantities stored on an item with the ones derived from its ledger
+type StockReconciliation struct {
+	ItemID            uint `json:"item"`
+	TotalQuantity     int  `json:"totalQuantity"`
+	AvailableQuantity int  `json:"availableQuantity"`
+	LedgerTotal       int  `json:"ledgerTotal"`
+	LedgerAvailable   int  `json:"ledgerAvailable"`
+	Balanced          bool `json:"balanced"`
+}
+
+// StockMovementRequest model that has a movement of the stock of an item recorded by hand, why it happened and a note
+type StockMovementRequest struct {
+	Type       StockMovementType `json:"type" binding:"required,oneof=receipt adjustment write_off" example:"receipt"`
+	Quantity   int               `json:"quantity" binding:"required" example:"10"`
+	ReasonCode string            `json:"reasonCode" binding:"required" example:"purchase"`
+	Note       string            `json:"note" binding:"max=500"`
+}
+
+// StockMovement returns the stock movement of the request
+func (r StockMovementRequest) StockMovement() StockMovement {
+	return StockMovement{
+		Type:       r.Type,
+		Quantity:   r.Quantity,
+		ReasonCode: r.ReasonCode,
+		Note:       r.Note,
+	}
+}
@@ -2,15 +2,73 @@ package models
 
 import "gorm.io/gorm"
 
-// Truck model that has unique id as primary key, chassis number and license plate
+// Truck model that has unique id as primary key, chassis number, license plate, payload capacity in kilograms and cubic metres and whether it is out of service, with the version of its last change
 type Truck struct {
 	gorm.Model
-	ChassisNumber string `json:"chassisNumber"`
-	LicensePlate  string `json:"licensePlate"`
+	ChassisNumber string  `json:"chassisNumber"`
+	LicensePlate  string  `json:"licensePlate"`
+	MaxWeight     float64 `json:"maxWeight"`
+	MaxVolume     float64 `json:"maxVolume"`
+	OutOfService  bool    `json:"outOfService"`
+	Version       uint    `json:"version" gorm:"not null;default:1"`
 }
 
 type TruckDTO struct {
-	ID            uint   `json:"id"`
-	ChassisNumber string `json:"chassisNumber"`
-	LicensePlate  string `json:"licensePlate"`
+	ID            uint    `json:"id"`
+	ChassisNumber string  `json:"chassisNumber"`
+	LicensePlate  string  `json:"licensePlate"`
+	MaxWeight     float64 `json:"maxWeight"`
+	MaxVolume     float64 `json:"maxVolume"`
+	OutOfService  bool    `json:"outOfService"`
+	Version       uint    `json:"version" example:"1"`
+}
+
+// TruckQueryFields are the fields the list of trucks can be filtered and sorted by
+var TruckQueryFields = QueryFields{
+	"id":            {Column: "id", Type: NumberField},
+	"chassisNumber": {Column: "chassis_number", Type: StringField},
+	"licensePlate":  {Column: "license_plate", Type: StringField},
+	"maxWeight":     {Column: "max_weight", Type: NumberField},
+	"maxVolume":     {Column: "max_volume", Type: NumberField},
+	"outOfService":  {Column: "out_of_service", Type: BoolField},
+}
+
+// CreateTruckRequest model that has the fields of a new truck
+type CreateTruckRequest struct {
+	ChassisNumber string  `json:"chassisNumber" binding:"required,alphanum,max=17" example:"WDB9634031L123456"`
+	LicensePlate  string  `json:"licensePlate" binding:"required,licenseplate" example:"AB123CD"`
+	MaxWeight     float64 `json:"maxWeight" binding:"gte=0" example:"12000"`
+	MaxVolume     float64 `json:"maxVolume" binding:"gte=0" example:"60"`
+	OutOfService  bool    `json:"outOfService"`
+}
+
+// Truck returns the truck of the request
+func (r CreateTruckRequest) Truck() Truck {
+	return Truck{
+		ChassisNumber: r.ChassisNumber,
+		LicensePlate:  r.LicensePlate,
+		MaxWeight:     r.MaxWeight,
+		MaxVolume:     r.MaxVolume,
+		OutOfService:  r.OutOfService,
+	}
+}
+
+// UpdateTruckRequest model that has every field of a truck a PUT replaces, the fields left out are cleared
+type UpdateTruckRequest struct {
+	ChassisNumber string  `json:"chassisNumber" binding:"required,alphanum,max=17" example:"WDB9634031L123456"`
+	LicensePlate  string  `json:"licensePlate" binding:"required,licenseplate" example:"AB123CD"`
+	MaxWeight     float64 `json:"maxWeight" binding:"gte=0" example:"12000"`
+	MaxVolume     float64 `json:"maxVolume" binding:"gte=0" example:"60"`
+	OutOfService  bool    `json:"outOfService"`
+}
+
+// Truck returns the truck of the request
+func (r UpdateTruckRequest) Truck() Truck {
+	return Truck{
+		ChassisNumber: r.ChassisNumber,
+		LicensePlate:  r.LicensePlate,
+		MaxWeight:     r.MaxWeight,
+		MaxVolume:     r.MaxVolume,
+		OutOfService:  r.OutOfService,
+	}
 }
@@ -2,7 +2,7 @@ package models
 
 import "gorm.io/gorm"
 
-// User model that has unique id as primary key, first name, last name, unique username, password and role id
+// User model that has unique id as primary key, first name, last name, unique username, password, role id and version
 type User struct {
 	gorm.Model
 	FirstName string `json:"firstName" example:"John"`
@@ -10,6 +10,7 @@ type User struct {
 	Username  string `json:"username" gorm:"uniqueIndex" example:"johndoe"`
 	Password  string `json:"password,omitempty" example:"Password123!"`
 	RoleID    int    `json:"role" example:"1"`
+	Version   uint   `json:"version" gorm:"not null;default:1" example:"1"`
 }
 
 type UserDTO struct {
@@ -18,6 +19,7 @@ type UserDTO struct {
 	LastName  string `json:"lastName" example:"Doe"`
 	Username  string `json:"username" example:"johndoe"`
 	RoleID    int    `json:"role" example:"1"`
+	Version   uint   `json:"version" example:"1"`
 }
 
 type Login struct {
@@ -25,7 +27,55 @@ type Login struct {
 	Password string `json:"password" example:"Password123!"`
 }
 
+// UserQueryFields are the fields the list of users can be filtered and sorted by, the password is left out
+var UserQueryFields = QueryFields{
+	"id":        {Column: "id", Type: NumberField},
+	"firstName": {Column: "first_name", Type: StringField},
+	"lastName":  {Column: "last_name", Type: StringField},
+	"username":  {Column: "username", Type: StringField},
+	"role":      {Column: "role_id", Type: NumberField},
+}
+
 // TableName overrides the table name used by User to `go-warehouse.users`
 func (User) TableName() string {
 	return "go-warehouse.users"
 }
+
+// CreateUserRequest model that has the fields of a new user
+type CreateUserRequest struct {
+	FirstName string `json:"firstName" binding:"required,max=50" example:"John"`
+	LastName  string `json:"lastName" binding:"required,max=50" example:"Doe"`
+	Username  string `json:"username" binding:"required,alphanum,min=3,max=50" example:"johndoe"`
+	Password  string `json:"password" binding:"required,min=8,max=72" example:"Password123!"`
+	RoleID    int    `json:"role" binding:"required,gt=0" example:"1"`
+}
+
+// User returns the user of the request
+func (r CreateUserRequest) User() User {
+	return User{
+		FirstName: r.FirstName,
+		LastName:  r.LastName,
+		Username:  r.Username,
+		Password:  r.Password,
+		RoleID:    r.RoleID,
+	}
+}
+
+// UpdateUserRequest model that has every field of a user a PUT replaces, the fields left out are cleared.
+// The password is left out since it only changes through a reset
+type UpdateUserRequest struct {
+	FirstName string `json:"firstName" binding:"required,max=50" example:"John"`
+	LastName  string `json:"lastName" binding:"required,max=50" example:"Doe"`
+	Username  string `json:"username" binding:"required,alphanum,min=3,max=50" example:"johndoe"`
+	RoleID    int    `json:"role" binding:"required,gt=0" example:"1"`
+}
+
+// User returns the user of the request
+func (r UpdateUserRequest) User() User {
+	return User{
+		FirstName: r.FirstName,
+		LastName:  r.LastName,
+		Username:  r.Username,
+		RoleID:    r.RoleID,
+	}
+}
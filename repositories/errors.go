@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/laertkokona/crud-test/errs"
+	"regexp"
+)
+
+// uniqueViolationCode is the sqlstate postgres reports when a row breaks a unique constraint
+const uniqueViolationCode = "23505"
+
+// uniqueKey matches the column in the detail of a unique violation, like Key (code)=(itm1) already exists.
+var uniqueKey = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// uniqueViolation returns a unique violation of the database as a conflict on the column it broke,
+// any other error is returned as it is
+func uniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolationCode {
+		return err
+	}
+	field := pgErr.ConstraintName
+	if match := uniqueKey.FindStringSubmatch(pgErr.Detail); match != nil {
+		field = match[1]
+	}
+	return errs.ConflictOn(field, fmt.Errorf("%s already exists", field))
+}
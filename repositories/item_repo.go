@@ -1,10 +1,20 @@
 package repositories
 
 import (
+	"fmt"
 	"github.com/laertkokona/crud-test/models"
 	"gorm.io/gorm"
+	"regexp"
+	"strings"
 )
 
+// searchWords matches the words of a search text, everything else separates them
+var searchWords = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// htmlEscapes are the characters escaped in the text of a snippet and their escapes, the ampersand first so the escapes
+// are not escaped again
+var htmlEscapes = [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&quot;"}, {"'", "&#39;"}}
+
 // itemRepo struct
 type itemRepo struct {
 	DB *gorm.DB
@@ -12,10 +22,12 @@ type itemRepo struct {
 
 // ItemRepo interface for item repository
 type ItemRepo interface {
-	FindAll(pagination models.Pagination) ([]models.Item, error)
+	FindAll(pagination models.Pagination, query models.ListQuery) ([]models.Item, int64, error)
 	FindByID(int) (models.Item, error)
 	FindByName(string) (models.Item, error)
-	Save(models.Item) (models.Item, error)
+	Search(text string, pagination models.Pagination) ([]models.ItemSearchResult, int64, error)
+	FindByIDs([]int) ([]models.Item, error)
+	Save(models.Item, uint) (models.Item, error)
 	Update(models.Item) (models.Item, error)
 	Delete(models.Item) error
 	DeleteById(int) (models.Item, error)
@@ -28,21 +40,9 @@ func NewItemRepo(db *gorm.DB) ItemRepo {
 	}
 }
 
-// FindAll returns all items
-func (p itemRepo) FindAll(pagination models.Pagination) ([]models.Item, error) {
-	// If pagination is not set, return all items
-	// If pagination is set, return items based on pagination
-	var items []models.Item
-	if pagination.Limit == 0 || pagination.Page == 0 {
-		if err := p.DB.Find(&items).Error; err != nil {
-			return nil, err
-		}
-		return items, p.DB.Find(&items).Error
-	}
-	if err := p.DB.Offset((pagination.Page - 1) * pagination.Limit).Limit(pagination.Limit).Find(&items).Error; err != nil {
-		return nil, err
-	}
-	return items, p.DB.Offset((pagination.Page - 1) * pagination.Limit).Limit(pagination.Limit).Find(&items).Error
+// FindAll returns a page of the items matching the filters of the query in its order, then oldest first, and their total count
+func (p itemRepo) FindAll(pagination models.Pagination, query models.ListQuery) ([]models.Item, int64, error) {
+	return paginate[models.Item](p.DB.Scopes(filtered(query)), pagination, oldestFirst, sorted(query))
 }
 
 // FindByID returns an item by id
@@ -54,6 +54,52 @@ func (p itemRepo) FindByID(id int) (models.Item, error) {
 	return item, p.DB.First(&item, id).Error
 }
 
+// Search returns a page of the items matching the text, best match first, and their total count. Every word of the text
+// matches the start of a word of the name, code, category or description, and names and codes also match when they are
+// similar to the text, so a misspelled word still finds the item. The snippet is highlighted on the text escaped as
+// HTML, so the text of an item can not add tags to the page showing it
+func (p itemRepo) Search(text string, pagination models.Pagination) ([]models.ItemSearchResult, int64, error) {
+	pagination = pagination.Normalize()
+	query := prefixQuery(text)
+	matches := p.DB.Model(&models.Item{}).
+		Where(`search @@ to_tsquery('simple', ?) OR name % ? OR code % ?`, query, text, text).
+		Session(&gorm.Session{})
+	page := matches.Select(`id, name, description, code, total_quantity, available_quantity, price_amount, price_currency, category, unit_weight, unit_volume, version,
+		ts_rank(search, to_tsquery('simple', ?)) + greatest(similarity(name, ?), similarity(code, ?)) AS rank,
+		ts_headline('simple', `+htmlEscaped("concat_ws(' ', name, code, description)")+`, to_tsquery('simple', ?),
+			'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=3, MaxWords=12') AS snippet`,
+		query, text, text, query).
+		Order("rank DESC").Order("id").
+		Offset(pagination.Offset()).Limit(pagination.Limit)
+	return findWithCount[models.ItemSearchResult](page, matches)
+}
+
+// htmlEscaped returns the SQL expression of the text of the expression escaped as HTML
+func htmlEscaped(expression string) string {
+	for _, escape := range htmlEscapes {
+		expression = fmt.Sprintf("replace(%s, '%s', '%s')", expression, strings.ReplaceAll(escape[0], "'", "''"), escape[1])
+	}
+	return expression
+}
+
+// prefixQuery returns the tsquery matching every word of the text as the start of a word, like ham:* & 16:*
+func prefixQuery(text string) string {
+	words := searchWords.FindAllString(strings.ToLower(text), -1)
+	for i := range words {
+		words[i] += ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// FindByIDs returns the items with the given ids, ids that do not exist are left out
+func (p itemRepo) FindByIDs(ids []int) ([]models.Item, error) {
+	var items []models.Item
+	if len(ids) == 0 {
+		return items, nil
+	}
+	return items, p.DB.Where("id IN ?", ids).Find(&items).Error
+}
+
 // FindByName returns an item by name
 func (p itemRepo) FindByName(name string) (models.Item, error) {
 	var item models.Item
@@ -63,19 +109,48 @@ func (p itemRepo) FindByName(name string) (models.Item, error) {
 	return item, p.DB.First(&item, "name=?", name).Error
 }
 
-// Save saves an item
-func (p itemRepo) Save(item models.Item) (models.Item, error) {
-	return item, p.DB.Create(&item).Error
+// Save saves an item and records its initial quantity as the opening balance of its ledger, made by the given user
+func (p itemRepo) Save(item models.Item, userID uint) (models.Item, error) {
+	// create the item without stock, the ledger puts the opening balance on it
+	opening := item.TotalQuantity
+	item.TotalQuantity = 0
+	item.AvailableQuantity = 0
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+		if opening <= 0 {
+			return nil
+		}
+		movements := []models.StockMovement{
+			{
+				ItemID:     item.ID,
+				Type:       models.StockMovementReceipt,
+				Quantity:   opening,
+				ReasonCode: models.ReasonOpeningBalance,
+				UserID:     userID,
+			},
+		}
+		if err := applyMovements(tx, movements); err != nil {
+			return err
+		}
+		item.TotalQuantity = movements[0].TotalAfter
+		item.AvailableQuantity = movements[0].AvailableAfter
+		item.Version++
+		return nil
+	})
+	return item, uniqueViolation(err)
 }
 
-// Update updates an item
+// Update updates an item if it is still at the version it was read at, the quantities are left alone since they only
+// change through the ledger
 func (p itemRepo) Update(item models.Item) (models.Item, error) {
-	return item, p.DB.Save(&item).Error
+	return item, uniqueViolation(updateVersion(p.DB, &item, &item.Version, "total_quantity", "available_quantity"))
 }
 
-// Delete deletes an item
+// Delete deletes an item if it is still at the version it was read at
 func (p itemRepo) Delete(item models.Item) error {
-	return p.DB.Delete(&item).Error
+	return deleteVersion(p.DB, &item, item.Version)
 }
 
 // DeleteById deletes an item by id
@@ -1,18 +1,25 @@
 package repositories
 
 import (
+	"errors"
 	"github.com/laertkokona/crud-test/models"
 	"gorm.io/gorm"
+	"sort"
 )
 
+// ErrOrderStatusChanged is returned when the order status was changed by someone else while a transition was in progress
+var ErrOrderStatusChanged = errors.New("order status was changed concurrently")
+
 // OrderRepo interface
 type OrderRepo interface {
-	FindAll(pagination models.Pagination) ([]models.Order, error)
+	FindAll(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) ([]models.Order, int64, error)
 	FindByID(int) (models.Order, error)
 	Save(models.Order) (models.Order, error)
 	Update(models.Order) (models.Order, error)
 	Delete(models.Order) error
 	DeleteById(int) (models.Order, error)
+	SaveTransition(models.Order, models.OrderStatusChange) (models.Order, error)
+	FindHistory(orderID int, pagination models.Pagination) ([]models.OrderStatusChange, int64, error)
 }
 
 // orderRepo struct
@@ -27,52 +34,186 @@ func NewOrderRepo(db *gorm.DB) OrderRepo {
 	}
 }
 
-// FindAll returns all orders
-func (o orderRepo) FindAll(pagination models.Pagination) ([]models.Order, error) {
-	// If pagination is not set, return all orders
-	// If pagination is set, return orders based on pagination
-	var orders []models.Order
-	if pagination.Limit == 0 || pagination.Page == 0 {
-		//if err := o.DB.Preload("OrderItems").Find(&orders).Error; err != nil {
-		//	return nil, err
-		//}
-		return orders, o.DB.Preload("OrderItems").Find(&orders).Error
+// FindAll returns a page of the orders in the scope matching the filters of the query in its order, then oldest first,
+// and their total count
+func (o orderRepo) FindAll(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) ([]models.Order, int64, error) {
+	return paginate[models.Order](o.DB.Scopes(inOrderScope(scope), filtered(query)), pagination, oldestFirst, sorted(query), func(db *gorm.DB) *gorm.DB {
+		return db.Preload("OrderItems.Item")
+	})
+}
+
+// inOrderScope restricts a query to the orders of the scope
+func inOrderScope(scope models.OrderScope) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope.All {
+			return db
+		}
+		return db.Where("user_id = ?", scope.UserID)
 	}
-	//if err := o.DB.Offset((pagination.Page - 1) * pagination.Limit).Limit(pagination.Limit).Preload("OrderItems").Find(&orders).Error; err != nil {
-	//	return nil, err
-	//}
-	return orders, o.DB.Offset((pagination.Page - 1) * pagination.Limit).Limit(pagination.Limit).Preload("OrderItems").Find(&orders).Error
 }
 
 // FindByID returns an order by id
 func (o orderRepo) FindByID(id int) (models.Order, error) {
 	var order models.Order
-	if err := o.DB.Preload("OrderItems").First(&order, id).Error; err != nil {
+	if err := o.DB.Preload("OrderItems.Item").First(&order, id).Error; err != nil {
 		return order, err
 	}
-	return order, o.DB.Preload("OrderItems").First(&order, id).Error
+	return order, o.DB.Preload("OrderItems.Item").First(&order, id).Error
 }
 
-// Save saves an order
+// Save saves an order and reserves the stock of its lines in the same transaction, the items of the lines are only
+// referenced. A code that is already taken is reported as a conflict on the code
 func (o orderRepo) Save(order models.Order) (models.Order, error) {
-	return order, o.DB.Create(&order).Error
+	err := o.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("OrderItems.Item").Create(&order).Error; err != nil {
+			return err
+		}
+		return adjustStock(tx, lineQuantities(order.OrderItems), order.ID, uint(order.UserID))
+	})
+	return order, uniqueViolation(err)
 }
 
-// Update updates an order
+// Update updates an order and its totals if it is still at the version it was read at, replacing its lines if they
+// changed and moving the stock reservation to the new quantities
 func (o orderRepo) Update(order models.Order) (models.Order, error) {
-	return order, o.DB.Save(&order).Error
+	// save the order itself, which holds its row until the transaction ends, its status only changes through transitions
+	// load the stored lines inside the transaction
+	// keep the stored lines if they are the same as the new ones
+	// reserve the difference between the new and the stored quantities
+	// replace the stored lines with the new ones
+	err := o.DB.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersion(tx, &order, &order.Version, "status"); err != nil {
+			return err
+		}
+		var oldLines []models.OrderItem
+		if err := tx.Where("order_id = ?", order.ID).Order("id").Find(&oldLines).Error; err != nil {
+			return err
+		}
+		if sameLines(oldLines, order.OrderItems) {
+			return nil
+		}
+		if order.Status.ReservesStock() {
+			if err := adjustStock(tx, quantityDeltas(oldLines, order.OrderItems), order.ID, uint(order.UserID)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("order_id = ?", order.ID).Delete(&models.OrderItem{}).Error; err != nil {
+			return err
+		}
+		for i := range order.OrderItems {
+			order.OrderItems[i].ID = 0
+			order.OrderItems[i].OrderId = int(order.ID)
+		}
+		if len(order.OrderItems) == 0 {
+			return nil
+		}
+		return tx.Omit("Item").Create(&order.OrderItems).Error
+	})
+	return order, uniqueViolation(err)
 }
 
-// Delete deletes an order
+// Delete deletes an order if it is still at the version it was read at and gives back the stock it still holds
 func (o orderRepo) Delete(order models.Order) error {
-	return o.DB.Delete(&order).Error
+	return o.DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteVersion(tx, &order, order.Version); err != nil {
+			return err
+		}
+		return releaseOrderStock(tx, order, uint(order.UserID))
+	})
 }
 
-// DeleteById deletes an order by id
+// DeleteById deletes an order by id and gives back the stock it still holds
 func (o orderRepo) DeleteById(id int) (models.Order, error) {
 	var order models.Order
 	if err := o.DB.First(&order, id).Error; err != nil {
 		return order, err
 	}
-	return order, o.DB.Delete(&order).Error
+	return order, o.Delete(order)
+}
+
+// SaveTransition moves an order to the status of the given change and its next version and records the change in the
+// same transaction
+func (o orderRepo) SaveTransition(order models.Order, change models.OrderStatusChange) (models.Order, error) {
+	// only update the order if it is still in the status the transition started from
+	// record the change in the status history
+	err := o.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ?", order.ID, change.FromStatus).
+			Updates(map[string]interface{}{"status": change.ToStatus, "version": nextVersion})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrderStatusChanged
+		}
+		switch change.ToStatus {
+		case models.OrderStatusCancelled:
+			if err := releaseOrderStock(tx, models.Order{Model: order.Model, Status: change.FromStatus}, change.ChangedBy); err != nil {
+				return err
+			}
+		case models.OrderStatusShipped:
+			if err := shipOrderStock(tx, order, change.ChangedBy); err != nil {
+				return err
+			}
+		}
+		change.OrderID = order.ID
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		return order, err
+	}
+	order.Status = change.ToStatus
+	order.Version++
+	return order, nil
+}
+
+// FindHistory returns a page of the status changes of an order, oldest first, and their total count
+func (o orderRepo) FindHistory(orderID int, pagination models.Pagination) ([]models.OrderStatusChange, int64, error) {
+	return paginate[models.OrderStatusChange](o.DB.Where("order_id = ?", orderID), pagination, oldestFirst)
+}
+
+// releaseOrderStock gives back the stock reserved by the stored lines of an order, if its status still holds a reservation
+func releaseOrderStock(tx *gorm.DB, order models.Order, userID uint) error {
+	if !order.Status.ReservesStock() {
+		return nil
+	}
+	var lines []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Find(&lines).Error; err != nil {
+		return err
+	}
+	return adjustStock(tx, negate(lineQuantities(lines)), order.ID, userID)
+}
+
+// shipOrderStock takes the reserved stock of the stored lines of an order out of the warehouse
+func shipOrderStock(tx *gorm.DB, order models.Order, userID uint) error {
+	var lines []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Find(&lines).Error; err != nil {
+		return err
+	}
+	quantities := lineQuantities(lines)
+	ids := make([]int, 0, len(quantities))
+	for itemID := range quantities {
+		ids = append(ids, itemID)
+	}
+	sort.Ints(ids)
+	movements := make([]models.StockMovement, 0, len(ids))
+	for _, itemID := range ids {
+		movements = append(movements, orderMovement(models.StockMovementShipment, itemID, quantities[itemID], order.ID, userID))
+	}
+	return applyMovements(tx, movements)
+}
+
+// sameLines checks if two sets of order lines are the same lines in the same order, ordering the same quantities of the
+// same items at the same prices, discounts and tax percents
+func sameLines(a, b []models.OrderItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ItemId != b[i].ItemId || a[i].Quantity != b[i].Quantity || a[i].UnitPrice != b[i].UnitPrice ||
+			a[i].DiscountPercent != b[i].DiscountPercent || a[i].TaxPercent != b[i].TaxPercent {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/laertkokona/crud-test/models"
+	"gorm.io/gorm"
+	"sync"
+)
+
+// keyOrder is the direction the rows of a list are ordered in by their (created_at, id) key
+type keyOrder string
+
+// Key order constants
+const (
+	oldestFirst keyOrder = "ASC"
+	newestFirst keyOrder = "DESC"
+)
+
+// paginate returns a page of the query, ordered by the scopes and then by the (created_at, id) key, and the count of
+// every row it matches. A pagination with a cursor returns the rows after the cursor instead of skipping to a page, so
+// deep pages stay fast and rows are not skipped or repeated when the list changes between calls. The count runs
+// alongside the page query, the scopes like sorts and preloads are only applied to the page query.
+// A page after a cursor is not counted, which would scan every row the query matches for every page, its count is 0.
+// It returns one row more than the limit instead when another page follows it, which models.NewPage leaves out
+func paginate[T any](query *gorm.DB, pagination models.Pagination, order keyOrder, scopes ...func(*gorm.DB) *gorm.DB) ([]T, int64, error) {
+	pagination = pagination.Normalize()
+	query = query.Model(new(T)).Session(&gorm.Session{})
+
+	// scopes run when the query does, so the key order goes in a scope after the ones of the caller
+	page := query.Scopes(append(scopes, func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at " + string(order)).Order("id " + string(order))
+	})...)
+	if !pagination.Keyset() {
+		return findWithCount[T](page.Offset(pagination.Offset()).Limit(pagination.Limit), query)
+	}
+
+	cursor, err := models.DecodeCursor(pagination.After)
+	if err != nil {
+		return nil, 0, err
+	}
+	operator := ">"
+	if order == newestFirst {
+		operator = "<"
+	}
+	var rows []T
+	if err := page.Where("(created_at, id) "+operator+" (?, ?)", cursor.CreatedAt, cursor.ID).Limit(pagination.Limit + 1).Find(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	return rows, 0, nil
+}
+
+// paginateByNumber returns a numbered page of the query, in the order of the scopes, and the count of every row it
+// matches. It is for the tables without a created_at column, like roles and permissions, which have no cursor
+func paginateByNumber[T any](query *gorm.DB, pagination models.Pagination, scopes ...func(*gorm.DB) *gorm.DB) ([]T, int64, error) {
+	pagination = pagination.Normalize()
+	query = query.Model(new(T)).Session(&gorm.Session{})
+	return findWithCount[T](query.Scopes(scopes...).Offset(pagination.Offset()).Limit(pagination.Limit), query)
+}
+
+// findWithCount returns the rows of the page query and the count of the count query, running both at the same time
+func findWithCount[T any](page *gorm.DB, count *gorm.DB) ([]T, int64, error) {
+	var total int64
+	var countErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		countErr = count.Count(&total).Error
+	}()
+
+	var rows []T
+	err := page.Find(&rows).Error
+	wg.Wait()
+	if err = errors.Join(err, countErr); err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"github.com/laertkokona/crud-test/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+// likeEscaper escapes the wildcards of a like filter, so its value is matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// filtered returns a scope that restricts a query to the rows matching every filter of the list query.
+// The columns come from the allow-list of the model and the values are bound, never put in the sql
+func filtered(query models.ListQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, filter := range query.Filters {
+			column := clause.Column{Name: filter.Column}
+			switch filter.Operator {
+			case models.FilterEq:
+				db = db.Where(clause.Eq{Column: column, Value: filter.Value})
+			case models.FilterNe:
+				db = db.Where(clause.Neq{Column: column, Value: filter.Value})
+			case models.FilterLt:
+				db = db.Where(clause.Lt{Column: column, Value: filter.Value})
+			case models.FilterLte:
+				db = db.Where(clause.Lte{Column: column, Value: filter.Value})
+			case models.FilterGt:
+				db = db.Where(clause.Gt{Column: column, Value: filter.Value})
+			case models.FilterGte:
+				db = db.Where(clause.Gte{Column: column, Value: filter.Value})
+			case models.FilterIn:
+				values, _ := filter.Value.([]interface{})
+				db = db.Where(clause.IN{Column: column, Values: values})
+			case models.FilterLike:
+				value, _ := filter.Value.(string)
+				db = db.Where(clause.Expr{SQL: "? ILIKE ?", Vars: []interface{}{column, "%" + likeEscaper.Replace(value) + "%"}})
+			}
+		}
+		return db
+	}
+}
+
+// sorted returns a scope that orders a query by the sorts of the list query, before the (created_at, id) key
+func sorted(query models.ListQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, sort := range query.Sorts {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Column}, Desc: sort.Desc})
+		}
+		return db
+	}
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/laertkokona/crud-test/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ErrRefreshTokenUsed is returned when a refresh token was already exchanged or revoked by the time it is rotated
+var ErrRefreshTokenUsed = errors.New("refresh token was already used")
+
+// RefreshTokenRepo interface
+type RefreshTokenRepo interface {
+	Save(models.RefreshToken) error
+	FindByHash(string) (models.RefreshToken, error)
+	Rotate(used models.RefreshToken, next models.RefreshToken) error
+	RevokeFamily(familyID string) error
+	RevokeUser(userID uint) error
+}
+
+// refreshTokenRepo struct
+type refreshTokenRepo struct {
+	DB *gorm.DB
+}
+
+// NewRefreshTokenRepo returns a new instance of refreshTokenRepo
+func NewRefreshTokenRepo(db *gorm.DB) RefreshTokenRepo {
+	return refreshTokenRepo{
+		DB: db,
+	}
+}
+
+// Save saves a refresh token
+func (r refreshTokenRepo) Save(token models.RefreshToken) error {
+	return r.DB.Create(&token).Error
+}
+
+// FindByHash returns a refresh token by the hash of its value
+func (r refreshTokenRepo) FindByHash(hash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	return token, r.DB.Where("token_hash = ?", hash).First(&token).Error
+}
+
+// Rotate marks a refresh token as used and saves the one replacing it in the same transaction.
+// The token is only marked if it is still unused and not revoked, otherwise ErrRefreshTokenUsed is returned
+func (r refreshTokenRepo) Rotate(used models.RefreshToken, next models.RefreshToken) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", used.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenUsed
+		}
+		return tx.Create(&next).Error
+	})
+}
+
+// RevokeFamily revokes every refresh token of a family
+func (r refreshTokenRepo) RevokeFamily(familyID string) error {
+	return r.DB.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUser revokes every refresh token of a user
+func (r refreshTokenRepo) RevokeUser(userID uint) error {
+	return r.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
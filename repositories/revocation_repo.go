@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"github.com/laertkokona/crud-test/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// RevocationRepo interface
+type RevocationRepo interface {
+	SaveToken(models.RevokedToken) error
+	SaveCutoff(models.TokenCutoff) error
+	FindActive(now time.Time) ([]models.RevokedToken, []models.TokenCutoff, error)
+	DeleteExpired(now time.Time, cutoffsBefore time.Time) error
+}
+
+// revocationRepo struct
+type revocationRepo struct {
+	DB *gorm.DB
+}
+
+// NewRevocationRepo returns a new instance of revocationRepo
+func NewRevocationRepo(db *gorm.DB) RevocationRepo {
+	return revocationRepo{
+		DB: db,
+	}
+}
+
+// SaveToken saves a revoked token, revoking the same token twice is not an error
+func (r revocationRepo) SaveToken(token models.RevokedToken) error {
+	return r.DB.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "token_id"}}, DoNothing: true}).Create(&token).Error
+}
+
+// SaveCutoff saves the time up to which the tokens of a user are revoked, replacing the previous one
+func (r revocationRepo) SaveCutoff(cutoff models.TokenCutoff) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(&cutoff).Error
+}
+
+// FindActive returns the revoked tokens that have not expired yet and all the cutoffs
+func (r revocationRepo) FindActive(now time.Time) ([]models.RevokedToken, []models.TokenCutoff, error) {
+	var tokens []models.RevokedToken
+	var cutoffs []models.TokenCutoff
+	if err := r.DB.Where("expires_at > ?", now).Find(&tokens).Error; err != nil {
+		return nil, nil, err
+	}
+	return tokens, cutoffs, r.DB.Find(&cutoffs).Error
+}
+
+// DeleteExpired deletes the revoked tokens that have expired and the cutoffs older than any token that could still be valid
+func (r revocationRepo) DeleteExpired(now time.Time, cutoffsBefore time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("revoked_before < ?", cutoffsBefore).Delete(&models.TokenCutoff{}).Error
+	})
+}
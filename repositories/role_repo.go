@@ -12,13 +12,16 @@ type roleRepo struct {
 
 // RoleRepo interface
 type RoleRepo interface {
-	FindAll() ([]models.Role, error)
+	FindAll(pagination models.Pagination) ([]models.Role, int64, error)
 	FindByID(int) (models.Role, error)
 	FindByName(string) (models.Role, error)
 	Save(models.Role) (models.Role, error)
 	Update(models.Role) (models.Role, error)
 	Delete(models.Role) error
 	DeleteById(int) (models.Role, error)
+	FindAllPermissions(pagination models.Pagination) ([]models.Permission, int64, error)
+	FindPermissionsByNames([]string) ([]models.Permission, error)
+	ReplacePermissions(models.Role, []models.Permission) (models.Role, error)
 }
 
 // NewRoleRepo returns a new instance of roleRepo
@@ -28,13 +31,11 @@ func NewRoleRepo(db *gorm.DB) RoleRepo {
 	}
 }
 
-// FindAll returns all roles
-func (r roleRepo) FindAll() ([]models.Role, error) {
-	var roles []models.Role
-	if err := r.DB.Find(&roles).Error; err != nil {
-		return nil, err
-	}
-	return roles, r.DB.Find(&roles).Error
+// FindAll returns a page of the roles with their permissions, by id, and the total count
+func (r roleRepo) FindAll(pagination models.Pagination) ([]models.Role, int64, error) {
+	return paginateByNumber[models.Role](r.DB, pagination, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Permissions").Order("id")
+	})
 }
 
 // FindByID returns a role by id
@@ -43,16 +44,13 @@ func (r roleRepo) FindByID(id int) (models.Role, error) {
 	//if err := r.DB.Preload("Users").First(&role, id).Error; err != nil {
 	//	return role, err
 	//}
-	return role, r.DB.First(&role, id).Error
+	return role, r.DB.Preload("Permissions").First(&role, id).Error
 }
 
 // FindByName returns a role by name
 func (r roleRepo) FindByName(name string) (models.Role, error) {
 	var role models.Role
-	if err := r.DB.First(&role, "name=?", name).Error; err != nil {
-		return role, err
-	}
-	return role, r.DB.First(&role, "name=?", name).Error
+	return role, r.DB.Preload("Permissions").First(&role, "name=?", name).Error
 }
 
 // Save saves a role
@@ -60,14 +58,14 @@ func (r roleRepo) Save(role models.Role) (models.Role, error) {
 	return role, r.DB.Create(&role).Error
 }
 
-// Update updates a role
+// Update updates a role if it is still at the version it was read at, its permissions are left alone
 func (r roleRepo) Update(role models.Role) (models.Role, error) {
-	return role, r.DB.Save(&role).Error
+	return role, updateVersion(r.DB, &role, &role.Version)
 }
 
-// Delete deletes a role
+// Delete deletes a role if it is still at the version it was read at
 func (r roleRepo) Delete(role models.Role) error {
-	return r.DB.Delete(&role).Error
+	return deleteVersion(r.DB, &role, role.Version)
 }
 
 // DeleteById deletes a role by id
@@ -78,3 +76,39 @@ func (r roleRepo) DeleteById(id int) (models.Role, error) {
 	}
 	return role, r.DB.Delete(&models.Role{}, id).Error
 }
+
+// FindAllPermissions returns a page of the permissions, by name, and the total count
+func (r roleRepo) FindAllPermissions(pagination models.Pagination) ([]models.Permission, int64, error) {
+	return paginateByNumber[models.Permission](r.DB, pagination, func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	})
+}
+
+// FindPermissionsByNames returns the permissions with the given names, unknown names are left out
+func (r roleRepo) FindPermissionsByNames(names []string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	return permissions, r.DB.Where("name IN ?", names).Find(&permissions).Error
+}
+
+// ReplacePermissions replaces the permissions of a role and moves the role to its next version
+func (r roleRepo) ReplacePermissions(role models.Role, permissions []models.Permission) (models.Role, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		association := tx.Model(&role).Association("Permissions")
+		var err error
+		if len(permissions) == 0 {
+			err = association.Clear()
+		} else {
+			err = association.Replace(permissions)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Role{}).Where("id = ?", role.ID).UpdateColumn("version", nextVersion).Error
+	})
+	if err != nil {
+		return role, err
+	}
+	role.Permissions = permissions
+	role.Version++
+	return role, nil
+}
@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"fmt"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// ShipmentRepo interface
+type ShipmentRepo interface {
+	FindAll(pagination models.Pagination) ([]models.Shipment, int64, error)
+	FindByID(int) (models.Shipment, error)
+	Save(models.Shipment) (models.Shipment, error)
+	Update(models.Shipment) (models.Shipment, error)
+	Delete(models.Shipment) error
+	DeleteById(int) (models.Shipment, error)
+}
+
+// shipmentRepo struct
+type shipmentRepo struct {
+	DB *gorm.DB
+}
+
+// NewShipmentRepo returns a new instance of shipmentRepo
+func NewShipmentRepo(db *gorm.DB) ShipmentRepo {
+	return shipmentRepo{
+		DB: db,
+	}
+}
+
+// preloadStops loads the stops of the shipments in delivery order
+func preloadStops(db *gorm.DB) *gorm.DB {
+	return db.Order("sequence")
+}
+
+// FindAll returns a page of the shipments, oldest first, and the total count
+func (s shipmentRepo) FindAll(pagination models.Pagination) ([]models.Shipment, int64, error) {
+	return paginate[models.Shipment](s.DB, pagination, oldestFirst, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Stops", preloadStops)
+	})
+}
+
+// FindByID returns a shipment by id
+func (s shipmentRepo) FindByID(id int) (models.Shipment, error) {
+	var shipment models.Shipment
+	return shipment, s.DB.Preload("Stops", preloadStops).First(&shipment, id).Error
+}
+
+// bookTruck locks the truck of the shipment until the transaction ends and checks that no other shipment has it on the
+// departure day, so two shipments booking the truck at the same time can not both pass the check
+func bookTruck(tx *gorm.DB, shipment models.Shipment) error {
+	var truck models.Truck
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&truck, shipment.TruckID).Error; err != nil {
+		return err
+	}
+	day := shipment.DepartureDate.Format("2006-01-02")
+	var booked []models.Shipment
+	if err := tx.Where("truck_id = ? AND departure_date = ? AND id <> ?", shipment.TruckID, day, shipment.ID).Limit(1).Find(&booked).Error; err != nil {
+		return err
+	}
+	if len(booked) > 0 {
+		return errs.Conflict(fmt.Errorf("truck %d is already booked on %s by shipment %d", shipment.TruckID, day, booked[0].ID))
+	}
+	return nil
+}
+
+// bookOrders locks the orders of the shipment until the transaction ends and checks that every one can still leave on
+// it: it is in a status that can be put on a truck, it is due no earlier than the departure day and no other shipment
+// carries it. Two shipments booking the same order at the same time can then not both pass the checks
+func bookOrders(tx *gorm.DB, shipment models.Shipment) error {
+	orderIDs := make([]uint, len(shipment.Stops))
+	for i, stop := range shipment.Stops {
+		orderIDs[i] = stop.OrderID
+	}
+	var orders []models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", orderIDs).Order("id").Find(&orders).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]models.Order, len(orders))
+	for _, order := range orders {
+		byID[order.ID] = order
+	}
+	for _, orderID := range orderIDs {
+		order, ok := byID[orderID]
+		if !ok {
+			return errs.NotFound(fmt.Errorf("order %d: %w", orderID, gorm.ErrRecordNotFound))
+		}
+		if !order.Status.Plannable() {
+			return errs.Conflict(fmt.Errorf("order %d is %s", orderID, order.Status))
+		}
+		due := time.Date(order.DeadlineDate.Year(), order.DeadlineDate.Month(), order.DeadlineDate.Day(), 0, 0, 0, 0, time.UTC)
+		if !order.DeadlineDate.IsZero() && due.Before(shipment.DepartureDate) {
+			return errs.Conflict(fmt.Errorf("order %d is due on %s, before the departure date", orderID, order.DeadlineDate.Format("2006-01-02")))
+		}
+	}
+	var stops []models.ShipmentStop
+	if err := tx.Where("order_id IN ? AND shipment_id <> ?", orderIDs, shipment.ID).Limit(1).Find(&stops).Error; err != nil {
+		return err
+	}
+	if len(stops) > 0 {
+		return errs.Conflict(fmt.Errorf("order %d is already on shipment %d", stops[0].OrderID, stops[0].ShipmentID))
+	}
+	return nil
+}
+
+// Save saves a shipment with its stops if its truck is free on the departure day and its orders can leave on it
+func (s shipmentRepo) Save(shipment models.Shipment) (models.Shipment, error) {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bookTruck(tx, shipment); err != nil {
+			return err
+		}
+		if err := bookOrders(tx, shipment); err != nil {
+			return err
+		}
+		return tx.Create(&shipment).Error
+	})
+	return shipment, uniqueViolation(err)
+}
+
+// Update updates a shipment if it is still at the version it was read at, its truck is free on the departure day and its
+// orders can leave on it, replacing its stops with the new ones
+func (s shipmentRepo) Update(shipment models.Shipment) (models.Shipment, error) {
+	// book the truck and the orders, which holds their rows until the transaction ends
+	// save the shipment itself, which holds its row until the transaction ends
+	// remove the stored stops so the orders can be planned again
+	// create the new stops
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bookTruck(tx, shipment); err != nil {
+			return err
+		}
+		if err := bookOrders(tx, shipment); err != nil {
+			return err
+		}
+		if err := updateVersion(tx, &shipment, &shipment.Version); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("shipment_id = ?", shipment.ID).Delete(&models.ShipmentStop{}).Error; err != nil {
+			return err
+		}
+		for i := range shipment.Stops {
+			shipment.Stops[i].ID = 0
+			shipment.Stops[i].ShipmentID = shipment.ID
+		}
+		if len(shipment.Stops) == 0 {
+			return nil
+		}
+		return tx.Create(&shipment.Stops).Error
+	})
+	return shipment, uniqueViolation(err)
+}
+
+// Delete deletes a shipment if it is still at the version it was read at and releases its orders
+func (s shipmentRepo) Delete(shipment models.Shipment) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteVersion(tx, &shipment, shipment.Version); err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("shipment_id = ?", shipment.ID).Delete(&models.ShipmentStop{}).Error
+	})
+}
+
+// DeleteById deletes a shipment by id
+func (s shipmentRepo) DeleteById(id int) (models.Shipment, error) {
+	shipment, err := s.FindByID(id)
+	if err != nil {
+		return shipment, err
+	}
+	return shipment, s.Delete(shipment)
+}
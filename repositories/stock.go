@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"fmt"
+	"github.com/laertkokona/crud-test/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+)
+
+// lineQuantities sums the ordered quantity per item of the given order lines
+func lineQuantities(lines []models.OrderItem) map[int]int {
+	quantities := make(map[int]int)
+	for _, line := range lines {
+		quantities[line.ItemId] += line.Quantity
+	}
+	return quantities
+}
+
+// quantityDeltas returns, per item, how much more stock the new lines need compared to the old ones
+func quantityDeltas(oldLines, newLines []models.OrderItem) map[int]int {
+	deltas := lineQuantities(newLines)
+	for itemID, quantity := range lineQuantities(oldLines) {
+		deltas[itemID] -= quantity
+	}
+	return deltas
+}
+
+// negate returns the deltas with their sign flipped, turning a reservation into a release
+func negate(deltas map[int]int) map[int]int {
+	negated := make(map[int]int, len(deltas))
+	for itemID, delta := range deltas {
+		negated[itemID] = -delta
+	}
+	return negated
+}
+
+// adjustStock reserves (positive delta) or releases (negative delta) available stock of items for an order inside the transaction tx
+func adjustStock(tx *gorm.DB, deltas map[int]int, orderID uint, userID uint) error {
+	ids := make([]int, 0, len(deltas))
+	for itemID := range deltas {
+		ids = append(ids, itemID)
+	}
+	sort.Ints(ids)
+	var movements []models.StockMovement
+	for _, itemID := range ids {
+		delta := deltas[itemID]
+		switch {
+		case delta > 0:
+			movements = append(movements, orderMovement(models.StockMovementReservation, itemID, delta, orderID, userID))
+		case delta < 0:
+			movements = append(movements, orderMovement(models.StockMovementRelease, itemID, -delta, orderID, userID))
+		}
+	}
+	return applyMovements(tx, movements)
+}
+
+// orderMovement returns a stock movement made on behalf of an order
+func orderMovement(movementType models.StockMovementType, itemID int, quantity int, orderID uint, userID uint) models.StockMovement {
+	return models.StockMovement{
+		ItemID:     uint(itemID),
+		Type:       movementType,
+		Quantity:   quantity,
+		ReasonCode: models.ReasonOrder,
+		OrderID:    &orderID,
+		UserID:     userID,
+	}
+}
+
+// applyMovements applies stock movements to the quantities of their items and appends them to the ledger inside the transaction tx.
+// The movements are filled in place with their id, deltas and resulting balances.
+// The item rows are locked in id order so concurrent orders queue up instead of overselling or deadlocking, and every
+// moved item goes to its next version.
+// If any movement would take an item below zero nothing is changed and a *models.InsufficientStockError listing every short item is returned.
+func applyMovements(tx *gorm.DB, movements []models.StockMovement) error {
+	// collect the ids of the items that are moved
+	// lock the item rows for the rest of the transaction
+	// compute the new quantities of every item and check them
+	// save the new quantities and the movements
+	if len(movements) == 0 {
+		return nil
+	}
+	idSet := make(map[uint]bool)
+	for _, m := range movements {
+		idSet[m.ItemID] = true
+	}
+	ids := make([]uint, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var lockedItems []models.Item
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Order("id").Find(&lockedItems).Error; err != nil {
+		return err
+	}
+	items := make(map[uint]*models.Item, len(lockedItems))
+	for i := range lockedItems {
+		items[lockedItems[i].ID] = &lockedItems[i]
+	}
+	var shortages []models.StockShortage
+	applied := make([]*models.StockMovement, 0, len(movements))
+	for i := range movements {
+		m := &movements[i]
+		item, ok := items[m.ItemID]
+		if !ok {
+			// stock can still be handed back to an item that no longer exists, it just has nowhere to go
+			if m.Type == models.StockMovementRelease {
+				continue
+			}
+			shortages = append(shortages, models.StockShortage{ItemID: int(m.ItemID), Requested: m.Quantity})
+			continue
+		}
+		m.TotalDelta, m.AvailableDelta = m.Type.Deltas(m.Quantity)
+		total := item.TotalQuantity + m.TotalDelta
+		available := item.AvailableQuantity + m.AvailableDelta
+		if total < 0 || available < 0 {
+			shortages = append(shortages, models.StockShortage{ItemID: int(m.ItemID), Requested: m.Quantity, Available: item.AvailableQuantity})
+			continue
+		}
+		if available > total {
+			return fmt.Errorf("%s of %d would leave item %d with more available than total stock", m.Type, m.Quantity, m.ItemID)
+		}
+		item.TotalQuantity, item.AvailableQuantity = total, available
+		m.TotalAfter, m.AvailableAfter = total, available
+		applied = append(applied, m)
+	}
+	if len(shortages) > 0 {
+		return &models.InsufficientStockError{Shortages: shortages}
+	}
+	for _, item := range lockedItems {
+		err := tx.Model(&models.Item{}).Where("id = ?", item.ID).UpdateColumns(map[string]interface{}{
+			"total_quantity":     item.TotalQuantity,
+			"available_quantity": item.AvailableQuantity,
+			"version":            nextVersion,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+	return tx.Create(&applied).Error
+}
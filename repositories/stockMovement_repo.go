@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"github.com/laertkokona/crud-test/models"
+	"gorm.io/gorm"
+)
+
+// StockMovementRepo interface for the inventory ledger
+type StockMovementRepo interface {
+	FindByItem(itemID int, pagination models.Pagination) ([]models.StockMovement, int64, error)
+	Record(models.StockMovement) (models.StockMovement, error)
+	Balance(itemID int) (int, int, error)
+}
+
+// stockMovementRepo struct
+type stockMovementRepo struct {
+	DB *gorm.DB
+}
+
+// NewStockMovementRepo returns a new instance of stockMovementRepo
+func NewStockMovementRepo(db *gorm.DB) StockMovementRepo {
+	return stockMovementRepo{
+		DB: db,
+	}
+}
+
+// FindByItem returns a page of the movements of an item, newest first, and their total count
+func (s stockMovementRepo) FindByItem(itemID int, pagination models.Pagination) ([]models.StockMovement, int64, error) {
+	return paginate[models.StockMovement](s.DB.Where("item_id = ?", itemID), pagination, newestFirst)
+}
+
+// Record applies a movement to the quantities of its item and appends it to the ledger in one transaction
+func (s stockMovementRepo) Record(movement models.StockMovement) (models.StockMovement, error) {
+	movements := []models.StockMovement{movement}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		return applyMovements(tx, movements)
+	})
+	return movements[0], err
+}
+
+// Balance returns the total and available quantity of an item as derived from its ledger
+func (s stockMovementRepo) Balance(itemID int) (int, int, error) {
+	var balance struct {
+		Total     int
+		Available int
+	}
+	err := s.DB.Model(&models.StockMovement{}).
+		Select("COALESCE(SUM(total_delta), 0) AS total, COALESCE(SUM(available_delta), 0) AS available").
+		Where("item_id = ?", itemID).
+		Scan(&balance).Error
+	return balance.Total, balance.Available, err
+}
@@ -7,7 +7,8 @@ import (
 
 // TruckRepo interface
 type TruckRepo interface {
-	FindAll(pagination models.Pagination) ([]models.Truck, error)
+	FindAll(pagination models.Pagination, query models.ListQuery) ([]models.Truck, int64, error)
+	FindInService() ([]models.Truck, error)
 	FindByID(int) (models.Truck, error)
 	Save(models.Truck) (models.Truck, error)
 	Update(models.Truck) (models.Truck, error)
@@ -27,21 +28,15 @@ func NewTruckRepo(db *gorm.DB) TruckRepo {
 	}
 }
 
-// FindAll returns all trucks
-func (t truckRepo) FindAll(pagination models.Pagination) ([]models.Truck, error) {
-	// If pagination is not set, return all trucks
-	// If pagination is set, return trucks based on pagination
+// FindAll returns a page of the trucks matching the filters of the query in its order, then oldest first, and their total count
+func (t truckRepo) FindAll(pagination models.Pagination, query models.ListQuery) ([]models.Truck, int64, error) {
+	return paginate[models.Truck](t.DB.Scopes(filtered(query)), pagination, oldestFirst, sorted(query))
+}
+
+// FindInService returns every truck that is not out of service
+func (t truckRepo) FindInService() ([]models.Truck, error) {
 	var trucks []models.Truck
-	if pagination.Limit == 0 || pagination.Page == 0 {
-		//if err := t.DB.Find(&trucks).Error; err != nil {
-		//	return nil, err
-		//}
-		return trucks, t.DB.Find(&trucks).Error
-	}
-	//if err := t.DB.Offset((pagination.Page - 1) * pagination.Limit).Limit(pagination.Limit).Find(&trucks).Error; err != nil {
-	//	return nil, err
-	//}
-	return trucks, t.DB.Offset((pagination.Page - 1) * pagination.Limit).Limit(pagination.Limit).Find(&trucks).Error
+	return trucks, t.DB.Where("out_of_service = ?", false).Order("id").Find(&trucks).Error
 }
 
 // FindByID returns a truck by id
@@ -58,14 +53,14 @@ func (t truckRepo) Save(truck models.Truck) (models.Truck, error) {
 	return truck, t.DB.Create(&truck).Error
 }
 
-// Update updates a truck
+// Update updates a truck if it is still at the version it was read at
 func (t truckRepo) Update(truck models.Truck) (models.Truck, error) {
-	return truck, t.DB.Save(&truck).Error
+	return truck, updateVersion(t.DB, &truck, &truck.Version)
 }
 
-// Delete deletes a truck
+// Delete deletes a truck if it is still at the version it was read at
 func (t truckRepo) Delete(truck models.Truck) error {
-	return t.DB.Delete(&truck).Error
+	return deleteVersion(t.DB, &truck, truck.Version)
 }
 
 // DeleteById deletes a truck by id
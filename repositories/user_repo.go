@@ -10,7 +10,7 @@ type userRepo struct {
 }
 
 type UserRepo interface {
-	FindAll(pagination models.Pagination) ([]models.User, error)
+	FindAll(pagination models.Pagination, query models.ListQuery) ([]models.User, int64, error)
 	FindByID(int) (models.User, error)
 	FindByUsername(string) (models.User, error)
 	Save(models.User) (models.User, error)
@@ -26,22 +26,9 @@ func NewUserRepo(db *gorm.DB) UserRepo {
 	}
 }
 
-// FindAll returns all users
-func (u userRepo) FindAll(pagination models.Pagination) ([]models.User, error) {
-	// If pagination is not set, return all users
-	// If pagination is set, return users based on pagination
-	var users []models.User
-	if pagination.Limit == 0 || pagination.Page == 0 {
-		if err := u.DB.Find(&users).Error; err != nil {
-			return nil, err
-		}
-		return users, u.DB.Find(&users).Error
-	}
-
-	if err := u.DB.Offset((pagination.Page - 1) * pagination.Limit).Limit(pagination.Limit).Find(&users).Error; err != nil {
-		return nil, err
-	}
-	return users, u.DB.Offset((pagination.Page - 1) * pagination.Limit).Limit(pagination.Limit).Find(&users).Error
+// FindAll returns a page of the users matching the filters of the query in its order, then oldest first, and their total count
+func (u userRepo) FindAll(pagination models.Pagination, query models.ListQuery) ([]models.User, int64, error) {
+	return paginate[models.User](u.DB.Scopes(filtered(query)), pagination, oldestFirst, sorted(query))
 }
 
 // FindByID returns a user by id
@@ -62,26 +49,19 @@ func (u userRepo) FindByUsername(username string) (models.User, error) {
 	return user, u.DB.First(&user, "username=?", username).Error
 }
 
-// Save saves a user
+// Save saves a user, a taken username is a conflict on the username
 func (u userRepo) Save(user models.User) (models.User, error) {
-	return user, u.DB.Create(&user).Error
+	return user, uniqueViolation(u.DB.Create(&user).Error)
 }
 
-// Update updates a user
+// Update updates a user if it is still at the version it was read at, a taken username is a conflict on the username
 func (u userRepo) Update(user models.User) (models.User, error) {
-	return user, u.DB.Save(&user).Error
-	//if err := u.DB.First(&user, user.ID).Error; err != nil {
-	//	return user, err
-	//}
-	//return user, u.DB.Model(&user).Updates(&user).Error
+	return user, uniqueViolation(updateVersion(u.DB, &user, &user.Version))
 }
 
-// Delete deletes a user
+// Delete deletes a user if it is still at the version it was read at
 func (u userRepo) Delete(user models.User) error {
-	if err := u.DB.First(&user, user.ID).Error; err != nil {
-		return err
-	}
-	return u.DB.Delete(&user).Error
+	return deleteVersion(u.DB, &user, user.Version)
 }
 
 // DeleteById deletes a user by id
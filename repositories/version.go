@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionChanged is returned when a row was changed or deleted by someone else since the version it was read at
+var ErrVersionChanged = errors.New("it was changed by another request since it was read")
+
+// updateVersion replaces every column of the row of value, except the omitted ones and the associations, as long as
+// the row is still at the version it was read at, and moves it to the next version.
+// The version the row was read at is the one the value has, it is the next one when the update succeeds
+func updateVersion(tx *gorm.DB, value interface{}, version *uint, omit ...string) error {
+	read := *version
+	*version = read + 1
+	result := tx.Model(value).Where("version = ?", read).Select("*").Omit(append(omit, clause.Associations)...).Updates(value)
+	if result.Error != nil {
+		*version = read
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		*version = read
+		return ErrVersionChanged
+	}
+	return nil
+}
+
+// deleteVersion deletes the row of value as long as it is still at the version it was read at
+func deleteVersion(tx *gorm.DB, value interface{}, version uint) error {
+	result := tx.Where("version = ?", version).Delete(value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionChanged
+	}
+	return nil
+}
+
+// nextVersion is the update of a version column that moves the row to its next version
+var nextVersion = gorm.Expr("version + 1")
@@ -2,12 +2,18 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/laertkokona/crud-test/database"
 	"github.com/laertkokona/crud-test/handlers"
+	"github.com/laertkokona/crud-test/helpers"
+	"github.com/laertkokona/crud-test/initializers"
 	"github.com/laertkokona/crud-test/middleware"
+	"github.com/laertkokona/crud-test/models"
 	"github.com/laertkokona/crud-test/repositories"
 	"github.com/laertkokona/crud-test/services"
 	"github.com/laertkokona/crud-test/utils"
 	"gorm.io/gorm"
+	"net/http"
+	"time"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -15,11 +21,21 @@ import (
 	_ "github.com/laertkokona/crud-test/docs"
 )
 
-// SetupRoutes sets up the routes
-func SetupRoutes(DB *gorm.DB) {
+// SetupRoutes sets up the routes with the configuration and returns the handler serving them
+func SetupRoutes(DB *gorm.DB, vars *initializers.Vars) http.Handler {
+	// gin only logs its debug output at the debug log level
+	if vars.LogLevel == "debug" {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
 	// new gin engine
 	router := gin.New()
 
+	// the signing secret and lifetimes of the tokens
+	tokens := vars.TokenConfig()
+
 	// new user repository
 	userRepo := repositories.NewUserRepo(DB)
 	// new role repository
@@ -30,17 +46,37 @@ func SetupRoutes(DB *gorm.DB) {
 	truckRepo := repositories.NewTruckRepo(DB)
 	// new order repository
 	orderRepo := repositories.NewOrderRepo(DB)
+	// new stock movement repository
+	stockMovementRepo := repositories.NewStockMovementRepo(DB)
+	// new shipment repository
+	shipmentRepo := repositories.NewShipmentRepo(DB)
+	// new refresh token repository
+	refreshTokenRepo := repositories.NewRefreshTokenRepo(DB)
+	// new token revocation repository
+	revocationRepo := repositories.NewRevocationRepo(DB)
+
+	// new service for the token revocations, shared by the auth middleware of every route group
+	revocationService := services.NewRevocationService(revocationRepo, 30*time.Second, tokens.AccessTTL)
 
 	// new service for the user repository
-	userService := services.NewUserService(userRepo, roleRepo)
+	userService := services.NewUserService(userRepo, roleRepo, refreshTokenRepo, revocationService, tokens)
 	// new service for the role repository
 	roleService := services.NewRoleService(roleRepo)
 	// new service for the item repository
-	itemService := services.NewItemService(itemRepo)
+	itemService := services.NewItemService(itemRepo, stockMovementRepo, vars.DefaultCurrency)
 	// new service for the truck repository
 	truckService := services.NewTruckService(truckRepo)
-	// new service for the order repository
-	orderService := services.NewOrderService(orderRepo)
+	// new service for the order repository, taxing the order lines at the rates of the categories of their items
+	orderService := services.NewOrderService(orderRepo, itemRepo, vars.TaxRates())
+	// new service for load planning over the order, item and truck repositories
+	planningService := services.NewPlanningService(orderRepo, itemRepo, truckRepo)
+	// new service for the shipment repository
+	shipmentService := services.NewShipmentService(shipmentRepo, truckRepo)
+	// new service for the probes, ready once the database answers and its migrations are current
+	healthService := services.NewHealthService(initializers.Build(), 2*time.Second,
+		services.HealthCheck{Name: "database", Check: database.Ping(DB)},
+		services.HealthCheck{Name: "migrations", Check: database.MigrationsCurrent(DB, vars.TablePrefix, vars.DefaultCurrency)},
+	)
 
 	// new handler for the user service
 	userHandler := handlers.NewUserHandler(userService, roleService)
@@ -50,78 +86,141 @@ func SetupRoutes(DB *gorm.DB) {
 	orderHandler := handlers.NewOrderHandler(orderService)
 	// new handler for the truck service
 	truckHandler := handlers.NewTruckHandler(truckService)
+	// new handler for the planning service
+	planningHandler := handlers.NewPlanningHandler(planningService)
+	// new handler for the shipment service
+	shipmentHandler := handlers.NewShipmentHandler(shipmentService)
+	// new handler for the health service
+	healthHandler := handlers.NewHealthHandler(healthService)
+
+	// adding the request id, recovery and logger middleware to the router, a panic is answered with a problem
+	router.Use(middleware.RequestID(), gin.CustomRecovery(func(c *gin.Context, _ interface{}) {
+		helpers.FailedResponse(c, http.StatusInternalServerError, "internal server error", nil)
+		c.Abort()
+	}), gin.Logger())
+	// unknown routes are answered with a problem as well
+	router.NoRoute(func(c *gin.Context) {
+		helpers.FailedResponse(c, http.StatusNotFound, "no route for "+c.Request.Method+" "+c.Request.URL.Path, nil)
+	})
+	// adding the cors middleware when browsers on other origins may call the api
+	if len(vars.CORSOrigins) > 0 {
+		router.Use(middleware.CORSMiddleware(vars.CORSOrigins))
+	}
 
-	// adding the recovery and logger middleware to the router
-	router.Use(gin.Recovery(), gin.Logger())
+	// the probe and build information routes, open for the load balancer
+	router.GET("/healthz", healthHandler.Healthz)
+	router.GET("/readyz", healthHandler.Readyz)
+	router.GET("/version", healthHandler.Version)
 
 	// the user routes
 	userRoutes := router.Group("/users")
-	userRoutes.Use(middleware.AuthMiddleware(utils.GetRoleName(utils.SysAdmin)))
+	userRoutes.Use(middleware.AuthMiddleware(tokens, revocationService))
 	{
-		userRoutes.GET("/", userHandler.GetAllUsers)
-		userRoutes.GET("/:id", userHandler.GetUser)
-		userRoutes.POST("/", userHandler.CreateUser)
-		userRoutes.PUT("/:id", userHandler.UpdateUser)
-		userRoutes.DELETE("/:id", userHandler.DeleteUser)
+		userRoutes.GET("/", middleware.RequirePermissions(utils.UsersRead), userHandler.GetAllUsers)
+		userRoutes.GET("/:id", middleware.RequirePermissions(utils.UsersRead), userHandler.GetUser)
+		userRoutes.POST("/", middleware.RequirePermissions(utils.UsersWrite), userHandler.CreateUser)
+		userRoutes.PUT("/:id", middleware.RequirePermissions(utils.UsersWrite), userHandler.UpdateUser)
+		userRoutes.PATCH("/:id", middleware.RequirePermissions(utils.UsersWrite), userHandler.PatchUser)
+		userRoutes.DELETE("/:id", middleware.RequirePermissions(utils.UsersWrite), userHandler.DeleteUser)
 	}
 
 	// the sign in and out routes
 	router.POST("/signIn", userHandler.SignInUser)
-	router.POST("/signOut", userHandler.SignOutUser)
+	router.POST("/token/refresh", userHandler.RefreshToken)
+	router.POST("/signOut", middleware.AuthMiddleware(tokens, revocationService), userHandler.SignOutUser)
+	router.POST("/signOutAll", middleware.AuthMiddleware(tokens, revocationService), userHandler.SignOutAllUser)
 
 	// the role routes
 	roleRoutes := router.Group("/roles")
 	// the auth middleware to protect the routes from unauthorized access
-	roleRoutes.Use(middleware.AuthMiddleware(utils.GetRoleName(utils.SysAdmin)))
+	roleRoutes.Use(middleware.AuthMiddleware(tokens, revocationService))
 	{
-		roleRoutes.GET("/", userHandler.GetAllRoles)
-		roleRoutes.GET("/:id", userHandler.GetRole)
-		roleRoutes.POST("/", userHandler.CreateRole)
-		roleRoutes.PUT("/:id", userHandler.UpdateRole)
-		roleRoutes.DELETE("/:id", userHandler.DeleteRole)
+		roleRoutes.GET("/", middleware.RequirePermissions(utils.RolesRead), userHandler.GetAllRoles)
+		roleRoutes.GET("/:id", middleware.RequirePermissions(utils.RolesRead), userHandler.GetRole)
+		roleRoutes.POST("/", middleware.RequirePermissions(utils.RolesWrite), userHandler.CreateRole)
+		roleRoutes.PUT("/:id", middleware.RequirePermissions(utils.RolesWrite), userHandler.UpdateRole)
+		roleRoutes.PATCH("/:id", middleware.RequirePermissions(utils.RolesWrite), userHandler.PatchRole)
+		roleRoutes.DELETE("/:id", middleware.RequirePermissions(utils.RolesWrite), userHandler.DeleteRole)
+		roleRoutes.PUT("/:id/permissions", middleware.RequirePermissions(utils.RolesWrite), userHandler.SetRolePermissions)
 	}
 
+	// the permission routes
+	router.GET("/permissions", middleware.AuthMiddleware(tokens, revocationService), middleware.RequirePermissions(utils.RolesRead), userHandler.GetAllPermissions)
+
 	// the item routes
 	itemRoutes := router.Group("/items")
 	// the auth middleware to protect the routes from unauthorized access
-	itemRoutes.Use(middleware.AuthMiddleware())
+	itemRoutes.Use(middleware.AuthMiddleware(tokens, revocationService))
 	{
-		itemRoutes.GET("/", itemHandler.GetAllItems)
-		itemRoutes.GET("/:id", itemHandler.GetItem)
-		itemRoutes.POST("/", itemHandler.CreateItem)
-		itemRoutes.PUT("/:id", itemHandler.UpdateItem)
-		itemRoutes.DELETE("/:id", itemHandler.DeleteItem)
+		itemRoutes.GET("/", middleware.RequirePermissions(utils.ItemsRead), itemHandler.GetAllItems)
+		itemRoutes.GET("/search", middleware.RequirePermissions(utils.ItemsRead), itemHandler.SearchItems)
+		itemRoutes.GET("/:id", middleware.RequirePermissions(utils.ItemsRead), itemHandler.GetItem)
+		itemRoutes.POST("/", middleware.RequirePermissions(utils.ItemsWrite), itemHandler.CreateItem)
+		itemRoutes.PUT("/:id", middleware.RequirePermissions(utils.ItemsWrite), itemHandler.UpdateItem)
+		itemRoutes.PATCH("/:id", middleware.RequirePermissions(utils.ItemsWrite), itemHandler.PatchItem)
+		itemRoutes.DELETE("/:id", middleware.RequirePermissions(utils.ItemsWrite), itemHandler.DeleteItem)
+		itemRoutes.GET("/:id/movements", middleware.RequirePermissions(utils.ItemsRead), itemHandler.GetItemMovements)
+		itemRoutes.POST("/:id/movements", middleware.RequirePermissions(utils.StockWrite), itemHandler.RecordMovement)
+		itemRoutes.GET("/:id/reconciliation", middleware.RequirePermissions(utils.ItemsRead), itemHandler.ReconcileItem)
 	}
 
 	// the truck routes
 	truckRoutes := router.Group("/trucks")
 	// the auth middleware to protect the routes from unauthorized access
-	truckRoutes.Use(middleware.AuthMiddleware(utils.GetRoleName(utils.Admin)))
+	truckRoutes.Use(middleware.AuthMiddleware(tokens, revocationService))
+	{
+		truckRoutes.GET("/", middleware.RequirePermissions(utils.TrucksRead), truckHandler.GetAllTrucks)
+		truckRoutes.GET("/:id", middleware.RequirePermissions(utils.TrucksRead), truckHandler.GetTruck)
+		truckRoutes.POST("/", middleware.RequirePermissions(utils.TrucksWrite), truckHandler.CreateTruck)
+		truckRoutes.PUT("/:id", middleware.RequirePermissions(utils.TrucksWrite), truckHandler.UpdateTruck)
+		truckRoutes.PATCH("/:id", middleware.RequirePermissions(utils.TrucksWrite), truckHandler.PatchTruck)
+		truckRoutes.DELETE("/:id", middleware.RequirePermissions(utils.TrucksWrite), truckHandler.DeleteTruck)
+	}
+
+	// the planning routes
+	planningRoutes := router.Group("/planning")
+	// the auth middleware to protect the routes from unauthorized access
+	planningRoutes.Use(middleware.AuthMiddleware(tokens, revocationService))
 	{
-		truckRoutes.GET("/", truckHandler.GetAllTrucks)
-		truckRoutes.GET("/:id", truckHandler.GetTruck)
-		truckRoutes.POST("/", truckHandler.CreateTruck)
-		truckRoutes.PUT("/:id", truckHandler.UpdateTruck)
-		truckRoutes.DELETE("/:id", truckHandler.DeleteTruck)
+		planningRoutes.POST("/loads", middleware.RequirePermissions(utils.PlanningWrite), planningHandler.PlanLoads)
+	}
+
+	// the shipment routes
+	shipmentRoutes := router.Group("/shipments")
+	// the auth middleware to protect the routes from unauthorized access
+	shipmentRoutes.Use(middleware.AuthMiddleware(tokens, revocationService))
+	{
+		shipmentRoutes.GET("/", middleware.RequirePermissions(utils.ShipmentsRead), shipmentHandler.GetAllShipments)
+		shipmentRoutes.GET("/:id", middleware.RequirePermissions(utils.ShipmentsRead), shipmentHandler.GetShipment)
+		shipmentRoutes.POST("/", middleware.RequirePermissions(utils.ShipmentsWrite), shipmentHandler.CreateShipment)
+		shipmentRoutes.PUT("/:id", middleware.RequirePermissions(utils.ShipmentsWrite), shipmentHandler.UpdateShipment)
+		shipmentRoutes.PATCH("/:id", middleware.RequirePermissions(utils.ShipmentsWrite), shipmentHandler.PatchShipment)
+		shipmentRoutes.DELETE("/:id", middleware.RequirePermissions(utils.ShipmentsWrite), shipmentHandler.DeleteShipment)
 	}
 
 	// the order routes
 	orderRoutes := router.Group("/orders")
 	// the auth middleware to protect the routes from unauthorized access
-	orderRoutes.Use(middleware.AuthMiddleware(utils.GetRoleName(utils.User)))
+	orderRoutes.Use(middleware.AuthMiddleware(tokens, revocationService))
 	{
-		orderRoutes.GET("/", orderHandler.GetAllOrders)
-		orderRoutes.GET("/:id", orderHandler.GetOrder)
-		orderRoutes.POST("/", orderHandler.CreateOrder)
-		orderRoutes.PUT("/:id", orderHandler.UpdateOrder)
-		orderRoutes.DELETE("/:id", orderHandler.DeleteOrder)
+		orderRoutes.GET("/", middleware.RequirePermissions(utils.OrdersRead), orderHandler.GetAllOrders)
+		orderRoutes.GET("/:id", middleware.RequirePermissions(utils.OrdersRead), orderHandler.GetOrder)
+		orderRoutes.POST("/", middleware.RequirePermissions(utils.OrdersWrite), orderHandler.CreateOrder)
+		orderRoutes.PUT("/:id", middleware.RequirePermissions(utils.OrdersWrite), orderHandler.UpdateOrder)
+		orderRoutes.PATCH("/:id", middleware.RequirePermissions(utils.OrdersWrite), orderHandler.PatchOrder)
+		orderRoutes.DELETE("/:id", middleware.RequirePermissions(utils.OrdersWrite), orderHandler.DeleteOrder)
+		orderRoutes.GET("/:id/history", middleware.RequirePermissions(utils.OrdersRead), orderHandler.GetOrderHistory)
+		orderRoutes.POST("/:id/submit", middleware.RequirePermissions(utils.OrdersWrite), orderHandler.TransitionOrder(models.OrderStatusSubmitted))
+		orderRoutes.POST("/:id/reopen", middleware.RequirePermissions(utils.OrdersWrite), orderHandler.TransitionOrder(models.OrderStatusDraft))
+		orderRoutes.POST("/:id/approve", middleware.RequirePermissions(utils.OrdersApprove), orderHandler.TransitionOrder(models.OrderStatusApproved))
+		orderRoutes.POST("/:id/pick", middleware.RequirePermissions(utils.OrdersFulfil), orderHandler.TransitionOrder(models.OrderStatusPicking))
+		orderRoutes.POST("/:id/pack", middleware.RequirePermissions(utils.OrdersFulfil), orderHandler.TransitionOrder(models.OrderStatusPacked))
+		orderRoutes.POST("/:id/ship", middleware.RequirePermissions(utils.OrdersFulfil), orderHandler.TransitionOrder(models.OrderStatusShipped))
+		orderRoutes.POST("/:id/deliver", middleware.RequirePermissions(utils.OrdersFulfil), orderHandler.TransitionOrder(models.OrderStatusDelivered))
+		orderRoutes.POST("/:id/cancel", middleware.RequirePermissions(utils.OrdersWrite), orderHandler.TransitionOrder(models.OrderStatusCancelled))
 	}
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// start the server
-	err := router.Run(":8001")
-	if err != nil {
-		panic(err)
-	}
+	return router
 }
@@ -1,8 +1,70 @@
 package routes
 
-import "testing"
+import (
+	"github.com/laertkokona/crud-test/initializers"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
 
-// TestSetupRoutes tests the SetupRoutes function
+// testVars are the settings the routes are set up with in the tests
+var testVars = &initializers.Vars{
+	SecretKey:       "test-secret",
+	Port:            "8001",
+	AccessTokenTTL:  15 * time.Minute,
+	RefreshTokenTTL: 720 * time.Hour,
+	CORSOrigins:     []string{"https://example.com"},
+	LogLevel:        "error",
+	ShutdownTimeout: time.Second,
+}
+
+// TestSetupRoutes tests that the returned handler serves the routes and protects them
 func TestSetupRoutes(t *testing.T) {
+	handler := SetupRoutes(nil, testVars)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+// TestSetupRoutes_CORS tests that preflight requests from a configured origin are answered
+func TestSetupRoutes_CORS(t *testing.T) {
+	handler := SetupRoutes(nil, testVars)
+
+	request := httptest.NewRequest(http.MethodOptions, "/items/", nil)
+	request.Header.Set("Origin", "https://example.com")
+	request.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, "https://example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+
+	request.Header.Set("Origin", "https://other.example.com")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"), "other origins should not be allowed")
+}
+
+// TestSetupRoutes_Probes tests that the probes answer without a token and that readiness reports the missing database
+func TestSetupRoutes_Probes(t *testing.T) {
+	handler := SetupRoutes(nil, testVars)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "no database connection")
 
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"goVersion"`)
 }
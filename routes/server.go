@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"github.com/laertkokona/crud-test/initializers"
+	"gorm.io/gorm"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Serve serves the handler on the configured port until SIGINT or SIGTERM, then drains the in-flight requests
+// and closes the database connection pool. It returns nil after a clean shutdown
+func Serve(handler http.Handler, DB *gorm.DB, vars *initializers.Vars) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", ":"+vars.Port)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	return serve(ctx, server, listener, vars.ShutdownTimeout, func() error {
+		sqlDB, err := DB.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+}
+
+// serve runs the server on the listener until the context is done, then gives the in-flight requests
+// the shutdown timeout to finish and calls onShutdown
+func serve(ctx context.Context, server *http.Server, listener net.Listener, shutdownTimeout time.Duration, onShutdown func() error) error {
+	// serve in the background
+	// wait for the server to fail or the context to be done
+	// stop accepting requests and wait for the in-flight ones
+	// release what the requests used
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", listener.Addr())
+		errs <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		// the server stopped without being asked to
+		return errors.Join(err, onShutdown())
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, waiting up to %s for in-flight requests", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if serveErr := <-errs; !errors.Is(serveErr, http.ErrServerClosed) {
+		err = errors.Join(err, serveErr)
+	}
+	return errors.Join(err, onShutdown())
+}
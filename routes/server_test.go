@@ -0,0 +1,80 @@
+package routes
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestServe_DrainsRequests tests that a shutdown waits for the in-flight request and then calls onShutdown
+func TestServe_DrainsRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	closed := false
+	result := make(chan error, 1)
+	go func() {
+		result <- serve(ctx, server, listener, time.Second, func() error {
+			closed = true
+			return nil
+		})
+	}()
+
+	response := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		response <- string(body)
+	}()
+	<-started
+	cancel()
+
+	assert.Equal(t, "done", <-response, "the in-flight request should finish")
+	assert.NoError(t, <-result)
+	assert.True(t, closed, "onShutdown should be called")
+}
+
+// TestServe_ShutdownTimeout tests that a request outliving the shutdown timeout makes serve fail
+func TestServe_ShutdownTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- serve(ctx, server, listener, 50*time.Millisecond, func() error { return nil })
+	}()
+
+	go func() {
+		res, err := http.Get("http://" + listener.Addr().String())
+		if err == nil {
+			res.Body.Close()
+		}
+	}()
+	<-started
+	cancel()
+
+	assert.ErrorIs(t, <-result, context.DeadlineExceeded)
+}
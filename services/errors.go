@@ -0,0 +1,16 @@
+package services
+
+import (
+	"errors"
+	"github.com/laertkokona/crud-test/errs"
+	"gorm.io/gorm"
+)
+
+// notFoundError returns an error of a repository as not found when the entity does not exist, any other error, like a
+// lost connection, is returned as it is
+func notFoundError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errs.NotFound(err)
+	}
+	return err
+}
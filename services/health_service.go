@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/models"
+	"time"
+)
+
+// HealthCheck is a dependency the service needs to take traffic and the check whether it is up
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthService interface
+type HealthService interface {
+	Readiness(ctx context.Context) (models.Readiness, error)
+	BuildInfo() models.BuildInfo
+}
+
+// healthService struct
+type healthService struct {
+	build   models.BuildInfo
+	checks  []HealthCheck
+	timeout time.Duration
+}
+
+// NewHealthService returns a new instance of HealthService that gives each check the timeout to answer
+func NewHealthService(build models.BuildInfo, timeout time.Duration, checks ...HealthCheck) HealthService {
+	return healthService{
+		build:   build,
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Readiness method that runs every check and reports the state of each dependency,
+// the service is only ready when all of them are up
+func (h healthService) Readiness(ctx context.Context) (models.Readiness, error) {
+	readiness := models.Readiness{Status: models.DependencyUp, Dependencies: make([]models.DependencyStatus, 0, len(h.checks))}
+	var failures []error
+	for _, check := range h.checks {
+		dependency := models.DependencyStatus{Name: check.Name, Status: models.DependencyUp}
+		checkCtx, cancel := context.WithTimeout(ctx, h.timeout)
+		if err := check.Check(checkCtx); err != nil {
+			dependency.Status = models.DependencyDown
+			dependency.Error = err.Error()
+			readiness.Status = models.DependencyDown
+			failures = append(failures, fmt.Errorf("%s: %w", check.Name, err))
+		}
+		cancel()
+		readiness.Dependencies = append(readiness.Dependencies, dependency)
+	}
+	if len(failures) > 0 {
+		return readiness, errs.Unavailable(errors.Join(failures...))
+	}
+	return readiness, nil
+}
+
+// BuildInfo method that returns the build information of the binary
+func (h healthService) BuildInfo() models.BuildInfo {
+	return h.build
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestReadiness tests that the service is ready when every check passes
+func TestReadiness(t *testing.T) {
+	healthService := NewHealthService(models.BuildInfo{}, time.Second,
+		HealthCheck{Name: "database", Check: func(ctx context.Context) error { return nil }},
+		HealthCheck{Name: "migrations", Check: func(ctx context.Context) error { return nil }},
+	)
+
+	readiness, err := healthService.Readiness(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.Readiness{
+		Status: models.DependencyUp,
+		Dependencies: []models.DependencyStatus{
+			{Name: "database", Status: models.DependencyUp},
+			{Name: "migrations", Status: models.DependencyUp},
+		},
+	}, readiness)
+}
+
+// TestReadiness_DependencyDown tests that one failing check makes the service unavailable and reports why
+func TestReadiness_DependencyDown(t *testing.T) {
+	healthService := NewHealthService(models.BuildInfo{}, time.Second,
+		HealthCheck{Name: "database", Check: func(ctx context.Context) error { return nil }},
+		HealthCheck{Name: "migrations", Check: func(ctx context.Context) error { return errors.New("1 migrations are pending") }},
+	)
+
+	readiness, err := healthService.Readiness(context.Background())
+
+	assert.EqualError(t, err, "migrations: 1 migrations are pending")
+	assert.Equal(t, errs.KindUnavailable, errs.KindOf(err))
+	assert.Equal(t, models.DependencyDown, readiness.Status)
+	assert.Equal(t, models.DependencyUp, readiness.Dependencies[0].Status)
+	assert.Equal(t, models.DependencyStatus{Name: "migrations", Status: models.DependencyDown, Error: "1 migrations are pending"}, readiness.Dependencies[1])
+}
+
+// TestReadiness_Timeout tests that a check that does not answer in time is reported as down
+func TestReadiness_Timeout(t *testing.T) {
+	healthService := NewHealthService(models.BuildInfo{}, 10*time.Millisecond,
+		HealthCheck{Name: "database", Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+
+	readiness, err := healthService.Readiness(context.Background())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, errs.KindUnavailable, errs.KindOf(err))
+	assert.Equal(t, models.DependencyDown, readiness.Dependencies[0].Status)
+}
+
+// TestBuildInfo tests that the build information is returned as given
+func TestBuildInfo(t *testing.T) {
+	build := models.BuildInfo{Commit: "abc1234", BuildTime: "2024-01-01T00:00:00Z", GoVersion: "go1.20"}
+	healthService := NewHealthService(build, time.Second)
+
+	assert.Equal(t, build, healthService.BuildInfo())
+}
@@ -1,98 +1,244 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/laertkokona/crud-test/repositories"
-	"github.com/laertkokona/crud-test/utils"
 	"github.com/peteprogrammer/go-automapper"
-	"net/http"
+	"strings"
 )
 
 // ItemService interface with gin services
 type ItemService interface {
-	CreateItem(item models.Item) (models.ItemDTO, int, error)
-	GetItem(id int) (models.ItemDTO, int, error)
-	GetAllItems(pagination models.Pagination) ([]models.ItemDTO, int, error)
-	UpdateItem(id int, item models.Item) (models.ItemDTO, int, error)
-	DeleteItem(id int) (models.ItemDTO, int, error)
+	CreateItem(item models.Item, userID uint) (models.ItemDTO, error)
+	GetItem(id int) (models.ItemDTO, error)
+	GetAllItems(pagination models.Pagination, query models.ListQuery) (models.Page[models.ItemDTO], error)
+	SearchItems(text string, pagination models.Pagination) (models.Page[models.ItemSearchResult], error)
+	UpdateItem(id int, item models.Item, version uint) (models.ItemDTO, error)
+	DeleteItem(id int, version uint) (models.ItemDTO, error)
+	RecordMovement(id int, movement models.StockMovement, userID uint) (models.StockMovement, error)
+	GetItemMovements(id int, pagination models.Pagination) (models.Page[models.StockMovement], error)
+	ReconcileItem(id int) (models.StockReconciliation, error)
 }
 
 // itemService struct
 type itemService struct {
-	ItemRepo repositories.ItemRepo
+	ItemRepo          repositories.ItemRepo
+	StockMovementRepo repositories.StockMovementRepo
+	DefaultCurrency   string
 }
 
-// NewItemService returns a new instance of itemService
-func NewItemService(itemRepo repositories.ItemRepo) ItemService {
+// NewItemService returns a new instance of itemService, pricing the items without a currency in the default currency
+func NewItemService(itemRepo repositories.ItemRepo, stockMovementRepo repositories.StockMovementRepo, defaultCurrency string) ItemService {
 	return itemService{
-		ItemRepo: itemRepo,
+		ItemRepo:          itemRepo,
+		StockMovementRepo: stockMovementRepo,
+		DefaultCurrency:   defaultCurrency,
 	}
 }
 
-// CreateItem method that takes a models.Item object and saves it to the database
-func (p itemService) CreateItem(item models.Item) (models.ItemDTO, int, error) {
-	item, err := p.ItemRepo.Save(item)
+// priced returns the price in the default currency when it was given without one, a zero price is left without one
+func (p itemService) priced(price models.Money) models.Money {
+	if price.Currency == "" && !price.IsZero() {
+		price.Currency = p.DefaultCurrency
+	}
+	return price
+}
+
+// validateMovement checks that a movement recorded by hand has a manual type, a reason code allowed for that type and a sensible quantity
+func validateMovement(movement models.StockMovement) error {
+	reasons, ok := models.StockReasonCodes[movement.Type]
+	if !ok {
+		return fmt.Errorf("movements of type %q cannot be recorded by hand", movement.Type)
+	}
+	if !containsString(reasons, movement.ReasonCode) {
+		return fmt.Errorf("reason code %q is not allowed for %s, use one of %v", movement.ReasonCode, movement.Type, reasons)
+	}
+	if movement.Type == models.StockMovementAdjustment {
+		if movement.Quantity == 0 {
+			return errors.New("adjustment quantity must not be zero")
+		}
+	} else if movement.Quantity <= 0 {
+		return fmt.Errorf("%s quantity must be positive", movement.Type)
+	}
+	return nil
+}
+
+// containsString checks if a string is in a slice of strings
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateItem method that takes a models.Item object and saves it to the database, its total quantity becomes the opening balance of its ledger
+func (p itemService) CreateItem(item models.Item, userID uint) (models.ItemDTO, error) {
+	if item.TotalQuantity < 0 {
+		return models.ItemDTO{}, errs.Validation(errors.New("total quantity must not be negative"))
+	}
+	item.Price = p.priced(item.Price)
+	item, err := p.ItemRepo.Save(item, userID)
 	if err != nil {
-		return models.ItemDTO{}, http.StatusInternalServerError, err
+		return models.ItemDTO{}, err
 	}
 	var itemDTO models.ItemDTO
 	automapper.Map(item, &itemDTO)
-	return itemDTO, http.StatusOK, nil
+	return itemDTO, nil
 }
 
 // GetItem method that takes an item id and returns the item object
-func (p itemService) GetItem(id int) (models.ItemDTO, int, error) {
+func (p itemService) GetItem(id int) (models.ItemDTO, error) {
 	item, err := p.ItemRepo.FindByID(id)
 	if err != nil {
-		return models.ItemDTO{}, http.StatusNotFound, err
+		return models.ItemDTO{}, notFoundError(err)
 	}
 	var itemDTO models.ItemDTO
 	automapper.Map(item, &itemDTO)
-	return itemDTO, http.StatusOK, nil
+	return itemDTO, nil
 }
 
-// GetAllItems method that returns all items
-func (p itemService) GetAllItems(pagination models.Pagination) ([]models.ItemDTO, int, error) {
-	items, err := p.ItemRepo.FindAll(pagination)
+// GetAllItems method that returns a page of the items matching the filters and sorts of the query
+func (p itemService) GetAllItems(pagination models.Pagination, query models.ListQuery) (models.Page[models.ItemDTO], error) {
+	if err := query.Validate(pagination); err != nil {
+		return models.Page[models.ItemDTO]{}, errs.Validation(err)
+	}
+	items, total, err := p.ItemRepo.FindAll(pagination, query)
 	if err != nil {
-		return []models.ItemDTO{}, http.StatusInternalServerError, err
+		return models.Page[models.ItemDTO]{}, err
 	}
 	var itemsDTO []models.ItemDTO
 	automapper.Map(items, &itemsDTO)
-	return itemsDTO, http.StatusOK, nil
+	page := models.NewPage(itemsDTO, total, pagination)
+	if n := len(page.Data); n > 0 && !query.Sorted() {
+		page = page.WithNextCursor(items[n-1].Model)
+	}
+	return page, nil
+}
+
+// SearchItems method that takes a search text and returns a page of the matching items, best match first
+func (p itemService) SearchItems(text string, pagination models.Pagination) (models.Page[models.ItemSearchResult], error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return models.Page[models.ItemSearchResult]{}, errs.Validation(errors.New("search text must not be empty"))
+	}
+	results, total, err := p.ItemRepo.Search(text, pagination)
+	if err != nil {
+		return models.Page[models.ItemSearchResult]{}, err
+	}
+	return models.NewPage(results, total, pagination), nil
 }
 
-// UpdateItem method that takes an item id and an item object and updates the item
-func (p itemService) UpdateItem(id int, item models.Item) (models.ItemDTO, int, error) {
+// UpdateItem method that takes an item id, an item object and the version it was made to and replaces the item with it
+func (p itemService) UpdateItem(id int, item models.Item, version uint) (models.ItemDTO, error) {
 
 	itemDb, err := p.ItemRepo.FindByID(id)
 	if err != nil {
-		return models.ItemDTO{}, http.StatusNotFound, err
+		return models.ItemDTO{}, notFoundError(err)
 	}
-	//err = json.Unmarshal([]byte(itemString), &itemDb)
-	// set all the fields of the item that are not empty to the itemDb
-	utils.CopyNonEmptyFields(&itemDb, &item)
+	if err := checkVersion(itemDb.Version, version); err != nil {
+		return models.ItemDTO{}, err
+	}
+	// quantities only change through stock movements
+	// every other field is replaced, the empty ones included
+	item.Model = itemDb.Model
+	item.TotalQuantity = itemDb.TotalQuantity
+	item.AvailableQuantity = itemDb.AvailableQuantity
+	item.Version = itemDb.Version
+	item.Price = p.priced(item.Price)
 
-	itemDb, err = p.ItemRepo.Update(itemDb)
+	itemDb, err = p.ItemRepo.Update(item)
 	if err != nil {
-		return models.ItemDTO{}, http.StatusInternalServerError, err
+		return models.ItemDTO{}, versionError(err)
 	}
 	var itemDTO models.ItemDTO
 	automapper.Map(itemDb, &itemDTO)
-	return itemDTO, http.StatusOK, nil
+	return itemDTO, nil
 }
 
-// DeleteItem method that takes an item id and deletes the item
-func (p itemService) DeleteItem(id int) (models.ItemDTO, int, error) {
+// DeleteItem method that takes an item id and the version it was read at and deletes the item
+func (p itemService) DeleteItem(id int, version uint) (models.ItemDTO, error) {
 	item, err := p.ItemRepo.FindByID(id)
 	if err != nil {
-		return models.ItemDTO{}, http.StatusNotFound, err
+		return models.ItemDTO{}, notFoundError(err)
+	}
+	if err := checkVersion(item.Version, version); err != nil {
+		return models.ItemDTO{}, err
 	}
 	err = p.ItemRepo.Delete(item)
 	if err != nil {
-		return models.ItemDTO{}, http.StatusInternalServerError, err
+		return models.ItemDTO{}, versionError(err)
 	}
 	var itemDTO models.ItemDTO
 	automapper.Map(item, &itemDTO)
-	return itemDTO, http.StatusOK, nil
+	return itemDTO, nil
+}
+
+// RecordMovement method that takes an item id and a stock movement and applies it to the item through its ledger
+func (p itemService) RecordMovement(id int, movement models.StockMovement, userID uint) (models.StockMovement, error) {
+	// check the movement
+	// check that the item exists
+	// record the movement, made by the given user
+	if err := validateMovement(movement); err != nil {
+		return models.StockMovement{}, errs.Validation(err)
+	}
+	if _, err := p.ItemRepo.FindByID(id); err != nil {
+		return models.StockMovement{}, notFoundError(err)
+	}
+	movement.ID = 0
+	movement.ItemID = uint(id)
+	movement.OrderID = nil
+	movement.UserID = userID
+	movement, err := p.StockMovementRepo.Record(movement)
+	if err != nil {
+		var shortage *models.InsufficientStockError
+		if errors.As(err, &shortage) {
+			return movement, errs.Conflict(err)
+		}
+		return movement, err
+	}
+	return movement, nil
+}
+
+// GetItemMovements method that takes an item id and returns a page of its stock movements
+func (p itemService) GetItemMovements(id int, pagination models.Pagination) (models.Page[models.StockMovement], error) {
+	if err := pagination.Validate(); err != nil {
+		return models.Page[models.StockMovement]{}, errs.Validation(err)
+	}
+	if _, err := p.ItemRepo.FindByID(id); err != nil {
+		return models.Page[models.StockMovement]{}, notFoundError(err)
+	}
+	movements, total, err := p.StockMovementRepo.FindByItem(id, pagination)
+	if err != nil {
+		return models.Page[models.StockMovement]{}, err
+	}
+	page := models.NewPage(movements, total, pagination)
+	if n := len(page.Data); n > 0 {
+		page = page.WithNextCursor(movements[n-1].Model)
+	}
+	return page, nil
+}
+
+// ReconcileItem method that takes an item id and compares its stored quantities with the ones derived from its ledger
+func (p itemService) ReconcileItem(id int) (models.StockReconciliation, error) {
+	item, err := p.ItemRepo.FindByID(id)
+	if err != nil {
+		return models.StockReconciliation{}, notFoundError(err)
+	}
+	total, available, err := p.StockMovementRepo.Balance(id)
+	if err != nil {
+		return models.StockReconciliation{}, err
+	}
+	return models.StockReconciliation{
+		ItemID:            item.ID,
+		TotalQuantity:     item.TotalQuantity,
+		AvailableQuantity: item.AvailableQuantity,
+		LedgerTotal:       total,
+		LedgerAvailable:   available,
+		Balanced:          item.TotalQuantity == total && item.AvailableQuantity == available,
+	}, nil
 }
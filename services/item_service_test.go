@@ -2,11 +2,12 @@ package services
 
 import (
 	"errors"
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/repositories"
 	"github.com/peteprogrammer/go-automapper"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
-	"net/http"
 	"testing"
 )
 
@@ -35,7 +36,7 @@ var mockItems = []models.Item{
 		Code:              "itm1",
 		TotalQuantity:     100,
 		AvailableQuantity: 100,
-		Price:             9.99,
+		Price:             models.MustParseMoney("9.99 EUR"),
 		Category:          "Category Test",
 	},
 	{
@@ -45,7 +46,7 @@ var mockItems = []models.Item{
 		Code:              "itm2",
 		TotalQuantity:     200,
 		AvailableQuantity: 200,
-		Price:             19.99,
+		Price:             models.MustParseMoney("19.99 EUR"),
 		Category:          "Category Test",
 	},
 	{
@@ -55,7 +56,7 @@ var mockItems = []models.Item{
 		Code:              "itm3",
 		TotalQuantity:     300,
 		AvailableQuantity: 300,
-		Price:             29.99,
+		Price:             models.MustParseMoney("29.99 EUR"),
 		Category:          "Category Test",
 	},
 	{
@@ -65,7 +66,7 @@ var mockItems = []models.Item{
 		Code:              "itm4",
 		TotalQuantity:     400,
 		AvailableQuantity: 400,
-		Price:             39.99,
+		Price:             models.MustParseMoney("39.99 EUR"),
 		Category:          "Category Test",
 	},
 	{
@@ -75,7 +76,7 @@ var mockItems = []models.Item{
 		Code:              "itm5",
 		TotalQuantity:     500,
 		AvailableQuantity: 500,
-		Price:             49.99,
+		Price:             models.MustParseMoney("49.99 EUR"),
 		Category:          "Category Test",
 	},
 }
@@ -83,13 +84,17 @@ var mockItems = []models.Item{
 // mockItemRepo is a mock implementation of the repositories.ItemRepo interface
 type mockItemRepo struct {
 	// findAll is a mock function with given fields: pagination
-	findAll func(pagination models.Pagination) ([]models.Item, error)
+	findAll func(pagination models.Pagination, query models.ListQuery) ([]models.Item, int64, error)
 	// findByID is a mock function with given fields: id
 	findByID func(id int) (models.Item, error)
 	// findByName is a mock function with given fields: name
 	findByName func(name string) (models.Item, error)
+	// search is a mock function with given fields: text, pagination
+	search func(text string, pagination models.Pagination) ([]models.ItemSearchResult, int64, error)
+	// findByIDs is a mock function with given fields: ids
+	findByIDs func(ids []int) ([]models.Item, error)
 	// save is a mock function with given fields: item
-	save func(item models.Item) (models.Item, error)
+	save func(item models.Item, userID uint) (models.Item, error)
 	// update is a mock function with given fields: item
 	update func(item models.Item) (models.Item, error)
 	// delete is a mock function with given fields: item
@@ -99,8 +104,8 @@ type mockItemRepo struct {
 }
 
 // FindAll is a mock function with given fields: pagination
-func (_m *mockItemRepo) FindAll(pagination models.Pagination) ([]models.Item, error) {
-	return _m.findAll(pagination)
+func (_m *mockItemRepo) FindAll(pagination models.Pagination, query models.ListQuery) ([]models.Item, int64, error) {
+	return _m.findAll(pagination, query)
 }
 
 // FindByID is a mock function with given fields: id
@@ -113,9 +118,19 @@ func (_m *mockItemRepo) FindByName(name string) (models.Item, error) {
 	return _m.findByName(name)
 }
 
+// Search is a mock function with given fields: text, pagination
+func (_m *mockItemRepo) Search(text string, pagination models.Pagination) ([]models.ItemSearchResult, int64, error) {
+	return _m.search(text, pagination)
+}
+
+// FindByIDs is a mock function with given fields: ids
+func (_m *mockItemRepo) FindByIDs(ids []int) ([]models.Item, error) {
+	return _m.findByIDs(ids)
+}
+
 // Save is a mock function with given fields: item
-func (_m *mockItemRepo) Save(item models.Item) (models.Item, error) {
-	return _m.save(item)
+func (_m *mockItemRepo) Save(item models.Item, userID uint) (models.Item, error) {
+	return _m.save(item, userID)
 }
 
 // Update is a mock function with given fields: item
@@ -136,8 +151,8 @@ func (_m *mockItemRepo) DeleteById(id int) (models.Item, error) {
 // newMockItemRepo returns a new instance of the mockItemRepo
 func newMockItemRepo() *mockItemRepo {
 	return &mockItemRepo{
-		findAll: func(pagination models.Pagination) ([]models.Item, error) {
-			return mockItems, nil
+		findAll: func(pagination models.Pagination, query models.ListQuery) ([]models.Item, int64, error) {
+			return mockItems, int64(len(mockItems)), nil
 		},
 		findByID: func(id int) (models.Item, error) {
 			return mockItems[id-1], nil
@@ -151,7 +166,25 @@ func newMockItemRepo() *mockItemRepo {
 			}
 			return itm, nil
 		},
-		save: func(item models.Item) (models.Item, error) {
+		search: func(text string, pagination models.Pagination) ([]models.ItemSearchResult, int64, error) {
+			var results []models.ItemSearchResult
+			for _, item := range mockItems {
+				var result models.ItemSearchResult
+				automapper.Map(item, &result.ItemDTO)
+				results = append(results, result)
+			}
+			return results, int64(len(results)), nil
+		},
+		findByIDs: func(ids []int) ([]models.Item, error) {
+			var items []models.Item
+			for _, id := range ids {
+				if id > 0 && id <= len(mockItems) {
+					items = append(items, mockItems[id-1])
+				}
+			}
+			return items, nil
+		},
+		save: func(item models.Item, userID uint) (models.Item, error) {
 			return item, nil
 		},
 		update: func(item models.Item) (models.Item, error) {
@@ -172,16 +205,22 @@ func newMockItemRepo() *mockItemRepo {
 // newMockItemErrorRepo returns a new instance of the mockItemErrorRepo
 func newMockItemErrorRepo() *mockItemRepo {
 	return &mockItemRepo{
-		findAll: func(pagination models.Pagination) ([]models.Item, error) {
-			return []models.Item{}, errors.New("error")
+		findAll: func(pagination models.Pagination, query models.ListQuery) ([]models.Item, int64, error) {
+			return []models.Item{}, 0, errors.New("error")
 		},
 		findByID: func(id int) (models.Item, error) {
-			return models.Item{}, errors.New("error")
+			return models.Item{}, gorm.ErrRecordNotFound
 		},
 		findByName: func(name string) (models.Item, error) {
 			return models.Item{}, errors.New("error")
 		},
-		save: func(item models.Item) (models.Item, error) {
+		search: func(text string, pagination models.Pagination) ([]models.ItemSearchResult, int64, error) {
+			return nil, 0, errors.New("error")
+		},
+		findByIDs: func(ids []int) ([]models.Item, error) {
+			return []models.Item{}, errors.New("error")
+		},
+		save: func(item models.Item, userID uint) (models.Item, error) {
 			return models.Item{}, errors.New("error")
 		},
 		update: func(item models.Item) (models.Item, error) {
@@ -199,8 +238,8 @@ func newMockItemErrorRepo() *mockItemRepo {
 // newMockItemSpecificErrorRepo returns a new instance of the mockItemSpecificErrorRepo
 func newMockItemSpecificErrorRepo() *mockItemRepo {
 	return &mockItemRepo{
-		findAll: func(pagination models.Pagination) ([]models.Item, error) {
-			return mockItems, nil
+		findAll: func(pagination models.Pagination, query models.ListQuery) ([]models.Item, int64, error) {
+			return mockItems, int64(len(mockItems)), nil
 		},
 		findByID: func(id int) (models.Item, error) {
 			return mockItems[id-1], nil
@@ -214,7 +253,25 @@ func newMockItemSpecificErrorRepo() *mockItemRepo {
 			}
 			return itm, nil
 		},
-		save: func(item models.Item) (models.Item, error) {
+		search: func(text string, pagination models.Pagination) ([]models.ItemSearchResult, int64, error) {
+			var results []models.ItemSearchResult
+			for _, item := range mockItems {
+				var result models.ItemSearchResult
+				automapper.Map(item, &result.ItemDTO)
+				results = append(results, result)
+			}
+			return results, int64(len(results)), nil
+		},
+		findByIDs: func(ids []int) ([]models.Item, error) {
+			var items []models.Item
+			for _, id := range ids {
+				if id > 0 && id <= len(mockItems) {
+					items = append(items, mockItems[id-1])
+				}
+			}
+			return items, nil
+		},
+		save: func(item models.Item, userID uint) (models.Item, error) {
 			return models.Item{}, errors.New("error")
 		},
 		update: func(item models.Item) (models.Item, error) {
@@ -229,10 +286,81 @@ func newMockItemSpecificErrorRepo() *mockItemRepo {
 	}
 }
 
+var mockMovements = []models.StockMovement{
+	{
+		Model:          itmModels[0],
+		ItemID:         1,
+		Type:           models.StockMovementReceipt,
+		Quantity:       100,
+		ReasonCode:     models.ReasonOpeningBalance,
+		UserID:         1,
+		TotalDelta:     100,
+		AvailableDelta: 100,
+		TotalAfter:     100,
+		AvailableAfter: 100,
+	},
+}
+
+// mockStockMovementRepo is a mock implementation of the repositories.StockMovementRepo interface
+type mockStockMovementRepo struct {
+	// findByItem is a mock function with given fields: itemID, pagination
+	findByItem func(itemID int, pagination models.Pagination) ([]models.StockMovement, int64, error)
+	// record is a mock function with given fields: movement
+	record func(movement models.StockMovement) (models.StockMovement, error)
+	// balance is a mock function with given fields: itemID
+	balance func(itemID int) (int, int, error)
+}
+
+// FindByItem is a mock function with given fields: itemID, pagination
+func (_m *mockStockMovementRepo) FindByItem(itemID int, pagination models.Pagination) ([]models.StockMovement, int64, error) {
+	return _m.findByItem(itemID, pagination)
+}
+
+// Record is a mock function with given fields: movement
+func (_m *mockStockMovementRepo) Record(movement models.StockMovement) (models.StockMovement, error) {
+	return _m.record(movement)
+}
+
+// Balance is a mock function with given fields: itemID
+func (_m *mockStockMovementRepo) Balance(itemID int) (int, int, error) {
+	return _m.balance(itemID)
+}
+
+// newMockStockMovementRepo returns a new instance of the mockStockMovementRepo
+func newMockStockMovementRepo() *mockStockMovementRepo {
+	return &mockStockMovementRepo{
+		findByItem: func(itemID int, pagination models.Pagination) ([]models.StockMovement, int64, error) {
+			return mockMovements, int64(len(mockMovements)), nil
+		},
+		record: func(movement models.StockMovement) (models.StockMovement, error) {
+			movement.TotalDelta, movement.AvailableDelta = movement.Type.Deltas(movement.Quantity)
+			return movement, nil
+		},
+		balance: func(itemID int) (int, int, error) {
+			return 100, 100, nil
+		},
+	}
+}
+
+// newMockStockMovementErrorRepo returns a new instance of the mockStockMovementRepo with errors
+func newMockStockMovementErrorRepo() *mockStockMovementRepo {
+	return &mockStockMovementRepo{
+		findByItem: func(itemID int, pagination models.Pagination) ([]models.StockMovement, int64, error) {
+			return nil, 0, errors.New("error")
+		},
+		record: func(movement models.StockMovement) (models.StockMovement, error) {
+			return movement, errors.New("error")
+		},
+		balance: func(itemID int) (int, int, error) {
+			return 0, 0, errors.New("error")
+		},
+	}
+}
+
 // TestNewItemService is a test function for the NewItemService function
 func TestNewItemService(t *testing.T) {
 	mockRepo := newMockItemRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 	assert.NotNil(t, mockService)
 	assert.IsType(t, itemService{}, mockService)
 }
@@ -240,7 +368,7 @@ func TestNewItemService(t *testing.T) {
 // TestCreateItem tests services.CreateItem function using a mock repository mockItemRepo and gin
 func TestCreateItem(t *testing.T) {
 	mockRepo := newMockItemRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
 	mockItem := models.Item{
 		Name:              "Item 6",
@@ -248,22 +376,37 @@ func TestCreateItem(t *testing.T) {
 		Code:              "itm6",
 		TotalQuantity:     600,
 		AvailableQuantity: 600,
-		Price:             59.99,
+		Price:             models.MustParseMoney("59.99 EUR"),
 		Category:          "Category Test",
 	}
 
-	itemDTO, status, err := mockService.CreateItem(mockItem)
+	itemDTO, err := mockService.CreateItem(mockItem, 1)
 	var mockItemDTO models.ItemDTO
 	automapper.Map(mockItem, &mockItemDTO)
 	assert.NoError(t, err, "Error while creating item: %v", err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, mockItemDTO, itemDTO)
 }
 
+// TestCreateItem_DefaultCurrency tests that an item priced without a currency is priced in the default currency and a
+// free one is left without one
+func TestCreateItem_DefaultCurrency(t *testing.T) {
+	mockService := NewItemService(newMockItemRepo(), newMockStockMovementRepo(), "USD")
+
+	price := models.MustParseMoney("59.99 EUR")
+	price.Currency = ""
+	itemDTO, err := mockService.CreateItem(models.Item{Code: "itm6", Price: price}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MustParseMoney("59.99 USD"), itemDTO.Price)
+
+	itemDTO, err = mockService.CreateItem(models.Item{Code: "itm7"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "", itemDTO.Price.Currency)
+}
+
 // TestCreateItem_SaveError tests services.CreateItem function using a mock repository mockItemErrorRepo and gin
 func TestCreateItem_SaveError(t *testing.T) {
 	mockRepo := newMockItemErrorRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
 	mockItem := models.Item{
 		Name:              "Item 6",
@@ -271,68 +414,163 @@ func TestCreateItem_SaveError(t *testing.T) {
 		Code:              "itm6",
 		TotalQuantity:     600,
 		AvailableQuantity: 600,
-		Price:             59.99,
+		Price:             models.MustParseMoney("59.99 EUR"),
 		Category:          "Category Test",
 	}
 
-	itemDTO, status, err := mockService.CreateItem(mockItem)
+	itemDTO, err := mockService.CreateItem(mockItem, 1)
 	assert.Error(t, err, "Error while creating item: %v", err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.ItemDTO{}, itemDTO)
 }
 
 // TestGetItem tests services.GetItem function using a mock repository mockItemRepo and gin
 func TestGetItem(t *testing.T) {
 	mockRepo := newMockItemRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
-	itemDTO, status, err := mockService.GetItem(1)
+	itemDTO, err := mockService.GetItem(1)
 	var mockItemDTO models.ItemDTO
 	automapper.Map(mockItems[0], &mockItemDTO)
 	assert.NoError(t, err, "Error while getting item: %v", err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, mockItemDTO, itemDTO)
 }
 
 // TestGetItem_FindByIDError tests services.GetItem function using a mock repository mockItemErrorRepo and gin
 func TestGetItem_FindByIDError(t *testing.T) {
 	mockRepo := newMockItemErrorRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
-	itemDTO, status, err := mockService.GetItem(1)
+	itemDTO, err := mockService.GetItem(1)
 	assert.Error(t, err, "Error while getting item: %v", err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Equal(t, models.ItemDTO{}, itemDTO)
 }
 
+// TestGetItem_FindByIDFailure tests that an error of the repository other than a missing item is not a not found error
+func TestGetItem_FindByIDFailure(t *testing.T) {
+	mockRepo := newMockItemRepo()
+	mockRepo.findByID = func(id int) (models.Item, error) {
+		return models.Item{}, errors.New("connection lost")
+	}
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	_, err := mockService.GetItem(1)
+	assert.EqualError(t, err, "connection lost")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+}
+
 // TestGetAllItems tests services.GetAllItems function using a mock repository mockItemRepo and gin
 func TestGetAllItems(t *testing.T) {
 	mockRepo := newMockItemRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
-	itemsDTO, status, err := mockService.GetAllItems(models.Pagination{})
+	itemsDTO, err := mockService.GetAllItems(models.Pagination{}, models.ListQuery{})
 	var mockItemsDTO []models.ItemDTO
 	automapper.Map(mockItems, &mockItemsDTO)
 	assert.NoError(t, err, "Error while getting all items: %v", err)
-	assert.Equal(t, http.StatusOK, status)
-	assert.Equal(t, mockItemsDTO, itemsDTO)
+	assert.Equal(t, mockItemsDTO, itemsDTO.Data)
+	assert.Equal(t, int64(len(mockItems)), *itemsDTO.Total)
+	assert.Equal(t, models.DefaultPageLimit, itemsDTO.Limit)
+}
+
+// TestGetAllItems_Page tests that services.GetAllItems counts the pages of the total and caps the limit
+func TestGetAllItems_Page(t *testing.T) {
+	mockRepo := newMockItemRepo()
+	mockRepo.findAll = func(pagination models.Pagination, query models.ListQuery) ([]models.Item, int64, error) {
+		return mockItems[:2], 250, nil
+	}
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	itemsDTO, err := mockService.GetAllItems(models.Pagination{Page: 2, Limit: 500}, models.ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, itemsDTO.Data, 2)
+	assert.Equal(t, int64(250), *itemsDTO.Total)
+	assert.Equal(t, 2, itemsDTO.Page)
+	assert.Equal(t, models.MaxPageLimit, itemsDTO.Limit)
+	assert.Equal(t, 3, *itemsDTO.TotalPages)
+}
+
+// TestGetAllItems_Sorted tests that services.GetAllItems passes the query and returns no cursor for a sorted list
+func TestGetAllItems_Sorted(t *testing.T) {
+	var gotQuery models.ListQuery
+	mockRepo := newMockItemRepo()
+	mockRepo.findAll = func(pagination models.Pagination, query models.ListQuery) ([]models.Item, int64, error) {
+		gotQuery = query
+		return mockItems[:2], 10, nil
+	}
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	query := models.ListQuery{Sorts: []models.Sort{{Column: "price", Desc: true}}}
+	itemsDTO, err := mockService.GetAllItems(models.Pagination{Page: 1, Limit: 2}, query)
+	assert.NoError(t, err)
+	assert.Equal(t, query, gotQuery)
+	assert.Empty(t, itemsDTO.NextCursor)
+}
+
+// TestGetAllItems_SortWithCursor tests that services.GetAllItems rejects a sorted list after a cursor
+func TestGetAllItems_SortWithCursor(t *testing.T) {
+	mockService := NewItemService(newMockItemRepo(), newMockStockMovementRepo(), "EUR")
+
+	query := models.ListQuery{Sorts: []models.Sort{{Column: "price"}}}
+	_, err := mockService.GetAllItems(models.Pagination{After: models.Cursor{ID: 1}.Encode()}, query)
+	assert.ErrorIs(t, err, models.ErrSortWithCursor)
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
+}
+
+// TestSearchItems tests services.SearchItems function using a mock repository mockItemRepo
+func TestSearchItems(t *testing.T) {
+	var gotText string
+	mockRepo := newMockItemRepo()
+	search := mockRepo.search
+	mockRepo.search = func(text string, pagination models.Pagination) ([]models.ItemSearchResult, int64, error) {
+		gotText = text
+		return search(text, pagination)
+	}
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	results, err := mockService.SearchItems("  hamm ", models.Pagination{})
+	assert.NoError(t, err)
+	assert.Equal(t, "hamm", gotText)
+	assert.Len(t, results.Data, len(mockItems))
+	assert.Equal(t, mockItems[0].Name, results.Data[0].Name)
+	assert.Equal(t, int64(len(mockItems)), *results.Total)
+}
+
+// TestSearchItems_EmptyText tests that services.SearchItems rejects an empty search text
+func TestSearchItems_EmptyText(t *testing.T) {
+	mockService := NewItemService(newMockItemRepo(), newMockStockMovementRepo(), "EUR")
+
+	_, err := mockService.SearchItems(" ", models.Pagination{})
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
+}
+
+// TestSearchItems_SearchError tests services.SearchItems function using a mock repository mockItemErrorRepo
+func TestSearchItems_SearchError(t *testing.T) {
+	mockService := NewItemService(newMockItemErrorRepo(), newMockStockMovementRepo(), "EUR")
+
+	results, err := mockService.SearchItems("hammer", models.Pagination{})
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+	assert.Equal(t, models.Page[models.ItemSearchResult]{}, results)
 }
 
 // TestGetAllItems_FindAllError tests services.GetAllItems function using a mock repository mockItemErrorRepo and gin
 func TestGetAllItems_FindAllError(t *testing.T) {
 	mockRepo := newMockItemErrorRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
-	itemsDTO, status, err := mockService.GetAllItems(models.Pagination{})
+	itemsDTO, err := mockService.GetAllItems(models.Pagination{}, models.ListQuery{})
 	assert.Error(t, err, "Error while getting all items: %v", err)
-	assert.Equal(t, http.StatusInternalServerError, status)
-	assert.Equal(t, []models.ItemDTO{}, itemsDTO)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+	assert.Equal(t, models.Page[models.ItemDTO]{}, itemsDTO)
 }
 
 // TestUpdateItem tests services.UpdateItem function using a mock repository mockItemRepo and gin
 func TestUpdateItem(t *testing.T) {
 	mockRepo := newMockItemRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
 	mockItem := models.Item{
 		Name:              "Item 6",
@@ -340,22 +578,37 @@ func TestUpdateItem(t *testing.T) {
 		Code:              "itm6",
 		TotalQuantity:     600,
 		AvailableQuantity: 600,
-		Price:             59.99,
+		Price:             models.MustParseMoney("59.99 EUR"),
 		Category:          "Category Test",
 	}
-	itemDTO, status, err := mockService.UpdateItem(1, mockItem)
+	itemDTO, err := mockService.UpdateItem(1, mockItem, AnyVersion)
 	mockItem.ID = 1
+	// quantities are not updated, they only change through stock movements
+	mockItem.TotalQuantity = mockItems[0].TotalQuantity
+	mockItem.AvailableQuantity = mockItems[0].AvailableQuantity
 	var mockItemDTO models.ItemDTO
 	automapper.Map(mockItem, &mockItemDTO)
 	assert.NoError(t, err, "Error while updating item: %v", err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, mockItemDTO, itemDTO)
 }
 
+// TestUpdateItem_ClearsFields tests that the fields left empty are cleared instead of keeping their stored value
+func TestUpdateItem_ClearsFields(t *testing.T) {
+	mockRepo := newMockItemRepo()
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	itemDTO, err := mockService.UpdateItem(1, models.Item{Name: "Item 1", Code: "itm1"}, AnyVersion)
+	assert.NoError(t, err, "Error while updating item: %v", err)
+	assert.True(t, itemDTO.Price.IsZero())
+	assert.Empty(t, itemDTO.Description)
+	assert.Empty(t, itemDTO.Category)
+	assert.Equal(t, mockItems[0].TotalQuantity, itemDTO.TotalQuantity)
+}
+
 // TestUpdateItem_FindByIDError tests services.UpdateItem function using a mock repository mockItemErrorRepo and gin
 func TestUpdateItem_FindByIDError(t *testing.T) {
 	mockRepo := newMockItemErrorRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
 	mockItem := models.Item{
 		Name:              "Item 6",
@@ -363,19 +616,19 @@ func TestUpdateItem_FindByIDError(t *testing.T) {
 		Code:              "itm6",
 		TotalQuantity:     600,
 		AvailableQuantity: 600,
-		Price:             59.99,
+		Price:             models.MustParseMoney("59.99 EUR"),
 		Category:          "Category Test",
 	}
-	itemDTO, status, err := mockService.UpdateItem(1, mockItem)
+	itemDTO, err := mockService.UpdateItem(1, mockItem, AnyVersion)
 	assert.Error(t, err, "Error while updating item: %v", err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Equal(t, models.ItemDTO{}, itemDTO)
 }
 
 // TestUpdateItem_UpdateError tests services.UpdateItem function using a mock repository mockItemErrorRepo and gin
 func TestUpdateItem_UpdateError(t *testing.T) {
 	mockRepo := newMockItemSpecificErrorRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
 	mockItem := models.Item{
 		Name:              "Item 6",
@@ -383,46 +636,248 @@ func TestUpdateItem_UpdateError(t *testing.T) {
 		Code:              "itm6",
 		TotalQuantity:     600,
 		AvailableQuantity: 600,
-		Price:             59.99,
+		Price:             models.MustParseMoney("59.99 EUR"),
 		Category:          "Category Test",
 	}
-	itemDTO, status, err := mockService.UpdateItem(1, mockItem)
+	itemDTO, err := mockService.UpdateItem(1, mockItem, AnyVersion)
 	assert.Error(t, err, "Error while updating item: %v", err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.ItemDTO{}, itemDTO)
 }
 
+// newMockItemRepoAtVersion returns a mockItemRepo whose items are at the version
+func newMockItemRepoAtVersion(version uint) *mockItemRepo {
+	mockRepo := newMockItemRepo()
+	mockRepo.findByID = func(id int) (models.Item, error) {
+		item := mockItems[id-1]
+		item.Version = version
+		return item, nil
+	}
+	return mockRepo
+}
+
+// TestUpdateItem_Version tests that an update made to the current version is saved guarded by that version
+func TestUpdateItem_Version(t *testing.T) {
+	var updated models.Item
+	mockRepo := newMockItemRepoAtVersion(3)
+	mockRepo.update = func(item models.Item) (models.Item, error) {
+		updated = item
+		item.Version++
+		return item, nil
+	}
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	itemDTO, err := mockService.UpdateItem(1, models.Item{Name: "Item 1", Code: "itm1"}, 3)
+	assert.NoError(t, err, "Error while updating item: %v", err)
+	assert.Equal(t, uint(3), updated.Version)
+	assert.Equal(t, uint(4), itemDTO.Version)
+}
+
+// TestUpdateItem_Stale tests that an update made to an older version is stale and not saved
+func TestUpdateItem_Stale(t *testing.T) {
+	mockRepo := newMockItemRepoAtVersion(3)
+	mockRepo.update = func(item models.Item) (models.Item, error) {
+		t.Fatal("a stale update must not be saved")
+		return item, nil
+	}
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	_, err := mockService.UpdateItem(1, models.Item{Name: "Item 1", Code: "itm1"}, 2)
+	assert.Equal(t, errs.KindStale, errs.KindOf(err))
+}
+
+// TestUpdateItem_VersionChanged tests that an item changed by someone else while it was updated is stale
+func TestUpdateItem_VersionChanged(t *testing.T) {
+	mockRepo := newMockItemRepo()
+	mockRepo.update = func(item models.Item) (models.Item, error) {
+		return item, repositories.ErrVersionChanged
+	}
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	_, err := mockService.UpdateItem(1, models.Item{Name: "Item 1", Code: "itm1"}, AnyVersion)
+	assert.Equal(t, errs.KindStale, errs.KindOf(err))
+	assert.ErrorIs(t, err, repositories.ErrVersionChanged)
+}
+
 // TestDeleteItem tests services.DeleteItem function using a mock repository mockItemRepo and gin
 func TestDeleteItem(t *testing.T) {
 	mockRepo := newMockItemRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
-	itemDTO, status, err := mockService.DeleteItem(1)
+	itemDTO, err := mockService.DeleteItem(1, AnyVersion)
 	var mockItemDTO models.ItemDTO
 	automapper.Map(mockItems[0], &mockItemDTO)
 	assert.NoError(t, err, "Error while deleting item: %v", err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, mockItemDTO, itemDTO)
 }
 
+// TestDeleteItem_Stale tests that a delete made to an older version is stale and does not delete the item
+func TestDeleteItem_Stale(t *testing.T) {
+	mockRepo := newMockItemRepoAtVersion(3)
+	mockRepo.delete = func(item models.Item) error {
+		t.Fatal("a stale delete must not delete the item")
+		return nil
+	}
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	_, err := mockService.DeleteItem(1, 2)
+	assert.Equal(t, errs.KindStale, errs.KindOf(err))
+}
+
 // TestDeleteItem_FindByIDError tests services.DeleteItem function using a mock repository mockItemErrorRepo and gin
 func TestDeleteItem_FindByIDError(t *testing.T) {
 	mockRepo := newMockItemErrorRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
-	itemDTO, status, err := mockService.DeleteItem(1)
+	itemDTO, err := mockService.DeleteItem(1, AnyVersion)
 	assert.Error(t, err, "Error while deleting item: %v", err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Equal(t, models.ItemDTO{}, itemDTO)
 }
 
 // TestDeleteItem_DeleteError tests services.DeleteItem function using a mock repository mockItemSpecificErrorRepo and gin
 func TestDeleteItem_DeleteError(t *testing.T) {
 	mockRepo := newMockItemSpecificErrorRepo()
-	mockService := NewItemService(mockRepo)
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
 
-	itemDTO, status, err := mockService.DeleteItem(1)
+	itemDTO, err := mockService.DeleteItem(1, AnyVersion)
 	assert.Error(t, err, "Error while deleting item: %v", err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.ItemDTO{}, itemDTO)
 }
+
+// TestValidateMovement tests the validateMovement function
+func TestValidateMovement(t *testing.T) {
+	assert.NoError(t, validateMovement(models.StockMovement{Type: models.StockMovementReceipt, Quantity: 10, ReasonCode: models.ReasonPurchase}))
+	assert.NoError(t, validateMovement(models.StockMovement{Type: models.StockMovementAdjustment, Quantity: -3, ReasonCode: models.ReasonCycleCount}))
+	assert.NoError(t, validateMovement(models.StockMovement{Type: models.StockMovementWriteOff, Quantity: 2, ReasonCode: models.ReasonDamaged}))
+	assert.Error(t, validateMovement(models.StockMovement{Type: models.StockMovementReservation, Quantity: 10, ReasonCode: models.ReasonOrder}))
+	assert.Error(t, validateMovement(models.StockMovement{Type: models.StockMovementReceipt, Quantity: 10, ReasonCode: models.ReasonDamaged}))
+	assert.Error(t, validateMovement(models.StockMovement{Type: models.StockMovementWriteOff, Quantity: -2, ReasonCode: models.ReasonLost}))
+	assert.Error(t, validateMovement(models.StockMovement{Type: models.StockMovementAdjustment, Quantity: 0, ReasonCode: models.ReasonCorrection}))
+}
+
+// TestCreateItem_NegativeQuantity tests services.CreateItem function with a negative opening quantity
+func TestCreateItem_NegativeQuantity(t *testing.T) {
+	mockRepo := newMockItemRepo()
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	_, err := mockService.CreateItem(models.Item{Code: "itm6", TotalQuantity: -1}, 1)
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
+}
+
+// TestCreateItem_RecordsUser tests that services.CreateItem passes the user to the repository for the opening balance
+func TestCreateItem_RecordsUser(t *testing.T) {
+	var gotUserID uint
+	mockRepo := newMockItemRepo()
+	mockRepo.save = func(item models.Item, userID uint) (models.Item, error) {
+		gotUserID = userID
+		return item, nil
+	}
+	mockService := NewItemService(mockRepo, newMockStockMovementRepo(), "EUR")
+
+	_, err := mockService.CreateItem(models.Item{Code: "itm6", TotalQuantity: 10}, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(4), gotUserID)
+}
+
+// TestRecordMovement tests services.RecordMovement function using mock repositories
+func TestRecordMovement(t *testing.T) {
+	mockService := NewItemService(newMockItemRepo(), newMockStockMovementRepo(), "EUR")
+
+	movement, err := mockService.RecordMovement(2, models.StockMovement{
+		Type:       models.StockMovementWriteOff,
+		Quantity:   5,
+		ReasonCode: models.ReasonDamaged,
+		UserID:     99,
+	}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), movement.ItemID)
+	assert.Equal(t, uint(3), movement.UserID)
+	assert.Equal(t, -5, movement.TotalDelta)
+	assert.Equal(t, -5, movement.AvailableDelta)
+}
+
+// TestRecordMovement_InvalidMovement tests services.RecordMovement function with a movement that cannot be recorded by hand
+func TestRecordMovement_InvalidMovement(t *testing.T) {
+	mockService := NewItemService(newMockItemRepo(), newMockStockMovementRepo(), "EUR")
+
+	_, err := mockService.RecordMovement(1, models.StockMovement{Type: models.StockMovementShipment, Quantity: 5}, 3)
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
+}
+
+// TestRecordMovement_FindByIDError tests services.RecordMovement function for an item that does not exist
+func TestRecordMovement_FindByIDError(t *testing.T) {
+	mockService := NewItemService(newMockItemErrorRepo(), newMockStockMovementRepo(), "EUR")
+
+	_, err := mockService.RecordMovement(1, models.StockMovement{Type: models.StockMovementReceipt, Quantity: 5, ReasonCode: models.ReasonPurchase}, 3)
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
+}
+
+// TestRecordMovement_InsufficientStock tests services.RecordMovement function writing off more than the stock
+func TestRecordMovement_InsufficientStock(t *testing.T) {
+	mockMovementRepo := newMockStockMovementRepo()
+	mockMovementRepo.record = func(movement models.StockMovement) (models.StockMovement, error) {
+		return movement, &models.InsufficientStockError{Shortages: []models.StockShortage{{ItemID: 1, Requested: 500, Available: 100}}}
+	}
+	mockService := NewItemService(newMockItemRepo(), mockMovementRepo, "EUR")
+
+	_, err := mockService.RecordMovement(1, models.StockMovement{Type: models.StockMovementWriteOff, Quantity: 500, ReasonCode: models.ReasonLost}, 3)
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindConflict, errs.KindOf(err))
+}
+
+// TestRecordMovement_RecordError tests services.RecordMovement function using mockStockMovementErrorRepo
+func TestRecordMovement_RecordError(t *testing.T) {
+	mockService := NewItemService(newMockItemRepo(), newMockStockMovementErrorRepo(), "EUR")
+
+	_, err := mockService.RecordMovement(1, models.StockMovement{Type: models.StockMovementReceipt, Quantity: 5, ReasonCode: models.ReasonPurchase}, 3)
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+}
+
+// TestGetItemMovements tests services.GetItemMovements function using mock repositories
+func TestGetItemMovements(t *testing.T) {
+	mockService := NewItemService(newMockItemRepo(), newMockStockMovementRepo(), "EUR")
+
+	movements, err := mockService.GetItemMovements(1, models.Pagination{Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, mockMovements, movements.Data)
+	assert.Equal(t, 10, movements.Limit)
+}
+
+// TestGetItemMovements_FindByItemError tests services.GetItemMovements function using mockStockMovementErrorRepo
+func TestGetItemMovements_FindByItemError(t *testing.T) {
+	mockService := NewItemService(newMockItemRepo(), newMockStockMovementErrorRepo(), "EUR")
+
+	_, err := mockService.GetItemMovements(1, models.Pagination{})
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+}
+
+// TestReconcileItem tests services.ReconcileItem function using mock repositories
+func TestReconcileItem(t *testing.T) {
+	mockService := NewItemService(newMockItemRepo(), newMockStockMovementRepo(), "EUR")
+
+	reconciliation, err := mockService.ReconcileItem(1)
+	assert.NoError(t, err)
+	assert.True(t, reconciliation.Balanced)
+
+	reconciliation, err = mockService.ReconcileItem(2)
+	assert.NoError(t, err)
+	assert.False(t, reconciliation.Balanced)
+	assert.Equal(t, 200, reconciliation.TotalQuantity)
+	assert.Equal(t, 100, reconciliation.LedgerTotal)
+}
+
+// TestReconcileItem_BalanceError tests services.ReconcileItem function using mockStockMovementErrorRepo
+func TestReconcileItem_BalanceError(t *testing.T) {
+	mockService := NewItemService(newMockItemRepo(), newMockStockMovementErrorRepo(), "EUR")
+
+	_, err := mockService.ReconcileItem(1)
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+}
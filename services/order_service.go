@@ -1,95 +1,351 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/laertkokona/crud-test/repositories"
 	"github.com/laertkokona/crud-test/utils"
-	"net/http"
+	"math"
+	"time"
 )
 
+// orderTransitions is the table of legal status transitions, keyed by the current status of the order
+var orderTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderStatusDraft:     {models.OrderStatusSubmitted, models.OrderStatusCancelled},
+	models.OrderStatusSubmitted: {models.OrderStatusApproved, models.OrderStatusDraft, models.OrderStatusCancelled},
+	models.OrderStatusApproved:  {models.OrderStatusPicking, models.OrderStatusCancelled},
+	models.OrderStatusPicking:   {models.OrderStatusPacked, models.OrderStatusCancelled},
+	models.OrderStatusPacked:    {models.OrderStatusShipped, models.OrderStatusCancelled},
+	models.OrderStatusShipped:   {models.OrderStatusDelivered},
+	models.OrderStatusDelivered: {},
+	models.OrderStatusCancelled: {},
+}
+
+// linesEditable checks if the lines of an order in the given status may still be changed
+func linesEditable(status models.OrderStatus) bool {
+	return status == models.OrderStatusDraft || status == models.OrderStatusSubmitted
+}
+
+// validateLines checks that every order line asks for a positive quantity
+func validateLines(lines []models.OrderItem) error {
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return fmt.Errorf("quantity of item %d must be positive", line.ItemId)
+		}
+	}
+	return nil
+}
+
+// sameDiscounts checks if two sets of order lines give the same discount on every item, weighing the discount of every
+// line by its quantity
+func sameDiscounts(a, b []models.OrderItem) bool {
+	discounts := make(map[int]float64)
+	for _, line := range a {
+		discounts[line.ItemId] += line.DiscountPercent * float64(line.Quantity)
+	}
+	for _, line := range b {
+		discounts[line.ItemId] -= line.DiscountPercent * float64(line.Quantity)
+	}
+	for _, discount := range discounts {
+		if math.Abs(discount) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+// sameQuantities checks if two sets of order lines ask for the same quantity of every item
+func sameQuantities(a, b []models.OrderItem) bool {
+	quantities := make(map[int]int)
+	for _, line := range a {
+		quantities[line.ItemId] += line.Quantity
+	}
+	for _, line := range b {
+		quantities[line.ItemId] -= line.Quantity
+	}
+	for _, quantity := range quantities {
+		if quantity != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// priceLines checks that the item of every order line exists and sets the unit price, tax percent and item summary of
+// the lines. Items already in the stored lines keep the unit price and tax percent they were ordered at, even when they
+// were deleted since, the other lines get the current price of their item and the tax percent of its category
+func (p orderService) priceLines(lines, stored []models.OrderItem) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(lines))
+	for _, line := range lines {
+		ids = append(ids, line.ItemId)
+	}
+	items, err := p.ItemRepo.FindByIDs(ids)
+	if err != nil {
+		return err
+	}
+	current := make(map[int]models.Item, len(items))
+	for _, item := range items {
+		current[int(item.ID)] = item
+	}
+	ordered := make(map[int]models.OrderItem, len(stored))
+	for _, line := range stored {
+		ordered[line.ItemId] = line
+	}
+	currency := ""
+	for i := range lines {
+		item, exists := current[lines[i].ItemId]
+		switch line, ok := ordered[lines[i].ItemId]; {
+		case ok:
+			lines[i].UnitPrice = line.UnitPrice
+			lines[i].TaxPercent = line.TaxPercent
+			lines[i].Item = line.Item
+		case exists:
+			lines[i].UnitPrice = item.Price
+			lines[i].TaxPercent = p.TaxRates.Percent(item.Category)
+		default:
+			return errs.UnprocessableOn(fmt.Sprintf("orderItems[%d].item", i), fmt.Errorf("item %d does not exist", lines[i].ItemId))
+		}
+		if exists {
+			lines[i].Item = item.Summary()
+		}
+		if !lines[i].UnitPrice.SameCurrency(models.NewMoney(0, currency)) {
+			return errs.UnprocessableOn(fmt.Sprintf("orderItems[%d].item", i), fmt.Errorf("item %d is priced in %s, the order in %s",
+				lines[i].ItemId, lines[i].UnitPrice.Currency, currency))
+		}
+		if lines[i].UnitPrice.Currency != "" {
+			currency = lines[i].UnitPrice.Currency
+		}
+	}
+	return nil
+}
+
+// stockError returns an error of the order repository as a conflict when it is a stock shortage
+func stockError(err error) error {
+	var shortage *models.InsufficientStockError
+	if errors.As(err, &shortage) {
+		return errs.Conflict(err)
+	}
+	return err
+}
+
+// canTransition checks if an order in status from may be moved to status to
+func canTransition(from, to models.OrderStatus) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrOrderNotFound is returned as not found for orders outside the scope of the request, like for the ones that do not
+// exist, so users cannot tell the orders of others apart from missing ones
+var ErrOrderNotFound = errors.New("order not found")
+
 // OrderService interface using gin context
 type OrderService interface {
-	CreateOrder(order models.Order) (models.Order, int, error)
-	GetOrder(id int) (models.Order, int, error)
-	GetAllOrders(pagination models.Pagination) ([]models.Order, int, error)
-	UpdateOrder(id int, order models.Order) (models.Order, int, error)
-	DeleteOrder(id int) (models.Order, int, error)
+	CreateOrder(order models.Order, scope models.OrderScope) (models.Order, error)
+	GetOrder(id int, scope models.OrderScope) (models.Order, error)
+	GetAllOrders(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) (models.Page[models.Order], error)
+	UpdateOrder(id int, order models.Order, version uint, scope models.OrderScope) (models.Order, error)
+	DeleteOrder(id int, version uint, scope models.OrderScope) (models.Order, error)
+	TransitionOrder(id int, status models.OrderStatus, scope models.OrderScope) (models.Order, error)
+	GetOrderHistory(id int, pagination models.Pagination, scope models.OrderScope) (models.Page[models.OrderStatusChange], error)
 }
 
 // orderService struct
 type orderService struct {
 	OrderRepo repositories.OrderRepo
+	ItemRepo  repositories.ItemRepo
+	TaxRates  utils.TaxRates
 }
 
-// NewOrderService returns a new instance of orderService
-func NewOrderService(orderRepo repositories.OrderRepo) OrderService {
+// NewOrderService returns a new instance of orderService that taxes the lines of the orders at the tax rates
+func NewOrderService(orderRepo repositories.OrderRepo, itemRepo repositories.ItemRepo, taxRates utils.TaxRates) OrderService {
 	return orderService{
 		OrderRepo: orderRepo,
+		ItemRepo:  itemRepo,
+		TaxRates:  taxRates,
 	}
 }
 
-// CreateOrder method that takes a models.Order object and saves it to the database
-func (p orderService) CreateOrder(order models.Order) (models.Order, int, error) {
-	// call the order repository to save the order
+// CreateOrder method that takes a models.Order object and saves it to the database as an order of the scope's user
+func (p orderService) CreateOrder(order models.Order, scope models.OrderScope) (models.Order, error) {
+	// check the order lines and price them at the current prices and tax rates of their items
+	// compute the discounts, taxes and totals of the order
+	// every order starts as a draft of the requesting user, whatever the request says
+	// record the creation as the first entry of the status history
+	// call the order repository to save the order and reserve its stock
 	// return the order object
+	if err := validateLines(order.OrderItems); err != nil {
+		return order, errs.Validation(err)
+	}
+	if err := p.priceLines(order.OrderItems, nil); err != nil {
+		return order, err
+	}
+	priceOrder(&order)
+	order.Status = models.OrderStatusDraft
+	order.UserID = int(scope.UserID)
+	order.StatusHistory = []models.OrderStatusChange{
+		{
+			ToStatus:  models.OrderStatusDraft,
+			ChangedBy: uint(order.UserID),
+			ChangedAt: time.Now(),
+		},
+	}
 	order, err := p.OrderRepo.Save(order)
 	if err != nil {
-		return order, http.StatusInternalServerError, err
+		return order, stockError(err)
 	}
-	return order, http.StatusOK, nil
+	return order, nil
 }
 
 // GetOrder method that takes an order id and returns the order object
-func (p orderService) GetOrder(id int) (models.Order, int, error) {
+func (p orderService) GetOrder(id int, scope models.OrderScope) (models.Order, error) {
 	// call the order repository to get the order
 	// return the order object
-	order, err := p.OrderRepo.FindByID(id)
+	order, err := p.findOrder(id, scope)
 	if err != nil {
-		return order, http.StatusInternalServerError, err
+		return order, err
 	}
-	return order, http.StatusOK, nil
+	return order, nil
 }
 
-// GetAllOrders method that returns all the orders
-func (p orderService) GetAllOrders(pagination models.Pagination) ([]models.Order, int, error) {
-	// call the order repository to get all the orders
-	// return the orders
-	orders, err := p.OrderRepo.FindAll(pagination)
+// GetAllOrders method that returns a page of the orders in the scope matching the filters and sorts of the query
+func (p orderService) GetAllOrders(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) (models.Page[models.Order], error) {
+	if err := query.Validate(pagination); err != nil {
+		return models.Page[models.Order]{}, errs.Validation(err)
+	}
+	// call the order repository to get a page of the orders in the scope
+	// return the page
+	orders, total, err := p.OrderRepo.FindAll(pagination, query, scope)
 	if err != nil {
-		return orders, http.StatusInternalServerError, err
+		return models.Page[models.Order]{}, err
+	}
+	page := models.NewPage(orders, total, pagination)
+	if n := len(page.Data); n > 0 && !query.Sorted() {
+		page = page.WithNextCursor(orders[n-1].Model)
 	}
-	return orders, http.StatusOK, nil
+	return page, nil
 }
 
-// UpdateOrder method that takes an order id and a models.Order object and updates the order
-func (p orderService) UpdateOrder(id int, order models.Order) (models.Order, int, error) {
-	// call the order repository to update the order
+// UpdateOrder method that takes an order id, a models.Order object and the version it was made to and replaces the order with it
+func (p orderService) UpdateOrder(id int, order models.Order, version uint, scope models.OrderScope) (models.Order, error) {
+	// check that the order is still at the version the change was made to
+	// price the lines, the items the order already had keep the price and tax rate they were ordered at
+	// recompute the discounts, taxes and totals of the order from its new lines
+	// call the order repository to replace the order and move its stock reservation
 	// return the order object
-	orderDb, err := p.OrderRepo.FindByID(id)
+	if err := validateLines(order.OrderItems); err != nil {
+		return order, errs.Validation(err)
+	}
+	orderDb, err := p.findOrder(id, scope)
 	if err != nil {
-		return orderDb, http.StatusNotFound, err
+		return orderDb, err
+	}
+	if err := checkVersion(orderDb.Version, version); err != nil {
+		return orderDb, err
+	}
+	if !linesEditable(orderDb.Status) && !sameQuantities(orderDb.OrderItems, order.OrderItems) {
+		return orderDb, errs.Conflict(fmt.Errorf("lines of an order in status %s cannot be changed", orderDb.Status))
+	}
+	if !linesEditable(orderDb.Status) && (!sameDiscounts(orderDb.OrderItems, order.OrderItems) || orderDb.DiscountPercent != order.DiscountPercent) {
+		return orderDb, errs.Conflict(fmt.Errorf("discounts of an order in status %s cannot be changed", orderDb.Status))
 	}
-	//var comparableOrderDb models.ComparableOrder
-	//var comparableOrder models.ComparableOrder
-	//comparableOrderDb = models.ComparableOrder{}
+	if err := p.priceLines(order.OrderItems, orderDb.OrderItems); err != nil {
+		return orderDb, err
+	}
+	priceOrder(&order)
 
-	utils.CopyNonEmptyFields(&orderDb, &order)
-	orderDb, err = p.OrderRepo.Update(orderDb)
+	// the status can only be changed through the transition endpoints and the owner never changes
+	order.Model = orderDb.Model
+	order.Status = orderDb.Status
+	order.UserID = orderDb.UserID
+	order.StatusHistory = nil
+	order.Version = orderDb.Version
+	orderDb, err = p.OrderRepo.Update(order)
 	if err != nil {
-		return orderDb, http.StatusInternalServerError, err
+		return orderDb, versionError(stockError(err))
 	}
-	return orderDb, http.StatusOK, nil
+	return orderDb, nil
 }
 
-// DeleteOrder method that takes an order id and deletes the order
-func (p orderService) DeleteOrder(id int) (models.Order, int, error) {
-	item, err := p.OrderRepo.FindByID(id)
+// DeleteOrder method that takes an order id and the version it was read at and deletes the order
+func (p orderService) DeleteOrder(id int, version uint, scope models.OrderScope) (models.Order, error) {
+	item, err := p.findOrder(id, scope)
 	if err != nil {
-		return item, http.StatusNotFound, err
+		return item, err
+	}
+	if err := checkVersion(item.Version, version); err != nil {
+		return item, err
 	}
 	err = p.OrderRepo.Delete(item)
 	if err != nil {
-		return item, http.StatusInternalServerError, err
+		return item, versionError(err)
+	}
+	return item, nil
+}
+
+// TransitionOrder method that takes an order id, the target status and the scope of the user making the change and moves the order to that status
+func (p orderService) TransitionOrder(id int, status models.OrderStatus, scope models.OrderScope) (models.Order, error) {
+	// get the order from the database
+	// check the transition table, illegal moves are a conflict with the current state
+	// save the new status together with the status change record
+	order, err := p.findOrder(id, scope)
+	if err != nil {
+		return order, err
+	}
+	if !canTransition(order.Status, status) {
+		return order, errs.Conflict(fmt.Errorf("cannot move order from %s to %s", order.Status, status))
+	}
+	change := models.OrderStatusChange{
+		FromStatus: order.Status,
+		ToStatus:   status,
+		ChangedBy:  scope.UserID,
+		ChangedAt:  time.Now(),
+	}
+	order, err = p.OrderRepo.SaveTransition(order, change)
+	if errors.Is(err, repositories.ErrOrderStatusChanged) {
+		return order, errs.Conflict(err)
+	}
+	if err != nil {
+		return order, err
+	}
+	return order, nil
+}
+
+// GetOrderHistory method that takes an order id and returns a page of its status changes
+func (p orderService) GetOrderHistory(id int, pagination models.Pagination, scope models.OrderScope) (models.Page[models.OrderStatusChange], error) {
+	if err := pagination.Validate(); err != nil {
+		return models.Page[models.OrderStatusChange]{}, errs.Validation(err)
+	}
+	if _, err := p.findOrder(id, scope); err != nil {
+		return models.Page[models.OrderStatusChange]{}, err
+	}
+	history, total, err := p.OrderRepo.FindHistory(id, pagination)
+	if err != nil {
+		return models.Page[models.OrderStatusChange]{}, err
+	}
+	page := models.NewPage(history, total, pagination)
+	if n := len(page.Data); n > 0 {
+		page = page.WithNextCursor(history[n-1].Model)
+	}
+	return page, nil
+}
+
+// findOrder returns an order by id if it is in the scope
+func (p orderService) findOrder(id int, scope models.OrderScope) (models.Order, error) {
+	order, err := p.OrderRepo.FindByID(id)
+	if err != nil {
+		return order, notFoundError(err)
+	}
+	if !scope.Includes(order) {
+		return models.Order{}, errs.NotFound(ErrOrderNotFound)
 	}
-	return item, http.StatusOK, nil
+	return order, nil
 }
@@ -2,10 +2,12 @@ package services
 
 import (
 	"errors"
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/repositories"
+	"github.com/laertkokona/crud-test/utils"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
-	"net/http"
 	"testing"
 )
 
@@ -31,8 +33,9 @@ var mockModels = []gorm.Model{
 }
 var mockOrders = []models.Order{
 	{
-		Model: mockModels[0],
-		Code:  "ord1",
+		Model:  mockModels[0],
+		Code:   "ord1",
+		Status: models.OrderStatusDraft,
 		OrderItems: []models.OrderItem{
 			{
 				Model:    mockModels[0],
@@ -47,8 +50,9 @@ var mockOrders = []models.Order{
 		},
 	},
 	{
-		Model: mockModels[1],
-		Code:  "ord2",
+		Model:  mockModels[1],
+		Code:   "ord2",
+		Status: models.OrderStatusApproved,
 		OrderItems: []models.OrderItem{
 			{
 				Model:    mockModels[2],
@@ -64,10 +68,30 @@ var mockOrders = []models.Order{
 	},
 }
 
+var mockHistory = []models.OrderStatusChange{
+	{
+		Model:     mockModels[0],
+		OrderID:   1,
+		ToStatus:  models.OrderStatusDraft,
+		ChangedBy: 1,
+	},
+}
+
+// mockTaxRates tax the mock items, which are all of one category, at 10 percent
+var mockTaxRates = utils.TaxRates{Default: 20, Categories: map[string]float64{"Category Test": 10}}
+
+// eur returns an amount of euros, the currency of the mock items
+func eur(amount string) models.Money {
+	return models.MustParseMoney(amount + " EUR")
+}
+
+// mockOrderScope is the scope of a user that may touch every order
+var mockOrderScope = models.OrderScope{UserID: 7, All: true}
+
 // mockOrderRepo is a mock implementation of the repositories.OrderRepo interface
 type mockOrderRepo struct {
-	// findAll is a mock function with given fields: pagination
-	findAll func(pagination models.Pagination) ([]models.Order, error)
+	// findAll is a mock function with given fields: pagination, scope
+	findAll func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) ([]models.Order, int64, error)
 	// findByID is a mock function with given fields: id
 	findByID func(id int) (models.Order, error)
 	// save is a mock function with given fields: order
@@ -78,11 +102,15 @@ type mockOrderRepo struct {
 	delete func(order models.Order) error
 	// deleteById is a mock function with given fields: id
 	deleteById func(id int) (models.Order, error)
+	// saveTransition is a mock function with given fields: order, change
+	saveTransition func(order models.Order, change models.OrderStatusChange) (models.Order, error)
+	// findHistory is a mock function with given fields: orderID, pagination
+	findHistory func(orderID int, pagination models.Pagination) ([]models.OrderStatusChange, int64, error)
 }
 
-// FindAll is a mock function with given fields: pagination
-func (_m *mockOrderRepo) FindAll(pagination models.Pagination) ([]models.Order, error) {
-	return _m.findAll(pagination)
+// FindAll is a mock function with given fields: pagination, scope
+func (_m *mockOrderRepo) FindAll(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) ([]models.Order, int64, error) {
+	return _m.findAll(pagination, query, scope)
 }
 
 // FindByID is a mock function with given fields: id
@@ -110,11 +138,21 @@ func (_m *mockOrderRepo) DeleteById(id int) (models.Order, error) {
 	return _m.deleteById(id)
 }
 
+// SaveTransition is a mock function with given fields: order, change
+func (_m *mockOrderRepo) SaveTransition(order models.Order, change models.OrderStatusChange) (models.Order, error) {
+	return _m.saveTransition(order, change)
+}
+
+// FindHistory is a mock function with given fields: orderID, pagination
+func (_m *mockOrderRepo) FindHistory(orderID int, pagination models.Pagination) ([]models.OrderStatusChange, int64, error) {
+	return _m.findHistory(orderID, pagination)
+}
+
 // newMockOrderRepo returns a new mockOrderRepo
 func newMockOrderRepo() *mockOrderRepo {
 	return &mockOrderRepo{
-		findAll: func(pagination models.Pagination) ([]models.Order, error) {
-			return mockOrders, nil
+		findAll: func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) ([]models.Order, int64, error) {
+			return mockOrders, int64(len(mockOrders)), nil
 		},
 		findByID: func(id int) (models.Order, error) {
 			var order models.Order
@@ -144,6 +182,13 @@ func newMockOrderRepo() *mockOrderRepo {
 			}
 			return order, nil
 		},
+		saveTransition: func(order models.Order, change models.OrderStatusChange) (models.Order, error) {
+			order.Status = change.ToStatus
+			return order, nil
+		},
+		findHistory: func(orderID int, pagination models.Pagination) ([]models.OrderStatusChange, int64, error) {
+			return mockHistory, int64(len(mockHistory)), nil
+		},
 	}
 }
 
@@ -152,11 +197,11 @@ func newMockOrderRepo() *mockOrderRepo {
 // newMockOrderErrorRepo returns a new mockOrderErrorRepo
 func newMockOrderErrorRepo() *mockOrderRepo {
 	return &mockOrderRepo{
-		findAll: func(pagination models.Pagination) ([]models.Order, error) {
-			return []models.Order{}, errors.New("error")
+		findAll: func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) ([]models.Order, int64, error) {
+			return []models.Order{}, 0, errors.New("error")
 		},
 		findByID: func(id int) (models.Order, error) {
-			return models.Order{}, errors.New("error")
+			return models.Order{}, gorm.ErrRecordNotFound
 		},
 		save: func(order models.Order) (models.Order, error) {
 			return models.Order{}, errors.New("error")
@@ -170,14 +215,20 @@ func newMockOrderErrorRepo() *mockOrderRepo {
 		deleteById: func(id int) (models.Order, error) {
 			return models.Order{}, errors.New("error")
 		},
+		saveTransition: func(order models.Order, change models.OrderStatusChange) (models.Order, error) {
+			return order, errors.New("error")
+		},
+		findHistory: func(orderID int, pagination models.Pagination) ([]models.OrderStatusChange, int64, error) {
+			return nil, 0, errors.New("error")
+		},
 	}
 }
 
 // newMockOrderSpecificErrorRepo returns a new mockOrderErrorRepo
 func newMockOrderSpecificErrorRepo() *mockOrderRepo {
 	return &mockOrderRepo{
-		findAll: func(pagination models.Pagination) ([]models.Order, error) {
-			return mockOrders, nil
+		findAll: func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) ([]models.Order, int64, error) {
+			return mockOrders, int64(len(mockOrders)), nil
 		},
 		findByID: func(id int) (models.Order, error) {
 			var order models.Order
@@ -200,13 +251,19 @@ func newMockOrderSpecificErrorRepo() *mockOrderRepo {
 		deleteById: func(id int) (models.Order, error) {
 			return models.Order{}, errors.New("error")
 		},
+		saveTransition: func(order models.Order, change models.OrderStatusChange) (models.Order, error) {
+			return order, errors.New("error")
+		},
+		findHistory: func(orderID int, pagination models.Pagination) ([]models.OrderStatusChange, int64, error) {
+			return nil, 0, errors.New("error")
+		},
 	}
 }
 
 // TestNewOrderService test the NewOrderService function
 func TestNewOrderService(t *testing.T) {
 	mockOrderRepo := newMockOrderRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
 	assert.NotNil(t, mockService)
 	assert.IsType(t, orderService{}, mockService)
@@ -215,7 +272,7 @@ func TestNewOrderService(t *testing.T) {
 // TestCreateOrder test the CreateOrder function using mockOrderRepo
 func TestCreateOrder(t *testing.T) {
 	mockOrderRepo := newMockOrderRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
 	mockOrder := models.Order{
 		Code: "ord3",
@@ -227,16 +284,20 @@ func TestCreateOrder(t *testing.T) {
 		},
 	}
 
-	order, status, err := mockService.CreateOrder(mockOrder)
+	order, err := mockService.CreateOrder(mockOrder, mockOrderScope)
 	assert.Nil(t, err)
-	assert.Equal(t, http.StatusOK, status)
-	assert.Equal(t, mockOrder, order)
+	assert.Equal(t, mockOrder.Code, order.Code)
+	assert.Equal(t, mockOrder.OrderItems, order.OrderItems)
+	assert.Equal(t, models.OrderStatusDraft, order.Status)
+	assert.Len(t, order.StatusHistory, 1)
+	assert.Equal(t, models.OrderStatusDraft, order.StatusHistory[0].ToStatus)
+	assert.Equal(t, 7, order.UserID)
 }
 
 // TestCreateOrder_SaveError test the CreateOrder function using mockOrderErrorRepo
 func TestCreateOrder_SaveError(t *testing.T) {
 	mockOrderRepo := newMockOrderErrorRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
 	mockOrder := models.Order{
 		Code: "ord3",
@@ -248,60 +309,134 @@ func TestCreateOrder_SaveError(t *testing.T) {
 		},
 	}
 
-	order, status, err := mockService.CreateOrder(mockOrder)
+	order, err := mockService.CreateOrder(mockOrder, mockOrderScope)
 	assert.NotNil(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.Order{}, order)
 }
 
 // TestGetOrder test the GetOrder function using mockOrderRepo
 func TestGetOrder(t *testing.T) {
 	mockOrderRepo := newMockOrderRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
-	order, status, err := mockService.GetOrder(1)
+	order, err := mockService.GetOrder(1, mockOrderScope)
 	assert.Nil(t, err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, mockOrders[0], order)
 }
 
 // TestGetOrder_FindByIdError test the GetOrder function using mockOrderErrorRepo
 func TestGetOrder_FindByIdError(t *testing.T) {
 	mockOrderRepo := newMockOrderErrorRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
-	order, status, err := mockService.GetOrder(1)
+	order, err := mockService.GetOrder(1, mockOrderScope)
 	assert.NotNil(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Equal(t, models.Order{}, order)
 }
 
+// TestGetOrder_FindByIdFailure tests that an error of the repository other than a missing order is not a not found error
+func TestGetOrder_FindByIdFailure(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.findByID = func(id int) (models.Order, error) {
+		return models.Order{}, errors.New("connection lost")
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	_, err := mockService.GetOrder(1, mockOrderScope)
+	assert.EqualError(t, err, "connection lost")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+}
+
 // TestGetAllOrders test the GetAllOrders function using mockOrderRepo
 func TestGetAllOrders(t *testing.T) {
 	mockOrderRepo := newMockOrderRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
-	orders, status, err := mockService.GetAllOrders(models.Pagination{})
+	orders, err := mockService.GetAllOrders(models.Pagination{}, models.ListQuery{}, mockOrderScope)
 	assert.Nil(t, err)
-	assert.Equal(t, http.StatusOK, status)
-	assert.Equal(t, mockOrders, orders)
+	assert.Equal(t, mockOrders, orders.Data)
+	assert.Equal(t, int64(len(mockOrders)), *orders.Total)
+}
+
+// TestGetAllOrders_Cursor test that the GetAllOrders function returns the cursor of the last order of a page after a cursor
+// when the repository found an order after it, and leaves that order out of the page
+func TestGetAllOrders_Cursor(t *testing.T) {
+	var gotPagination models.Pagination
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.findAll = func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) ([]models.Order, int64, error) {
+		gotPagination = pagination
+		return mockOrders, 0, nil
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	after := models.Cursor{ID: 7}.Encode()
+	limit := len(mockOrders) - 1
+	orders, err := mockService.GetAllOrders(models.Pagination{Limit: limit, After: after}, models.ListQuery{}, mockOrderScope)
+	assert.Nil(t, err)
+	assert.Equal(t, after, gotPagination.After)
+	assert.Equal(t, 0, orders.Page)
+	assert.Len(t, orders.Data, limit)
+	assert.Nil(t, orders.Total)
+	assert.Equal(t, models.CursorOf(mockOrders[limit-1].Model).Encode(), orders.NextCursor)
+
+	cursor, err := models.DecodeCursor(orders.NextCursor)
+	assert.Nil(t, err)
+	assert.Equal(t, mockOrders[limit-1].ID, cursor.ID)
+}
+
+// TestGetAllOrders_LastPage test that the GetAllOrders function returns no cursor on the last page
+func TestGetAllOrders_LastPage(t *testing.T) {
+	mockService := NewOrderService(newMockOrderRepo(), newMockItemRepo(), mockTaxRates)
+
+	orders, err := mockService.GetAllOrders(models.Pagination{Page: 1, Limit: len(mockOrders)}, models.ListQuery{}, mockOrderScope)
+	assert.Nil(t, err)
+	assert.Empty(t, orders.NextCursor)
+}
+
+// TestGetAllOrders_FullLastCursorPage test that the GetAllOrders function returns no cursor on a full page after a cursor
+// when no order comes after it
+func TestGetAllOrders_FullLastCursorPage(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.findAll = func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) ([]models.Order, int64, error) {
+		return mockOrders, 0, nil
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	after := models.Cursor{ID: 7}.Encode()
+	orders, err := mockService.GetAllOrders(models.Pagination{Limit: len(mockOrders), After: after}, models.ListQuery{}, mockOrderScope)
+	assert.Nil(t, err)
+	assert.Len(t, orders.Data, len(mockOrders))
+	assert.Empty(t, orders.NextCursor)
+	assert.Nil(t, orders.Total)
+	assert.Nil(t, orders.TotalPages)
+}
+
+// TestGetAllOrders_InvalidCursor test that the GetAllOrders function rejects a cursor it did not make
+func TestGetAllOrders_InvalidCursor(t *testing.T) {
+	mockService := NewOrderService(newMockOrderRepo(), newMockItemRepo(), mockTaxRates)
+
+	_, err := mockService.GetAllOrders(models.Pagination{After: "not-a-cursor"}, models.ListQuery{}, mockOrderScope)
+	assert.ErrorIs(t, err, models.ErrInvalidCursor)
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
 }
 
 // TestGetAllOrders_FindAllError test the GetAllOrders function using mockOrderErrorRepo
 func TestGetAllOrders_FindAllError(t *testing.T) {
 	mockOrderRepo := newMockOrderErrorRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
-	orders, status, err := mockService.GetAllOrders(models.Pagination{})
+	orders, err := mockService.GetAllOrders(models.Pagination{}, models.ListQuery{}, mockOrderScope)
 	assert.NotNil(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
-	assert.Equal(t, []models.Order{}, orders)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+	assert.Equal(t, models.Page[models.Order]{}, orders)
 }
 
 // TestUpdateOrder test the UpdateOrder function using mockOrderRepo
 func TestUpdateOrder(t *testing.T) {
 	mockOrderRepo := newMockOrderRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
 	mockOrder := models.Order{
 		Code: "ord3",
@@ -313,17 +448,18 @@ func TestUpdateOrder(t *testing.T) {
 		},
 	}
 
-	order, status, err := mockService.UpdateOrder(1, mockOrder)
+	order, err := mockService.UpdateOrder(1, mockOrder, AnyVersion, mockOrderScope)
 	mockOrder.ID = uint(1)
+	mockOrder.Status = models.OrderStatusDraft
+	mockOrder.OrderTotals = models.OrderTotals{Subtotal: eur("2499.50"), DiscountAmount: eur("0"), TaxAmount: eur("249.95"), GrandTotal: eur("2749.45")}
 	assert.Nil(t, err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, mockOrder, order)
 }
 
 // TestUpdateOrder_FindByIdError test the UpdateOrder function using mockOrderErrorRepo
 func TestUpdateOrder_FindByIdError(t *testing.T) {
 	mockOrderRepo := newMockOrderErrorRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
 	mockOrder := models.Order{
 		Code: "ord3",
@@ -335,16 +471,16 @@ func TestUpdateOrder_FindByIdError(t *testing.T) {
 		},
 	}
 
-	order, status, err := mockService.UpdateOrder(1, mockOrder)
+	order, err := mockService.UpdateOrder(1, mockOrder, AnyVersion, mockOrderScope)
 	assert.NotNil(t, err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Equal(t, models.Order{}, order)
 }
 
 // TestUpdateOrder_UpdateError test the UpdateOrder function using mockOrderSpecificErrorRepo
 func TestUpdateOrder_UpdateError(t *testing.T) {
 	mockOrderRepo := newMockOrderSpecificErrorRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
 	mockOrder := models.Order{
 		Code: "ord3",
@@ -356,49 +492,526 @@ func TestUpdateOrder_UpdateError(t *testing.T) {
 		},
 	}
 
-	order, status, err := mockService.UpdateOrder(1, mockOrder)
+	order, err := mockService.UpdateOrder(1, mockOrder, AnyVersion, mockOrderScope)
 	assert.NotNil(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.Order{}, order)
 }
 
 // TestDeleteOrder test the DeleteOrder function using mockOrderRepo
 func TestDeleteOrder(t *testing.T) {
 	mockOrderRepo := newMockOrderRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
-	order, status, err := mockService.DeleteOrder(1)
+	order, err := mockService.DeleteOrder(1, AnyVersion, mockOrderScope)
 	assert.Nil(t, err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, mockOrders[0], order)
 }
 
 // TestDeleteOrder_FindByIdError test the DeleteOrder function using mockOrderErrorRepo
 func TestDeleteOrder_FindByIdError(t *testing.T) {
 	mockOrderRepo := newMockOrderErrorRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
-	order, status, err := mockService.DeleteOrder(1)
+	order, err := mockService.DeleteOrder(1, AnyVersion, mockOrderScope)
 	assert.NotNil(t, err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Equal(t, models.Order{}, order)
 }
 
 // TestDeleteOrder_DeleteError test the DeleteOrder function using mockOrderSpecificErrorRepo
 func TestDeleteOrder_DeleteError(t *testing.T) {
 	mockOrderRepo := newMockOrderSpecificErrorRepo()
-	mockService := NewOrderService(mockOrderRepo)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 
-	order, status, err := mockService.DeleteOrder(1)
+	order, err := mockService.DeleteOrder(1, AnyVersion, mockOrderScope)
 	assert.NotNil(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, mockOrders[0], order)
 }
 
+// TestCreateOrder_InvalidQuantity test the CreateOrder function with a line that does not ask for a positive quantity
+func TestCreateOrder_InvalidQuantity(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	mockOrder := models.Order{
+		Code: "ord3",
+		OrderItems: []models.OrderItem{
+			{
+				ItemId:   5,
+				Quantity: -5,
+			},
+		},
+	}
+
+	_, err := mockService.CreateOrder(mockOrder, mockOrderScope)
+	assert.NotNil(t, err)
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
+}
+
+// TestCreateOrder_InsufficientStock test the CreateOrder function when the repository reports a stock shortage
+func TestCreateOrder_InsufficientStock(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.save = func(order models.Order) (models.Order, error) {
+		return order, &models.InsufficientStockError{Shortages: []models.StockShortage{{ItemID: 5, Requested: 50, Available: 10}}}
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	mockOrder := models.Order{
+		Code: "ord3",
+		OrderItems: []models.OrderItem{
+			{
+				ItemId:   5,
+				Quantity: 50,
+			},
+		},
+	}
+
+	_, err := mockService.CreateOrder(mockOrder, mockOrderScope)
+	var shortage *models.InsufficientStockError
+	assert.ErrorAs(t, err, &shortage)
+	assert.Equal(t, errs.KindConflict, errs.KindOf(err))
+	assert.Equal(t, 10, shortage.Shortages[0].Available)
+}
+
+// TestUpdateOrder_LinesLocked test the UpdateOrder function changing the lines of an approved order
+func TestUpdateOrder_LinesLocked(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	mockOrder := models.Order{
+		OrderItems: []models.OrderItem{
+			{
+				ItemId:   3,
+				Quantity: 1,
+			},
+		},
+	}
+
+	_, err := mockService.UpdateOrder(2, mockOrder, AnyVersion, mockOrderScope)
+	assert.NotNil(t, err)
+	assert.Equal(t, errs.KindConflict, errs.KindOf(err))
+}
+
+// TestUpdateOrder_LockedSameLines tests that an order whose lines are locked can be replaced with the same lines
+func TestUpdateOrder_LockedSameLines(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	mockOrder := models.Order{
+		Code: "ord2-renamed",
+		OrderItems: []models.OrderItem{
+			{ItemId: 4, Quantity: 40},
+			{ItemId: 3, Quantity: 30},
+		},
+	}
+
+	order, err := mockService.UpdateOrder(2, mockOrder, AnyVersion, mockOrderScope)
+	assert.Nil(t, err)
+	assert.Equal(t, "ord2-renamed", order.Code)
+	assert.Equal(t, models.OrderStatusApproved, order.Status)
+}
+
+// TestUpdateOrder_ClearsLines tests that replacing a draft order without lines removes its lines
+func TestUpdateOrder_ClearsLines(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	order, err := mockService.UpdateOrder(1, models.Order{Code: "ord1"}, AnyVersion, mockOrderScope)
+	assert.Nil(t, err)
+	assert.Empty(t, order.OrderItems)
+}
+
+// TestCreateOrder_PricesLines tests that the lines of a new order are priced at the current prices of their items and
+// have a summary of them
+func TestCreateOrder_PricesLines(t *testing.T) {
+	mockService := NewOrderService(newMockOrderRepo(), newMockItemRepo(), mockTaxRates)
+
+	mockOrder := models.Order{
+		Code:       "ord3",
+		OrderItems: []models.OrderItem{{ItemId: 2, Quantity: 3}},
+	}
+
+	order, err := mockService.CreateOrder(mockOrder, mockOrderScope)
+	assert.Nil(t, err)
+	assert.Equal(t, eur("19.99"), order.OrderItems[0].UnitPrice)
+	assert.Equal(t, eur("59.97"), order.OrderItems[0].LineTotal)
+	assert.Equal(t, &models.ItemSummary{ID: 2, Code: "itm2", Name: "Item 2"}, order.OrderItems[0].Item)
+}
+
+// TestCreateOrder_MissingItem tests that an order with a line of an item that does not exist is unprocessable and
+// names the line
+func TestCreateOrder_MissingItem(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.save = func(order models.Order) (models.Order, error) {
+		t.Fatal("an order with a missing item must not be saved")
+		return order, nil
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	mockOrder := models.Order{
+		Code:       "ord3",
+		OrderItems: []models.OrderItem{{ItemId: 1, Quantity: 1}, {ItemId: 99, Quantity: 1}},
+	}
+
+	_, err := mockService.CreateOrder(mockOrder, mockOrderScope)
+	assert.Equal(t, errs.KindUnprocessable, errs.KindOf(err))
+	assert.Equal(t, "orderItems[1].item", errs.FieldOf(err))
+}
+
+// TestCreateOrder_MixedCurrencies tests that an order with items priced in different currencies is unprocessable and
+// names the line of the first item in another currency
+func TestCreateOrder_MixedCurrencies(t *testing.T) {
+	mockItemRepo := newMockItemRepo()
+	mockItemRepo.findByIDs = func(ids []int) ([]models.Item, error) {
+		return []models.Item{
+			{Model: gorm.Model{ID: 1}, Code: "itm1", Price: eur("9.99")},
+			{Model: gorm.Model{ID: 2}, Code: "itm2", Price: models.MustParseMoney("19.99 USD")},
+		}, nil
+	}
+	mockService := NewOrderService(newMockOrderRepo(), mockItemRepo, mockTaxRates)
+
+	mockOrder := models.Order{
+		Code:       "ord3",
+		OrderItems: []models.OrderItem{{ItemId: 1, Quantity: 1}, {ItemId: 2, Quantity: 1}},
+	}
+
+	_, err := mockService.CreateOrder(mockOrder, mockOrderScope)
+	assert.Equal(t, errs.KindUnprocessable, errs.KindOf(err))
+	assert.Equal(t, "orderItems[1].item", errs.FieldOf(err))
+}
+
+// TestCreateOrder_FindItemsError tests that an error loading the items of the lines is returned
+func TestCreateOrder_FindItemsError(t *testing.T) {
+	mockService := NewOrderService(newMockOrderRepo(), newMockItemErrorRepo(), mockTaxRates)
+
+	mockOrder := models.Order{
+		Code:       "ord3",
+		OrderItems: []models.OrderItem{{ItemId: 1, Quantity: 1}},
+	}
+
+	_, err := mockService.CreateOrder(mockOrder, mockOrderScope)
+	assert.NotNil(t, err)
+}
+
+// TestUpdateOrder_KeepsOrderedPrices tests that the lines of items the order already had keep the price they were
+// ordered at, even when the item was deleted since, and new lines get the current price
+func TestUpdateOrder_KeepsOrderedPrices(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.findByID = func(id int) (models.Order, error) {
+		return models.Order{
+			Model:  mockModels[0],
+			Status: models.OrderStatusDraft,
+			OrderItems: []models.OrderItem{
+				{ItemId: 1, Quantity: 10, UnitPrice: eur("5"), LineTotal: eur("50")},
+				{ItemId: 99, Quantity: 1, UnitPrice: eur("7"), LineTotal: eur("7"), Item: &models.ItemSummary{ID: 99, Code: "old"}},
+			},
+		}, nil
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	mockOrder := models.Order{
+		Code: "ord1",
+		OrderItems: []models.OrderItem{
+			{ItemId: 1, Quantity: 4},
+			{ItemId: 99, Quantity: 2},
+			{ItemId: 3, Quantity: 1},
+		},
+	}
+
+	order, err := mockService.UpdateOrder(1, mockOrder, AnyVersion, mockOrderScope)
+	assert.Nil(t, err)
+	assert.Equal(t, eur("5"), order.OrderItems[0].UnitPrice)
+	assert.Equal(t, eur("20"), order.OrderItems[0].LineTotal)
+	assert.Equal(t, "itm1", order.OrderItems[0].Item.Code)
+	assert.Equal(t, eur("14"), order.OrderItems[1].LineTotal)
+	assert.Equal(t, "old", order.OrderItems[1].Item.Code)
+	assert.Equal(t, eur("29.99"), order.OrderItems[2].UnitPrice)
+}
+
+// TestCreateOrder_Totals tests that a new order is taxed at the rate of the category of its items and its discounts
+// are taken off its totals
+func TestCreateOrder_Totals(t *testing.T) {
+	mockService := NewOrderService(newMockOrderRepo(), newMockItemRepo(), mockTaxRates)
+
+	mockOrder := models.Order{
+		Code:        "ord3",
+		OrderItems:  []models.OrderItem{{ItemId: 2, Quantity: 10, DiscountPercent: 50}},
+		OrderTotals: models.OrderTotals{DiscountPercent: 20},
+	}
+
+	order, err := mockService.CreateOrder(mockOrder, mockOrderScope)
+	assert.Nil(t, err)
+	assert.Equal(t, 10.0, order.OrderItems[0].TaxPercent)
+	assert.Equal(t, eur("99.95"), order.OrderItems[0].LineTotal)
+	assert.Equal(t, models.OrderTotals{
+		Subtotal:        eur("99.95"),
+		DiscountPercent: 20,
+		DiscountAmount:  eur("19.99"),
+		TaxAmount:       eur("8"),
+		GrandTotal:      eur("87.96"),
+	}, order.OrderTotals)
+}
+
+// TestUpdateOrder_DiscountsLocked tests that the discounts of an order whose lines are locked can not be changed
+func TestUpdateOrder_DiscountsLocked(t *testing.T) {
+	mockService := NewOrderService(newMockOrderRepo(), newMockItemRepo(), mockTaxRates)
+
+	lines := []models.OrderItem{{ItemId: 3, Quantity: 30}, {ItemId: 4, Quantity: 40}}
+	_, err := mockService.UpdateOrder(2, models.Order{Code: "ord2", OrderItems: lines, OrderTotals: models.OrderTotals{DiscountPercent: 5}}, AnyVersion, mockOrderScope)
+	assert.Equal(t, errs.KindConflict, errs.KindOf(err))
+
+	lines = []models.OrderItem{{ItemId: 3, Quantity: 30, DiscountPercent: 5}, {ItemId: 4, Quantity: 40}}
+	_, err = mockService.UpdateOrder(2, models.Order{Code: "ord2", OrderItems: lines}, AnyVersion, mockOrderScope)
+	assert.Equal(t, errs.KindConflict, errs.KindOf(err))
+}
+
+// TestUpdateOrder_MissingItem tests that adding a line of an item that does not exist to an order is unprocessable
+func TestUpdateOrder_MissingItem(t *testing.T) {
+	mockService := NewOrderService(newMockOrderRepo(), newMockItemRepo(), mockTaxRates)
+
+	mockOrder := models.Order{
+		Code:       "ord1",
+		OrderItems: []models.OrderItem{{ItemId: 99, Quantity: 1}},
+	}
+
+	_, err := mockService.UpdateOrder(1, mockOrder, AnyVersion, mockOrderScope)
+	assert.Equal(t, errs.KindUnprocessable, errs.KindOf(err))
+}
+
+// newMockOrderRepoWithStatus returns a new mockOrderRepo whose orders are in the given status
+func newMockOrderRepoWithStatus(status models.OrderStatus) *mockOrderRepo {
+	repo := newMockOrderRepo()
+	repo.findByID = func(id int) (models.Order, error) {
+		order := mockOrders[id-1]
+		order.Status = status
+		return order, nil
+	}
+	return repo
+}
+
+// TestCanTransition tests the order transition table
+func TestCanTransition(t *testing.T) {
+	assert.True(t, canTransition(models.OrderStatusDraft, models.OrderStatusSubmitted))
+	assert.True(t, canTransition(models.OrderStatusPacked, models.OrderStatusShipped))
+	assert.True(t, canTransition(models.OrderStatusApproved, models.OrderStatusCancelled))
+	assert.False(t, canTransition(models.OrderStatusDraft, models.OrderStatusShipped))
+	assert.False(t, canTransition(models.OrderStatusShipped, models.OrderStatusCancelled))
+	assert.False(t, canTransition(models.OrderStatusCancelled, models.OrderStatusDraft))
+	assert.False(t, canTransition(models.OrderStatusDelivered, models.OrderStatusDelivered))
+}
+
+// TestTransitionOrder test the TransitionOrder function using mockOrderRepo
+func TestTransitionOrder(t *testing.T) {
+	var savedChange models.OrderStatusChange
+	mockOrderRepo := newMockOrderRepoWithStatus(models.OrderStatusDraft)
+	mockOrderRepo.saveTransition = func(order models.Order, change models.OrderStatusChange) (models.Order, error) {
+		savedChange = change
+		order.Status = change.ToStatus
+		return order, nil
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	order, err := mockService.TransitionOrder(1, models.OrderStatusSubmitted, mockOrderScope)
+	assert.Nil(t, err)
+	assert.Equal(t, models.OrderStatusSubmitted, order.Status)
+	assert.Equal(t, models.OrderStatusDraft, savedChange.FromStatus)
+	assert.Equal(t, models.OrderStatusSubmitted, savedChange.ToStatus)
+	assert.Equal(t, uint(7), savedChange.ChangedBy)
+	assert.False(t, savedChange.ChangedAt.IsZero())
+}
+
+// TestTransitionOrder_IllegalTransition test the TransitionOrder function with a move that is not in the transition table
+func TestTransitionOrder_IllegalTransition(t *testing.T) {
+	mockOrderRepo := newMockOrderRepoWithStatus(models.OrderStatusDraft)
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	order, err := mockService.TransitionOrder(1, models.OrderStatusShipped, mockOrderScope)
+	assert.NotNil(t, err)
+	assert.Equal(t, errs.KindConflict, errs.KindOf(err))
+	assert.Equal(t, models.OrderStatusDraft, order.Status)
+}
+
+// TestTransitionOrder_ConcurrentChange test the TransitionOrder function when the status changed in the meantime
+func TestTransitionOrder_ConcurrentChange(t *testing.T) {
+	mockOrderRepo := newMockOrderRepoWithStatus(models.OrderStatusDraft)
+	mockOrderRepo.saveTransition = func(order models.Order, change models.OrderStatusChange) (models.Order, error) {
+		return order, repositories.ErrOrderStatusChanged
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	_, err := mockService.TransitionOrder(1, models.OrderStatusSubmitted, mockOrderScope)
+	assert.NotNil(t, err)
+	assert.Equal(t, errs.KindConflict, errs.KindOf(err))
+}
+
+// TestTransitionOrder_FindByIdError test the TransitionOrder function using mockOrderErrorRepo
+func TestTransitionOrder_FindByIdError(t *testing.T) {
+	mockOrderRepo := newMockOrderErrorRepo()
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	_, err := mockService.TransitionOrder(1, models.OrderStatusSubmitted, mockOrderScope)
+	assert.NotNil(t, err)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
+}
+
+// TestTransitionOrder_SaveError test the TransitionOrder function using mockOrderSpecificErrorRepo
+func TestTransitionOrder_SaveError(t *testing.T) {
+	mockOrderRepo := newMockOrderSpecificErrorRepo()
+	mockOrderRepo.findByID = newMockOrderRepoWithStatus(models.OrderStatusPacked).findByID
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	_, err := mockService.TransitionOrder(1, models.OrderStatusShipped, mockOrderScope)
+	assert.NotNil(t, err)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+}
+
+// TestGetOrderHistory test the GetOrderHistory function using mockOrderRepo
+func TestGetOrderHistory(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	history, err := mockService.GetOrderHistory(1, models.Pagination{}, mockOrderScope)
+	assert.Nil(t, err)
+	assert.Equal(t, mockHistory, history.Data)
+	assert.Equal(t, int64(len(mockHistory)), *history.Total)
+}
+
+// TestGetOrderHistory_InvalidCursor test that the GetOrderHistory function rejects a cursor it did not make
+func TestGetOrderHistory_InvalidCursor(t *testing.T) {
+	mockService := NewOrderService(newMockOrderRepo(), newMockItemRepo(), mockTaxRates)
+
+	_, err := mockService.GetOrderHistory(1, models.Pagination{After: "not-a-cursor"}, mockOrderScope)
+	assert.ErrorIs(t, err, models.ErrInvalidCursor)
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
+}
+
+// TestGetOrderHistory_FindByIdError test the GetOrderHistory function using mockOrderErrorRepo
+func TestGetOrderHistory_FindByIdError(t *testing.T) {
+	mockOrderRepo := newMockOrderErrorRepo()
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	history, err := mockService.GetOrderHistory(1, models.Pagination{}, mockOrderScope)
+	assert.NotNil(t, err)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
+	assert.Nil(t, history.Data)
+}
+
+// TestCreateOrder_UserFromScope test that the CreateOrder function takes the owner from the scope and not from the request
+func TestCreateOrder_UserFromScope(t *testing.T) {
+	mockService := NewOrderService(newMockOrderRepo(), newMockItemRepo(), mockTaxRates)
+
+	order, err := mockService.CreateOrder(models.Order{Code: "ord3", UserID: 99}, models.OrderScope{UserID: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, order.UserID)
+	assert.Equal(t, uint(3), order.StatusHistory[0].ChangedBy)
+}
+
+// TestGetAllOrders_Scope test that the GetAllOrders function passes the scope to the repository
+func TestGetAllOrders_Scope(t *testing.T) {
+	var gotScope models.OrderScope
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.findAll = func(pagination models.Pagination, query models.ListQuery, scope models.OrderScope) ([]models.Order, int64, error) {
+		gotScope = scope
+		return nil, 0, nil
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	_, err := mockService.GetAllOrders(models.Pagination{}, models.ListQuery{}, models.OrderScope{UserID: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, models.OrderScope{UserID: 3}, gotScope)
+}
+
+// TestOrder_OutOfScope test that orders of other users are not found
+func TestOrder_OutOfScope(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.update = func(order models.Order) (models.Order, error) {
+		t.Fatal("order of another user should not be updated")
+		return order, nil
+	}
+	mockOrderRepo.delete = func(order models.Order) error {
+		t.Fatal("order of another user should not be deleted")
+		return nil
+	}
+	mockOrderRepo.saveTransition = func(order models.Order, change models.OrderStatusChange) (models.Order, error) {
+		t.Fatal("order of another user should not be transitioned")
+		return order, nil
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+	scope := models.OrderScope{UserID: 3}
+
+	order, err := mockService.GetOrder(1, scope)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
+	assert.Equal(t, models.Order{}, order)
+
+	_, err = mockService.UpdateOrder(1, models.Order{Code: "mine"}, AnyVersion, scope)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
+
+	_, err = mockService.DeleteOrder(1, AnyVersion, scope)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
+
+	_, err = mockService.TransitionOrder(1, models.OrderStatusSubmitted, scope)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
+
+	_, err = mockService.GetOrderHistory(1, models.Pagination{}, scope)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
+}
+
+// TestUpdateOrder_KeepsOwner test that the UpdateOrder function does not move an order to another user
+func TestUpdateOrder_KeepsOwner(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.findByID = func(id int) (models.Order, error) {
+		order := mockOrders[id-1]
+		order.UserID = 3
+		return order, nil
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	order, err := mockService.UpdateOrder(1, models.Order{Code: "mine", UserID: 99}, AnyVersion, models.OrderScope{UserID: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, order.UserID)
+}
+
+// TestUpdateOrder_Stale test that the UpdateOrder function refuses a change made to an older version of the order
+func TestUpdateOrder_Stale(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.findByID = func(id int) (models.Order, error) {
+		order := mockOrders[id-1]
+		order.Version = 5
+		return order, nil
+	}
+	mockOrderRepo.update = func(order models.Order) (models.Order, error) {
+		t.Fatal("a stale update must not be saved")
+		return order, nil
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	_, err := mockService.UpdateOrder(1, models.Order{Code: "ord1"}, 4, mockOrderScope)
+	assert.Equal(t, errs.KindStale, errs.KindOf(err))
+}
+
+// TestDeleteOrder_VersionChanged test that the DeleteOrder function reports an order changed while it was deleted as stale
+func TestDeleteOrder_VersionChanged(t *testing.T) {
+	mockOrderRepo := newMockOrderRepo()
+	mockOrderRepo.delete = func(order models.Order) error {
+		return repositories.ErrVersionChanged
+	}
+	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
+
+	_, err := mockService.DeleteOrder(1, AnyVersion, mockOrderScope)
+	assert.Equal(t, errs.KindStale, errs.KindOf(err))
+}
+
 //// TestCreateOrder test the CreateOrder function using mockOrderRepo and gin
 //func TestCreateOrder(t *testing.T) {
 //	mockOrderRepo := newMockOrderRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.POST("/orders", mockService.CreateOrder)
@@ -436,7 +1049,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestCreateOrder_BindError test the CreateOrder function using mockOrderRepo and gin
 //func TestCreateOrder_BindError(t *testing.T) {
 //	mockOrderRepo := newMockOrderRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.POST("/orders", mockService.CreateOrder)
@@ -459,7 +1072,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestCreateOrder_SaveError test the CreateOrder function using mockOrderRepo and gin
 //func TestCreateOrder_SaveError(t *testing.T) {
 //	mockOrderRepo := newMockOrderErrorRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.POST("/orders", mockService.CreateOrder)
@@ -497,7 +1110,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestGetAllOrders test the GetAllOrders function using mockOrderRepo and gin
 //func TestGetAllOrders(t *testing.T) {
 //	mockOrderRepo := newMockOrderRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.GET("/orders", mockService.GetAllOrders)
@@ -520,7 +1133,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestGetAllOrders_FindAllError test the GetAllOrders function using mockOrderRepo and gin
 //func TestGetAllOrders_FindAllError(t *testing.T) {
 //	mockOrderRepo := newMockOrderErrorRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.GET("/orders", mockService.GetAllOrders)
@@ -543,7 +1156,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestGetOrder test the GetOrder function using mockOrderRepo and gin
 //func TestGetOrder(t *testing.T) {
 //	mockOrderRepo := newMockOrderRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.GET("/orders/:id", mockService.GetOrder)
@@ -566,7 +1179,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestGetOrder_InvalidID test the GetOrder function using mockOrderRepo and gin
 //func TestGetOrder_InvalidID(t *testing.T) {
 //	mockOrderRepo := newMockOrderRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.GET("/orders/:id", mockService.GetOrder)
@@ -589,7 +1202,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestGetOrder_FindError test the GetOrder function using mockOrderRepo and gin
 //func TestGetOrder_FindError(t *testing.T) {
 //	mockOrderRepo := newMockOrderErrorRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.GET("/orders/:id", mockService.GetOrder)
@@ -612,7 +1225,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestUpdateOrder test the UpdateOrder function using mockOrderRepo and gin
 //func TestUpdateOrder(t *testing.T) {
 //	mockOrderRepo := newMockOrderRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.PUT("/orders/:id", mockService.UpdateOrder)
@@ -650,7 +1263,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestUpdateOrder_InvalidID test the UpdateOrder function using mockOrderRepo and gin
 //func TestUpdateOrder_InvalidID(t *testing.T) {
 //	mockOrderRepo := newMockOrderRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.PUT("/orders/:id", mockService.UpdateOrder)
@@ -688,7 +1301,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestUpdateOrder_FindError test the UpdateOrder function using mockOrderRepo and gin
 //func TestUpdateOrder_FindError(t *testing.T) {
 //	mockOrderRepo := newMockOrderErrorRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.PUT("/orders/:id", mockService.UpdateOrder)
@@ -726,7 +1339,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestUpdateOrder_BindError test the UpdateOrder function using mockOrderRepo and gin
 //func TestUpdateOrder_BindError(t *testing.T) {
 //	mockOrderRepo := newMockOrderRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.PUT("/orders/:id", mockService.UpdateOrder)
@@ -749,7 +1362,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestUpdateOrder_UpdateError test the UpdateOrder function using mockOrderRepo and gin
 //func TestUpdateOrder_UpdateError(t *testing.T) {
 //	mockOrderRepo := newMockOrderSpecificErrorRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.PUT("/orders/:id", mockService.UpdateOrder)
@@ -787,7 +1400,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestDeleteOrder test the DeleteOrder function using mockOrderRepo and gin
 //func TestDeleteOrder(t *testing.T) {
 //	mockOrderRepo := newMockOrderRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.DELETE("/orders/:id", mockService.DeleteOrder)
@@ -810,7 +1423,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestDeleteOrder_InvalidID test the DeleteOrder function using mockOrderRepo and gin
 //func TestDeleteOrder_InvalidID(t *testing.T) {
 //	mockOrderRepo := newMockOrderRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.DELETE("/orders/:id", mockService.DeleteOrder)
@@ -833,7 +1446,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestDeleteOrder_FindError test the DeleteOrder function using mockOrderRepo and gin
 //func TestDeleteOrder_FindError(t *testing.T) {
 //	mockOrderRepo := newMockOrderErrorRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.DELETE("/orders/:id", mockService.DeleteOrder)
@@ -856,7 +1469,7 @@ func TestDeleteOrder_DeleteError(t *testing.T) {
 //// TestDeleteOrder_DeleteError test the DeleteOrder function using mockOrderRepo and gin
 //func TestDeleteOrder_DeleteError(t *testing.T) {
 //	mockOrderRepo := newMockOrderSpecificErrorRepo()
-//	mockService := NewOrderService(mockOrderRepo)
+//	mockService := NewOrderService(mockOrderRepo, newMockItemRepo(), mockTaxRates)
 //
 //	r := gin.Default()
 //	r.DELETE("/orders/:id", mockService.DeleteOrder)
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/repositories"
+	"github.com/peteprogrammer/go-automapper"
+	"sort"
+)
+
+// PlanningService interface for truck load planning
+type PlanningService interface {
+	PlanLoads(request models.LoadPlanRequest) (models.LoadPlan, error)
+}
+
+// planningService struct
+type planningService struct {
+	OrderRepo repositories.OrderRepo
+	ItemRepo  repositories.ItemRepo
+	TruckRepo repositories.TruckRepo
+}
+
+// NewPlanningService returns a new instance of planningService
+func NewPlanningService(orderRepo repositories.OrderRepo, itemRepo repositories.ItemRepo, truckRepo repositories.TruckRepo) PlanningService {
+	return planningService{
+		OrderRepo: orderRepo,
+		ItemRepo:  itemRepo,
+		TruckRepo: truckRepo,
+	}
+}
+
+// orderLoad is the weight and volume an order takes on a truck
+type orderLoad struct {
+	OrderID uint
+	Weight  float64
+	Volume  float64
+}
+
+// truckBin is a truck being filled by the planner
+type truckBin struct {
+	truck  models.Truck
+	orders []uint
+	weight float64
+	volume float64
+}
+
+// fits checks if the load still fits in the remaining capacity of the truck
+func (b *truckBin) fits(load orderLoad) bool {
+	return b.weight+load.Weight <= b.truck.MaxWeight && b.volume+load.Volume <= b.truck.MaxVolume
+}
+
+// slackAfter returns the free capacity the truck would have left after taking the load, as the larger of its weight and volume share
+func (b *truckBin) slackAfter(load orderLoad) float64 {
+	weightSlack := 1 - (b.weight+load.Weight)/b.truck.MaxWeight
+	volumeSlack := 1 - (b.volume+load.Volume)/b.truck.MaxVolume
+	if weightSlack > volumeSlack {
+		return weightSlack
+	}
+	return volumeSlack
+}
+
+// PlanLoads method that takes a set of orders and assigns them to the available trucks without exceeding their weight or volume capacity
+func (p planningService) PlanLoads(request models.LoadPlanRequest) (models.LoadPlan, error) {
+	// get the orders and work out the weight and volume of each one from its items
+	// get the trucks that are in service, limited to the requested ones if any
+	// pack the orders on the trucks
+	if len(request.OrderIDs) == 0 {
+		return models.LoadPlan{}, errs.Validation(errors.New("at least one order is required"))
+	}
+	var orders []models.Order
+	unplanned := []models.UnplannedOrder{}
+	for _, id := range request.OrderIDs {
+		order, err := p.OrderRepo.FindByID(id)
+		if err != nil {
+			return models.LoadPlan{}, notFoundError(fmt.Errorf("order %d: %w", id, err))
+		}
+		if !order.Status.Plannable() {
+			unplanned = append(unplanned, models.UnplannedOrder{OrderID: order.ID, Reason: fmt.Sprintf("order is %s", order.Status)})
+			continue
+		}
+		orders = append(orders, order)
+	}
+	loads, unsized, err := p.orderLoads(orders)
+	if err != nil {
+		return models.LoadPlan{}, err
+	}
+	unplanned = append(unplanned, unsized...)
+	trucks, err := p.availableTrucks(request.TruckIDs)
+	if err != nil {
+		return models.LoadPlan{}, err
+	}
+	plan := planLoads(loads, trucks)
+	plan.Unplanned = append(unplanned, plan.Unplanned...)
+	return plan, nil
+}
+
+// orderLoads works out the weight and volume of every order from the unit weight and volume of its items. An order with
+// no lines or with an item that is missing or has no unit weight or volume can not be sized, it is returned as unplanned
+// with the reason
+func (p planningService) orderLoads(orders []models.Order) ([]orderLoad, []models.UnplannedOrder, error) {
+	idSet := make(map[int]bool)
+	for _, order := range orders {
+		for _, line := range order.OrderItems {
+			idSet[line.ItemId] = true
+		}
+	}
+	ids := make([]int, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+	items, err := p.ItemRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	itemsByID := make(map[int]models.Item, len(items))
+	for _, item := range items {
+		itemsByID[int(item.ID)] = item
+	}
+	loads := make([]orderLoad, 0, len(orders))
+	var unplanned []models.UnplannedOrder
+	for _, order := range orders {
+		load := orderLoad{OrderID: order.ID}
+		reason := ""
+		if len(order.OrderItems) == 0 {
+			reason = "order has no lines"
+		}
+		for _, line := range order.OrderItems {
+			item, found := itemsByID[line.ItemId]
+			if !found {
+				reason = fmt.Sprintf("item %d is missing", line.ItemId)
+				break
+			}
+			if item.UnitWeight <= 0 || item.UnitVolume <= 0 {
+				reason = fmt.Sprintf("item %d has no unit weight or volume", line.ItemId)
+				break
+			}
+			load.Weight += item.UnitWeight * float64(line.Quantity)
+			load.Volume += item.UnitVolume * float64(line.Quantity)
+		}
+		if reason != "" {
+			unplanned = append(unplanned, models.UnplannedOrder{OrderID: order.ID, Reason: reason})
+			continue
+		}
+		loads = append(loads, load)
+	}
+	return loads, unplanned, nil
+}
+
+// availableTrucks returns the trucks that are in service and have a capacity, limited to the given ids if there are any
+func (p planningService) availableTrucks(ids []int) ([]models.Truck, error) {
+	trucks, err := p.TruckRepo.FindInService()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		wanted[uint(id)] = true
+	}
+	available := make([]models.Truck, 0, len(trucks))
+	for _, truck := range trucks {
+		if truck.OutOfService || truck.MaxWeight <= 0 || truck.MaxVolume <= 0 {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[truck.ID] {
+			continue
+		}
+		available = append(available, truck)
+	}
+	return available, nil
+}
+
+// planLoads packs the orders on the trucks with a best-fit decreasing heuristic.
+// The largest orders are placed first, each one on the truck already in use that it leaves the least room in,
+// and only when none of those can take it on the smallest unused truck that can.
+func planLoads(loads []orderLoad, trucks []models.Truck) models.LoadPlan {
+	// size the orders relative to the largest truck so weight and volume count the same
+	// sort the orders from the largest to the smallest
+	// sort the trucks from the smallest to the largest
+	// place every order
+	var maxWeight, maxVolume float64
+	for _, truck := range trucks {
+		if truck.MaxWeight > maxWeight {
+			maxWeight = truck.MaxWeight
+		}
+		if truck.MaxVolume > maxVolume {
+			maxVolume = truck.MaxVolume
+		}
+	}
+	size := func(load orderLoad) float64 {
+		if maxWeight == 0 || maxVolume == 0 {
+			return 0
+		}
+		weightShare := load.Weight / maxWeight
+		volumeShare := load.Volume / maxVolume
+		if weightShare > volumeShare {
+			return weightShare
+		}
+		return volumeShare
+	}
+	sorted := append([]orderLoad(nil), loads...)
+	sort.SliceStable(sorted, func(i, j int) bool { return size(sorted[i]) > size(sorted[j]) })
+	bins := make([]*truckBin, len(trucks))
+	for i, truck := range trucks {
+		bins[i] = &truckBin{truck: truck}
+	}
+	sort.SliceStable(bins, func(i, j int) bool {
+		return bins[i].truck.MaxWeight*bins[i].truck.MaxVolume < bins[j].truck.MaxWeight*bins[j].truck.MaxVolume
+	})
+
+	plan := models.LoadPlan{Loads: []models.TruckLoad{}, Unplanned: []models.UnplannedOrder{}}
+	for _, load := range sorted {
+		var best *truckBin
+		for _, bin := range bins {
+			if len(bin.orders) == 0 || !bin.fits(load) {
+				continue
+			}
+			if best == nil || bin.slackAfter(load) < best.slackAfter(load) {
+				best = bin
+			}
+		}
+		if best == nil {
+			for _, bin := range bins {
+				if len(bin.orders) == 0 && bin.fits(load) {
+					best = bin
+					break
+				}
+			}
+		}
+		if best == nil {
+			reason := "larger than any available truck"
+			for _, truck := range trucks {
+				if load.Weight <= truck.MaxWeight && load.Volume <= truck.MaxVolume {
+					reason = "does not fit in the remaining capacity of the available trucks"
+					break
+				}
+			}
+			plan.Unplanned = append(plan.Unplanned, models.UnplannedOrder{OrderID: load.OrderID, Weight: load.Weight, Volume: load.Volume, Reason: reason})
+			continue
+		}
+		best.orders = append(best.orders, load.OrderID)
+		best.weight += load.Weight
+		best.volume += load.Volume
+	}
+	for _, bin := range bins {
+		if len(bin.orders) == 0 {
+			continue
+		}
+		var truckDTO models.TruckDTO
+		automapper.Map(bin.truck, &truckDTO)
+		plan.Loads = append(plan.Loads, models.TruckLoad{
+			Truck:             truckDTO,
+			OrderIDs:          bin.orders,
+			Weight:            bin.weight,
+			Volume:            bin.volume,
+			WeightUtilization: bin.weight / bin.truck.MaxWeight,
+			VolumeUtilization: bin.volume / bin.truck.MaxVolume,
+		})
+	}
+	return plan
+}
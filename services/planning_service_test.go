@@ -0,0 +1,171 @@
+package services
+
+import (
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+)
+
+var planTrucks = []models.Truck{
+	{Model: gorm.Model{ID: 1}, LicensePlate: "AA111AA", MaxWeight: 1000, MaxVolume: 10},
+	{Model: gorm.Model{ID: 2}, LicensePlate: "AA222AA", MaxWeight: 3000, MaxVolume: 30},
+	{Model: gorm.Model{ID: 3}, LicensePlate: "AA333AA", MaxWeight: 5000, MaxVolume: 50, OutOfService: true},
+}
+
+// newMockPlanningRepos returns the order, item and truck mock repositories used by the planning tests,
+// every order has one line of item 1 whose quantity is ten times the order id
+func newMockPlanningRepos(status models.OrderStatus) (*mockOrderRepo, *mockItemRepo, *mockTruckRepo) {
+	orderRepo := newMockOrderRepo()
+	orderRepo.findByID = func(id int) (models.Order, error) {
+		return models.Order{
+			Model:      gorm.Model{ID: uint(id)},
+			Status:     status,
+			OrderItems: []models.OrderItem{{ItemId: 1, Quantity: 10 * id}},
+		}, nil
+	}
+	itemRepo := newMockItemRepo()
+	itemRepo.findByIDs = func(ids []int) ([]models.Item, error) {
+		return []models.Item{{Model: gorm.Model{ID: 1}, UnitWeight: 10, UnitVolume: 0.1}}, nil
+	}
+	truckRepo := newMockTruckRepo()
+	truckRepo.findInService = func() ([]models.Truck, error) {
+		return planTrucks, nil
+	}
+	return orderRepo, itemRepo, truckRepo
+}
+
+// TestPlanLoads tests services.PlanLoads using the mock repositories
+func TestPlanLoads(t *testing.T) {
+	mockService := NewPlanningService(newMockPlanningRepos(models.OrderStatusApproved))
+
+	plan, err := mockService.PlanLoads(models.LoadPlanRequest{OrderIDs: []int{1, 2, 5}})
+	assert.NoError(t, err, "should not return error")
+	assert.Len(t, plan.Loads, 1, "should use one truck")
+	assert.Equal(t, uint(1), plan.Loads[0].Truck.ID, "should use the smallest truck the orders fit on")
+	assert.Equal(t, []uint{5, 2, 1}, plan.Loads[0].OrderIDs, "should place the largest orders first")
+	assert.InDelta(t, 800, plan.Loads[0].Weight, 0.0001, "should add up the weight of the orders")
+	assert.InDelta(t, 8, plan.Loads[0].Volume, 0.0001, "should add up the volume of the orders")
+	assert.Empty(t, plan.Unplanned, "should plan every order")
+}
+func TestPlanLoads_NoOrders(t *testing.T) {
+	mockService := NewPlanningService(newMockPlanningRepos(models.OrderStatusApproved))
+
+	_, err := mockService.PlanLoads(models.LoadPlanRequest{})
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err), "should return a validation error")
+}
+func TestPlanLoads_OrderNotFound(t *testing.T) {
+	orderRepo, itemRepo, truckRepo := newMockPlanningRepos(models.OrderStatusApproved)
+	orderRepo.findByID = func(id int) (models.Order, error) {
+		return models.Order{}, gorm.ErrRecordNotFound
+	}
+	mockService := NewPlanningService(orderRepo, itemRepo, truckRepo)
+
+	_, err := mockService.PlanLoads(models.LoadPlanRequest{OrderIDs: []int{1}})
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err), "should return a not found error")
+}
+func TestPlanLoads_NotPlannable(t *testing.T) {
+	mockService := NewPlanningService(newMockPlanningRepos(models.OrderStatusShipped))
+
+	plan, err := mockService.PlanLoads(models.LoadPlanRequest{OrderIDs: []int{1}})
+	assert.NoError(t, err, "should not return error")
+	assert.Empty(t, plan.Loads, "should not use any truck")
+	assert.Equal(t, []models.UnplannedOrder{{OrderID: 1, Reason: "order is shipped"}}, plan.Unplanned, "should report the order")
+}
+func TestPlanLoads_MissingItem(t *testing.T) {
+	orderRepo, itemRepo, truckRepo := newMockPlanningRepos(models.OrderStatusApproved)
+	itemRepo.findByIDs = func(ids []int) ([]models.Item, error) {
+		return []models.Item{}, nil
+	}
+	mockService := NewPlanningService(orderRepo, itemRepo, truckRepo)
+
+	plan, err := mockService.PlanLoads(models.LoadPlanRequest{OrderIDs: []int{1}})
+	assert.NoError(t, err, "should not return error")
+	assert.Empty(t, plan.Loads, "should not put the order on a truck")
+	assert.Equal(t, []models.UnplannedOrder{{OrderID: 1, Reason: "item 1 is missing"}}, plan.Unplanned, "should report the order")
+}
+func TestPlanLoads_MissingDimensions(t *testing.T) {
+	orderRepo, itemRepo, truckRepo := newMockPlanningRepos(models.OrderStatusApproved)
+	itemRepo.findByIDs = func(ids []int) ([]models.Item, error) {
+		return []models.Item{{Model: gorm.Model{ID: 1}, UnitWeight: 10}}, nil
+	}
+	mockService := NewPlanningService(orderRepo, itemRepo, truckRepo)
+
+	plan, err := mockService.PlanLoads(models.LoadPlanRequest{OrderIDs: []int{1}})
+	assert.NoError(t, err, "should not return error")
+	assert.Empty(t, plan.Loads, "should not put the order on a truck")
+	assert.Equal(t, []models.UnplannedOrder{{OrderID: 1, Reason: "item 1 has no unit weight or volume"}}, plan.Unplanned, "should report the order")
+}
+func TestPlanLoads_NoLines(t *testing.T) {
+	orderRepo, itemRepo, truckRepo := newMockPlanningRepos(models.OrderStatusApproved)
+	orderRepo.findByID = func(id int) (models.Order, error) {
+		return models.Order{Model: gorm.Model{ID: uint(id)}, Status: models.OrderStatusApproved}, nil
+	}
+	mockService := NewPlanningService(orderRepo, itemRepo, truckRepo)
+
+	plan, err := mockService.PlanLoads(models.LoadPlanRequest{OrderIDs: []int{1}})
+	assert.NoError(t, err, "should not return error")
+	assert.Empty(t, plan.Loads, "should not put the order on a truck")
+	assert.Equal(t, []models.UnplannedOrder{{OrderID: 1, Reason: "order has no lines"}}, plan.Unplanned, "should report the order")
+}
+func TestPlanLoads_RequestedTrucks(t *testing.T) {
+	mockService := NewPlanningService(newMockPlanningRepos(models.OrderStatusApproved))
+
+	plan, err := mockService.PlanLoads(models.LoadPlanRequest{OrderIDs: []int{20}, TruckIDs: []int{1, 3}})
+	assert.NoError(t, err, "should not return error")
+	assert.Empty(t, plan.Loads, "should not use a truck that was not requested or is out of service")
+	assert.Len(t, plan.Unplanned, 1, "should report the order")
+	assert.Equal(t, "larger than any available truck", plan.Unplanned[0].Reason, "should say the order is too large")
+}
+func TestPlanLoads_ItemError(t *testing.T) {
+	orderRepo, _, truckRepo := newMockPlanningRepos(models.OrderStatusApproved)
+	mockService := NewPlanningService(orderRepo, newMockItemErrorRepo(), truckRepo)
+
+	_, err := mockService.PlanLoads(models.LoadPlanRequest{OrderIDs: []int{1}})
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err), "should return an internal error")
+}
+func TestPlanLoads_TruckError(t *testing.T) {
+	orderRepo, itemRepo, _ := newMockPlanningRepos(models.OrderStatusApproved)
+	mockService := NewPlanningService(orderRepo, itemRepo, newMockTruckErrorRepo())
+
+	_, err := mockService.PlanLoads(models.LoadPlanRequest{OrderIDs: []int{1}})
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err), "should return an internal error")
+}
+
+// TestPlanLoadsBestFit tests that planLoads fills the trucks in use before opening new ones and reports what does not fit
+func TestPlanLoadsBestFit(t *testing.T) {
+	loads := []orderLoad{
+		{OrderID: 1, Weight: 600, Volume: 2},
+		{OrderID: 2, Weight: 600, Volume: 2},
+		{OrderID: 3, Weight: 300, Volume: 8},
+		{OrderID: 4, Weight: 200, Volume: 1},
+		{OrderID: 5, Weight: 4000, Volume: 1},
+	}
+	trucks := []models.Truck{planTrucks[1], planTrucks[0]}
+
+	plan := planLoads(loads, trucks)
+	assert.Len(t, plan.Loads, 2, "should use both trucks")
+	for _, load := range plan.Loads {
+		truck := planTrucks[load.Truck.ID-1]
+		assert.LessOrEqual(t, load.Weight, truck.MaxWeight, "should not overload the weight of a truck")
+		assert.LessOrEqual(t, load.Volume, truck.MaxVolume, "should not overload the volume of a truck")
+	}
+	assert.Equal(t, []models.UnplannedOrder{{OrderID: 5, Weight: 4000, Volume: 1, Reason: "larger than any available truck"}}, plan.Unplanned, "should report the order that fits no truck")
+}
+func TestPlanLoadsBestFit_NoCapacity(t *testing.T) {
+	loads := []orderLoad{
+		{OrderID: 1, Weight: 700, Volume: 1},
+		{OrderID: 2, Weight: 700, Volume: 1},
+	}
+
+	plan := planLoads(loads, planTrucks[:1])
+	assert.Len(t, plan.Loads, 1, "should use the truck")
+	assert.Equal(t, []uint{1}, plan.Loads[0].OrderIDs, "should load the first order")
+	assert.InDelta(t, 0.7, plan.Loads[0].WeightUtilization, 0.0001, "should report the weight utilization")
+	assert.Equal(t, "does not fit in the remaining capacity of the available trucks", plan.Unplanned[0].Reason, "should say the trucks are full")
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"github.com/laertkokona/crud-test/models"
+)
+
+// priceOrder computes the discounts, taxes and totals of an order from the unit prices, discounts and tax percents of
+// its lines and the discount of the order. The discount of a line comes off its price first, the discount of the order
+// then comes off what is left of every line, and every line is taxed at its own percent on what is left after both.
+// Every amount is rounded to cents on its line, discounts down and the rest half up, and the totals are the sums of the
+// rounded amounts, so an invoice adds up. The lines must be priced in one currency, which the totals are in
+func priceOrder(order *models.Order) {
+	currency := ""
+	for _, line := range order.OrderItems {
+		if line.UnitPrice.Currency != "" {
+			currency = line.UnitPrice.Currency
+			break
+		}
+	}
+	zero := models.NewMoney(0, currency)
+	totals := models.OrderTotals{
+		Subtotal:        zero,
+		DiscountPercent: order.DiscountPercent,
+		DiscountAmount:  zero,
+		TaxAmount:       zero,
+	}
+	for i := range order.OrderItems {
+		line := &order.OrderItems[i]
+		line.UnitPrice.Currency = currency
+		gross := line.UnitPrice.Times(line.Quantity).Round(models.RoundHalfUp)
+		line.DiscountAmount = gross.Percent(line.DiscountPercent, models.RoundDown)
+		line.LineTotal = gross.Sub(line.DiscountAmount)
+		orderDiscount := line.LineTotal.Percent(order.DiscountPercent, models.RoundDown)
+		line.TaxAmount = line.LineTotal.Sub(orderDiscount).Percent(line.TaxPercent, models.RoundHalfUp)
+
+		totals.Subtotal = totals.Subtotal.Add(line.LineTotal)
+		totals.DiscountAmount = totals.DiscountAmount.Add(orderDiscount)
+		totals.TaxAmount = totals.TaxAmount.Add(line.TaxAmount)
+	}
+	totals.GrandTotal = totals.Subtotal.Sub(totals.DiscountAmount).Add(totals.TaxAmount)
+	order.OrderTotals = totals
+}
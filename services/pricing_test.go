@@ -0,0 +1,91 @@
+package services
+
+import (
+	"github.com/laertkokona/crud-test/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestPriceOrder tests that the discounts of the lines come off first, the discount of the order comes off what is left
+// and every line is taxed at its own percent, rounded to cents line by line
+func TestPriceOrder(t *testing.T) {
+	order := models.Order{
+		OrderItems: []models.OrderItem{
+			{ItemId: 1, Quantity: 3, UnitPrice: eur("9.99"), DiscountPercent: 10, TaxPercent: 20},
+			{ItemId: 2, Quantity: 2, UnitPrice: eur("20"), TaxPercent: 5},
+		},
+		OrderTotals: models.OrderTotals{DiscountPercent: 10},
+	}
+
+	priceOrder(&order)
+
+	assert.Equal(t, eur("2.99"), order.OrderItems[0].DiscountAmount)
+	assert.Equal(t, eur("26.98"), order.OrderItems[0].LineTotal)
+	assert.Equal(t, eur("4.86"), order.OrderItems[0].TaxAmount)
+	assert.Equal(t, eur("0"), order.OrderItems[1].DiscountAmount)
+	assert.Equal(t, eur("40"), order.OrderItems[1].LineTotal)
+	assert.Equal(t, eur("1.80"), order.OrderItems[1].TaxAmount)
+	assert.Equal(t, models.OrderTotals{
+		Subtotal:        eur("66.98"),
+		DiscountPercent: 10,
+		DiscountAmount:  eur("6.69"),
+		TaxAmount:       eur("6.66"),
+		GrandTotal:      eur("66.95"),
+	}, order.OrderTotals)
+}
+
+// TestPriceOrder_Rounding tests that discounts are rounded down and taxes and line amounts half up
+func TestPriceOrder_Rounding(t *testing.T) {
+	order := models.Order{
+		OrderItems: []models.OrderItem{
+			{ItemId: 1, Quantity: 3, UnitPrice: eur("0.3333"), TaxPercent: 25},
+			{ItemId: 2, Quantity: 1, UnitPrice: eur("0.10"), DiscountPercent: 25, TaxPercent: 50},
+		},
+	}
+
+	priceOrder(&order)
+
+	assert.Equal(t, eur("1.00"), order.OrderItems[0].LineTotal)
+	assert.Equal(t, eur("0.25"), order.OrderItems[0].TaxAmount)
+	assert.Equal(t, eur("0.02"), order.OrderItems[1].DiscountAmount)
+	assert.Equal(t, eur("0.08"), order.OrderItems[1].LineTotal)
+	assert.Equal(t, eur("0.04"), order.OrderItems[1].TaxAmount)
+	assert.Equal(t, eur("1.37"), order.GrandTotal)
+}
+
+// TestPriceOrder_NoLines tests that an order without lines has no totals but keeps its discount
+func TestPriceOrder_NoLines(t *testing.T) {
+	order := models.Order{OrderTotals: models.OrderTotals{Subtotal: eur("10"), DiscountPercent: 5, GrandTotal: eur("10")}}
+
+	priceOrder(&order)
+
+	assert.Equal(t, models.OrderTotals{DiscountPercent: 5}, order.OrderTotals)
+}
+
+// TestPriceOrder_FullDiscount tests that a line given away is neither charged nor taxed
+func TestPriceOrder_FullDiscount(t *testing.T) {
+	order := models.Order{
+		OrderItems: []models.OrderItem{{ItemId: 1, Quantity: 1, UnitPrice: eur("9.99"), DiscountPercent: 100, TaxPercent: 20}},
+	}
+
+	priceOrder(&order)
+
+	assert.Equal(t, eur("9.99"), order.OrderItems[0].DiscountAmount)
+	assert.Equal(t, eur("0"), order.OrderItems[0].TaxAmount)
+	assert.Equal(t, eur("0"), order.GrandTotal)
+}
+
+// TestPriceOrder_FreeItems tests that lines of items without a price are priced in the currency of the other lines
+func TestPriceOrder_FreeItems(t *testing.T) {
+	order := models.Order{
+		OrderItems: []models.OrderItem{
+			{ItemId: 1, Quantity: 1},
+			{ItemId: 2, Quantity: 1, UnitPrice: eur("5")},
+		},
+	}
+
+	priceOrder(&order)
+
+	assert.Equal(t, eur("0"), order.OrderItems[0].LineTotal)
+	assert.Equal(t, eur("5"), order.GrandTotal)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/repositories"
+	"sync"
+	"time"
+)
+
+// RevocationService interface for revoking tokens before they expire
+type RevocationService interface {
+	Revoke(tokenID string, userID uint, expiresAt time.Time) error
+	RevokeAll(userID uint) error
+	IsRevoked(tokenID string, userID uint, issuedAt time.Time) bool
+}
+
+// revocationService struct that keeps the revocations in memory and reloads them from the database
+// every refresh interval, so revocations made by other instances are picked up as well
+type revocationService struct {
+	RevocationRepo repositories.RevocationRepo
+	refresh        time.Duration
+	tokenTTL       time.Duration
+	now            func() time.Time
+
+	mu       sync.RWMutex
+	loadedAt time.Time
+	tokens   map[string]time.Time
+	cutoffs  map[uint]time.Time
+}
+
+// NewRevocationService returns a new instance of revocationService that reloads its cache every refresh interval
+// and forgets the revocations of users once every access token issued before them has expired
+func NewRevocationService(revocationRepo repositories.RevocationRepo, refresh time.Duration, tokenTTL time.Duration) RevocationService {
+	return &revocationService{
+		RevocationRepo: revocationRepo,
+		refresh:        refresh,
+		tokenTTL:       tokenTTL,
+		now:            time.Now,
+		tokens:         make(map[string]time.Time),
+		cutoffs:        make(map[uint]time.Time),
+	}
+}
+
+// Revoke method that revokes a single token until it expires
+func (r *revocationService) Revoke(tokenID string, userID uint, expiresAt time.Time) error {
+	err := r.RevocationRepo.SaveToken(models.RevokedToken{TokenID: tokenID, UserID: userID, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.tokens[tokenID] = expiresAt
+	r.mu.Unlock()
+	return nil
+}
+
+// RevokeAll method that revokes every token issued to a user before now
+func (r *revocationService) RevokeAll(userID uint) error {
+	// token issue times and the stored cutoffs are to the microsecond
+	cutoff := r.now().Truncate(time.Microsecond)
+	err := r.RevocationRepo.SaveCutoff(models.TokenCutoff{UserID: userID, RevokedBefore: cutoff})
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.cutoffs[userID] = cutoff
+	r.mu.Unlock()
+	return nil
+}
+
+// IsRevoked method that reports whether a token was revoked on its own or by its user signing out everywhere
+func (r *revocationService) IsRevoked(tokenID string, userID uint, issuedAt time.Time) bool {
+	r.reloadIfStale()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.tokens[tokenID]; ok && tokenID != "" {
+		return true
+	}
+	cutoff, ok := r.cutoffs[userID]
+	return ok && issuedAt.Before(cutoff)
+}
+
+// reloadIfStale replaces the cache with the revocations in the database once the refresh interval has passed. The reload
+// is claimed before the database is read, so only one check reads it, and on a database error the current cache is kept
+// until the next interval instead of the database being read again on every check
+func (r *revocationService) reloadIfStale() {
+	now := r.now()
+	r.mu.Lock()
+	stale := now.Sub(r.loadedAt) >= r.refresh
+	if stale {
+		r.loadedAt = now
+	}
+	r.mu.Unlock()
+	if !stale {
+		return
+	}
+	// drop what can no longer matter, then load what is left
+	_ = r.RevocationRepo.DeleteExpired(now, now.Add(-r.tokenTTL))
+	tokens, cutoffs, err := r.RevocationRepo.FindActive(now)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens = make(map[string]time.Time, len(tokens))
+	for _, token := range tokens {
+		r.tokens[token.TokenID] = token.ExpiresAt
+	}
+	r.cutoffs = make(map[uint]time.Time, len(cutoffs))
+	for _, cutoff := range cutoffs {
+		r.cutoffs[cutoff.UserID] = cutoff.RevokedBefore
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"errors"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// mockRevocationRepo is a mock implementation of the repositories.RevocationRepo interface
+type mockRevocationRepo struct {
+	// saveToken is a mock function with given fields: token
+	saveToken func(token models.RevokedToken) error
+	// saveCutoff is a mock function with given fields: cutoff
+	saveCutoff func(cutoff models.TokenCutoff) error
+	// findActive is a mock function with given fields: now
+	findActive func(now time.Time) ([]models.RevokedToken, []models.TokenCutoff, error)
+	// deleteExpired is a mock function with given fields: now, cutoffsBefore
+	deleteExpired func(now time.Time, cutoffsBefore time.Time) error
+}
+
+// SaveToken is a mock function with given fields: token
+func (_m *mockRevocationRepo) SaveToken(token models.RevokedToken) error {
+	return _m.saveToken(token)
+}
+
+// SaveCutoff is a mock function with given fields: cutoff
+func (_m *mockRevocationRepo) SaveCutoff(cutoff models.TokenCutoff) error {
+	return _m.saveCutoff(cutoff)
+}
+
+// FindActive is a mock function with given fields: now
+func (_m *mockRevocationRepo) FindActive(now time.Time) ([]models.RevokedToken, []models.TokenCutoff, error) {
+	return _m.findActive(now)
+}
+
+// DeleteExpired is a mock function with given fields: now, cutoffsBefore
+func (_m *mockRevocationRepo) DeleteExpired(now time.Time, cutoffsBefore time.Time) error {
+	return _m.deleteExpired(now, cutoffsBefore)
+}
+
+// newMockRevocationRepo returns a new instance of the mockRevocationRepo that keeps what it saves in memory
+func newMockRevocationRepo() *mockRevocationRepo {
+	var tokens []models.RevokedToken
+	var cutoffs []models.TokenCutoff
+	return &mockRevocationRepo{
+		saveToken: func(token models.RevokedToken) error {
+			tokens = append(tokens, token)
+			return nil
+		},
+		saveCutoff: func(cutoff models.TokenCutoff) error {
+			cutoffs = append(cutoffs, cutoff)
+			return nil
+		},
+		findActive: func(now time.Time) ([]models.RevokedToken, []models.TokenCutoff, error) {
+			var active []models.RevokedToken
+			for _, token := range tokens {
+				if token.ExpiresAt.After(now) {
+					active = append(active, token)
+				}
+			}
+			return active, cutoffs, nil
+		},
+		deleteExpired: func(now time.Time, cutoffsBefore time.Time) error {
+			return nil
+		},
+	}
+}
+
+// newMockRevocationErrorRepo returns a new instance of the mockRevocationRepo with error
+func newMockRevocationErrorRepo() *mockRevocationRepo {
+	return &mockRevocationRepo{
+		saveToken: func(token models.RevokedToken) error {
+			return errors.New("error")
+		},
+		saveCutoff: func(cutoff models.TokenCutoff) error {
+			return errors.New("error")
+		},
+		findActive: func(now time.Time) ([]models.RevokedToken, []models.TokenCutoff, error) {
+			return nil, nil, errors.New("error")
+		},
+		deleteExpired: func(now time.Time, cutoffsBefore time.Time) error {
+			return errors.New("error")
+		},
+	}
+}
+
+// TestRevoke tests services.Revoke using mockRevocationRepo
+func TestRevoke(t *testing.T) {
+	mockService := NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL)
+
+	err := mockService.Revoke("token-1", 1, time.Now().Add(time.Hour))
+	assert.NoError(t, err, "should not return error")
+	assert.True(t, mockService.IsRevoked("token-1", 1, time.Now()), "should revoke the token")
+	assert.False(t, mockService.IsRevoked("", 1, time.Now()), "should not revoke tokens without an id")
+}
+func TestRevoke_SaveError(t *testing.T) {
+	mockService := NewRevocationService(newMockRevocationErrorRepo(), time.Minute, mockTokens.AccessTTL)
+
+	err := mockService.Revoke("token-1", 1, time.Now().Add(time.Hour))
+	assert.Error(t, err, "should return error")
+	assert.False(t, mockService.IsRevoked("token-1", 1, time.Now()), "should not revoke a token that was not saved")
+}
+
+// TestRevokeAll tests services.RevokeAll using mockRevocationRepo
+func TestRevokeAll(t *testing.T) {
+	mockService := NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL)
+
+	err := mockService.RevokeAll(1)
+	assert.NoError(t, err, "should not return error")
+	assert.True(t, mockService.IsRevoked("token-1", 1, time.Now().Truncate(time.Second)), "should revoke the tokens issued up to now")
+	assert.False(t, mockService.IsRevoked("token-1", 1, time.Now().Add(2*time.Second)), "should not revoke later tokens")
+}
+func TestRevokeAll_ReissuedInSameSecond(t *testing.T) {
+	mockService := NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL).(*revocationService)
+	now := time.Date(2026, 10, 17, 9, 30, 0, int(400*time.Millisecond), time.UTC)
+	mockService.now = func() time.Time { return now }
+
+	err := mockService.RevokeAll(1)
+	assert.NoError(t, err, "should not return error")
+	assert.True(t, mockService.IsRevoked("token-1", 1, now.Add(-300*time.Millisecond)), "should revoke the tokens issued earlier in the second")
+	assert.True(t, mockService.IsRevoked("token-1", 1, now.Truncate(time.Second)), "should revoke the tokens issued in whole seconds")
+	assert.False(t, mockService.IsRevoked("token-2", 1, now.Add(300*time.Millisecond)), "should not revoke the token issued after it in the same second")
+}
+func TestRevokeAll_SaveError(t *testing.T) {
+	mockService := NewRevocationService(newMockRevocationErrorRepo(), time.Minute, mockTokens.AccessTTL)
+
+	err := mockService.RevokeAll(1)
+	assert.Error(t, err, "should return error")
+}
+
+// TestIsRevoked_Reload tests that services.IsRevoked picks up revocations made by other instances once the cache is stale
+func TestIsRevoked_Reload(t *testing.T) {
+	mockRepo := newMockRevocationRepo()
+	mockService := NewRevocationService(mockRepo, time.Minute, mockTokens.AccessTTL).(*revocationService)
+	now := time.Now()
+	mockService.now = func() time.Time { return now }
+	assert.False(t, mockService.IsRevoked("token-1", 1, now), "should not revoke unknown tokens")
+
+	// another instance revokes the token
+	assert.NoError(t, mockRepo.SaveToken(models.RevokedToken{TokenID: "token-1", UserID: 1, ExpiresAt: now.Add(time.Hour)}))
+	assert.False(t, mockService.IsRevoked("token-1", 1, now), "should use the cache until it is stale")
+
+	now = now.Add(time.Minute)
+	assert.True(t, mockService.IsRevoked("token-1", 1, now), "should reload the cache once it is stale")
+}
+func TestIsRevoked_ReloadError(t *testing.T) {
+	mockRepo := newMockRevocationRepo()
+	mockService := NewRevocationService(mockRepo, time.Minute, mockTokens.AccessTTL).(*revocationService)
+	now := time.Now()
+	mockService.now = func() time.Time { return now }
+	assert.NoError(t, mockService.Revoke("token-1", 1, now.Add(time.Hour)))
+
+	mockRepo.findActive = newMockRevocationErrorRepo().findActive
+	now = now.Add(time.Minute)
+	assert.True(t, mockService.IsRevoked("token-1", 1, now), "should keep the cache when the database cannot be read")
+}
+func TestIsRevoked_ReloadErrorBackOff(t *testing.T) {
+	mockRepo := newMockRevocationRepo()
+	mockService := NewRevocationService(mockRepo, time.Minute, mockTokens.AccessTTL).(*revocationService)
+	now := time.Now()
+	mockService.now = func() time.Time { return now }
+	assert.NoError(t, mockService.Revoke("token-1", 1, now.Add(time.Hour)))
+
+	reads := 0
+	mockRepo.findActive = func(now time.Time) ([]models.RevokedToken, []models.TokenCutoff, error) {
+		reads++
+		return nil, nil, errors.New("error")
+	}
+	now = now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		assert.True(t, mockService.IsRevoked("token-1", 1, now), "should keep the cache when the database cannot be read")
+	}
+	assert.Equal(t, 1, reads, "should not read the database again before the next refresh interval")
+
+	now = now.Add(time.Minute)
+	assert.True(t, mockService.IsRevoked("token-1", 1, now), "should keep the cache when the database cannot be read")
+	assert.Equal(t, 2, reads, "should read the database again once the interval has passed")
+}
@@ -1,20 +1,22 @@
 package services
 
 import (
+	"fmt"
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/laertkokona/crud-test/repositories"
-	"github.com/laertkokona/crud-test/utils"
 	"github.com/peteprogrammer/go-automapper"
-	"net/http"
 )
 
 // RoleService interface like UserService
 type RoleService interface {
-	CreateRole(role models.Role) (models.RoleDTO, int, error)
-	GetRole(id int) (models.RoleDTO, int, error)
-	GetAllRoles() ([]models.RoleDTO, int, error)
-	UpdateRole(id int, role models.Role) (models.RoleDTO, int, error)
-	DeleteRole(id int) (models.RoleDTO, int, error)
+	CreateRole(role models.Role) (models.RoleDTO, error)
+	GetRole(id int) (models.RoleDTO, error)
+	GetAllRoles(pagination models.Pagination) (models.Page[models.RoleDTO], error)
+	UpdateRole(id int, role models.Role, version uint) (models.RoleDTO, error)
+	DeleteRole(id int, version uint) (models.RoleDTO, error)
+	GetAllPermissions(pagination models.Pagination) (models.Page[models.Permission], error)
+	SetRolePermissions(id int, names []string) (models.RoleDTO, error)
 }
 
 // roleService struct like userService
@@ -30,78 +32,141 @@ func NewRoleService(repo repositories.RoleRepo) RoleService {
 }
 
 // CreateRole method that takes a models.Role object and saves it to the database
-func (r roleService) CreateRole(role models.Role) (models.RoleDTO, int, error) {
+func (r roleService) CreateRole(role models.Role) (models.RoleDTO, error) {
 	// save the role to the database
 	// return the role object
 	role, err := r.roleRepo.Save(role)
 	if err != nil {
-		return models.RoleDTO{}, http.StatusInternalServerError, err
+		return models.RoleDTO{}, err
 	}
 	var returnRole models.RoleDTO
 	automapper.Map(role, &returnRole)
-	return returnRole, http.StatusOK, nil
+	return returnRole, nil
 }
 
 // GetRole method that takes a role id and returns the role object
-func (r roleService) GetRole(id int) (models.RoleDTO, int, error) {
+func (r roleService) GetRole(id int) (models.RoleDTO, error) {
 	// get the role object from the database
 	// return the role object
 	role, err := r.roleRepo.FindByID(id)
 	if err != nil {
-		return models.RoleDTO{}, http.StatusInternalServerError, err
+		return models.RoleDTO{}, err
 	}
 	var returnRole models.RoleDTO
 	automapper.Map(role, &returnRole)
-	return returnRole, http.StatusOK, nil
+	return returnRole, nil
 }
 
-// GetAllRoles method that returns all the roles
-func (r roleService) GetAllRoles() ([]models.RoleDTO, int, error) {
-	// get all the roles from the database
+// GetAllRoles method that returns a page of the roles, the roles are only paged by number
+func (r roleService) GetAllRoles(pagination models.Pagination) (models.Page[models.RoleDTO], error) {
+	// get a page of the roles from the database
 	// return the roles
-	roles, err := r.roleRepo.FindAll()
+	if pagination.Keyset() {
+		return models.Page[models.RoleDTO]{}, errs.Validation(models.ErrNoCursor)
+	}
+	roles, total, err := r.roleRepo.FindAll(pagination)
 	if err != nil {
-		return []models.RoleDTO{}, http.StatusInternalServerError, err
+		return models.Page[models.RoleDTO]{}, err
 	}
 	var returnRoles []models.RoleDTO
 	automapper.Map(roles, &returnRoles)
-	return returnRoles, http.StatusOK, nil
+	return models.NewPage(returnRoles, total, pagination), nil
 }
 
-// UpdateRole method that takes a role id and updates the role object
-func (r roleService) UpdateRole(id int, role models.Role) (models.RoleDTO, int, error) {
-	// get the role object from the database
-	// unmarshal the role object
-	// save the role to the database
+// UpdateRole method that takes a role id and the version it was made to and updates the role object
+func (r roleService) UpdateRole(id int, role models.Role, version uint) (models.RoleDTO, error) {
+	// get the role object from the database and check that it is still at the version
+	// replace the role in the database, its permissions only change through their own endpoint
 	// return the role object
 	roleDB, err := r.roleRepo.FindByID(id)
 	if err != nil {
-		return models.RoleDTO{}, http.StatusNotFound, err
+		return models.RoleDTO{}, notFoundError(err)
+	}
+	if err := checkVersion(roleDB.Version, version); err != nil {
+		return models.RoleDTO{}, err
 	}
-	utils.CopyNonEmptyFields(&roleDB, &role)
+	role.ID = roleDB.ID
+	role.Version = roleDB.Version
 	role, err = r.roleRepo.Update(role)
 	if err != nil {
-		return models.RoleDTO{}, http.StatusInternalServerError, err
+		return models.RoleDTO{}, versionError(err)
 	}
+	role.Permissions = roleDB.Permissions
 	var returnRole models.RoleDTO
 	automapper.Map(role, &returnRole)
-	return returnRole, http.StatusOK, nil
+	return returnRole, nil
 }
 
-// DeleteRole method that takes a role id and deletes the role object
-func (r roleService) DeleteRole(id int) (models.RoleDTO, int, error) {
-	// get the role object from the database
+// DeleteRole method that takes a role id and the version it was read at and deletes the role object
+func (r roleService) DeleteRole(id int, version uint) (models.RoleDTO, error) {
+	// get the role object from the database and check that it is still at the version
 	// delete the role object from the database
 	// return the role object
 	role, err := r.roleRepo.FindByID(id)
 	if err != nil {
-		return models.RoleDTO{}, http.StatusNotFound, err
+		return models.RoleDTO{}, notFoundError(err)
+	}
+	if err := checkVersion(role.Version, version); err != nil {
+		return models.RoleDTO{}, err
 	}
 	err = r.roleRepo.Delete(role)
 	if err != nil {
-		return models.RoleDTO{}, http.StatusInternalServerError, err
+		return models.RoleDTO{}, versionError(err)
 	}
 	var returnRole models.RoleDTO
 	automapper.Map(role, &returnRole)
-	return returnRole, http.StatusOK, nil
+	return returnRole, nil
+}
+
+// GetAllPermissions method that returns a page of the permissions a role can be given, they are only paged by number
+func (r roleService) GetAllPermissions(pagination models.Pagination) (models.Page[models.Permission], error) {
+	if pagination.Keyset() {
+		return models.Page[models.Permission]{}, errs.Validation(models.ErrNoCursor)
+	}
+	permissions, total, err := r.roleRepo.FindAllPermissions(pagination)
+	if err != nil {
+		return models.Page[models.Permission]{}, err
+	}
+	return models.NewPage(permissions, total, pagination), nil
+}
+
+// SetRolePermissions method that takes a role id and permission names and replaces the permissions of the role
+func (r roleService) SetRolePermissions(id int, names []string) (models.RoleDTO, error) {
+	// get the role object from the database
+	// find the permissions by name, every name must be a known permission
+	// replace the permissions of the role
+	// return the role object
+	role, err := r.roleRepo.FindByID(id)
+	if err != nil {
+		return models.RoleDTO{}, notFoundError(err)
+	}
+	permissions := []models.Permission{}
+	if len(names) > 0 {
+		permissions, err = r.roleRepo.FindPermissionsByNames(names)
+		if err != nil {
+			return models.RoleDTO{}, err
+		}
+	}
+	for _, name := range names {
+		if !hasPermission(permissions, name) {
+			return models.RoleDTO{}, errs.Validation(fmt.Errorf("unknown permission %q", name))
+		}
+	}
+	role, err = r.roleRepo.ReplacePermissions(role, permissions)
+	if err != nil {
+		return models.RoleDTO{}, err
+	}
+	var returnRole models.RoleDTO
+	automapper.Map(role, &returnRole)
+	return returnRole, nil
+}
+
+// hasPermission checks if a permission with the given name is in the slice
+func hasPermission(permissions []models.Permission, name string) bool {
+	for _, p := range permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
 }
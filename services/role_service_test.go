@@ -2,11 +2,12 @@ package services
 
 import (
 	"errors"
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/peteprogrammer/go-automapper"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 	"log"
-	"net/http"
 	"testing"
 )
 
@@ -32,8 +33,8 @@ var mockCreateRole = models.Role{
 
 // mockRoleRepo is a mock implementation of the repositories.RoleRepo interface
 type mockRoleRepo struct {
-	// findAll is a mock function with no given fields
-	findAll func() ([]models.Role, error)
+	// findAll is a mock function with given fields: pagination
+	findAll func(pagination models.Pagination) ([]models.Role, int64, error)
 	// findByID is a mock function with given fields: id
 	findByID func(id int) (models.Role, error)
 	// findByName is a mock function with given fields: roleName
@@ -46,11 +47,17 @@ type mockRoleRepo struct {
 	delete func(role models.Role) error
 	// deleteById is a mock function with given fields: id
 	deleteById func(id int) (models.Role, error)
+	// findAllPermissions is a mock function with given fields: pagination
+	findAllPermissions func(pagination models.Pagination) ([]models.Permission, int64, error)
+	// findPermissionsByNames is a mock function with given fields: names
+	findPermissionsByNames func(names []string) ([]models.Permission, error)
+	// replacePermissions is a mock function with given fields: role, permissions
+	replacePermissions func(role models.Role, permissions []models.Permission) (models.Role, error)
 }
 
 // FindAll is a mock function with given fields: pagination
-func (_m *mockRoleRepo) FindAll() ([]models.Role, error) {
-	return _m.findAll()
+func (_m *mockRoleRepo) FindAll(pagination models.Pagination) ([]models.Role, int64, error) {
+	return _m.findAll(pagination)
 }
 
 // FindByID is a mock function with given fields: id
@@ -84,11 +91,47 @@ func (_m *mockRoleRepo) DeleteById(id int) (models.Role, error) {
 	return _m.deleteById(id)
 }
 
+// FindAllPermissions is a mock function with given fields: pagination
+func (_m *mockRoleRepo) FindAllPermissions(pagination models.Pagination) ([]models.Permission, int64, error) {
+	return _m.findAllPermissions(pagination)
+}
+
+// FindPermissionsByNames is a mock function with given fields: names
+func (_m *mockRoleRepo) FindPermissionsByNames(names []string) ([]models.Permission, error) {
+	return _m.findPermissionsByNames(names)
+}
+
+// ReplacePermissions is a mock function with given fields: role, permissions
+func (_m *mockRoleRepo) ReplacePermissions(role models.Role, permissions []models.Permission) (models.Role, error) {
+	return _m.replacePermissions(role, permissions)
+}
+
+// mockPermissions are the permissions known to the mock role repos
+var mockPermissions = []models.Permission{
+	{ID: 1, Name: "items:read"},
+	{ID: 2, Name: "items:write"},
+	{ID: 3, Name: "orders:approve"},
+}
+
+// findMockPermissions returns the mock permissions with the given names
+func findMockPermissions(names []string) ([]models.Permission, error) {
+	var found []models.Permission
+	for _, p := range mockPermissions {
+		for _, name := range names {
+			if p.Name == name {
+				found = append(found, p)
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
 // NewMockRoleRepo returns a new instance of mockRoleRepo
 func NewMockRoleRepo() *mockRoleRepo {
 	return &mockRoleRepo{
-		findAll: func() ([]models.Role, error) {
-			return mockRoles, nil
+		findAll: func(pagination models.Pagination) ([]models.Role, int64, error) {
+			return mockRoles, int64(len(mockRoles)), nil
 		},
 		findByID: func(id int) (models.Role, error) {
 			return mockRoles[id-1], nil
@@ -116,17 +159,25 @@ func NewMockRoleRepo() *mockRoleRepo {
 		deleteById: func(id int) (models.Role, error) {
 			return mockRoles[id-1], nil
 		},
+		findAllPermissions: func(pagination models.Pagination) ([]models.Permission, int64, error) {
+			return mockPermissions, int64(len(mockPermissions)), nil
+		},
+		findPermissionsByNames: findMockPermissions,
+		replacePermissions: func(role models.Role, permissions []models.Permission) (models.Role, error) {
+			role.Permissions = permissions
+			return role, nil
+		},
 	}
 }
 
 // NewMockRoleErrorRepo returns a new instance of mockRoleRepo with error
 func NewMockRoleErrorRepo() *mockRoleRepo {
 	return &mockRoleRepo{
-		findAll: func() ([]models.Role, error) {
-			return []models.Role{}, errors.New("error")
+		findAll: func(pagination models.Pagination) ([]models.Role, int64, error) {
+			return []models.Role{}, 0, errors.New("error")
 		},
 		findByID: func(id int) (models.Role, error) {
-			return models.Role{}, errors.New("error")
+			return models.Role{}, gorm.ErrRecordNotFound
 		},
 		findByName: func(name string) (models.Role, error) {
 			return models.Role{}, errors.New("error")
@@ -143,14 +194,23 @@ func NewMockRoleErrorRepo() *mockRoleRepo {
 		deleteById: func(id int) (models.Role, error) {
 			return models.Role{}, errors.New("error")
 		},
+		findAllPermissions: func(pagination models.Pagination) ([]models.Permission, int64, error) {
+			return nil, 0, errors.New("error")
+		},
+		findPermissionsByNames: func(names []string) ([]models.Permission, error) {
+			return nil, errors.New("error")
+		},
+		replacePermissions: func(role models.Role, permissions []models.Permission) (models.Role, error) {
+			return models.Role{}, errors.New("error")
+		},
 	}
 }
 
 // NewMockRoleErrorRepo returns a new instance of mockRoleRepo with error
 func NewMockRoleSpecificErrorRepo() *mockRoleRepo {
 	return &mockRoleRepo{
-		findAll: func() ([]models.Role, error) {
-			return mockRoles, nil
+		findAll: func(pagination models.Pagination) ([]models.Role, int64, error) {
+			return mockRoles, int64(len(mockRoles)), nil
 		},
 		findByID: func(id int) (models.Role, error) {
 			return mockRoles[id-1], nil
@@ -177,6 +237,13 @@ func NewMockRoleSpecificErrorRepo() *mockRoleRepo {
 		deleteById: func(id int) (models.Role, error) {
 			return models.Role{}, errors.New("error")
 		},
+		findAllPermissions: func(pagination models.Pagination) ([]models.Permission, int64, error) {
+			return mockPermissions, int64(len(mockPermissions)), nil
+		},
+		findPermissionsByNames: findMockPermissions,
+		replacePermissions: func(role models.Role, permissions []models.Permission) (models.Role, error) {
+			return models.Role{}, errors.New("error")
+		},
 	}
 }
 
@@ -195,15 +262,13 @@ func TestCreateRole(t *testing.T) {
 	// create a mock role service
 	// call the create role function
 	// assert that the role returned is the same as the role passed in
-	// assert that the status is OK
 	// assert that there is no error
 	mockRepo := NewMockRoleRepo()
 	mockService := NewRoleService(mockRepo)
-	role, status, err := mockService.CreateRole(mockCreateRole)
+	role, err := mockService.CreateRole(mockCreateRole)
 	var expectedDTO models.RoleDTO
 	automapper.Map(mockCreateRole, &expectedDTO)
 	assert.NoError(t, err, "Error creating role")
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, expectedDTO, role)
 }
 
@@ -213,13 +278,13 @@ func TestCreateRole_SaveError(t *testing.T) {
 	// create a mock role service
 	// call the create role function
 	// assert that the role returned is the same as the role passed in
-	// assert that the status is InternalServerError
+	// assert that the error is internal
 	// assert that there is no error
 	mockRepo := NewMockRoleErrorRepo()
 	mockService := NewRoleService(mockRepo)
-	role, status, err := mockService.CreateRole(mockCreateRole)
+	role, err := mockService.CreateRole(mockCreateRole)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.RoleDTO{}, role)
 }
 
@@ -229,15 +294,13 @@ func TestGetRole(t *testing.T) {
 	// create a mock role service
 	// call the get role function
 	// assert that the role returned is the same as the role passed in
-	// assert that the status is OK
 	// assert that there is no error
 	mockRepo := NewMockRoleRepo()
 	mockService := NewRoleService(mockRepo)
-	role, status, err := mockService.GetRole(1)
+	role, err := mockService.GetRole(1)
 	var expectedDTO models.RoleDTO
 	automapper.Map(mockRoles[0], &expectedDTO)
 	assert.NoError(t, err, "Error getting role")
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, expectedDTO, role)
 }
 
@@ -247,13 +310,13 @@ func TestGetRole_FindByIDError(t *testing.T) {
 	// create a mock role service
 	// call the get role function
 	// assert that the role returned is the same as the role passed in
-	// assert that the status is InternalServerError
+	// assert that the error is internal
 	// assert that there is no error
 	mockRepo := NewMockRoleErrorRepo()
 	mockService := NewRoleService(mockRepo)
-	role, status, err := mockService.GetRole(1)
+	role, err := mockService.GetRole(1)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.RoleDTO{}, role)
 }
 
@@ -263,16 +326,23 @@ func TestGetAllRoles(t *testing.T) {
 	// create a mock role service
 	// call the get all roles function
 	// assert that the roles returned is the same as the roles passed in
-	// assert that the status is OK
 	// assert that there is no error
 	mockRepo := NewMockRoleRepo()
 	mockService := NewRoleService(mockRepo)
-	roles, status, err := mockService.GetAllRoles()
+	roles, err := mockService.GetAllRoles(models.Pagination{})
 	var expectedDTOs []models.RoleDTO
 	automapper.Map(mockRoles, &expectedDTOs)
 	assert.NoError(t, err, "Error getting all roles")
-	assert.Equal(t, http.StatusOK, status)
-	assert.Equal(t, expectedDTOs, roles)
+	assert.Equal(t, expectedDTOs, roles.Data)
+	assert.Equal(t, int64(len(mockRoles)), *roles.Total)
+}
+
+// TestGetAllRoles_Cursor tests that the GetAllRoles function rejects a cursor, the roles are only paged by number
+func TestGetAllRoles_Cursor(t *testing.T) {
+	mockService := NewRoleService(NewMockRoleRepo())
+	_, err := mockService.GetAllRoles(models.Pagination{After: models.Cursor{ID: 1}.Encode()})
+	assert.ErrorIs(t, err, models.ErrNoCursor)
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
 }
 
 // TestGetAllRoles_FindAllError tests the GetAllRoles function using mockRoleRepo
@@ -281,14 +351,14 @@ func TestGetAllRoles_FindAllError(t *testing.T) {
 	// create a mock role service
 	// call the get all roles function
 	// assert that the roles returned is the same as the roles passed in
-	// assert that the status is InternalServerError
+	// assert that the error is internal
 	// assert that there is no error
 	mockRepo := NewMockRoleErrorRepo()
 	mockService := NewRoleService(mockRepo)
-	roles, status, err := mockService.GetAllRoles()
+	roles, err := mockService.GetAllRoles(models.Pagination{})
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
-	assert.Equal(t, []models.RoleDTO{}, roles)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+	assert.Empty(t, roles.Data)
 }
 
 // TestUpdateRole tests the UpdateRole function using mockRoleRepo
@@ -297,19 +367,17 @@ func TestUpdateRole(t *testing.T) {
 	// create a mock role service
 	// call the update role function
 	// assert that the role returned is the same as the role passed in
-	// assert that the status is OK
 	// assert that there is no error
 	mockRepo := NewMockRoleRepo()
 	mockService := NewRoleService(mockRepo)
 	testRole := models.Role{
 		Name: "TestTest",
 	}
-	role, status, err := mockService.UpdateRole(1, testRole)
+	role, err := mockService.UpdateRole(1, testRole, AnyVersion)
 	testRole.ID = uint(1)
 	var expectedDTO models.RoleDTO
 	automapper.Map(testRole, &expectedDTO)
 	assert.NoError(t, err, "Error updating role")
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, expectedDTO, role)
 }
 
@@ -319,16 +387,16 @@ func TestUpdateRole_FindByIDError(t *testing.T) {
 	// create a mock role service
 	// call the update role function
 	// assert that the role returned is the same as the role passed in
-	// assert that the status is NotFound
+	// assert that the error is not found
 	// assert that there is no error
 	mockRepo := NewMockRoleErrorRepo()
 	mockService := NewRoleService(mockRepo)
 	testRole := models.Role{
 		Name: "TestTest",
 	}
-	role, status, err := mockService.UpdateRole(1, testRole)
+	role, err := mockService.UpdateRole(1, testRole, AnyVersion)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Equal(t, models.RoleDTO{}, role)
 }
 
@@ -338,16 +406,16 @@ func TestUpdateRole_UpdateError(t *testing.T) {
 	// create a mock role service
 	// call the update role function
 	// assert that the role returned is the same as the role passed in
-	// assert that the status is InternalServerError
+	// assert that the error is internal
 	// assert that there is no error
 	mockRepo := NewMockRoleSpecificErrorRepo()
 	mockService := NewRoleService(mockRepo)
 	testRole := models.Role{
 		Name: "TestTest",
 	}
-	role, status, err := mockService.UpdateRole(1, testRole)
+	role, err := mockService.UpdateRole(1, testRole, AnyVersion)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.RoleDTO{}, role)
 }
 
@@ -356,15 +424,13 @@ func TestDeleteRole(t *testing.T) {
 	// create a mock role repo
 	// create a mock role service
 	// call the delete role function
-	// assert that the status is OK
 	// assert that there is no error
 	mockRepo := NewMockRoleRepo()
 	mockService := NewRoleService(mockRepo)
-	role, status, err := mockService.DeleteRole(1)
+	role, err := mockService.DeleteRole(1, AnyVersion)
 	var expectedDTO models.RoleDTO
 	automapper.Map(mockRoles[0], &expectedDTO)
 	assert.NoError(t, err, "Error deleting role")
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, expectedDTO, role)
 }
 
@@ -373,13 +439,13 @@ func TestDeleteRole_FindByIDError(t *testing.T) {
 	// create a mock role repo
 	// create a mock role service
 	// call the delete role function
-	// assert that the status is NotFound
+	// assert that the error is not found
 	// assert that there is no error
 	mockRepo := NewMockRoleErrorRepo()
 	mockService := NewRoleService(mockRepo)
-	role, status, err := mockService.DeleteRole(1)
+	role, err := mockService.DeleteRole(1, AnyVersion)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Equal(t, models.RoleDTO{}, role)
 }
 
@@ -388,12 +454,84 @@ func TestDeleteRole_DeleteError(t *testing.T) {
 	// create a mock role repo
 	// create a mock role service
 	// call the delete role function
-	// assert that the status is InternalServerError
+	// assert that the error is internal
 	// assert that there is no error
 	mockRepo := NewMockRoleSpecificErrorRepo()
 	mockService := NewRoleService(mockRepo)
-	role, status, err := mockService.DeleteRole(1)
+	role, err := mockService.DeleteRole(1, AnyVersion)
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+	assert.Equal(t, models.RoleDTO{}, role)
+}
+
+// TestGetAllPermissions tests the GetAllPermissions function using mockRoleRepo
+func TestGetAllPermissions(t *testing.T) {
+	mockService := NewRoleService(NewMockRoleRepo())
+	permissions, err := mockService.GetAllPermissions(models.Pagination{})
+	assert.NoError(t, err, "Error getting all permissions")
+	assert.Equal(t, mockPermissions, permissions.Data)
+	assert.Equal(t, int64(len(mockPermissions)), *permissions.Total)
+}
+
+// TestGetAllPermissions_FindAllPermissionsError tests the GetAllPermissions function using mockRoleRepo
+func TestGetAllPermissions_FindAllPermissionsError(t *testing.T) {
+	mockService := NewRoleService(NewMockRoleErrorRepo())
+	permissions, err := mockService.GetAllPermissions(models.Pagination{})
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+	assert.Empty(t, permissions.Data)
+}
+
+// TestSetRolePermissions tests the SetRolePermissions function using mockRoleRepo
+func TestSetRolePermissions(t *testing.T) {
+	mockService := NewRoleService(NewMockRoleRepo())
+	role, err := mockService.SetRolePermissions(2, []string{"items:write", "orders:approve"})
+	assert.NoError(t, err, "Error setting role permissions")
+	assert.Equal(t, "Admin", role.Name)
+	assert.Equal(t, mockPermissions[1:], role.Permissions)
+}
+
+// TestSetRolePermissions_Empty tests that the SetRolePermissions function can remove every permission of a role
+func TestSetRolePermissions_Empty(t *testing.T) {
+	mockRepo := NewMockRoleRepo()
+	mockRepo.findPermissionsByNames = func(names []string) ([]models.Permission, error) {
+		t.Fatal("permissions should not be looked up without names")
+		return nil, nil
+	}
+	mockService := NewRoleService(mockRepo)
+	role, err := mockService.SetRolePermissions(2, []string{})
+	assert.NoError(t, err, "Error setting role permissions")
+	assert.Empty(t, role.Permissions)
+}
+
+// TestSetRolePermissions_UnknownPermission tests the SetRolePermissions function with a permission that does not exist
+func TestSetRolePermissions_UnknownPermission(t *testing.T) {
+	mockRepo := NewMockRoleRepo()
+	mockRepo.replacePermissions = func(role models.Role, permissions []models.Permission) (models.Role, error) {
+		t.Fatal("permissions should not be replaced")
+		return role, nil
+	}
+	mockService := NewRoleService(mockRepo)
+	role, err := mockService.SetRolePermissions(2, []string{"items:write", "items:steal"})
+	assert.EqualError(t, err, `unknown permission "items:steal"`)
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
+	assert.Equal(t, models.RoleDTO{}, role)
+}
+
+// TestSetRolePermissions_FindByIDError tests the SetRolePermissions function using mockRoleRepo
+func TestSetRolePermissions_FindByIDError(t *testing.T) {
+	mockService := NewRoleService(NewMockRoleErrorRepo())
+	role, err := mockService.SetRolePermissions(2, []string{"items:write"})
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
+	assert.Equal(t, models.RoleDTO{}, role)
+}
+
+// TestSetRolePermissions_ReplaceError tests the SetRolePermissions function using mockRoleRepo
+func TestSetRolePermissions_ReplaceError(t *testing.T) {
+	mockService := NewRoleService(NewMockRoleSpecificErrorRepo())
+	role, err := mockService.SetRolePermissions(2, []string{"items:write"})
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.RoleDTO{}, role)
 }
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/repositories"
+	"time"
+)
+
+// ShipmentService interface
+type ShipmentService interface {
+	CreateShipment(shipment models.Shipment) (models.Shipment, error)
+	GetShipment(id int) (models.Shipment, error)
+	GetAllShipments(pagination models.Pagination) (models.Page[models.Shipment], error)
+	UpdateShipment(id int, shipment models.Shipment, version uint) (models.Shipment, error)
+	DeleteShipment(id int, version uint) (models.Shipment, error)
+}
+
+// shipmentService struct
+type shipmentService struct {
+	ShipmentRepo repositories.ShipmentRepo
+	TruckRepo    repositories.TruckRepo
+}
+
+// NewShipmentService returns a new instance of shipmentService
+func NewShipmentService(shipmentRepo repositories.ShipmentRepo, truckRepo repositories.TruckRepo) ShipmentService {
+	return shipmentService{
+		ShipmentRepo: shipmentRepo,
+		TruckRepo:    truckRepo,
+	}
+}
+
+// dayOf returns the calendar day of a time, shipments are booked per day
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// CreateShipment method that takes a models.Shipment object, validates it and saves it to the database if its truck is
+// free on the departure day and its orders can leave on it
+func (p shipmentService) CreateShipment(shipment models.Shipment) (models.Shipment, error) {
+	shipment.ID = 0
+	if err := p.validateShipment(&shipment); err != nil {
+		return models.Shipment{}, err
+	}
+	shipment, err := p.ShipmentRepo.Save(shipment)
+	if err != nil {
+		return models.Shipment{}, err
+	}
+	return shipment, nil
+}
+
+// GetShipment method that takes a shipment id and returns the shipment object
+func (p shipmentService) GetShipment(id int) (models.Shipment, error) {
+	shipment, err := p.ShipmentRepo.FindByID(id)
+	if err != nil {
+		return models.Shipment{}, notFoundError(err)
+	}
+	return shipment, nil
+}
+
+// GetAllShipments method that returns a page of the shipments
+func (p shipmentService) GetAllShipments(pagination models.Pagination) (models.Page[models.Shipment], error) {
+	if err := pagination.Validate(); err != nil {
+		return models.Page[models.Shipment]{}, errs.Validation(err)
+	}
+	shipments, total, err := p.ShipmentRepo.FindAll(pagination)
+	if err != nil {
+		return models.Page[models.Shipment]{}, err
+	}
+	page := models.NewPage(shipments, total, pagination)
+	if n := len(page.Data); n > 0 {
+		page = page.WithNextCursor(shipments[n-1].Model)
+	}
+	return page, nil
+}
+
+// UpdateShipment method that takes a shipment id and the version it was made to, validates the changes and updates the
+// shipment in the database
+func (p shipmentService) UpdateShipment(id int, shipment models.Shipment, version uint) (models.Shipment, error) {
+	shipmentDb, err := p.ShipmentRepo.FindByID(id)
+	if err != nil {
+		return models.Shipment{}, notFoundError(err)
+	}
+	if err := checkVersion(shipmentDb.Version, version); err != nil {
+		return models.Shipment{}, err
+	}
+	shipment.Model = shipmentDb.Model
+	shipment.Version = shipmentDb.Version
+	if err := p.validateShipment(&shipment); err != nil {
+		return models.Shipment{}, err
+	}
+	shipmentDb, err = p.ShipmentRepo.Update(shipment)
+	if err != nil {
+		return models.Shipment{}, versionError(err)
+	}
+	return shipmentDb, nil
+}
+
+// DeleteShipment method that takes a shipment id and the version it was read at and deletes the shipment from the database
+func (p shipmentService) DeleteShipment(id int, version uint) (models.Shipment, error) {
+	shipment, err := p.ShipmentRepo.FindByID(id)
+	if err != nil {
+		return models.Shipment{}, notFoundError(err)
+	}
+	if err := checkVersion(shipment.Version, version); err != nil {
+		return models.Shipment{}, err
+	}
+	err = p.ShipmentRepo.Delete(shipment)
+	if err != nil {
+		return models.Shipment{}, versionError(err)
+	}
+	return shipment, nil
+}
+
+// validateShipment checks the required fields and that the truck is in service, it numbers the stops in the order they
+// were given. The repository checks that the truck is free on the departure day and that every order can still leave on
+// it when it saves the shipment, while it holds the truck and the orders
+func (p shipmentService) validateShipment(shipment *models.Shipment) error {
+	// check the required fields and number the stops
+	// check that the truck exists and is in service
+	if shipment.TruckID == 0 {
+		return errs.Validation(errors.New("truck is required"))
+	}
+	if shipment.DepartureDate.IsZero() {
+		return errs.Validation(errors.New("departure date is required"))
+	}
+	if len(shipment.Stops) == 0 {
+		return errs.Validation(errors.New("at least one order is required"))
+	}
+	shipment.DepartureDate = dayOf(shipment.DepartureDate)
+	seen := make(map[uint]bool, len(shipment.Stops))
+	for i := range shipment.Stops {
+		orderID := shipment.Stops[i].OrderID
+		if seen[orderID] {
+			return errs.Validation(fmt.Errorf("order %d is listed more than once", orderID))
+		}
+		seen[orderID] = true
+		shipment.Stops[i].Sequence = i + 1
+	}
+
+	truck, err := p.TruckRepo.FindByID(int(shipment.TruckID))
+	if err != nil {
+		return notFoundError(fmt.Errorf("truck %d: %w", shipment.TruckID, err))
+	}
+	if truck.OutOfService {
+		return errs.Conflict(fmt.Errorf("truck %d is out of service", truck.ID))
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"errors"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+var departure = time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+var mockShipments = []models.Shipment{
+	{
+		Model:         gorm.Model{ID: 1},
+		TruckID:       1,
+		DepartureDate: departure,
+		Stops: []models.ShipmentStop{
+			{ShipmentID: 1, OrderID: 1, Sequence: 1},
+		},
+	},
+}
+
+// mockShipmentRepo is a mock implementation of the repositories.ShipmentRepo interface
+type mockShipmentRepo struct {
+	// findAll is a mock function with given fields: pagination
+	findAll func(pagination models.Pagination) ([]models.Shipment, int64, error)
+	// findByID is a mock function with given fields: id
+	findByID func(id int) (models.Shipment, error)
+	// save is a mock function with given fields: shipment
+	save func(shipment models.Shipment) (models.Shipment, error)
+	// update is a mock function with given fields: shipment
+	update func(shipment models.Shipment) (models.Shipment, error)
+	// delete is a mock function with given fields: shipment
+	delete func(shipment models.Shipment) error
+	// deleteById is a mock function with given fields: id
+	deleteById func(id int) (models.Shipment, error)
+}
+
+// FindAll is a mock function with given fields: pagination
+func (_m *mockShipmentRepo) FindAll(pagination models.Pagination) ([]models.Shipment, int64, error) {
+	return _m.findAll(pagination)
+}
+
+// FindByID is a mock function with given fields: id
+func (_m *mockShipmentRepo) FindByID(id int) (models.Shipment, error) {
+	return _m.findByID(id)
+}
+
+// Save is a mock function with given fields: shipment
+func (_m *mockShipmentRepo) Save(shipment models.Shipment) (models.Shipment, error) {
+	return _m.save(shipment)
+}
+
+// Update is a mock function with given fields: shipment
+func (_m *mockShipmentRepo) Update(shipment models.Shipment) (models.Shipment, error) {
+	return _m.update(shipment)
+}
+
+// Delete is a mock function with given fields: shipment
+func (_m *mockShipmentRepo) Delete(shipment models.Shipment) error {
+	return _m.delete(shipment)
+}
+
+// DeleteById is a mock function with given fields: id
+func (_m *mockShipmentRepo) DeleteById(id int) (models.Shipment, error) {
+	return _m.deleteById(id)
+}
+
+// newMockShipmentRepo returns a new instance of the mockShipmentRepo where the trucks are free and the orders can leave on them
+func newMockShipmentRepo() *mockShipmentRepo {
+	return &mockShipmentRepo{
+		findAll: func(pagination models.Pagination) ([]models.Shipment, int64, error) {
+			return mockShipments, int64(len(mockShipments)), nil
+		},
+		findByID: func(id int) (models.Shipment, error) {
+			return mockShipments[id-1], nil
+		},
+		save: func(shipment models.Shipment) (models.Shipment, error) {
+			shipment.ID = 2
+			return shipment, nil
+		},
+		update: func(shipment models.Shipment) (models.Shipment, error) {
+			return shipment, nil
+		},
+		delete: func(shipment models.Shipment) error {
+			return nil
+		},
+		deleteById: func(id int) (models.Shipment, error) {
+			return mockShipments[id-1], nil
+		},
+	}
+}
+
+// newMockShipmentErrorRepo returns a new instance of the mockShipmentRepo with error
+func newMockShipmentErrorRepo() *mockShipmentRepo {
+	return &mockShipmentRepo{
+		findAll: func(pagination models.Pagination) ([]models.Shipment, int64, error) {
+			return []models.Shipment{}, 0, errors.New("error")
+		},
+		findByID: func(id int) (models.Shipment, error) {
+			return models.Shipment{}, gorm.ErrRecordNotFound
+		},
+		save: func(shipment models.Shipment) (models.Shipment, error) {
+			return models.Shipment{}, errors.New("error")
+		},
+		update: func(shipment models.Shipment) (models.Shipment, error) {
+			return models.Shipment{}, errors.New("error")
+		},
+		delete: func(shipment models.Shipment) error {
+			return errors.New("error")
+		},
+		deleteById: func(id int) (models.Shipment, error) {
+			return models.Shipment{}, errors.New("error")
+		},
+	}
+}
+
+// newShipmentRequest returns a shipment for truck 1 leaving at noon on the departure day with the given orders
+func newShipmentRequest(orderIDs ...uint) models.Shipment {
+	shipment := models.Shipment{TruckID: 1, DepartureDate: departure.Add(12 * time.Hour)}
+	for _, id := range orderIDs {
+		shipment.Stops = append(shipment.Stops, models.ShipmentStop{OrderID: id})
+	}
+	return shipment
+}
+
+// TestCreateShipment tests services.CreateShipment using the mock repositories
+func TestCreateShipment(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentRepo(), newMockTruckRepo())
+
+	shipment, err := mockService.CreateShipment(newShipmentRequest(2, 1))
+	assert.NoError(t, err, "should not return error")
+	assert.Equal(t, departure, shipment.DepartureDate, "should book the whole day")
+	assert.Equal(t, []models.ShipmentStop{{OrderID: 2, Sequence: 1}, {OrderID: 1, Sequence: 2}}, shipment.Stops, "should number the stops in the given order")
+}
+func TestCreateShipment_MissingFields(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentRepo(), newMockTruckRepo())
+
+	_, err := mockService.CreateShipment(models.Shipment{TruckID: 1, DepartureDate: departure})
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err), "should return a validation error")
+}
+func TestCreateShipment_DuplicateOrder(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentRepo(), newMockTruckRepo())
+
+	_, err := mockService.CreateShipment(newShipmentRequest(1, 1))
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err), "should return a validation error")
+}
+func TestCreateShipment_TruckNotFound(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentRepo(), newMockTruckErrorRepo())
+
+	_, err := mockService.CreateShipment(newShipmentRequest(1))
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err), "should return a not found error")
+}
+func TestCreateShipment_TruckDoubleBooked(t *testing.T) {
+	shipmentRepo := newMockShipmentRepo()
+	shipmentRepo.save = func(shipment models.Shipment) (models.Shipment, error) {
+		return models.Shipment{}, errs.Conflict(errors.New("truck 1 is already booked on 2024-03-10 by shipment 1"))
+	}
+	mockService := NewShipmentService(shipmentRepo, newMockTruckRepo())
+
+	_, err := mockService.CreateShipment(newShipmentRequest(2))
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindConflict, errs.KindOf(err), "should return a conflict error")
+}
+func TestCreateShipment_OrderNotShippable(t *testing.T) {
+	shipmentRepo := newMockShipmentRepo()
+	shipmentRepo.save = func(shipment models.Shipment) (models.Shipment, error) {
+		return models.Shipment{}, errs.Conflict(errors.New("order 1 is already on shipment 1"))
+	}
+	mockService := NewShipmentService(shipmentRepo, newMockTruckRepo())
+
+	_, err := mockService.CreateShipment(newShipmentRequest(1))
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindConflict, errs.KindOf(err), "should return a conflict error")
+}
+func TestCreateShipment_OrderNotFound(t *testing.T) {
+	shipmentRepo := newMockShipmentRepo()
+	shipmentRepo.save = func(shipment models.Shipment) (models.Shipment, error) {
+		return models.Shipment{}, errs.NotFound(errors.New("order 9: record not found"))
+	}
+	mockService := NewShipmentService(shipmentRepo, newMockTruckRepo())
+
+	_, err := mockService.CreateShipment(newShipmentRequest(9))
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err), "should return a not found error")
+}
+func TestCreateShipment_SaveError(t *testing.T) {
+	shipmentRepo := newMockShipmentRepo()
+	shipmentRepo.save = newMockShipmentErrorRepo().save
+	mockService := NewShipmentService(shipmentRepo, newMockTruckRepo())
+
+	_, err := mockService.CreateShipment(newShipmentRequest(1))
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err), "should return an internal error")
+}
+
+// TestGetShipment tests services.GetShipment using mockShipmentRepo
+func TestGetShipment(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentRepo(), newMockTruckRepo())
+
+	shipment, err := mockService.GetShipment(1)
+	assert.NoError(t, err, "should not return error")
+	assert.Equal(t, mockShipments[0], shipment, "should return shipment")
+}
+func TestGetShipment_NotFound(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentErrorRepo(), newMockTruckRepo())
+
+	_, err := mockService.GetShipment(1)
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err), "should return a not found error")
+}
+
+// TestGetAllShipments tests services.GetAllShipments using mockShipmentRepo
+func TestGetAllShipments(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentRepo(), newMockTruckRepo())
+
+	shipments, err := mockService.GetAllShipments(models.Pagination{Page: 1, Limit: 10})
+	assert.NoError(t, err, "should not return error")
+	assert.Equal(t, mockShipments, shipments.Data, "should return shipments")
+	assert.Equal(t, int64(len(mockShipments)), *shipments.Total, "should return the total count")
+}
+func TestGetAllShipments_FindError(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentErrorRepo(), newMockTruckRepo())
+
+	_, err := mockService.GetAllShipments(models.Pagination{Page: 1, Limit: 10})
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err), "should return an internal error")
+}
+
+// TestUpdateShipment tests services.UpdateShipment using the mock repositories
+func TestUpdateShipment(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentRepo(), newMockTruckRepo())
+
+	shipment, err := mockService.UpdateShipment(1, newShipmentRequest(3, 1), AnyVersion)
+	assert.NoError(t, err, "should not return error")
+	assert.Equal(t, uint(1), shipment.ID, "should keep the shipment id")
+	assert.Equal(t, []models.ShipmentStop{{OrderID: 3, Sequence: 1}, {OrderID: 1, Sequence: 2}}, shipment.Stops, "should replace the stops")
+}
+func TestUpdateShipment_NotFound(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentErrorRepo(), newMockTruckRepo())
+
+	_, err := mockService.UpdateShipment(1, newShipmentRequest(1), AnyVersion)
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err), "should return a not found error")
+}
+
+// TestDeleteShipment tests services.DeleteShipment using mockShipmentRepo
+func TestDeleteShipment(t *testing.T) {
+	mockService := NewShipmentService(newMockShipmentRepo(), newMockTruckRepo())
+
+	shipment, err := mockService.DeleteShipment(1, AnyVersion)
+	assert.NoError(t, err, "should not return error")
+	assert.Equal(t, mockShipments[0], shipment, "should return the deleted shipment")
+}
+func TestDeleteShipment_DeleteError(t *testing.T) {
+	shipmentRepo := newMockShipmentRepo()
+	shipmentRepo.delete = newMockShipmentErrorRepo().delete
+	mockService := NewShipmentService(shipmentRepo, newMockTruckRepo())
+
+	_, err := mockService.DeleteShipment(1, AnyVersion)
+	assert.Error(t, err, "should return error")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err), "should return an internal error")
+}
@@ -1,20 +1,19 @@
 package services
 
 import (
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/laertkokona/crud-test/repositories"
-	"github.com/laertkokona/crud-test/utils"
 	"github.com/peteprogrammer/go-automapper"
-	"net/http"
 )
 
 // TruckService interface using gin context
 type TruckService interface {
-	CreateTruck(truck models.Truck) (models.TruckDTO, int, error)
-	GetTruck(id int) (models.TruckDTO, int, error)
-	GetAllTrucks(pagination models.Pagination) ([]models.TruckDTO, int, error)
-	UpdateTruck(id int, truck models.Truck) (models.TruckDTO, int, error)
-	DeleteTruck(id int) (models.TruckDTO, int, error)
+	CreateTruck(truck models.Truck) (models.TruckDTO, error)
+	GetTruck(id int) (models.TruckDTO, error)
+	GetAllTrucks(pagination models.Pagination, query models.ListQuery) (models.Page[models.TruckDTO], error)
+	UpdateTruck(id int, truck models.Truck, version uint) (models.TruckDTO, error)
+	DeleteTruck(id int, version uint) (models.TruckDTO, error)
 }
 
 // truckService struct
@@ -30,65 +29,79 @@ func NewTruckService(truckRepo repositories.TruckRepo) TruckService {
 }
 
 // CreateTruck method that takes a models.Truck object and saves it to the database
-func (p truckService) CreateTruck(truck models.Truck) (models.TruckDTO, int, error) {
+func (p truckService) CreateTruck(truck models.Truck) (models.TruckDTO, error) {
 	truck, err := p.TruckRepo.Save(truck)
 	if err != nil {
-		return models.TruckDTO{}, http.StatusInternalServerError, err
+		return models.TruckDTO{}, err
 	}
 	var truckDTO models.TruckDTO
 	automapper.Map(truck, &truckDTO)
-	return truckDTO, http.StatusOK, nil
+	return truckDTO, nil
 }
 
 // GetTruck method that takes a truck id and returns the truck object
-func (p truckService) GetTruck(id int) (models.TruckDTO, int, error) {
+func (p truckService) GetTruck(id int) (models.TruckDTO, error) {
 	truck, err := p.TruckRepo.FindByID(id)
 	if err != nil {
-		return models.TruckDTO{}, http.StatusNotFound, err
+		return models.TruckDTO{}, notFoundError(err)
 	}
 	var truckDTO models.TruckDTO
 	automapper.Map(truck, &truckDTO)
-	return truckDTO, http.StatusOK, nil
+	return truckDTO, nil
 }
 
-// GetAllTrucks method that returns all trucks
-func (p truckService) GetAllTrucks(pagination models.Pagination) ([]models.TruckDTO, int, error) {
-	trucks, err := p.TruckRepo.FindAll(pagination)
+// GetAllTrucks method that returns a page of the trucks matching the filters and sorts of the query
+func (p truckService) GetAllTrucks(pagination models.Pagination, query models.ListQuery) (models.Page[models.TruckDTO], error) {
+	if err := query.Validate(pagination); err != nil {
+		return models.Page[models.TruckDTO]{}, errs.Validation(err)
+	}
+	trucks, total, err := p.TruckRepo.FindAll(pagination, query)
 	if err != nil {
-		return []models.TruckDTO{}, http.StatusInternalServerError, err
+		return models.Page[models.TruckDTO]{}, err
 	}
 	var trucksDTO []models.TruckDTO
 	automapper.Map(trucks, &trucksDTO)
-	return trucksDTO, http.StatusOK, nil
+	page := models.NewPage(trucksDTO, total, pagination)
+	if n := len(page.Data); n > 0 && !query.Sorted() {
+		page = page.WithNextCursor(trucks[n-1].Model)
+	}
+	return page, nil
 }
 
-// UpdateTruck method that takes a truck id and updates the truck in the database
-func (p truckService) UpdateTruck(id int, truck models.Truck) (models.TruckDTO, int, error) {
+// UpdateTruck method that takes a truck id and the version it was made to and updates the truck in the database
+func (p truckService) UpdateTruck(id int, truck models.Truck, version uint) (models.TruckDTO, error) {
 	truckDb, err := p.TruckRepo.FindByID(id)
 	if err != nil {
-		return models.TruckDTO{}, http.StatusNotFound, err
+		return models.TruckDTO{}, notFoundError(err)
 	}
-	utils.CopyNonEmptyFields(&truckDb, &truck)
-	truckDb, err = p.TruckRepo.Update(truckDb)
+	if err := checkVersion(truckDb.Version, version); err != nil {
+		return models.TruckDTO{}, err
+	}
+	truck.Model = truckDb.Model
+	truck.Version = truckDb.Version
+	truckDb, err = p.TruckRepo.Update(truck)
 	if err != nil {
-		return models.TruckDTO{}, http.StatusInternalServerError, err
+		return models.TruckDTO{}, versionError(err)
 	}
 	var truckDTO models.TruckDTO
 	automapper.Map(truckDb, &truckDTO)
-	return truckDTO, http.StatusOK, nil
+	return truckDTO, nil
 }
 
-// DeleteTruck method that takes a truck id and deletes the truck from the database
-func (p truckService) DeleteTruck(id int) (models.TruckDTO, int, error) {
+// DeleteTruck method that takes a truck id and the version it was read at and deletes the truck from the database
+func (p truckService) DeleteTruck(id int, version uint) (models.TruckDTO, error) {
 	truck, err := p.TruckRepo.FindByID(id)
 	if err != nil {
-		return models.TruckDTO{}, http.StatusNotFound, err
+		return models.TruckDTO{}, notFoundError(err)
+	}
+	if err := checkVersion(truck.Version, version); err != nil {
+		return models.TruckDTO{}, err
 	}
 	err = p.TruckRepo.Delete(truck)
 	if err != nil {
-		return models.TruckDTO{}, http.StatusInternalServerError, err
+		return models.TruckDTO{}, versionError(err)
 	}
 	var truckDTO models.TruckDTO
 	automapper.Map(truck, &truckDTO)
-	return truckDTO, http.StatusOK, nil
+	return truckDTO, nil
 }
@@ -2,11 +2,11 @@ package services
 
 import (
 	"errors"
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/peteprogrammer/go-automapper"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
-	"net/http"
 	"testing"
 )
 
@@ -43,7 +43,9 @@ var mockTrucks = []models.Truck{
 // mockTruckRepo is a mock implementation of the repositories.TruckRepo interface
 type mockTruckRepo struct {
 	// findAll is a mock function with given fields: pagination
-	findAll func(pagination models.Pagination) ([]models.Truck, error)
+	findAll func(pagination models.Pagination, query models.ListQuery) ([]models.Truck, int64, error)
+	// findInService is a mock function with no given fields
+	findInService func() ([]models.Truck, error)
 	// findByID is a mock function with given fields: id
 	findByID func(id int) (models.Truck, error)
 	// save is a mock function with given fields: truck
@@ -57,8 +59,13 @@ type mockTruckRepo struct {
 }
 
 // FindAll is a mock function with given fields: pagination
-func (_m *mockTruckRepo) FindAll(pagination models.Pagination) ([]models.Truck, error) {
-	return _m.findAll(pagination)
+func (_m *mockTruckRepo) FindAll(pagination models.Pagination, query models.ListQuery) ([]models.Truck, int64, error) {
+	return _m.findAll(pagination, query)
+}
+
+// FindInService is a mock function with no given fields
+func (_m *mockTruckRepo) FindInService() ([]models.Truck, error) {
+	return _m.findInService()
 }
 
 // FindByID is a mock function with given fields: id
@@ -89,7 +96,10 @@ func (_m *mockTruckRepo) DeleteById(id int) (models.Truck, error) {
 // newMockTruckRepo returns a new instance of the mockTruckRepo
 func newMockTruckRepo() *mockTruckRepo {
 	return &mockTruckRepo{
-		findAll: func(pagination models.Pagination) ([]models.Truck, error) {
+		findAll: func(pagination models.Pagination, query models.ListQuery) ([]models.Truck, int64, error) {
+			return mockTrucks, int64(len(mockTrucks)), nil
+		},
+		findInService: func() ([]models.Truck, error) {
 			return mockTrucks, nil
 		},
 		findByID: func(id int) (models.Truck, error) {
@@ -114,11 +124,14 @@ func newMockTruckRepo() *mockTruckRepo {
 // newMockTruckErrorRepo returns a new instance of the mockTruckRepo with error
 func newMockTruckErrorRepo() *mockTruckRepo {
 	return &mockTruckRepo{
-		findAll: func(pagination models.Pagination) ([]models.Truck, error) {
+		findAll: func(pagination models.Pagination, query models.ListQuery) ([]models.Truck, int64, error) {
+			return nil, 0, errors.New("error")
+		},
+		findInService: func() ([]models.Truck, error) {
 			return nil, errors.New("error")
 		},
 		findByID: func(id int) (models.Truck, error) {
-			return models.Truck{}, errors.New("error")
+			return models.Truck{}, gorm.ErrRecordNotFound
 		},
 		save: func(truck models.Truck) (models.Truck, error) {
 			return models.Truck{}, errors.New("error")
@@ -138,7 +151,10 @@ func newMockTruckErrorRepo() *mockTruckRepo {
 // newMockTruckSpecificErrorRepo returns a new instance of the mockTruckRepo with error
 func newMockTruckSpecificErrorRepo() *mockTruckRepo {
 	return &mockTruckRepo{
-		findAll: func(pagination models.Pagination) ([]models.Truck, error) {
+		findAll: func(pagination models.Pagination, query models.ListQuery) ([]models.Truck, int64, error) {
+			return mockTrucks, int64(len(mockTrucks)), nil
+		},
+		findInService: func() ([]models.Truck, error) {
 			return mockTrucks, nil
 		},
 		findByID: func(id int) (models.Truck, error) {
@@ -178,11 +194,10 @@ func TestCreateTruck(t *testing.T) {
 		ChassisNumber: "AA444AA",
 	}
 
-	truckDTO, status, err := mockService.CreateTruck(mockTruck)
+	truckDTO, err := mockService.CreateTruck(mockTruck)
 	var mockTruckDTO models.TruckDTO
 	automapper.Map(mockTruck, &mockTruckDTO)
 	assert.NoError(t, err, "should not return error")
-	assert.Equal(t, http.StatusOK, status, "should return status ok")
 	assert.Equal(t, mockTruckDTO, truckDTO, "should return truck")
 }
 
@@ -196,9 +211,9 @@ func TestCreateTruck_SaveError(t *testing.T) {
 		ChassisNumber: "AA444AA",
 	}
 
-	truckDTO, status, err := mockService.CreateTruck(mockTruck)
+	truckDTO, err := mockService.CreateTruck(mockTruck)
 	assert.Error(t, err, "should return error")
-	assert.Equal(t, http.StatusInternalServerError, status, "should return status internal server error")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err), "should return an internal error")
 	assert.Equal(t, models.TruckDTO{}, truckDTO, "should return empty truck")
 }
 
@@ -207,11 +222,10 @@ func TestGetTruck(t *testing.T) {
 	mockRepo := newMockTruckRepo()
 	mockService := NewTruckService(mockRepo)
 
-	truckDTO, status, err := mockService.GetTruck(1)
+	truckDTO, err := mockService.GetTruck(1)
 	var mockTruckDTO models.TruckDTO
 	automapper.Map(&mockTrucks[0], &mockTruckDTO)
 	assert.NoError(t, err, "should not return error")
-	assert.Equal(t, http.StatusOK, status, "should return status ok")
 	assert.Equal(t, mockTruckDTO, truckDTO, "should return truck")
 }
 
@@ -220,9 +234,9 @@ func TestGetTruck_FindError(t *testing.T) {
 	mockRepo := newMockTruckErrorRepo()
 	mockService := NewTruckService(mockRepo)
 
-	truckDTO, status, err := mockService.GetTruck(1)
+	truckDTO, err := mockService.GetTruck(1)
 	assert.Error(t, err, "should return error")
-	assert.Equal(t, http.StatusNotFound, status, "should return status not found error")
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err), "should return a not found error")
 	assert.Equal(t, models.TruckDTO{}, truckDTO, "should return empty truck")
 }
 
@@ -235,12 +249,12 @@ func TestGetAllTrucks(t *testing.T) {
 		Page:  1,
 		Limit: 10,
 	}
-	trucksDTO, status, err := mockService.GetAllTrucks(pagination)
+	trucksDTO, err := mockService.GetAllTrucks(pagination, models.ListQuery{})
 	var mockTrucksDTO []models.TruckDTO
 	automapper.Map(mockTrucks, &mockTrucksDTO)
 	assert.NoError(t, err, "should not return error")
-	assert.Equal(t, http.StatusOK, status, "should return status ok")
-	assert.Equal(t, mockTrucksDTO, trucksDTO, "should return trucks")
+	assert.Equal(t, mockTrucksDTO, trucksDTO.Data, "should return trucks")
+	assert.Equal(t, 1, *trucksDTO.TotalPages, "should return one page")
 }
 
 // TestGetAllTrucks_FindError tests services.GetAllTrucks using mockTruckRepo and gin
@@ -252,10 +266,10 @@ func TestGetAllTrucks_FindError(t *testing.T) {
 		Page:  1,
 		Limit: 10,
 	}
-	trucksDTO, status, err := mockService.GetAllTrucks(pagination)
+	trucksDTO, err := mockService.GetAllTrucks(pagination, models.ListQuery{})
 	assert.Error(t, err, "should return error")
-	assert.Equal(t, http.StatusInternalServerError, status, "should return status not found error")
-	assert.Equal(t, []models.TruckDTO{}, trucksDTO, "should return empty trucks")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err), "should return an internal error")
+	assert.Equal(t, models.Page[models.TruckDTO]{}, trucksDTO, "should return empty page")
 }
 
 // TestUpdateTruck tests services.UpdateTruck using mockTruckRepo and gin
@@ -268,12 +282,11 @@ func TestUpdateTruck(t *testing.T) {
 		ChassisNumber: "AA444AA",
 	}
 
-	truckDTO, status, err := mockService.UpdateTruck(1, mockTruck)
+	truckDTO, err := mockService.UpdateTruck(1, mockTruck, AnyVersion)
 	var mockTruckDTO models.TruckDTO
 	mockTruck.ID = 1
 	automapper.Map(mockTruck, &mockTruckDTO)
 	assert.NoError(t, err, "should not return error")
-	assert.Equal(t, http.StatusOK, status, "should return status ok")
 	assert.Equal(t, mockTruckDTO, truckDTO, "should return truck")
 }
 
@@ -287,9 +300,9 @@ func TestUpdateTruck_FindError(t *testing.T) {
 		ChassisNumber: "AA444AA",
 	}
 
-	truckDTO, status, err := mockService.UpdateTruck(1, mockTruck)
+	truckDTO, err := mockService.UpdateTruck(1, mockTruck, AnyVersion)
 	assert.Error(t, err, "should return error")
-	assert.Equal(t, http.StatusNotFound, status, "should return status not found error")
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err), "should return a not found error")
 	assert.Equal(t, models.TruckDTO{}, truckDTO, "should return empty truck")
 }
 
@@ -303,9 +316,9 @@ func TestUpdateTruck_UpdateError(t *testing.T) {
 		ChassisNumber: "AA444AA",
 	}
 
-	truckDTO, status, err := mockService.UpdateTruck(1, mockTruck)
+	truckDTO, err := mockService.UpdateTruck(1, mockTruck, AnyVersion)
 	assert.Error(t, err, "should return error")
-	assert.Equal(t, http.StatusInternalServerError, status, "should return status internal server error")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err), "should return an internal error")
 	assert.Equal(t, models.TruckDTO{}, truckDTO, "should return empty truck")
 }
 
@@ -314,11 +327,10 @@ func TestDeleteTruck(t *testing.T) {
 	mockRepo := newMockTruckRepo()
 	mockService := NewTruckService(mockRepo)
 
-	truckDTO, status, err := mockService.DeleteTruck(1)
+	truckDTO, err := mockService.DeleteTruck(1, AnyVersion)
 	var mockTruckDTO models.TruckDTO
 	automapper.Map(mockTrucks[0], &mockTruckDTO)
 	assert.NoError(t, err, "should not return error")
-	assert.Equal(t, http.StatusOK, status, "should return status ok")
 	assert.Equal(t, mockTruckDTO, truckDTO, "should return truck")
 }
 
@@ -327,9 +339,9 @@ func TestDeleteTruck_FindError(t *testing.T) {
 	mockRepo := newMockTruckErrorRepo()
 	mockService := NewTruckService(mockRepo)
 
-	truckDTO, status, err := mockService.DeleteTruck(1)
+	truckDTO, err := mockService.DeleteTruck(1, AnyVersion)
 	assert.Error(t, err, "should return error")
-	assert.Equal(t, http.StatusNotFound, status, "should return status not found error")
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err), "should return a not found error")
 	assert.Equal(t, models.TruckDTO{}, truckDTO, "should return empty truck")
 }
 
@@ -338,8 +350,8 @@ func TestDeleteTruck_DeleteError(t *testing.T) {
 	mockRepo := newMockTruckSpecificErrorRepo()
 	mockService := NewTruckService(mockRepo)
 
-	truckDTO, status, err := mockService.DeleteTruck(1)
+	truckDTO, err := mockService.DeleteTruck(1, AnyVersion)
 	assert.Error(t, err, "should return error")
-	assert.Equal(t, http.StatusInternalServerError, status, "should return status internal server error")
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err), "should return an internal error")
 	assert.Equal(t, models.TruckDTO{}, truckDTO, "should return empty truck")
 }
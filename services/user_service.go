@@ -2,40 +2,51 @@ package services
 
 import (
 	"errors"
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/models"
 	"github.com/laertkokona/crud-test/repositories"
 	"github.com/laertkokona/crud-test/utils"
 	"github.com/peteprogrammer/go-automapper"
-	"net/http"
+	"gorm.io/gorm"
+	"time"
 )
 
 // UserService interface
 type UserService interface {
-	CreateUser(user models.User) (models.UserDTO, int, error)
-	GetUser(id int) (models.UserDTO, int, error)
-	GetAllUsers(pagination models.Pagination) ([]models.UserDTO, int, error)
-	SignInUser(loginUser models.Login) (string, int, error)
-	SignOutUser() string
-	UpdateUser(id int, user models.User) (models.UserDTO, int, error)
-	DeleteUser(id int) (models.UserDTO, int, error)
+	CreateUser(user models.User) (models.UserDTO, error)
+	GetUser(id int) (models.UserDTO, error)
+	GetAllUsers(pagination models.Pagination, query models.ListQuery) (models.Page[models.UserDTO], error)
+	SignInUser(loginUser models.Login) (models.TokenPair, error)
+	RefreshToken(refreshToken string) (models.TokenPair, error)
+	SignOutUser(tokenID string, userID uint, expiresAt time.Time, refreshToken string) (string, error)
+	SignOutAllUser(userID uint) (string, error)
+	ResetPassword(username string, password string) (models.UserDTO, error)
+	UpdateUser(id int, user models.User, version uint) (models.UserDTO, error)
+	DeleteUser(id int, version uint) (models.UserDTO, error)
 }
 
 // userService struct
 type userService struct {
-	userRepo repositories.UserRepo
-	roleRepo repositories.RoleRepo
+	userRepo         repositories.UserRepo
+	roleRepo         repositories.RoleRepo
+	refreshTokenRepo repositories.RefreshTokenRepo
+	revocations      RevocationService
+	tokens           utils.TokenConfig
 }
 
 // NewUserService returns a new instance of UserService
-func NewUserService(uRepo repositories.UserRepo, rRepo repositories.RoleRepo) UserService {
+func NewUserService(uRepo repositories.UserRepo, rRepo repositories.RoleRepo, tRepo repositories.RefreshTokenRepo, revocations RevocationService, tokens utils.TokenConfig) UserService {
 	return userService{
-		userRepo: uRepo,
-		roleRepo: rRepo,
+		userRepo:         uRepo,
+		roleRepo:         rRepo,
+		refreshTokenRepo: tRepo,
+		revocations:      revocations,
+		tokens:           tokens,
 	}
 }
 
 // CreateUser method that takes a models.User object, hashes its password and saves it to the database
-func (u userService) CreateUser(user models.User) (models.UserDTO, int, error) {
+func (u userService) CreateUser(user models.User) (models.UserDTO, error) {
 	// get the user's password
 	// hash the user's password
 	// save the user to the database
@@ -45,37 +56,40 @@ func (u userService) CreateUser(user models.User) (models.UserDTO, int, error) {
 	user.Password = password
 	user, err := u.userRepo.Save(user)
 	if err != nil {
-		return models.UserDTO{}, http.StatusInternalServerError, err
+		return models.UserDTO{}, err
 	}
 	user.Password = ""
 	var returnUser models.UserDTO
 	automapper.Map(user, &returnUser)
-	return returnUser, http.StatusOK, nil
+	return returnUser, nil
 }
 
 // GetUser method that takes a user id and returns the user object
-func (u userService) GetUser(id int) (models.UserDTO, int, error) {
+func (u userService) GetUser(id int) (models.UserDTO, error) {
 	// get the user object from the database
 	// set the user's password to an empty string
 	// return the user object
 	user, err := u.userRepo.FindByID(id)
 	if err != nil {
-		return models.UserDTO{}, http.StatusInternalServerError, err
+		return models.UserDTO{}, err
 	}
 	user.Password = ""
 	var returnUser models.UserDTO
 	automapper.Map(user, &returnUser)
-	return returnUser, http.StatusOK, nil
+	return returnUser, nil
 }
 
-// GetAllUsers method that returns all the users in the database
-func (u userService) GetAllUsers(pagination models.Pagination) ([]models.UserDTO, int, error) {
-	// get all the users from the database
+// GetAllUsers method that returns a page of the users in the database matching the filters and sorts of the query
+func (u userService) GetAllUsers(pagination models.Pagination, query models.ListQuery) (models.Page[models.UserDTO], error) {
+	if err := query.Validate(pagination); err != nil {
+		return models.Page[models.UserDTO]{}, errs.Validation(err)
+	}
+	// get a page of the users from the database
 	// set the user's password to an empty string
-	// return the user object
-	users, err := u.userRepo.FindAll(pagination)
+	// return the page
+	users, total, err := u.userRepo.FindAll(pagination, query)
 	if err != nil {
-		return []models.UserDTO{}, http.StatusInternalServerError, err
+		return models.Page[models.UserDTO]{}, err
 	}
 	for i := range users {
 		users[i].Password = ""
@@ -87,75 +101,206 @@ func (u userService) GetAllUsers(pagination models.Pagination) ([]models.UserDTO
 	//	automapper.Map(user, &returnUser)
 	//	returnUsers = append(returnUsers, returnUser)
 	//}
-	return returnUsers, http.StatusOK, nil
+	page := models.NewPage(returnUsers, total, pagination)
+	if n := len(page.Data); n > 0 && !query.Sorted() {
+		page = page.WithNextCursor(users[n-1].Model)
+	}
+	return page, nil
 }
 
-// UpdateUser method that takes a user id and updates the user object in the database
-func (u userService) UpdateUser(id int, user models.User) (models.UserDTO, int, error) {
-	// find the user object in the database
-	// unmarshal the user object from the request body
+// UpdateUser method that takes a user id and the version it was made to and updates the user object in the database
+func (u userService) UpdateUser(id int, user models.User, version uint) (models.UserDTO, error) {
+	// find the user object in the database and check that it is still at the version
 	// set the user's id to the id of the user object in the database
 	// set the user's password to the password of the user object in the database
-	// update the user object in the database
-	// set the user's password to an empty string
+	// replace the user object in the database
 	// return the user object
 	userDb, err := u.userRepo.FindByID(id)
 	if err != nil {
-		return models.UserDTO{}, http.StatusNotFound, err
+		return models.UserDTO{}, notFoundError(err)
 	}
-	utils.CopyNonEmptyFields(&userDb, &user)
-	userDb, err = u.userRepo.Update(userDb)
+	if err := checkVersion(userDb.Version, version); err != nil {
+		return models.UserDTO{}, err
+	}
+	user.Model = userDb.Model
+	user.Password = userDb.Password
+	user.Version = userDb.Version
+	userDb, err = u.userRepo.Update(user)
 	if err != nil {
-		return models.UserDTO{}, http.StatusInternalServerError, err
+		return models.UserDTO{}, versionError(err)
 	}
 	var returnUser models.UserDTO
 	automapper.Map(userDb, &returnUser)
-	return returnUser, http.StatusOK, nil
+	return returnUser, nil
 }
 
-// DeleteUser method that takes a user id and deletes the user object from the database
-func (u userService) DeleteUser(id int) (models.UserDTO, int, error) {
-	// find the user object in the database
+// DeleteUser method that takes a user id and the version it was read at and deletes the user object from the database
+func (u userService) DeleteUser(id int, version uint) (models.UserDTO, error) {
+	// find the user object in the database and check that it is still at the version
 	// delete the user object from the database
 	// set the user's password to an empty string
 	// return the user object
 	user, err := u.userRepo.FindByID(id)
 	if err != nil {
-		return models.UserDTO{}, http.StatusNotFound, err
+		return models.UserDTO{}, notFoundError(err)
+	}
+	if err := checkVersion(user.Version, version); err != nil {
+		return models.UserDTO{}, err
 	}
 	err = u.userRepo.Delete(user)
 	if err != nil {
-		return models.UserDTO{}, http.StatusInternalServerError, err
+		return models.UserDTO{}, versionError(err)
 	}
 	var returnUser models.UserDTO
 	automapper.Map(user, &returnUser)
-	return returnUser, http.StatusOK, nil
+	return returnUser, nil
 }
 
-// SignInUser method that takes a models.User object and returns a token
-func (u userService) SignInUser(loginUser models.Login) (string, int, error) {
+// errInvalidLogin is the error of a sign in with an unknown username or a wrong password, they are not told apart
+var errInvalidLogin = errors.New("invalid username or password")
+
+// SignInUser method that takes a models.User object and returns an access token and the refresh token of a new session
+func (u userService) SignInUser(loginUser models.Login) (models.TokenPair, error) {
 	// find the user object in the database
 	// compare the user's password with the password in the database
-	// if the passwords don't match, return an error
-	// find the user's role in the database
-	// generate a token
-	// return the token
+	// if the user doesn't exist or the passwords don't match, return the same error, so neither tells which usernames exist
+	// issue the tokens of a new token family
 	userDb, err := u.userRepo.FindByUsername(loginUser.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.TokenPair{}, errs.Unauthorized(errInvalidLogin)
+	}
 	if err != nil {
-		return "", http.StatusNotFound, err
+		return models.TokenPair{}, err
 	}
 	if !utils.ComparePassword([]byte(userDb.Password), []byte(loginUser.Password)) {
-		return "", http.StatusUnauthorized, errors.New("invalid password")
+		return models.TokenPair{}, errs.Unauthorized(errInvalidLogin)
 	}
-	role, err := u.roleRepo.FindByID(userDb.RoleID)
+	return u.issueTokens(userDb, "", nil)
+}
+
+// RefreshToken method that exchanges a refresh token for a new token pair of the same family.
+// A refresh token can only be used once, presenting it again revokes its whole family
+func (u userService) RefreshToken(refreshToken string) (models.TokenPair, error) {
+	// find the refresh token by its hash
+	// revoke the family if the token was already used
+	// check the token is not revoked or expired
+	// issue the next tokens of the family, replacing this one
+	stored, err := u.refreshTokenRepo.FindByHash(utils.HashRefreshToken(refreshToken))
 	if err != nil {
-		return "", http.StatusNotFound, err
+		return models.TokenPair{}, errs.Unauthorized(errors.New("invalid refresh token"))
 	}
-	token := utils.GenerateToken(userDb, role.Name)
-	return token, http.StatusOK, nil
+	if stored.UsedAt != nil {
+		return models.TokenPair{}, errs.Unauthorized(u.revokeReusedFamily(stored))
+	}
+	if stored.RevokedAt != nil || !time.Now().Before(stored.ExpiresAt) {
+		return models.TokenPair{}, errs.Unauthorized(errors.New("refresh token has expired or been revoked"))
+	}
+	userDb, err := u.userRepo.FindByID(int(stored.UserID))
+	if err != nil {
+		return models.TokenPair{}, errs.Unauthorized(errors.New("invalid refresh token"))
+	}
+	pair, err := u.issueTokens(userDb, stored.FamilyID, &stored)
+	if errors.Is(err, repositories.ErrRefreshTokenUsed) {
+		// another request exchanged the same token first
+		return models.TokenPair{}, errs.Unauthorized(u.revokeReusedFamily(stored))
+	}
+	return pair, err
+}
+
+// revokeReusedFamily revokes the family of a refresh token that was presented after it had been used
+func (u userService) revokeReusedFamily(token models.RefreshToken) error {
+	if err := u.refreshTokenRepo.RevokeFamily(token.FamilyID); err != nil {
+		return err
+	}
+	return errors.New("refresh token reuse detected, the session has been revoked")
 }
 
-// SignOutUser method that returns an expired token
-func (u userService) SignOutUser() string {
-	return utils.GenerateExpiredToken()
+// issueTokens generates an access token and a refresh token for the user.
+// Without a family id a new family is started, otherwise the used refresh token is replaced by the new one
+func (u userService) issueTokens(user models.User, familyID string, used *models.RefreshToken) (models.TokenPair, error) {
+	role, err := u.roleRepo.FindByID(user.RoleID)
+	if err != nil {
+		return models.TokenPair{}, notFoundError(err)
+	}
+	if familyID == "" {
+		familyID = utils.NewTokenID()
+	}
+	refreshToken := utils.NewRefreshToken()
+	next := models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(u.tokens.RefreshTTL),
+	}
+	if used == nil {
+		err = u.refreshTokenRepo.Save(next)
+	} else {
+		err = u.refreshTokenRepo.Rotate(*used, next)
+	}
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	return models.TokenPair{
+		AccessToken:  u.tokens.GenerateToken(user, role),
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(u.tokens.AccessTTL / time.Second),
+	}, nil
+}
+
+// SignOutUser method that revokes the access token of the request and, if given, the refresh token family of the session,
+// and returns an expired token
+func (u userService) SignOutUser(tokenID string, userID uint, expiresAt time.Time, refreshToken string) (string, error) {
+	// tokens issued before they carried an id can only be revoked together
+	if tokenID == "" {
+		return u.SignOutAllUser(userID)
+	}
+	if refreshToken != "" {
+		stored, err := u.refreshTokenRepo.FindByHash(utils.HashRefreshToken(refreshToken))
+		if err == nil && stored.UserID == userID {
+			if err := u.refreshTokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := u.revocations.Revoke(tokenID, userID, expiresAt); err != nil {
+		return "", err
+	}
+	return u.tokens.GenerateExpiredToken(), nil
+}
+
+// SignOutAllUser method that revokes every access and refresh token issued to the user so far and returns an expired token
+func (u userService) SignOutAllUser(userID uint) (string, error) {
+	if err := u.refreshTokenRepo.RevokeUser(userID); err != nil {
+		return "", err
+	}
+	if err := u.revocations.RevokeAll(userID); err != nil {
+		return "", err
+	}
+	return u.tokens.GenerateExpiredToken(), nil
+}
+
+// ResetPassword method that sets a new password for the user with the username and signs the user out everywhere
+func (u userService) ResetPassword(username string, password string) (models.UserDTO, error) {
+	// find the user object in the database
+	// hash the new password and update the user
+	// revoke every token issued with the old password
+	if password == "" {
+		return models.UserDTO{}, errs.Validation(errors.New("password cannot be empty"))
+	}
+	userDb, err := u.userRepo.FindByUsername(username)
+	if err != nil {
+		return models.UserDTO{}, notFoundError(err)
+	}
+	userDb.Password = utils.GetHashPassword(password)
+	userDb, err = u.userRepo.Update(userDb)
+	if err != nil {
+		return models.UserDTO{}, versionError(err)
+	}
+	if _, err := u.SignOutAllUser(userDb.ID); err != nil {
+		return models.UserDTO{}, err
+	}
+	userDb.Password = ""
+	var returnUser models.UserDTO
+	automapper.Map(userDb, &returnUser)
+	return returnUser, nil
 }
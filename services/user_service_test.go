@@ -3,17 +3,21 @@ package services
 import (
 	"errors"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/laertkokona/crud-test/errs"
 	"github.com/laertkokona/crud-test/models"
+	"github.com/laertkokona/crud-test/repositories"
 	"github.com/laertkokona/crud-test/utils"
 	"github.com/peteprogrammer/go-automapper"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
 	"math"
-	"net/http"
 	"testing"
 	"time"
 )
 
+// mockTokens is the token configuration the services sign and validate tokens with in the tests
+var mockTokens = utils.TokenConfig{Secret: "test-secret", AccessTTL: 15 * time.Minute, RefreshTTL: 30 * 24 * time.Hour}
+
 var mockModel = []gorm.Model{
 	{ID: 1},
 	{ID: 2},
@@ -46,7 +50,7 @@ var mockUsers = []models.User{
 // mockUserRepo is a mock implementation of the repositories.UserRepo interface
 type mockUserRepo struct {
 	// findAll is a mock function with given fields: pagination
-	findAll func(pagination models.Pagination) ([]models.User, error)
+	findAll func(pagination models.Pagination, query models.ListQuery) ([]models.User, int64, error)
 	// findByID is a mock function with given fields: id
 	findByID func(id int) (models.User, error)
 	// findByUsername is a mock function with given fields: username
@@ -62,8 +66,8 @@ type mockUserRepo struct {
 }
 
 // FindAll is a mock function with given fields: pagination
-func (_m *mockUserRepo) FindAll(pagination models.Pagination) ([]models.User, error) {
-	return _m.findAll(pagination)
+func (_m *mockUserRepo) FindAll(pagination models.Pagination, query models.ListQuery) ([]models.User, int64, error) {
+	return _m.findAll(pagination, query)
 }
 
 // FindByID is a mock function with given fields: id
@@ -99,8 +103,8 @@ func (_m *mockUserRepo) DeleteById(id int) (models.User, error) {
 // newMockUserRepo returns a new instance of mockUserRepo
 func newMockUserRepo() *mockUserRepo {
 	return &mockUserRepo{
-		findAll: func(pagination models.Pagination) ([]models.User, error) {
-			return mockUsers, nil
+		findAll: func(pagination models.Pagination, query models.ListQuery) ([]models.User, int64, error) {
+			return mockUsers, int64(len(mockUsers)), nil
 		},
 		findByID: func(id int) (models.User, error) {
 			return mockUsers[id-1], nil
@@ -136,14 +140,14 @@ func newMockUserRepo() *mockUserRepo {
 // newMockUserErrorRepo returns a new instance of mockUserRepo with error
 func newMockUserErrorRepo() *mockUserRepo {
 	return &mockUserRepo{
-		findAll: func(pagination models.Pagination) ([]models.User, error) {
-			return []models.User{}, errors.New("error")
+		findAll: func(pagination models.Pagination, query models.ListQuery) ([]models.User, int64, error) {
+			return []models.User{}, 0, errors.New("error")
 		},
 		findByID: func(id int) (models.User, error) {
-			return models.User{}, errors.New("error")
+			return models.User{}, gorm.ErrRecordNotFound
 		},
 		findByUsername: func(username string) (models.User, error) {
-			return models.User{}, errors.New("error")
+			return models.User{}, gorm.ErrRecordNotFound
 		},
 		save: func(user models.User) (models.User, error) {
 			return models.User{}, errors.New("error")
@@ -163,11 +167,11 @@ func newMockUserErrorRepo() *mockUserRepo {
 // newMockUserSpecificErrorRepo returns a new instance of mockUserRepo with error
 func newMockUserSpecificErrorRepo() *mockUserRepo {
 	return &mockUserRepo{
-		findAll: func(pagination models.Pagination) ([]models.User, error) {
+		findAll: func(pagination models.Pagination, query models.ListQuery) ([]models.User, int64, error) {
 			for _, u := range mockUsers {
 				u.Password = ""
 			}
-			return mockUsers, nil
+			return mockUsers, int64(len(mockUsers)), nil
 		},
 		findByID: func(id int) (models.User, error) {
 			return mockUsers[id-1], nil
@@ -200,7 +204,7 @@ func newMockUserSpecificErrorRepo() *mockUserRepo {
 func TestNewUserService(t *testing.T) {
 	mockUserRepo := newMockUserRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
 	assert.NotNil(t, mockService)
 	assert.IsType(t, userService{}, mockService)
 }
@@ -209,7 +213,7 @@ func TestNewUserService(t *testing.T) {
 func TestCreateUser(t *testing.T) {
 	mockUserRepo := newMockUserRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
 	mockUser := models.User{
 		FirstName: "User",
 		LastName:  "Test",
@@ -217,12 +221,11 @@ func TestCreateUser(t *testing.T) {
 		Password:  utils.GetHashPassword("Test1234!"),
 		RoleID:    1,
 	}
-	user, status, err := mockService.CreateUser(mockUser)
+	user, err := mockService.CreateUser(mockUser)
 	mockUser.Password = ""
 	var expectedDTO models.UserDTO
 	automapper.Map(mockUser, &expectedDTO)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, expectedDTO, user)
 }
 
@@ -230,7 +233,7 @@ func TestCreateUser(t *testing.T) {
 func TestCreateUser_SaveError(t *testing.T) {
 	mockUserRepo := newMockUserErrorRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
 	mockUser := models.User{
 		FirstName: "User",
 		LastName:  "Test",
@@ -238,10 +241,10 @@ func TestCreateUser_SaveError(t *testing.T) {
 		Password:  utils.GetHashPassword("Test1234!"),
 		RoleID:    1,
 	}
-	user, status, err := mockService.CreateUser(mockUser)
+	user, err := mockService.CreateUser(mockUser)
 	mockUser.Password = ""
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.UserDTO{}, user)
 }
 
@@ -249,12 +252,11 @@ func TestCreateUser_SaveError(t *testing.T) {
 func TestGetUser(t *testing.T) {
 	mockUserRepo := newMockUserRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
-	user, status, err := mockService.GetUser(1)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+	user, err := mockService.GetUser(1)
 	var expectedDTO models.UserDTO
 	automapper.Map(mockUsers[0], &expectedDTO)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, expectedDTO, user)
 }
 
@@ -262,10 +264,10 @@ func TestGetUser(t *testing.T) {
 func TestGetUser_FindByIDError(t *testing.T) {
 	mockUserRepo := newMockUserErrorRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
-	user, status, err := mockService.GetUser(1)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+	user, err := mockService.GetUser(1)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.UserDTO{}, user)
 }
 
@@ -273,43 +275,42 @@ func TestGetUser_FindByIDError(t *testing.T) {
 func TestGetAllUsers(t *testing.T) {
 	mockUserRepo := newMockUserRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
-	users, status, err := mockService.GetAllUsers(models.Pagination{Page: 1, Limit: 10})
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+	users, err := mockService.GetAllUsers(models.Pagination{Page: 1, Limit: 10}, models.ListQuery{})
 	var expectedDTOs []models.UserDTO
 	automapper.Map(mockUsers, &expectedDTOs)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, status)
-	assert.Equal(t, expectedDTOs, users)
+	assert.Equal(t, expectedDTOs, users.Data)
+	assert.Equal(t, int64(len(mockUsers)), *users.Total)
 }
 
 // TestGetAllUsers_FindAllError is a test function for GetAllUsers with error
 func TestGetAllUsers_FindAllError(t *testing.T) {
 	mockUserRepo := newMockUserErrorRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
-	users, status, err := mockService.GetAllUsers(models.Pagination{})
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+	users, err := mockService.GetAllUsers(models.Pagination{}, models.ListQuery{})
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
-	assert.Equal(t, []models.UserDTO{}, users)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+	assert.Equal(t, models.Page[models.UserDTO]{}, users)
 }
 
 // TestUpdateUser is a test function for UpdateUser
 func TestUpdateUser(t *testing.T) {
 	mockUserRepo := newMockUserRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
 	mockUser := models.User{
 		FirstName: "Test",
 		LastName:  "Test",
 		Username:  "testTest",
 		RoleID:    1,
 	}
-	resUser, status, err := mockService.UpdateUser(1, mockUser)
+	resUser, err := mockService.UpdateUser(1, mockUser, AnyVersion)
 	mockUser.ID = 1
 	var expectedDTO models.UserDTO
 	automapper.Map(mockUser, &expectedDTO)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, expectedDTO, resUser)
 }
 
@@ -317,17 +318,17 @@ func TestUpdateUser(t *testing.T) {
 func TestUpdateUser_FindByIdError(t *testing.T) {
 	mockUserRepo := newMockUserErrorRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
 	mockUser := models.User{
 		FirstName: "Test",
 		LastName:  "Test",
 		Username:  "testTest",
 		RoleID:    1,
 	}
-	resUser, status, err := mockService.UpdateUser(1, mockUser)
+	resUser, err := mockService.UpdateUser(1, mockUser, AnyVersion)
 	mockUser.ID = 1
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Equal(t, models.UserDTO{}, resUser)
 }
 
@@ -335,15 +336,15 @@ func TestUpdateUser_FindByIdError(t *testing.T) {
 func TestUpdateUser_UpdateError(t *testing.T) {
 	mockUserRepo := newMockUserSpecificErrorRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
 	mockUser := models.User{
 		FirstName: "Test",
 		LastName:  "Test",
 		Username:  "testTest",
 	}
-	resUser, status, err := mockService.UpdateUser(1, mockUser)
+	resUser, err := mockService.UpdateUser(1, mockUser, AnyVersion)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.UserDTO{}, resUser)
 }
 
@@ -351,12 +352,11 @@ func TestUpdateUser_UpdateError(t *testing.T) {
 func TestDeleteUser(t *testing.T) {
 	mockUserRepo := newMockUserRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
-	user, status, err := mockService.DeleteUser(1)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+	user, err := mockService.DeleteUser(1, AnyVersion)
 	var expectedDTO models.UserDTO
 	automapper.Map(mockUsers[0], &expectedDTO)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.Equal(t, expectedDTO, user)
 }
 
@@ -364,10 +364,10 @@ func TestDeleteUser(t *testing.T) {
 func TestDeleteUser_FindByIdError(t *testing.T) {
 	mockUserRepo := newMockUserErrorRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
-	user, status, err := mockService.DeleteUser(1)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+	user, err := mockService.DeleteUser(1, AnyVersion)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Equal(t, models.UserDTO{}, user)
 }
 
@@ -375,10 +375,10 @@ func TestDeleteUser_FindByIdError(t *testing.T) {
 func TestDeleteUser_DeleteError(t *testing.T) {
 	mockUserRepo := newMockUserSpecificErrorRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
-	user, status, err := mockService.DeleteUser(1)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+	user, err := mockService.DeleteUser(1, AnyVersion)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
 	assert.Equal(t, models.UserDTO{}, user)
 }
 
@@ -386,16 +386,15 @@ func TestDeleteUser_DeleteError(t *testing.T) {
 func TestSignInUser(t *testing.T) {
 	mockUserRepo := newMockUserRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
 	user := models.Login{
 		Username: "sysAdminTest",
 		Password: "Test1234!",
 	}
-	token, status, err := mockService.SignInUser(user)
+	token, err := mockService.SignInUser(user)
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, status)
 	assert.NotEmpty(t, token)
-	jwtToken, err := utils.ValidateToken(token)
+	jwtToken, err := mockTokens.ValidateToken(token.AccessToken)
 	assert.NoError(t, err)
 	assert.True(t, jwtToken.Valid)
 }
@@ -404,14 +403,15 @@ func TestSignInUser(t *testing.T) {
 func TestSignInUser_FindByUsernameError(t *testing.T) {
 	mockUserRepo := newMockUserErrorRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
 	user := models.Login{
 		Username: "sysAdminTest",
 		Password: "Test1234!",
 	}
-	token, status, err := mockService.SignInUser(user)
+	token, err := mockService.SignInUser(user)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindUnauthorized, errs.KindOf(err))
+	assert.EqualError(t, err, "invalid username or password")
 	assert.Empty(t, token)
 }
 
@@ -419,14 +419,15 @@ func TestSignInUser_FindByUsernameError(t *testing.T) {
 func TestSignInUser_ValidatePasswordError(t *testing.T) {
 	mockUserRepo := newMockUserRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
 	user := models.Login{
 		Username: "sysAdminTest",
 		Password: "11!",
 	}
-	token, status, err := mockService.SignInUser(user)
+	token, err := mockService.SignInUser(user)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, errs.KindUnauthorized, errs.KindOf(err))
+	assert.EqualError(t, err, "invalid username or password")
 	assert.Empty(t, token)
 }
 
@@ -434,14 +435,14 @@ func TestSignInUser_ValidatePasswordError(t *testing.T) {
 func TestSignInUser_FindByIDError(t *testing.T) {
 	mockUserRepo := newMockUserRepo()
 	mockRoleRepo := NewMockRoleErrorRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
 	user := models.Login{
 		Username: "sysAdminTest",
 		Password: "Test1234!",
 	}
-	token, status, err := mockService.SignInUser(user)
+	token, err := mockService.SignInUser(user)
 	assert.Error(t, err)
-	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
 	assert.Empty(t, token)
 }
 
@@ -449,9 +450,11 @@ func TestSignInUser_FindByIDError(t *testing.T) {
 func TestSignOutUser(t *testing.T) {
 	mockUserRepo := newMockUserRepo()
 	mockRoleRepo := NewMockRoleRepo()
-	mockService := NewUserService(mockUserRepo, mockRoleRepo)
-	token := mockService.SignOutUser()
-	jwtToken, err := utils.ValidateToken(token)
+	revocations := NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), revocations, mockTokens)
+	token, err := mockService.SignOutUser("token-1", 1, time.Now().Add(time.Hour), "")
+	assert.NoError(t, err)
+	jwtToken, err := mockTokens.ValidateToken(token)
 	assert.NoError(t, err)
 	assert.True(t, jwtToken.Valid)
 	claims, ok := jwtToken.Claims.(jwt.MapClaims)
@@ -459,4 +462,270 @@ func TestSignOutUser(t *testing.T) {
 	assert.Equal(t, claims["exp"], claims["iat"])
 	sec, dec := math.Modf(claims["exp"].(float64))
 	assert.True(t, time.Now().After(time.Unix(int64(sec), int64(dec*float64(time.Second)))))
+	assert.True(t, revocations.IsRevoked("token-1", 1, time.Now()), "the signed out token should be revoked")
+	assert.False(t, revocations.IsRevoked("token-2", 1, time.Now()), "other tokens of the user should stay valid")
+}
+func TestSignOutUser_RevokeError(t *testing.T) {
+	mockUserRepo := newMockUserRepo()
+	mockRoleRepo := NewMockRoleRepo()
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationErrorRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+	token, err := mockService.SignOutUser("token-1", 1, time.Now().Add(time.Hour), "")
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+	assert.Empty(t, token)
+}
+
+// TestSignOutAllUser is a test function for SignOutAllUser
+func TestSignOutAllUser(t *testing.T) {
+	mockUserRepo := newMockUserRepo()
+	mockRoleRepo := NewMockRoleRepo()
+	revocations := NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL)
+	mockService := NewUserService(mockUserRepo, mockRoleRepo, newMockRefreshTokenRepo(), revocations, mockTokens)
+	token, err := mockService.SignOutAllUser(1)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.True(t, revocations.IsRevoked("token-1", 1, time.Now().Add(-time.Hour)), "earlier tokens of the user should be revoked")
+	assert.False(t, revocations.IsRevoked("token-1", 2, time.Now().Add(-time.Hour)), "tokens of other users should stay valid")
+	assert.False(t, revocations.IsRevoked("token-2", 1, time.Now().Add(time.Minute)), "tokens issued afterwards should be valid")
+}
+
+// TestResetPassword tests that ResetPassword hashes the new password and signs the user out everywhere
+func TestResetPassword(t *testing.T) {
+	mockUserRepo := newMockUserRepo()
+	var saved models.User
+	mockUserRepo.update = func(user models.User) (models.User, error) {
+		saved = user
+		return user, nil
+	}
+	revocations := NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL)
+	mockService := NewUserService(mockUserRepo, NewMockRoleRepo(), newMockRefreshTokenRepo(), revocations, mockTokens)
+
+	user, err := mockService.ResetPassword("adminTest", "NewPassword1!")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "adminTest", user.Username)
+	assert.True(t, utils.ComparePassword([]byte(saved.Password), []byte("NewPassword1!")), "the new password should be saved hashed")
+	assert.True(t, revocations.IsRevoked("token-1", saved.ID, time.Now().Add(-time.Hour)), "tokens issued before the reset should be revoked")
+}
+
+// TestResetPassword_EmptyPassword tests that ResetPassword refuses an empty password
+func TestResetPassword_EmptyPassword(t *testing.T) {
+	mockService := NewUserService(newMockUserRepo(), NewMockRoleRepo(), newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+
+	_, err := mockService.ResetPassword("adminTest", "")
+
+	assert.EqualError(t, err, "password cannot be empty")
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
+}
+
+// TestResetPassword_NotFound tests ResetPassword for an unknown user
+func TestResetPassword_NotFound(t *testing.T) {
+	mockService := NewUserService(newMockUserErrorRepo(), NewMockRoleRepo(), newMockRefreshTokenRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+
+	_, err := mockService.ResetPassword("nobody", "NewPassword1!")
+
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindNotFound, errs.KindOf(err))
+}
+
+// mockRefreshTokenRepo is a mock implementation of the repositories.RefreshTokenRepo interface
+type mockRefreshTokenRepo struct {
+	// save is a mock function with given fields: token
+	save func(token models.RefreshToken) error
+	// findByHash is a mock function with given fields: hash
+	findByHash func(hash string) (models.RefreshToken, error)
+	// rotate is a mock function with given fields: used, next
+	rotate func(used models.RefreshToken, next models.RefreshToken) error
+	// revokeFamily is a mock function with given fields: familyID
+	revokeFamily func(familyID string) error
+	// revokeUser is a mock function with given fields: userID
+	revokeUser func(userID uint) error
+}
+
+// Save is a mock function with given fields: token
+func (_m *mockRefreshTokenRepo) Save(token models.RefreshToken) error {
+	return _m.save(token)
+}
+
+// FindByHash is a mock function with given fields: hash
+func (_m *mockRefreshTokenRepo) FindByHash(hash string) (models.RefreshToken, error) {
+	return _m.findByHash(hash)
+}
+
+// Rotate is a mock function with given fields: used, next
+func (_m *mockRefreshTokenRepo) Rotate(used models.RefreshToken, next models.RefreshToken) error {
+	return _m.rotate(used, next)
+}
+
+// RevokeFamily is a mock function with given fields: familyID
+func (_m *mockRefreshTokenRepo) RevokeFamily(familyID string) error {
+	return _m.revokeFamily(familyID)
+}
+
+// RevokeUser is a mock function with given fields: userID
+func (_m *mockRefreshTokenRepo) RevokeUser(userID uint) error {
+	return _m.revokeUser(userID)
+}
+
+// newMockRefreshTokenRepo returns a new instance of the mockRefreshTokenRepo that keeps the tokens in memory
+// and marks them used and revoked the way the database does
+func newMockRefreshTokenRepo() *mockRefreshTokenRepo {
+	tokens := map[string]*models.RefreshToken{}
+	now := time.Now()
+	return &mockRefreshTokenRepo{
+		save: func(token models.RefreshToken) error {
+			token.ID = uint(len(tokens) + 1)
+			tokens[token.TokenHash] = &token
+			return nil
+		},
+		findByHash: func(hash string) (models.RefreshToken, error) {
+			token, ok := tokens[hash]
+			if !ok {
+				return models.RefreshToken{}, gorm.ErrRecordNotFound
+			}
+			return *token, nil
+		},
+		rotate: func(used models.RefreshToken, next models.RefreshToken) error {
+			stored := tokens[used.TokenHash]
+			if stored.UsedAt != nil || stored.RevokedAt != nil {
+				return repositories.ErrRefreshTokenUsed
+			}
+			stored.UsedAt = &now
+			next.ID = uint(len(tokens) + 1)
+			tokens[next.TokenHash] = &next
+			return nil
+		},
+		revokeFamily: func(familyID string) error {
+			for _, token := range tokens {
+				if token.FamilyID == familyID {
+					token.RevokedAt = &now
+				}
+			}
+			return nil
+		},
+		revokeUser: func(userID uint) error {
+			for _, token := range tokens {
+				if token.UserID == userID {
+					token.RevokedAt = &now
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// newMockRefreshTokenErrorRepo returns a new instance of the mockRefreshTokenRepo with error
+func newMockRefreshTokenErrorRepo() *mockRefreshTokenRepo {
+	return &mockRefreshTokenRepo{
+		save: func(token models.RefreshToken) error {
+			return errors.New("error")
+		},
+		findByHash: func(hash string) (models.RefreshToken, error) {
+			return models.RefreshToken{}, errors.New("error")
+		},
+		rotate: func(used models.RefreshToken, next models.RefreshToken) error {
+			return errors.New("error")
+		},
+		revokeFamily: func(familyID string) error {
+			return errors.New("error")
+		},
+		revokeUser: func(userID uint) error {
+			return errors.New("error")
+		},
+	}
+}
+
+// newRefreshTestService returns a userService backed by an in-memory refresh token repository and the tokens of a signed in user
+// the signed in user is sysAdminTest, whose id is 3
+func newRefreshTestService(t *testing.T) (UserService, *mockRefreshTokenRepo, models.TokenPair) {
+	refreshTokenRepo := newMockRefreshTokenRepo()
+	mockService := NewUserService(newMockUserRepo(), NewMockRoleRepo(), refreshTokenRepo, NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+	tokens, err := mockService.SignInUser(models.Login{Username: "sysAdminTest", Password: "Test1234!"})
+	assert.NoError(t, err)
+	return mockService, refreshTokenRepo, tokens
+}
+
+// TestSignInUser_RefreshToken tests that SignInUser starts a session with a short-lived access token and a stored refresh token
+func TestSignInUser_RefreshToken(t *testing.T) {
+	_, refreshTokenRepo, tokens := newRefreshTestService(t)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, int64(mockTokens.AccessTTL/time.Second), tokens.ExpiresIn)
+	stored, err := refreshTokenRepo.FindByHash(utils.HashRefreshToken(tokens.RefreshToken))
+	assert.NoError(t, err, "should store the refresh token by its hash")
+	assert.NotEqual(t, tokens.RefreshToken, stored.TokenHash, "should not store the refresh token itself")
+}
+func TestSignInUser_SaveRefreshTokenError(t *testing.T) {
+	mockService := NewUserService(newMockUserRepo(), NewMockRoleRepo(), newMockRefreshTokenErrorRepo(), NewRevocationService(newMockRevocationRepo(), time.Minute, mockTokens.AccessTTL), mockTokens)
+	tokens, err := mockService.SignInUser(models.Login{Username: "sysAdminTest", Password: "Test1234!"})
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindInternal, errs.KindOf(err))
+	assert.Empty(t, tokens)
+}
+
+// TestRefreshToken tests that RefreshToken rotates the refresh token within the same family
+func TestRefreshToken(t *testing.T) {
+	mockService, refreshTokenRepo, tokens := newRefreshTestService(t)
+	refreshed, err := mockService.RefreshToken(tokens.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken, "should issue a new refresh token")
+	_, err = mockTokens.ValidateToken(refreshed.AccessToken)
+	assert.NoError(t, err, "should issue a valid access token")
+
+	used, _ := refreshTokenRepo.FindByHash(utils.HashRefreshToken(tokens.RefreshToken))
+	next, _ := refreshTokenRepo.FindByHash(utils.HashRefreshToken(refreshed.RefreshToken))
+	assert.NotNil(t, used.UsedAt, "should mark the old refresh token as used")
+	assert.Equal(t, used.FamilyID, next.FamilyID, "should keep the token family")
+}
+func TestRefreshToken_Reuse(t *testing.T) {
+	mockService, _, tokens := newRefreshTestService(t)
+	refreshed, err := mockService.RefreshToken(tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	_, err = mockService.RefreshToken(tokens.RefreshToken)
+	assert.Error(t, err, "should reject a refresh token that was already used")
+	assert.Equal(t, errs.KindUnauthorized, errs.KindOf(err))
+
+	_, err = mockService.RefreshToken(refreshed.RefreshToken)
+	assert.Error(t, err, "should revoke the rest of the family")
+	assert.Equal(t, errs.KindUnauthorized, errs.KindOf(err))
+}
+func TestRefreshToken_Unknown(t *testing.T) {
+	mockService, _, _ := newRefreshTestService(t)
+	_, err := mockService.RefreshToken("unknown")
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindUnauthorized, errs.KindOf(err))
+}
+func TestRefreshToken_Expired(t *testing.T) {
+	mockService, refreshTokenRepo, tokens := newRefreshTestService(t)
+	findByHash := refreshTokenRepo.findByHash
+	refreshTokenRepo.findByHash = func(hash string) (models.RefreshToken, error) {
+		token, err := findByHash(hash)
+		token.ExpiresAt = time.Now().Add(-time.Minute)
+		return token, err
+	}
+	_, err := mockService.RefreshToken(tokens.RefreshToken)
+	assert.Error(t, err)
+	assert.Equal(t, errs.KindUnauthorized, errs.KindOf(err))
+}
+
+// TestSignOutUser_RefreshToken tests that SignOutUser ends the session of the given refresh token
+func TestSignOutUser_RefreshToken(t *testing.T) {
+	mockService, _, tokens := newRefreshTestService(t)
+	_, err := mockService.SignOutUser("token-1", 3, time.Now().Add(time.Hour), tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	_, err = mockService.RefreshToken(tokens.RefreshToken)
+	assert.Error(t, err, "should revoke the refresh token of the session")
+	assert.Equal(t, errs.KindUnauthorized, errs.KindOf(err))
+}
+
+// TestSignOutAllUser_RefreshToken tests that SignOutAllUser revokes the refresh tokens of the user
+func TestSignOutAllUser_RefreshToken(t *testing.T) {
+	mockService, _, tokens := newRefreshTestService(t)
+	_, err := mockService.SignOutAllUser(3)
+	assert.NoError(t, err)
+
+	_, err = mockService.RefreshToken(tokens.RefreshToken)
+	assert.Error(t, err, "should revoke the refresh tokens of the user")
+	assert.Equal(t, errs.KindUnauthorized, errs.KindOf(err))
 }
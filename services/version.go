@@ -0,0 +1,28 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/laertkokona/crud-test/repositories"
+)
+
+// AnyVersion is the version a change is made to when the client did not say which version it read, it does not guard
+// against changes made by others since then
+const AnyVersion uint = 0
+
+// checkVersion checks that the version a change is made to is the current version of the entity
+func checkVersion(current, version uint) error {
+	if version != AnyVersion && version != current {
+		return errs.Stale(fmt.Errorf("version %d is not the current version %d", version, current))
+	}
+	return nil
+}
+
+// versionError returns an error of a repository as stale when the entity was changed by someone else since it was read
+func versionError(err error) error {
+	if errors.Is(err, repositories.ErrVersionChanged) {
+		return errs.Stale(err)
+	}
+	return err
+}
@@ -1,11 +1,15 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/laertkokona/crud-test/models"
 	"golang.org/x/crypto/bcrypt"
-	"os"
+	"math"
 	"time"
 )
 
@@ -34,28 +38,70 @@ func ComparePassword(hashedPassword, password []byte) bool {
 	return bcrypt.CompareHashAndPassword(hashedPassword, password) == nil
 }
 
-// GenerateToken generates token with claims for user id
-func GenerateToken(user models.User, role string) string {
+// TokenConfig has the secret tokens are signed with and how long they are valid,
+// access tokens are short-lived and renewed with a refresh token
+type TokenConfig struct {
+	Secret     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// NewTokenID returns a random id that identifies a single token so it can be revoked
+func NewTokenID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// NewRefreshToken returns a random opaque refresh token
+func NewRefreshToken() string {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// HashRefreshToken returns the hash a refresh token is stored and looked up by
+func HashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// GenerateToken generates token with claims for user id, a unique token id and the role with its permissions
+func (c TokenConfig) GenerateToken(user models.User, role models.Role) string {
+	now := time.Now()
 	// create claims
 	claims := jwt.MapClaims{
-		"exp":  time.Now().Add(time.Hour * 5).Unix(),
-		"iat":  time.Now().Unix(),
+		"exp":  now.Add(c.AccessTTL).Unix(),
+		"iat":  float64(now.UnixMicro()) / 1e6,
+		"jti":  NewTokenID(),
 		"sub":  user.ID,
 		"user": user.Username,
-		"role": role,
+		"role": role.Name,
+		"perm": role.PermissionNames(),
 	}
 
 	// create token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	t, err := token.SignedString([]byte(c.Secret))
 	if err != nil {
 		panic(err)
 	}
 	return t
 }
 
+// IssuedAt returns the time a token was issued from its iat claim, to the microsecond. The claim is in seconds with a
+// fraction, so a token issued right after its user signed out everywhere is told apart from the ones issued before
+func IssuedAt(claims jwt.MapClaims) time.Time {
+	iat, _ := claims["iat"].(float64)
+	return time.UnixMicro(int64(math.Round(iat * 1e6)))
+}
+
 // GenerateExpiredToken generates token with claims for user id and expiration time set to now
-func GenerateExpiredToken() string {
+func (c TokenConfig) GenerateExpiredToken() string {
 	now := time.Now().Unix()
 	// create claims
 	claims := jwt.MapClaims{
@@ -65,7 +111,7 @@ func GenerateExpiredToken() string {
 
 	// create token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	t, err := token.SignedString([]byte(c.Secret))
 	if err != nil {
 		panic(err)
 	}
@@ -73,21 +119,21 @@ func GenerateExpiredToken() string {
 }
 
 // ValidateToken validate the given token
-func ValidateToken(token string) (*jwt.Token, error) {
+func (c TokenConfig) ValidateToken(token string) (*jwt.Token, error) {
 	// 2nd arg function return secret key after checking if the signing method is HMAC and returned key is used by 'Parse' to decode the token)
 	return jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			// nil secret key
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(os.Getenv("JWT_SECRET")), nil
+		return []byte(c.Secret), nil
 	})
 }
 
 // GetClaimsFromToken get claims from token
-func GetClaimsFromToken(token string) (jwt.MapClaims, error) {
+func (c TokenConfig) GetClaimsFromToken(token string) (jwt.MapClaims, error) {
 	// validate token
-	t, err := ValidateToken(token)
+	t, err := c.ValidateToken(token)
 	if err != nil {
 		return nil, err
 	}
@@ -9,6 +9,9 @@ import (
 	"time"
 )
 
+// testTokens is the token configuration the token tests sign and validate with
+var testTokens = TokenConfig{Secret: "test-secret", AccessTTL: 15 * time.Minute, RefreshTTL: 30 * 24 * time.Hour}
+
 // TestGetHashPassword tests the GetHashPassword function
 func TestGetHashPassword(t *testing.T) {
 	password := "password"
@@ -33,26 +36,42 @@ func TestComparePasswords(t *testing.T) {
 
 // TestGenerateToken tests the GenerateToken function
 func TestGenerateToken(t *testing.T) {
-	token := GenerateToken(models.User{Model: gorm.Model{ID: 1}, Username: "username"}, "role")
+	role := models.Role{Name: "role", Permissions: []models.Permission{{Name: ItemsRead}}}
+	token := testTokens.GenerateToken(models.User{Model: gorm.Model{ID: 1}, Username: "username"}, role)
 	assert.NotEqual(t, token, "", "Expected token to be valid, got invalid")
-	tokenClaims, err := GetClaimsFromToken(token)
+	tokenClaims, err := testTokens.GetClaimsFromToken(token)
 	assert.NoError(t, err, "Expected no error, got", err)
 	assert.True(t, tokenClaims["exp"].(float64) > float64(time.Now().Unix()), "Expected token to be valid, got expired")
+	assert.Equal(t, "role", tokenClaims["role"], "Expected token to have the role name")
+	assert.Equal(t, []interface{}{ItemsRead}, tokenClaims["perm"], "Expected token to have the role permissions")
+}
+
+// TestGenerateToken_Config tests that a token expires after the access lifetime and only validates with its secret
+func TestGenerateToken_Config(t *testing.T) {
+	tokens := TokenConfig{Secret: "first-secret", AccessTTL: time.Hour}
+	token := tokens.GenerateToken(models.User{Model: gorm.Model{ID: 1}}, models.Role{Name: "role"})
+
+	claims, err := tokens.GetClaimsFromToken(token)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(time.Hour).Unix(), claims["exp"], 5, "Expected the token to expire after the access lifetime")
+
+	_, err = TokenConfig{Secret: "second-secret"}.GetClaimsFromToken(token)
+	assert.Error(t, err, "Expected a token signed with another secret to be invalid")
 }
 
 // TestGenerateExpiredToken tests the GenerateExpiredToken function
 func TestGenerateExpiredToken(t *testing.T) {
-	token := GenerateExpiredToken()
+	token := testTokens.GenerateExpiredToken()
 	assert.NotEqual(t, token, "", "Expected token to be valid, got invalid")
-	tokenClaims, err := GetClaimsFromToken(token)
+	tokenClaims, err := testTokens.GetClaimsFromToken(token)
 	assert.NoError(t, err, "Expected no error, got", err)
 	assert.True(t, tokenClaims["exp"].(float64) <= float64(time.Now().Unix()), "Expected token to be expired, got valid")
 }
 
 // TestValidateToken tests the ValidateToken function
 func TestValidateToken(t *testing.T) {
-	token := GenerateExpiredToken()
-	tkn, err := ValidateToken(token)
+	token := testTokens.GenerateExpiredToken()
+	tkn, err := testTokens.ValidateToken(token)
 	assert.NoError(t, err, "Expected error, got none")
 	// assert that tkn type is tha same as *jwt.Token
 	assert.IsType(t, &jwt.Token{}, tkn, "Expected type to be *jwt.Token, got", tkn)
@@ -60,17 +79,28 @@ func TestValidateToken(t *testing.T) {
 
 // TestGetClaimsFromToken tests the GetClaimsFromToken function
 func TestGetClaimsFromToken(t *testing.T) {
-	token := GenerateExpiredToken()
-	tokenClaims, err := GetClaimsFromToken(token)
+	token := testTokens.GenerateExpiredToken()
+	tokenClaims, err := testTokens.GetClaimsFromToken(token)
 	assert.NoError(t, err, "Expected no error, got", err)
 	assert.NotEmptyf(t, tokenClaims["exp"], "Expected token to have expiration time, got empty")
 	assert.NotEmptyf(t, tokenClaims["iat"], "Expected token to have issued at time, got empty")
 }
 
+// TestIssuedAt tests that the IssuedAt function reads the time a token was issued to the microsecond
+func TestIssuedAt(t *testing.T) {
+	before := time.Now().Truncate(time.Microsecond)
+	token := testTokens.GenerateToken(models.User{}, models.Role{})
+	tokenClaims, err := testTokens.GetClaimsFromToken(token)
+	assert.NoError(t, err, "Expected no error, got", err)
+	issuedAt := IssuedAt(tokenClaims)
+	assert.False(t, issuedAt.Before(before), "Expected the issue time %v not to be before %v", issuedAt, before)
+	assert.WithinDuration(t, before, issuedAt, time.Second)
+}
+
 // TestGetClaimsFromToken_ValidateTokenError tests the GetClaimsFromToken function
 func TestGetClaimsFromToken_ValidateTokenError(t *testing.T) {
 	token := generateBadToken()
-	tokenClaims, err := GetClaimsFromToken(token)
+	tokenClaims, err := testTokens.GetClaimsFromToken(token)
 	assert.Error(t, err, "Expected no error, got", err)
 	assert.Empty(t, tokenClaims, "Expected token claims to be empty, got", tokenClaims)
 }
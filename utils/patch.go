@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/laertkokona/crud-test/errs"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the patch documents a PATCH request can have
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// ApplyPatch applies a patch document of the media type to a json document and returns the patched document.
+// A patch that is not valid is a validation error, a patch that does not fit the document is a conflict
+func ApplyPatch(contentType string, document, patch []byte) ([]byte, error) {
+	switch contentType {
+	case MergePatchContentType:
+		return MergePatch(document, patch)
+	case JSONPatchContentType:
+		return JSONPatch(document, patch)
+	}
+	return nil, errs.Unsupported(fmt.Errorf("patches must be %s or %s", MergePatchContentType, JSONPatchContentType))
+}
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to a json document, members set to null in the patch are removed
+func MergePatch(document, patch []byte) ([]byte, error) {
+	var target, changes interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, errs.Validation(fmt.Errorf("invalid merge patch: %w", err))
+	}
+	return json.Marshal(mergeValue(target, changes))
+}
+
+// mergeValue returns the target merged with the patch, a patch that is not an object replaces the target
+func mergeValue(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	members, ok := target.(map[string]interface{})
+	if !ok {
+		members = map[string]interface{}{}
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(members, name)
+			continue
+		}
+		members[name] = mergeValue(members[name], value)
+	}
+	return members
+}
+
+// patchOperation is an operation of a JSON Patch, the value is kept raw so a null value can be told apart from a missing one
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSONPatch applies a JSON Patch (RFC 6902) to a json document. The operations are applied in order and the document is
+// only returned when all of them succeed
+func JSONPatch(document, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	var operations []patchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, errs.Validation(fmt.Errorf("invalid json patch: %w", err))
+	}
+	for i, operation := range operations {
+		var err error
+		if target, err = applyOperation(target, operation); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+// applyOperation applies one operation of a JSON Patch to the document and returns the changed document
+func applyOperation(document interface{}, operation patchOperation) (interface{}, error) {
+	if operation.Path == nil {
+		return nil, errs.Validation(fmt.Errorf("%s operation has no path", operation.Op))
+	}
+	path, err := parsePointer(*operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch operation.Op {
+	case "add", "replace", "test":
+		if len(operation.Value) == 0 {
+			return nil, errs.Validation(fmt.Errorf("%s operation has no value", operation.Op))
+		}
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, errs.Validation(fmt.Errorf("invalid value: %w", err))
+		}
+	case "move", "copy":
+		if operation.From == nil {
+			return nil, errs.Validation(fmt.Errorf("%s operation has no from", operation.Op))
+		}
+		from, err := parsePointer(*operation.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = pointerValue(document, from); err != nil {
+			return nil, err
+		}
+		if operation.Op == "copy" {
+			value = copyValue(value)
+			break
+		}
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, errs.Validation(fmt.Errorf("cannot move %s into one of its children", *operation.From))
+		}
+		if document, err = removeValue(document, from); err != nil {
+			return nil, err
+		}
+	case "remove":
+		return removeValue(document, path)
+	default:
+		return nil, errs.Validation(fmt.Errorf("unknown operation %q", operation.Op))
+	}
+	switch operation.Op {
+	case "replace":
+		if _, err := pointerValue(document, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		return changeParent(document, path, func(parent interface{}, key string) (interface{}, error) {
+			return setChild(parent, key, value), nil
+		})
+	case "test":
+		current, err := pointerValue(document, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, errs.Conflict(fmt.Errorf("test of %s failed", *operation.Path))
+		}
+		return document, nil
+	}
+	return addValue(document, path, value)
+}
+
+// parsePointer returns the reference tokens of a JSON Pointer (RFC 6901), the empty pointer is the whole document
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errs.Validation(fmt.Errorf("path %q must start with /", pointer))
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// pointerValue returns the value at the path of the document
+func pointerValue(document interface{}, path []string) (interface{}, error) {
+	value := document
+	for _, token := range path {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			member, ok := node[token]
+			if !ok {
+				return nil, errs.Conflict(fmt.Errorf("member %q does not exist", token))
+			}
+			value = member
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			value = node[i]
+		default:
+			return nil, errs.Conflict(fmt.Errorf("%q is not inside an object or array", token))
+		}
+	}
+	return value, nil
+}
+
+// addValue returns the document with the value added at the path, an array index inserts the value before the element
+// at that index and - appends it
+func addValue(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return changeParent(document, path, func(parent interface{}, key string) (interface{}, error) {
+		if members, ok := parent.(map[string]interface{}); ok {
+			members[key] = value
+			return members, nil
+		}
+		elements := parent.([]interface{})
+		i := len(elements)
+		if key != "-" {
+			var err error
+			if i, err = arrayIndex(key, len(elements)); err != nil {
+				return nil, err
+			}
+		}
+		added := make([]interface{}, 0, len(elements)+1)
+		added = append(append(append(added, elements[:i]...), value), elements[i:]...)
+		return added, nil
+	})
+}
+
+// removeValue returns the document without the value at the path
+func removeValue(document interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errs.Validation(errors.New("cannot remove the whole document"))
+	}
+	return changeParent(document, path, func(parent interface{}, key string) (interface{}, error) {
+		if members, ok := parent.(map[string]interface{}); ok {
+			if _, ok := members[key]; !ok {
+				return nil, errs.Conflict(fmt.Errorf("member %q does not exist", key))
+			}
+			delete(members, key)
+			return members, nil
+		}
+		elements := parent.([]interface{})
+		i, err := arrayIndex(key, len(elements)-1)
+		if err != nil {
+			return nil, err
+		}
+		removed := make([]interface{}, 0, len(elements)-1)
+		return append(append(removed, elements[:i]...), elements[i+1:]...), nil
+	})
+}
+
+// changeParent returns the node with the object or array that holds the last token of the path replaced by the result
+// of change. Arrays change length on add and remove, so every node on the way down gets its changed child set again
+func changeParent(node interface{}, path []string, change func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		switch node.(type) {
+		case map[string]interface{}, []interface{}:
+			return change(node, path[0])
+		}
+		return nil, errs.Conflict(fmt.Errorf("%q is not inside an object or array", path[0]))
+	}
+	child, err := pointerValue(node, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	changed, err := changeParent(child, path[1:], change)
+	if err != nil {
+		return nil, err
+	}
+	return setChild(node, path[0], changed), nil
+}
+
+// setChild sets the member or element of an object or array that is known to exist and returns the object or array
+func setChild(parent interface{}, key string, value interface{}) interface{} {
+	if members, ok := parent.(map[string]interface{}); ok {
+		members[key] = value
+		return members
+	}
+	elements := parent.([]interface{})
+	i, _ := strconv.Atoi(key)
+	elements[i] = value
+	return elements
+}
+
+// arrayIndex returns the index of an array token, which must be a number from 0 up to max without leading zeros
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, errs.Validation(fmt.Errorf("%q is not an array index", token))
+	}
+	if i > max {
+		return 0, errs.Conflict(fmt.Errorf("index %d is out of range", i))
+	}
+	return i, nil
+}
+
+// copyValue returns a deep copy of a decoded json value, so a copied value does not share objects or arrays with its source
+func copyValue(value interface{}) interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		members := make(map[string]interface{}, len(node))
+		for name, member := range node {
+			members[name] = copyValue(member)
+		}
+		return members
+	case []interface{}:
+		elements := make([]interface{}, len(node))
+		for i, element := range node {
+			elements[i] = copyValue(element)
+		}
+		return elements
+	}
+	return value
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"github.com/laertkokona/crud-test/errs"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// patchDocument is the document the patch tests are applied to
+const patchDocument = `{"name":"Hammer","price":9.99,"description":"Steel","tags":["a","b"],"size":{"width":1,"height":2}}`
+
+// TestMergePatch tests that a merge patch changes, adds and removes members, also inside nested objects
+func TestMergePatch(t *testing.T) {
+	patched, err := MergePatch([]byte(patchDocument), []byte(`{"price":0,"description":null,"tags":["c"],"size":{"height":null,"depth":3}}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Hammer","price":0,"tags":["c"],"size":{"width":1,"depth":3}}`, string(patched))
+}
+
+// TestMergePatch_Invalid tests that a merge patch that is not json is a validation error
+func TestMergePatch_Invalid(t *testing.T) {
+	_, err := MergePatch([]byte(patchDocument), []byte(`{`))
+	assert.Equal(t, errs.KindValidation, errs.KindOf(err))
+}
+
+// TestJSONPatch tests every operation of a json patch
+func TestJSONPatch(t *testing.T) {
+	patch := `[
+		{"op":"test","path":"/name","value":"Hammer"},
+		{"op":"replace","path":"/price","value":0},
+		{"op":"remove","path":"/description"},
+		{"op":"add","path":"/tags/1","value":"x"},
+		{"op":"add","path":"/tags/-","value":"z"},
+		{"op":"move","from":"/size/width","path":"/width"},
+		{"op":"copy","from":"/tags","path":"/labels"},
+		{"op":"remove","path":"/labels/0"}
+	]`
+	patched, err := JSONPatch([]byte(patchDocument), []byte(patch))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Hammer","price":0,"tags":["a","x","b","z"],"labels":["x","b","z"],"size":{"height":2},"width":1}`, string(patched))
+}
+
+// TestJSONPatch_EscapedPath tests that ~1 and ~0 in a path stand for / and ~
+func TestJSONPatch_EscapedPath(t *testing.T) {
+	patched, err := JSONPatch([]byte(`{"a/b":1,"c~d":2}`), []byte(`[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/c~0d"}]`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"a/b":3}`, string(patched))
+}
+
+// TestJSONPatch_Conflicts tests that operations that do not fit the document are conflicts
+func TestJSONPatch_Conflicts(t *testing.T) {
+	for _, patch := range []string{
+		`[{"op":"test","path":"/name","value":"Saw"}]`,
+		`[{"op":"replace","path":"/missing","value":1}]`,
+		`[{"op":"remove","path":"/tags/2"}]`,
+		`[{"op":"add","path":"/tags/3","value":"c"}]`,
+		`[{"op":"add","path":"/missing/child","value":1}]`,
+	} {
+		_, err := JSONPatch([]byte(patchDocument), []byte(patch))
+		assert.Equal(t, errs.KindConflict, errs.KindOf(err), patch)
+	}
+}
+
+// TestJSONPatch_Invalid tests that patches that are not valid are validation errors
+func TestJSONPatch_Invalid(t *testing.T) {
+	for _, patch := range []string{
+		`{"op":"remove","path":"/name"}`,
+		`[{"op":"rename","path":"/name"}]`,
+		`[{"op":"add","path":"/name"}]`,
+		`[{"op":"remove"}]`,
+		`[{"op":"remove","path":"name"}]`,
+		`[{"op":"move","path":"/size/width/x","from":"/size"}]`,
+		`[{"op":"remove","path":"/tags/01"}]`,
+	} {
+		_, err := JSONPatch([]byte(patchDocument), []byte(patch))
+		assert.Equal(t, errs.KindValidation, errs.KindOf(err), patch)
+	}
+}
+
+// TestJSONPatch_NullValue tests that a null value is set instead of being taken as a missing value
+func TestJSONPatch_NullValue(t *testing.T) {
+	patched, err := JSONPatch([]byte(patchDocument), []byte(`[{"op":"replace","path":"/description","value":null}]`))
+	assert.NoError(t, err)
+	assert.Contains(t, string(patched), `"description":null`)
+}
+
+// TestApplyPatch_Unsupported tests that a patch of another media type is unsupported
+func TestApplyPatch_Unsupported(t *testing.T) {
+	_, err := ApplyPatch("application/json", []byte(patchDocument), []byte(`{}`))
+	assert.Equal(t, errs.KindUnsupported, errs.KindOf(err))
+}
@@ -0,0 +1,51 @@
+package utils
+
+// Permission constants, each route requires one of them and orders:all lifts the restriction to the user's own orders
+const (
+	UsersRead      = "users:read"
+	UsersWrite     = "users:write"
+	RolesRead      = "roles:read"
+	RolesWrite     = "roles:write"
+	ItemsRead      = "items:read"
+	ItemsWrite     = "items:write"
+	StockWrite     = "stock:write"
+	TrucksRead     = "trucks:read"
+	TrucksWrite    = "trucks:write"
+	PlanningWrite  = "planning:write"
+	ShipmentsRead  = "shipments:read"
+	ShipmentsWrite = "shipments:write"
+	OrdersRead     = "orders:read"
+	OrdersWrite    = "orders:write"
+	OrdersAll      = "orders:all"
+	OrdersApprove  = "orders:approve"
+	OrdersFulfil   = "orders:fulfil"
+)
+
+// Permissions is every permission the routes know about
+var Permissions = []string{
+	UsersRead, UsersWrite,
+	RolesRead, RolesWrite,
+	ItemsRead, ItemsWrite, StockWrite,
+	TrucksRead, TrucksWrite, PlanningWrite,
+	ShipmentsRead, ShipmentsWrite,
+	OrdersRead, OrdersWrite, OrdersAll, OrdersApprove, OrdersFulfil,
+}
+
+// DefaultRolePermissions returns the permissions a built-in role starts with
+func DefaultRolePermissions(role int) []string {
+	switch role {
+	case User:
+		return []string{ItemsRead, OrdersRead, OrdersWrite}
+	case Admin:
+		return []string{
+			ItemsRead, ItemsWrite, StockWrite,
+			TrucksRead, TrucksWrite, PlanningWrite,
+			ShipmentsRead, ShipmentsWrite,
+			OrdersRead, OrdersAll, OrdersApprove, OrdersFulfil,
+		}
+	case SysAdmin:
+		return Permissions
+	default:
+		return nil
+	}
+}
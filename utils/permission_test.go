@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestDefaultRolePermissions tests the DefaultRolePermissions function
+func TestDefaultRolePermissions(t *testing.T) {
+	assert.ElementsMatch(t, Permissions, DefaultRolePermissions(SysAdmin), "Expected SysAdmin to have every permission")
+	assert.Contains(t, DefaultRolePermissions(Admin), TrucksWrite, "Expected Admin to manage trucks")
+	assert.NotContains(t, DefaultRolePermissions(User), ItemsWrite, "Expected User not to write items")
+	assert.Empty(t, DefaultRolePermissions(4), "Expected unknown role to have no permissions")
+	for _, role := range []int{User, Admin} {
+		for _, p := range DefaultRolePermissions(role) {
+			assert.Contains(t, Permissions, p, "Expected default permission to be known")
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package utils
+
+// TaxRates are the tax percents of the items by their category, the items of a category without a percent of its own
+// are taxed at the default percent
+type TaxRates struct {
+	Default    float64
+	Categories map[string]float64
+}
+
+// Percent returns the tax percent of the items of a category
+func (r TaxRates) Percent(category string) float64 {
+	if percent, ok := r.Categories[category]; ok {
+		return percent
+	}
+	return r.Default
+}
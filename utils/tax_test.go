@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestTaxRates_Percent tests that a category is taxed at its own percent and other categories at the default one
+func TestTaxRates_Percent(t *testing.T) {
+	rates := TaxRates{Default: 20, Categories: map[string]float64{"food": 5, "books": 0}}
+
+	assert.Equal(t, 5.0, rates.Percent("food"))
+	assert.Equal(t, 0.0, rates.Percent("books"))
+	assert.Equal(t, 20.0, rates.Percent("tools"))
+	assert.Equal(t, 0.0, TaxRates{}.Percent("tools"))
+}